@@ -0,0 +1,107 @@
+package announcer
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+var ErrCommandFailed = errors.New("announcement command failed")
+
+type inputSender interface {
+	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
+}
+
+type rconExecutor interface {
+	Exec(ctx context.Context, server *domain.Server, command string) (string, error)
+}
+
+// Announcer sends the messages about the scheduled actions to the players
+// through the game server console or RCON.
+type Announcer struct {
+	cfg     *config.Config
+	console inputSender
+	rcon    rconExecutor
+}
+
+func NewAnnouncer(cfg *config.Config, console inputSender, rcon rconExecutor) *Announcer {
+	return &Announcer{
+		cfg:     cfg,
+		console: console,
+		rcon:    rcon,
+	}
+}
+
+// AnnounceCountdown announces the action which will be executed after the left time.
+func (a *Announcer) AnnounceCountdown(
+	ctx context.Context, server *domain.Server, action string, left time.Duration,
+) error {
+	t := a.cfg.AnnouncementTemplate(server.Game().Code)
+
+	return a.send(ctx, server, t.Method, format(t.Countdown, action, left))
+}
+
+// AnnounceAborted announces the action won't be executed.
+func (a *Announcer) AnnounceAborted(ctx context.Context, server *domain.Server, action string) error {
+	t := a.cfg.AnnouncementTemplate(server.Game().Code)
+
+	return a.send(ctx, server, t.Method, format(t.Aborted, action, 0))
+}
+
+func (a *Announcer) send(ctx context.Context, server *domain.Server, method, command string) error {
+	if command == "" {
+		return nil
+	}
+
+	if method == config.CommandMethodRCON {
+		_, err := a.rcon.Exec(ctx, server, command)
+		if err != nil {
+			return errors.WithMessage(err, "[announcer.Announcer] failed to send announcement by RCON")
+		}
+
+		return nil
+	}
+
+	result, err := a.console.SendInput(ctx, command, server, io.Discard)
+	if err != nil {
+		return errors.WithMessage(err, "[announcer.Announcer] failed to send announcement to console")
+	}
+	if result != domain.SuccessResult {
+		return errors.WithMessage(ErrCommandFailed, "[announcer.Announcer] failed to send announcement to console")
+	}
+
+	return nil
+}
+
+func format(template, action string, left time.Duration) string {
+	return strings.NewReplacer(
+		"{action}", action,
+		"{time}", formatDuration(left),
+	).Replace(template)
+}
+
+// formatDuration formats the duration in the largest whole units, e.g. "2 hours", "5 minutes", "90 seconds".
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	case d >= time.Minute && d%time.Minute == 0:
+		return plural(int(d/time.Minute), "minute")
+	default:
+		return plural(int(d.Round(time.Second)/time.Second), "second")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return strconv.Itoa(n) + " " + unit + "s"
+}
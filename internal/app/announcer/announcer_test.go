@@ -0,0 +1,88 @@
+package announcer
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type inputSenderMock struct {
+	inputs []string
+}
+
+func (m *inputSenderMock) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	m.inputs = append(m.inputs, input)
+
+	return domain.SuccessResult, nil
+}
+
+type rconMock struct {
+	commands []string
+}
+
+func (m *rconMock) Exec(_ context.Context, _ *domain.Server, command string) (string, error) {
+	m.commands = append(m.commands, command)
+
+	return "", nil
+}
+
+func TestAnnouncer_Console(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Announcements.AnnouncementTemplate = config.AnnouncementTemplate{
+		Method:    config.CommandMethodConsole,
+		Countdown: "say Server {action} in {time}",
+		Aborted:   "say Server {action} is aborted",
+	}
+	console := &inputSenderMock{}
+	rcon := &rconMock{}
+	a := NewAnnouncer(cfg, console, rcon)
+
+	err := a.AnnounceCountdown(context.Background(), givenServer("cstrike"), "restart", 10*time.Minute)
+	require.NoError(t, err)
+	err = a.AnnounceAborted(context.Background(), givenServer("cstrike"), "restart")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"say Server restart in 10 minutes", "say Server restart is aborted"}, console.inputs)
+	assert.Empty(t, rcon.commands)
+}
+
+func TestAnnouncer_GameTemplateByRCON(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Announcements.AnnouncementTemplate = config.AnnouncementTemplate{
+		Method:    config.CommandMethodConsole,
+		Countdown: "say Server {action} in {time}",
+	}
+	cfg.Announcements.Games = map[string]config.AnnouncementTemplate{
+		"ark": {Method: config.CommandMethodRCON, Countdown: "broadcast {action} in {time}"},
+	}
+	console := &inputSenderMock{}
+	rcon := &rconMock{}
+	a := NewAnnouncer(cfg, console, rcon)
+
+	err := a.AnnounceCountdown(context.Background(), givenServer("ark"), "stop", time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"broadcast stop in 1 minute"}, rcon.commands)
+	assert.Empty(t, console.inputs)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "2 hours", formatDuration(2*time.Hour))
+	assert.Equal(t, "90 minutes", formatDuration(90*time.Minute))
+	assert.Equal(t, "1 minute", formatDuration(time.Minute))
+	assert.Equal(t, "90 seconds", formatDuration(90*time.Second))
+	assert.Equal(t, "1 second", formatDuration(time.Second))
+}
+
+func givenServer(game string) *domain.Server {
+	return domaintest.NewServer(domaintest.WithGame(domain.Game{Code: game}))
+}
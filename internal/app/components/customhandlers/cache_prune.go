@@ -0,0 +1,41 @@
+package customhandlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/pkg/errors"
+)
+
+type CachePrune struct {
+	cache *gamecache.Cache
+}
+
+func NewCachePrune(cache *gamecache.Cache) *CachePrune {
+	return &CachePrune{cache: cache}
+}
+
+func (p *CachePrune) Handle(ctx context.Context, _ []string, out io.Writer, _ contracts.ExecutorOptions) (int, error) {
+	result, err := p.cache.Prune(ctx)
+	if err != nil {
+		return int(domain.ErrorResult), errors.WithMessage(err, "[components.CachePrune] failed to prune game cache")
+	}
+
+	for _, key := range result.Removed {
+		_, _ = fmt.Fprintf(out, "Removed %s\n", key)
+	}
+
+	_, _ = fmt.Fprintf(
+		out,
+		"Removed %d entries, freed %d MB, skipped %d entries in use\n",
+		len(result.Removed),
+		result.Freed/(1024*1024),
+		result.Skipped,
+	)
+
+	return int(domain.SuccessResult), nil
+}
@@ -9,16 +9,18 @@ import (
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/verification"
 	"github.com/hashicorp/go-getter"
 	"github.com/pkg/errors"
 )
 
 type GetTool struct {
-	cfg *config.Config
+	cfg      *config.Config
+	verifier contracts.Verifier
 }
 
-func NewGetTool(cfg *config.Config) *GetTool {
-	return &GetTool{cfg: cfg}
+func NewGetTool(cfg *config.Config, verifier contracts.Verifier) *GetTool {
+	return &GetTool{cfg: cfg, verifier: verifier}
 }
 
 func (g *GetTool) Handle(ctx context.Context, args []string, out io.Writer, _ contracts.ExecutorOptions) (int, error) {
@@ -27,27 +29,68 @@ func (g *GetTool) Handle(ctx context.Context, args []string, out io.Writer, _ co
 	}
 
 	source := args[0]
+
+	if g.verifier.Required() && !verification.IsVerifiableSource(source) {
+		return int(domain.ErrorResult), verification.ErrUnverifiable
+	}
+
 	fileName := filepath.Base(source)
 	destination := filepath.Join(g.cfg.ToolsPath, fileName)
 
+	// The tool is downloaded next to the destination and replaces it only after the verification,
+	// so the installed tool is kept if the download or the verification fails
+	tmp, err := makeTempFile(g.cfg.ToolsPath, fileName)
+	if err != nil {
+		return int(domain.ErrorResult), errors.WithMessage(err, "[components.GetTool] failed to create temporary file")
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
 	c := getter.Client{
 		Ctx:  ctx,
 		Src:  args[0],
-		Dst:  destination,
+		Dst:  tmp,
 		Mode: getter.ClientModeFile,
 	}
 
 	_, _ = out.Write([]byte("Getting tool from " + source + " to " + destination + " ..."))
-	err := c.Get()
+	err = c.Get()
 	if err != nil {
 		return int(domain.ErrorResult), errors.WithMessage(err, "[components.GetTool] failed to get tool")
 	}
 
-	err = os.Chmod(destination, 0700)
+	err = g.verifier.Verify(ctx, source, tmp, out)
+	if err != nil {
+		_, _ = out.Write([]byte("Tool verification failed: " + err.Error()))
+		return int(domain.ErrorResult), errors.WithMessage(err, "[components.GetTool] failed to verify tool")
+	}
+
+	err = os.Chmod(tmp, 0700)
 	if err != nil {
 		_, _ = out.Write([]byte("Failed to chmod tool"))
 		return int(domain.ErrorResult), errors.WithMessage(err, "[components.GetTool] failed to chmod tool")
 	}
 
+	err = os.Rename(tmp, destination)
+	if err != nil {
+		_, _ = out.Write([]byte("Failed to replace tool"))
+		return int(domain.ErrorResult), errors.WithMessage(err, "[components.GetTool] failed to replace tool")
+	}
+
 	return int(domain.SuccessResult), nil
 }
+
+func makeTempFile(dir, name string) (string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
@@ -0,0 +1,75 @@
+package customhandlers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/components/customhandlers"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVerifier struct {
+	err error
+}
+
+func (v *fakeVerifier) Required() bool {
+	return false
+}
+
+func (v *fakeVerifier) Verify(_ context.Context, _, _ string, _ io.Writer) error {
+	return v.err
+}
+
+func Test_GetTool(t *testing.T) {
+	tests := []struct {
+		name         string
+		verifyErr    error
+		wantExitCode int
+		wantContent  string
+	}{
+		{
+			name:         "success, tool replaced",
+			wantExitCode: int(domain.SuccessResult),
+			wantContent:  "#!/bin/sh\necho new\n",
+		},
+		{
+			name:         "verification failed, installed tool kept",
+			verifyErr:    assert.AnError,
+			wantExitCode: int(domain.ErrorResult),
+			wantContent:  "#!/bin/sh\necho old\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// ARRANGE
+			source := filepath.Join(t.TempDir(), "tool.sh")
+			require.NoError(t, os.WriteFile(source, []byte("#!/bin/sh\necho new\n"), 0600))
+			cfg := &config.Config{ToolsPath: t.TempDir()}
+			installed := filepath.Join(cfg.ToolsPath, "tool.sh")
+			require.NoError(t, os.WriteFile(installed, []byte("#!/bin/sh\necho old\n"), 0700))
+
+			getTool := customhandlers.NewGetTool(cfg, &fakeVerifier{err: test.verifyErr})
+
+			// ACT
+			result, _ := getTool.Handle(context.Background(), []string{source}, &bytes.Buffer{}, contracts.ExecutorOptions{})
+
+			// ASSERT
+			assert.Equal(t, test.wantExitCode, result)
+			content, err := os.ReadFile(installed)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantContent, string(content))
+			entries, err := os.ReadDir(cfg.ToolsPath)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package customhandlers
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/processmanager"
+	"github.com/pkg/errors"
+)
+
+//go:generate go run go.uber.org/mock/mockgen -source=pm_migration.go -destination=pm_migration_mock_test.go -package=customhandlers_test
+type processManagerMigrator interface {
+	Migrate(ctx context.Context, servers []*domain.Server, options processmanager.MigrationOptions, out io.Writer) error
+}
+
+type serverLister interface {
+	IDs(ctx context.Context) ([]int, error)
+	FindByID(ctx context.Context, id int) (*domain.Server, error)
+}
+
+// ProcessManagerMigration moves the running game servers from the previous process managers to the current one.
+// Arguments: --server=<id> (all game servers of the node if not set), --from=<name>[,<name>...], --dry-run.
+type ProcessManagerMigration struct {
+	migrator   processManagerMigrator
+	serverRepo serverLister
+}
+
+func NewProcessManagerMigration(migrator processManagerMigrator, serverRepo serverLister) *ProcessManagerMigration {
+	return &ProcessManagerMigration{
+		migrator:   migrator,
+		serverRepo: serverRepo,
+	}
+}
+
+func (m *ProcessManagerMigration) Handle(
+	ctx context.Context, args []string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	options := processmanager.MigrationOptions{}
+	var serverIDs []int
+
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+
+		switch name {
+		case "--dry-run":
+			options.DryRun = true
+		case "--from":
+			for _, pm := range strings.Split(value, ",") {
+				if pm = strings.TrimSpace(pm); pm != "" {
+					options.From = append(options.From, pm)
+				}
+			}
+		case "--server":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return int(domain.ErrorResult), errors.New("invalid server id, should be integer")
+			}
+			serverIDs = append(serverIDs, id)
+		default:
+			return int(domain.ErrorResult), errors.Errorf("unknown argument %s", arg)
+		}
+	}
+
+	if len(serverIDs) == 0 {
+		var err error
+		serverIDs, err = m.serverRepo.IDs(ctx)
+		if err != nil {
+			return int(domain.ErrorResult), errors.WithMessage(err, "failed to get servers")
+		}
+	}
+
+	servers := make([]*domain.Server, 0, len(serverIDs))
+	for _, id := range serverIDs {
+		server, err := m.serverRepo.FindByID(ctx, id)
+		if err != nil {
+			return int(domain.ErrorResult), errors.WithMessage(err, "failed to get server")
+		}
+
+		if server == nil {
+			return int(domain.ErrorResult), errors.Errorf("server %d not found", id)
+		}
+
+		servers = append(servers, server)
+	}
+
+	err := m.migrator.Migrate(ctx, servers, options, out)
+	if err != nil {
+		return int(domain.ErrorResult), errors.WithMessage(err, "[components.ProcessManagerMigration] migration failed")
+	}
+
+	return int(domain.SuccessResult), nil
+}
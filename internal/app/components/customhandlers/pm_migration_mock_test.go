@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pm_migration.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pm_migration.go -destination=pm_migration_mock_test.go -package=customhandlers_test
+//
+
+// Package customhandlers_test is a generated GoMock package.
+package customhandlers_test
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	domain "github.com/gameap/daemon/internal/app/domain"
+	processmanager "github.com/gameap/daemon/internal/processmanager"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockprocessManagerMigrator is a mock of processManagerMigrator interface.
+type MockprocessManagerMigrator struct {
+	ctrl     *gomock.Controller
+	recorder *MockprocessManagerMigratorMockRecorder
+}
+
+// MockprocessManagerMigratorMockRecorder is the mock recorder for MockprocessManagerMigrator.
+type MockprocessManagerMigratorMockRecorder struct {
+	mock *MockprocessManagerMigrator
+}
+
+// NewMockprocessManagerMigrator creates a new mock instance.
+func NewMockprocessManagerMigrator(ctrl *gomock.Controller) *MockprocessManagerMigrator {
+	mock := &MockprocessManagerMigrator{ctrl: ctrl}
+	mock.recorder = &MockprocessManagerMigratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockprocessManagerMigrator) EXPECT() *MockprocessManagerMigratorMockRecorder {
+	return m.recorder
+}
+
+// Migrate mocks base method.
+func (m *MockprocessManagerMigrator) Migrate(ctx context.Context, servers []*domain.Server, options processmanager.MigrationOptions, out io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Migrate", ctx, servers, options, out)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Migrate indicates an expected call of Migrate.
+func (mr *MockprocessManagerMigratorMockRecorder) Migrate(ctx, servers, options, out any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockprocessManagerMigrator)(nil).Migrate), ctx, servers, options, out)
+}
+
+// MockserverLister is a mock of serverLister interface.
+type MockserverLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockserverListerMockRecorder
+}
+
+// MockserverListerMockRecorder is the mock recorder for MockserverLister.
+type MockserverListerMockRecorder struct {
+	mock *MockserverLister
+}
+
+// NewMockserverLister creates a new mock instance.
+func NewMockserverLister(ctrl *gomock.Controller) *MockserverLister {
+	mock := &MockserverLister{ctrl: ctrl}
+	mock.recorder = &MockserverListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockserverLister) EXPECT() *MockserverListerMockRecorder {
+	return m.recorder
+}
+
+// FindByID mocks base method.
+func (m *MockserverLister) FindByID(ctx context.Context, id int) (*domain.Server, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByID", ctx, id)
+	ret0, _ := ret[0].(*domain.Server)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByID indicates an expected call of FindByID.
+func (mr *MockserverListerMockRecorder) FindByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*MockserverLister)(nil).FindByID), ctx, id)
+}
+
+// IDs mocks base method.
+func (m *MockserverLister) IDs(ctx context.Context) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IDs", ctx)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IDs indicates an expected call of IDs.
+func (mr *MockserverListerMockRecorder) IDs(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IDs", reflect.TypeOf((*MockserverLister)(nil).IDs), ctx)
+}
@@ -0,0 +1,158 @@
+package customhandlers_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/components/customhandlers"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/processmanager"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_ProcessManagerMigration(t *testing.T) {
+	server1 := &domain.Server{}
+	server2 := &domain.Server{}
+
+	tests := []struct {
+		name           string
+		migratorMock   func(ctrl *gomock.Controller) *MockprocessManagerMigrator
+		serverRepoMock func(ctrl *gomock.Controller) *MockserverLister
+		args           []string
+		wantExitCode   int
+		wantErr        string
+	}{
+		{
+			name:         "unknown argument",
+			args:         []string{"--force"},
+			wantExitCode: int(domain.ErrorResult),
+			wantErr:      "unknown argument --force",
+		},
+		{
+			name:         "invalid server id, should be integer",
+			args:         []string{"--server=abc"},
+			wantExitCode: int(domain.ErrorResult),
+			wantErr:      "invalid server id, should be integer",
+		},
+		{
+			name: "failed to get servers",
+			args: []string{},
+			serverRepoMock: func(ctrl *gomock.Controller) *MockserverLister {
+				m := NewMockserverLister(ctrl)
+				m.EXPECT().IDs(gomock.Any()).Return(nil, assert.AnError)
+				return m
+			},
+			wantExitCode: int(domain.ErrorResult),
+			wantErr:      "failed to get servers",
+		},
+		{
+			name: "server not found",
+			args: []string{"--server=1"},
+			serverRepoMock: func(ctrl *gomock.Controller) *MockserverLister {
+				m := NewMockserverLister(ctrl)
+				m.EXPECT().FindByID(gomock.Any(), 1).Return(nil, nil)
+				return m
+			},
+			wantExitCode: int(domain.ErrorResult),
+			wantErr:      "server 1 not found",
+		},
+		{
+			name: "migration failed",
+			args: []string{"--server=1"},
+			serverRepoMock: func(ctrl *gomock.Controller) *MockserverLister {
+				m := NewMockserverLister(ctrl)
+				m.EXPECT().FindByID(gomock.Any(), 1).Return(server1, nil)
+				return m
+			},
+			migratorMock: func(ctrl *gomock.Controller) *MockprocessManagerMigrator {
+				m := NewMockprocessManagerMigrator(ctrl)
+				m.EXPECT().Migrate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError)
+				return m
+			},
+			wantExitCode: int(domain.ErrorResult),
+			wantErr:      "migration failed",
+		},
+		{
+			name: "success, all servers",
+			args: []string{},
+			serverRepoMock: func(ctrl *gomock.Controller) *MockserverLister {
+				m := NewMockserverLister(ctrl)
+				m.EXPECT().IDs(gomock.Any()).Return([]int{1, 2}, nil)
+				m.EXPECT().FindByID(gomock.Any(), 1).Return(server1, nil)
+				m.EXPECT().FindByID(gomock.Any(), 2).Return(server2, nil)
+				return m
+			},
+			migratorMock: func(ctrl *gomock.Controller) *MockprocessManagerMigrator {
+				m := NewMockprocessManagerMigrator(ctrl)
+				m.EXPECT().Migrate(
+					gomock.Any(),
+					[]*domain.Server{server1, server2},
+					processmanager.MigrationOptions{},
+					gomock.Any(),
+				).Return(nil)
+				return m
+			},
+			wantExitCode: int(domain.SuccessResult),
+		},
+		{
+			name: "success, with options",
+			args: []string{"--server=2", "--from=tmux, screen", "--dry-run"},
+			serverRepoMock: func(ctrl *gomock.Controller) *MockserverLister {
+				m := NewMockserverLister(ctrl)
+				m.EXPECT().FindByID(gomock.Any(), 2).Return(server2, nil)
+				return m
+			},
+			migratorMock: func(ctrl *gomock.Controller) *MockprocessManagerMigrator {
+				m := NewMockprocessManagerMigrator(ctrl)
+				m.EXPECT().Migrate(
+					gomock.Any(),
+					[]*domain.Server{server2},
+					processmanager.MigrationOptions{From: []string{"tmux", "screen"}, DryRun: true},
+					gomock.Any(),
+				).Return(nil)
+				return m
+			},
+			wantExitCode: int(domain.SuccessResult),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// ARRANGE
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var migratorMock *MockprocessManagerMigrator
+			var serverRepoMock *MockserverLister
+
+			if test.migratorMock != nil {
+				migratorMock = test.migratorMock(ctrl)
+			}
+			if test.serverRepoMock != nil {
+				serverRepoMock = test.serverRepoMock(ctrl)
+			}
+
+			m := customhandlers.NewProcessManagerMigration(
+				migratorMock,
+				serverRepoMock,
+			)
+			out := new(bytes.Buffer)
+
+			// ACT
+			result, err := m.Handle(context.Background(), test.args, out, contracts.ExecutorOptions{})
+
+			// ASSERT
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, test.wantExitCode, result)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				assert.Equal(t, test.wantExitCode, result)
+			}
+		})
+	}
+}
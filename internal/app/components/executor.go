@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -136,6 +137,17 @@ func ExecWithWriter(
 		}
 	}
 
+	// Canceled commands are killed with their child processes
+	setCMDProcessTreeKill(cmd)
+
+	if len(options.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+
+		cmd.Env = append(cmd.Env, envList(options.Env)...)
+	}
+
 	var exitError *exec.ExitError
 	err = cmd.Run()
 	if err != nil && !errors.As(err, &exitError) {
@@ -147,3 +159,15 @@ func ExecWithWriter(
 
 	return cmd.ProcessState.ExitCode(), nil
 }
+
+// envList returns the variables in the KEY=value form sorted by name.
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for key, value := range env {
+		list = append(list, key+"="+value)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
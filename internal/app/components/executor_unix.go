@@ -40,3 +40,16 @@ func setCMDSysProcCredential(cmd *exec.Cmd, options contracts.ExecutorOptions) (
 
 	return cmd, nil
 }
+
+// setCMDProcessTreeKill runs the command in its own process group,
+// so the whole group is killed when the context is done.
+func setCMDProcessTreeKill(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package components_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecWithWriter_ContextCanceled_ProcessTreeKilled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	out := components.NewSafeBuffer()
+	startedAt := time.Now()
+
+	// The background sleep keeps the output open, the command returns only if it's killed too
+	_, err := components.ExecWithWriter(ctx, `sh -c "sleep 30 & sleep 30"`, out, contracts.ExecutorOptions{
+		WorkDir: t.TempDir(),
+	})
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(startedAt), 10*time.Second)
+}
@@ -6,12 +6,20 @@ package components
 import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"os/exec"
+	"strconv"
 )
 
 func setCMDSysProcCredential(cmd *exec.Cmd, _ contracts.ExecutorOptions) (*exec.Cmd, error) {
 	return cmd, nil
 }
 
+// setCMDProcessTreeKill kills the command with its child processes when the context is done.
+func setCMDProcessTreeKill(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
+
 //nolint:lll
 //func ExecWithWriter(ctx context.Context, command string, out io.Writer, options contracts.ExecutorOptions) (int, error) {
 //	if command == "" {
@@ -12,6 +12,7 @@ import (
 	"github.com/gameap/daemon/internal/app/components/customhandlers"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/verification"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,9 +26,10 @@ func TestExtendableExecutor_ExecGetTool_ExpectToolDownloaded(t *testing.T) {
 		}
 	}(tmpDir)
 	executor := components.NewDefaultExtendableExecutor(components.NewCleanExecutor())
-	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(&config.Config{
+	cfg := &config.Config{
 		ToolsPath: tmpDir,
-	}).Handle)
+	}
+	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(cfg, verification.NewVerifier(cfg)).Handle)
 
 	result, code, err := executor.Exec(
 		context.Background(),
@@ -6,6 +6,30 @@ import (
 	"time"
 )
 
+// Game cache link modes define how cached game files are placed into the game server directory.
+const (
+	// GameCacheLinkModeAuto clones files on filesystems supporting reflinks (Btrfs, XFS) and copies them otherwise.
+	GameCacheLinkModeAuto     = "auto"
+	GameCacheLinkModeReflink  = "reflink"
+	GameCacheLinkModeHardlink = "hardlink"
+	GameCacheLinkModeCopy     = "copy"
+)
+
+// Task priorities, tasks with the higher priority are started first.
+const (
+	TaskPriorityHigh   = "high"
+	TaskPriorityNormal = "normal"
+	TaskPriorityLow    = "low"
+)
+
+const defaultTaskWorkersCount = 10
+
+// Command methods define how the commands are sent to the game server.
+const (
+	CommandMethodConsole = "console"
+	CommandMethodRCON    = "rcon"
+)
+
 type Scripts struct {
 	Install     string
 	Reinstall   string
@@ -27,6 +51,35 @@ type SteamConfig struct {
 	Password string `yaml:"password"`
 }
 
+// AnnouncementTemplate contains the game server commands announcing the scheduled action.
+// The {action} (restart, stop etc.) and {time} (e.g. "5 minutes") placeholders are replaced in the commands.
+type AnnouncementTemplate struct {
+	Method    string `yaml:"method"`
+	Countdown string `yaml:"countdown"`
+	Aborted   string `yaml:"aborted"`
+}
+
+// GracefulStopCommand is the game server command stopping the game server, e.g. "stop" or "quit".
+type GracefulStopCommand struct {
+	Method  string `yaml:"method"`
+	Command string `yaml:"command"`
+}
+
+// Stop signals allowed in graceful_stop.signal.
+var stopSignals = map[string]struct{}{
+	"SIGTERM": {},
+	"SIGINT":  {},
+	"SIGQUIT": {},
+	"SIGHUP":  {},
+	"SIGUSR1": {},
+	"SIGUSR2": {},
+}
+
+// defaultGracefulStopCommands are the stop commands of the games losing data if they're terminated by the signal.
+var defaultGracefulStopCommands = map[string]GracefulStopCommand{
+	"minecraft": {Command: "stop"},
+}
+
 //nolint:govet
 type Config struct {
 	NodeID uint `yaml:"ds_id"`
@@ -37,6 +90,17 @@ type Config struct {
 	APIHost string `yaml:"api_host"`
 	APIKey  string `yaml:"api_key"`
 
+	API struct {
+		RetryCount       int           `yaml:"retry_count"`
+		RetryWaitTime    time.Duration `yaml:"retry_wait_time"`
+		RetryMaxWaitTime time.Duration `yaml:"retry_max_wait_time"`
+
+		CircuitBreaker struct {
+			FailureThreshold int           `yaml:"failure_threshold"`
+			OpenTimeout      time.Duration `yaml:"open_timeout"`
+		} `yaml:"circuit_breaker"`
+	} `yaml:"api"`
+
 	DaemonLogin            string `yaml:"daemon_login"`
 	DaemonPassword         string `yaml:"daemon_password"`
 	PasswordAuthentication bool   `yaml:"password_authentication"`
@@ -55,8 +119,17 @@ type Config struct {
 
 	// Log config
 	LogLevel  string `yaml:"log_level"`
+	LogFormat string `yaml:"log_format"`
 	OutputLog string `yaml:"output_log"`
 	ErrorLog  string `yaml:"error_log"`
+	LogsPath  string `yaml:"logs_path"`
+
+	LogRotation struct {
+		MaxSize    int  `yaml:"max_size"`
+		MaxAge     int  `yaml:"max_age"`
+		MaxBackups int  `yaml:"max_backups"`
+		Compress   bool `yaml:"compress"`
+	} `yaml:"log_rotation"`
 
 	// Dedicated server config
 	Path7zip    string `yaml:"path_7zip"`
@@ -74,13 +147,95 @@ type Config struct {
 		UpdatePeriod  time.Duration `yaml:"update_period"`
 		RunTaskPeriod time.Duration `yaml:"run_task_period"`
 		WorkersCount  int           `yaml:"workers_count"`
+
+		// DefaultTimeout limits the execution time of the tasks without own timeout, zero means no limit.
+		DefaultTimeout time.Duration `yaml:"default_timeout"`
+		// Timeouts by task command (gsinst, gsupd, cmdexec, etc.), they override DefaultTimeout.
+		Timeouts map[string]time.Duration `yaml:"timeouts"`
+		// Priorities by task command: high, normal or low.
+		Priorities map[string]string `yaml:"priorities"`
+		// Concurrency limits the number of working tasks by command, "steamcmd" limits the tasks running SteamCMD.
+		Concurrency map[string]int `yaml:"concurrency"`
 	} `yaml:"task_manager"`
 
+	Announcements struct {
+		// Intervals before the server task, e.g. 10m, 5m, 1m. No intervals means no announcements.
+		Intervals []time.Duration `yaml:"intervals"`
+		// Server task commands to announce: stop, restart, update, reinstall.
+		Commands []string `yaml:"commands"`
+
+		AnnouncementTemplate `yaml:",inline"`
+
+		// Templates by game code, empty fields are taken from the default template.
+		Games map[string]AnnouncementTemplate `yaml:"games"`
+	} `yaml:"announcements"`
+
+	GracefulStop struct {
+		// Timeout to wait for the game server to stop after the stop command and after the signal.
+		Timeout time.Duration `yaml:"timeout"`
+		// Signal sent to the game server if the stop command isn't configured or the timeout is exceeded.
+		Signal string `yaml:"signal"`
+
+		GracefulStopCommand `yaml:",inline"`
+
+		// Stop commands by game code, empty method is taken from the default one.
+		Games map[string]GracefulStopCommand `yaml:"games"`
+	} `yaml:"graceful_stop"`
+
 	ProcessManager struct {
 		Name   string            `yaml:"name"`
 		Config map[string]string `yaml:"config"`
 	} `yaml:"process_manager"`
 
+	Push struct {
+		Enabled         bool          `yaml:"enabled"`
+		URL             string        `yaml:"url"`
+		ReconnectPeriod time.Duration `yaml:"reconnect_period"`
+		FallbackPeriod  time.Duration `yaml:"fallback_period"`
+	} `yaml:"push"`
+
+	Metrics struct {
+		Enabled       bool   `yaml:"enabled"`
+		ListenAddress string `yaml:"listen_address"`
+	} `yaml:"metrics"`
+
+	GameCache struct {
+		Enabled  bool          `yaml:"enabled"`
+		Path     string        `yaml:"path"`
+		LinkMode string        `yaml:"link_mode"`
+		MaxSize  int           `yaml:"max_size"`
+		MaxAge   time.Duration `yaml:"max_age"`
+	} `yaml:"game_cache"`
+
+	ConfigTemplates struct {
+		Enabled bool   `yaml:"enabled"`
+		Path    string `yaml:"path"`
+	} `yaml:"config_templates"`
+
+	Ports struct {
+		SkipStartCheck bool `yaml:"skip_start_check"`
+		RangeStart     int  `yaml:"range_start"`
+		RangeEnd       int  `yaml:"range_end"`
+	} `yaml:"ports"`
+
+	Update struct {
+		InPlace          bool          `yaml:"in_place"`
+		StartGracePeriod time.Duration `yaml:"start_grace_period"`
+	} `yaml:"update"`
+
+	Verification struct {
+		RequireChecksum  bool     `yaml:"require_checksum"`
+		RequireSignature bool     `yaml:"require_signature"`
+		MinisignKeys     []string `yaml:"minisign_keys"`
+		GPGKeys          []string `yaml:"gpg_keys"`
+	} `yaml:"verification"`
+
+	Offline struct {
+		Enabled      bool          `yaml:"enabled"`
+		StoreFile    string        `yaml:"store_file"`
+		ReplayPeriod time.Duration `yaml:"replay_period"`
+	} `yaml:"offline"`
+
 	Users map[string]string `yaml:"users"`
 }
 
@@ -98,6 +253,26 @@ func (cfg *Config) Init() error {
 		cfg.ToolsPath = filepath.Join(cfg.WorkPath, "tools")
 	}
 
+	if cfg.API.RetryCount == 0 {
+		cfg.API.RetryCount = 5
+	}
+
+	if cfg.API.RetryWaitTime == 0 {
+		cfg.API.RetryWaitTime = 500 * time.Millisecond
+	}
+
+	if cfg.API.RetryMaxWaitTime == 0 {
+		cfg.API.RetryMaxWaitTime = 30 * time.Second
+	}
+
+	if cfg.API.CircuitBreaker.FailureThreshold == 0 {
+		cfg.API.CircuitBreaker.FailureThreshold = 5
+	}
+
+	if cfg.API.CircuitBreaker.OpenTimeout == 0 {
+		cfg.API.CircuitBreaker.OpenTimeout = 30 * time.Second
+	}
+
 	if cfg.TaskManager.UpdatePeriod == 0 {
 		cfg.TaskManager.UpdatePeriod = 1 * time.Second
 	}
@@ -106,10 +281,102 @@ func (cfg *Config) Init() error {
 		cfg.TaskManager.RunTaskPeriod = 10 * time.Millisecond
 	}
 
+	if cfg.TaskManager.WorkersCount == 0 {
+		cfg.TaskManager.WorkersCount = defaultTaskWorkersCount
+	}
+
+	if cfg.Announcements.Commands == nil {
+		cfg.Announcements.Commands = []string{"stop", "restart"}
+	}
+
+	if cfg.Announcements.Method == "" {
+		cfg.Announcements.Method = CommandMethodConsole
+	}
+
+	if cfg.Announcements.Countdown == "" {
+		cfg.Announcements.Countdown = "say Server {action} in {time}"
+	}
+
+	if cfg.Announcements.Aborted == "" {
+		cfg.Announcements.Aborted = "say Server {action} is aborted"
+	}
+
+	if cfg.GracefulStop.Timeout == 0 {
+		cfg.GracefulStop.Timeout = 30 * time.Second
+	}
+
+	if cfg.GracefulStop.Signal == "" {
+		cfg.GracefulStop.Signal = "SIGTERM"
+	}
+
+	if cfg.GracefulStop.Method == "" {
+		cfg.GracefulStop.Method = CommandMethodConsole
+	}
+
+	for game, c := range defaultGracefulStopCommands {
+		if _, ok := cfg.GracefulStop.Games[game]; ok {
+			continue
+		}
+
+		if cfg.GracefulStop.Games == nil {
+			cfg.GracefulStop.Games = map[string]GracefulStopCommand{}
+		}
+
+		cfg.GracefulStop.Games[game] = c
+	}
+
 	if cfg.ProcessManager.Name == "" {
 		cfg.ProcessManager.Name = defaultProcessManager
 	}
 
+	if cfg.Push.URL == "" {
+		cfg.Push.URL = "/gdaemon_api/events"
+	}
+
+	if cfg.Push.ReconnectPeriod == 0 {
+		cfg.Push.ReconnectPeriod = 5 * time.Second
+	}
+
+	if cfg.Push.FallbackPeriod == 0 {
+		cfg.Push.FallbackPeriod = 1 * time.Minute
+	}
+
+	if cfg.Metrics.ListenAddress == "" {
+		cfg.Metrics.ListenAddress = "127.0.0.1:31718"
+	}
+
+	if cfg.GameCache.Path == "" {
+		cfg.GameCache.Path = filepath.Join(cfg.WorkPath, ".cache", "games")
+	}
+
+	if cfg.GameCache.LinkMode == "" {
+		cfg.GameCache.LinkMode = GameCacheLinkModeAuto
+	}
+
+	if cfg.ConfigTemplates.Path == "" {
+		cfg.ConfigTemplates.Path = filepath.Join(cfg.WorkPath, ".templates")
+	}
+
+	if cfg.Ports.RangeStart == 0 {
+		cfg.Ports.RangeStart = 27015
+	}
+
+	if cfg.Ports.RangeEnd == 0 {
+		cfg.Ports.RangeEnd = 27999
+	}
+
+	if cfg.Update.StartGracePeriod == 0 {
+		cfg.Update.StartGracePeriod = 30 * time.Second
+	}
+
+	if cfg.Offline.StoreFile == "" {
+		cfg.Offline.StoreFile = defaultOfflineStoreFile
+	}
+
+	if cfg.Offline.ReplayPeriod == 0 {
+		cfg.Offline.ReplayPeriod = 10 * time.Second
+	}
+
 	return cfg.validate()
 }
 
@@ -126,6 +393,83 @@ func (cfg *Config) validate() error {
 		return ErrEmptyAPIKey
 	}
 
+	switch cfg.GameCache.LinkMode {
+	case GameCacheLinkModeAuto, GameCacheLinkModeReflink, GameCacheLinkModeHardlink, GameCacheLinkModeCopy:
+	default:
+		return ErrInvalidGameCacheLinkMode
+	}
+
+	if cfg.TaskManager.DefaultTimeout < 0 {
+		return ErrInvalidTaskTimeout
+	}
+
+	for _, timeout := range cfg.TaskManager.Timeouts {
+		if timeout < 0 {
+			return ErrInvalidTaskTimeout
+		}
+	}
+
+	if cfg.TaskManager.WorkersCount < 0 {
+		return ErrInvalidTaskWorkersCount
+	}
+
+	for _, p := range cfg.TaskManager.Priorities {
+		switch p {
+		case TaskPriorityHigh, TaskPriorityNormal, TaskPriorityLow:
+		default:
+			return ErrInvalidTaskPriority
+		}
+	}
+
+	for _, limit := range cfg.TaskManager.Concurrency {
+		if limit < 0 {
+			return ErrInvalidTaskConcurrency
+		}
+	}
+
+	for _, interval := range cfg.Announcements.Intervals {
+		if interval <= 0 {
+			return ErrInvalidAnnouncementInterval
+		}
+	}
+
+	if !validCommandMethod(cfg.Announcements.Method, false) {
+		return ErrInvalidAnnouncementMethod
+	}
+
+	for _, t := range cfg.Announcements.Games {
+		if !validCommandMethod(t.Method, true) {
+			return ErrInvalidAnnouncementMethod
+		}
+	}
+
+	if cfg.GracefulStop.Timeout < 0 {
+		return ErrInvalidGracefulStopTimeout
+	}
+
+	if _, ok := stopSignals[cfg.GracefulStop.Signal]; !ok {
+		return ErrInvalidStopSignal
+	}
+
+	if !validCommandMethod(cfg.GracefulStop.Method, false) {
+		return ErrInvalidGracefulStopMethod
+	}
+
+	for _, c := range cfg.GracefulStop.Games {
+		if !validCommandMethod(c.Method, true) {
+			return ErrInvalidGracefulStopMethod
+		}
+	}
+
+	if cfg.Ports.RangeStart < 1 || cfg.Ports.RangeEnd > 65535 || cfg.Ports.RangeStart > cfg.Ports.RangeEnd {
+		return ErrInvalidPortsRange
+	}
+
+	if cfg.Verification.RequireSignature &&
+		len(cfg.Verification.MinisignKeys) == 0 && len(cfg.Verification.GPGKeys) == 0 {
+		return ErrNoTrustedKeys
+	}
+
 	if _, err := os.Stat(cfg.CACertificateFile); err != nil {
 		return NewInvalidFileError("invalid CA certificate file (ca_certificate_file)", err)
 	}
@@ -141,6 +485,88 @@ func (cfg *Config) validate() error {
 	return nil
 }
 
+func validCommandMethod(method string, allowEmpty bool) bool {
+	switch method {
+	case CommandMethodConsole, CommandMethodRCON:
+		return true
+	case "":
+		return allowEmpty
+	default:
+		return false
+	}
+}
+
 func (cfg *Config) WorkDir() string {
 	return cfg.WorkPath
 }
+
+// TaskTimeout returns the configured timeout of the task command, zero means no limit.
+func (cfg *Config) TaskTimeout(command string) time.Duration {
+	if timeout, ok := cfg.TaskManager.Timeouts[command]; ok {
+		return timeout
+	}
+
+	return cfg.TaskManager.DefaultTimeout
+}
+
+// AnnouncementTemplate returns the announcement template of the game,
+// the empty fields of the game template are taken from the default one.
+func (cfg *Config) AnnouncementTemplate(game string) AnnouncementTemplate {
+	result := cfg.Announcements.AnnouncementTemplate
+
+	t, ok := cfg.Announcements.Games[game]
+	if !ok {
+		return result
+	}
+
+	if t.Method != "" {
+		result.Method = t.Method
+	}
+	if t.Countdown != "" {
+		result.Countdown = t.Countdown
+	}
+	if t.Aborted != "" {
+		result.Aborted = t.Aborted
+	}
+
+	return result
+}
+
+// AnnouncementLead returns the longest announcement interval of the server task command,
+// zero means the command isn't announced.
+func (cfg *Config) AnnouncementLead(command string) time.Duration {
+	announced := false
+	for _, c := range cfg.Announcements.Commands {
+		if c == command {
+			announced = true
+			break
+		}
+	}
+
+	if !announced {
+		return 0
+	}
+
+	var lead time.Duration
+	for _, interval := range cfg.Announcements.Intervals {
+		if interval > lead {
+			lead = interval
+		}
+	}
+
+	return lead
+}
+
+// GracefulStopCommand returns the stop command of the game, the empty method is taken from the default one.
+func (cfg *Config) GracefulStopCommand(game string) GracefulStopCommand {
+	t, ok := cfg.GracefulStop.Games[game]
+	if !ok {
+		return cfg.GracefulStop.GracefulStopCommand
+	}
+
+	if t.Method == "" {
+		t.Method = cfg.GracefulStop.Method
+	}
+
+	return t
+}
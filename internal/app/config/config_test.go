@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -42,6 +43,55 @@ func TestValidate(t *testing.T) {
 			},
 			ErrEmptyAPIKey,
 		},
+		{
+			"invalid game cache link mode",
+			func(cfg *Config) {
+				cfg.GameCache.LinkMode = "symlink"
+			},
+			ErrInvalidGameCacheLinkMode,
+		},
+		{
+			"signature required without trusted keys",
+			func(cfg *Config) {
+				cfg.Verification.RequireSignature = true
+			},
+			ErrNoTrustedKeys,
+		},
+		{
+			"negative task timeout",
+			func(cfg *Config) {
+				cfg.TaskManager.Timeouts = map[string]time.Duration{"gsinst": -time.Hour}
+			},
+			ErrInvalidTaskTimeout,
+		},
+		{
+			"non-positive announcement interval",
+			func(cfg *Config) {
+				cfg.Announcements.Intervals = []time.Duration{time.Minute, 0}
+			},
+			ErrInvalidAnnouncementInterval,
+		},
+		{
+			"invalid game announcement method",
+			func(cfg *Config) {
+				cfg.Announcements.Games = map[string]AnnouncementTemplate{"cstrike": {Method: "chat"}}
+			},
+			ErrInvalidAnnouncementMethod,
+		},
+		{
+			"invalid stop signal",
+			func(cfg *Config) {
+				cfg.GracefulStop.Signal = "SIGSTOP"
+			},
+			ErrInvalidStopSignal,
+		},
+		{
+			"invalid game graceful stop method",
+			func(cfg *Config) {
+				cfg.GracefulStop.Games = map[string]GracefulStopCommand{"rust": {Method: "telnet", Command: "quit"}}
+			},
+			ErrInvalidGracefulStopMethod,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -55,6 +105,65 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestTaskTimeout(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.TaskManager.DefaultTimeout = time.Hour
+	cfg.TaskManager.Timeouts = map[string]time.Duration{
+		"gsinst":  3 * time.Hour,
+		"cmdexec": 0,
+	}
+
+	assert.Equal(t, 3*time.Hour, cfg.TaskTimeout("gsinst"))
+	assert.Equal(t, time.Duration(0), cfg.TaskTimeout("cmdexec"))
+	assert.Equal(t, time.Hour, cfg.TaskTimeout("gsupd"))
+}
+
+func TestAnnouncementTemplate(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.Announcements.Games = map[string]AnnouncementTemplate{
+		"ark": {Method: CommandMethodRCON, Countdown: "broadcast Server {action} in {time}"},
+	}
+	err := cfg.Init()
+	assert.NoError(t, err)
+
+	assert.Equal(t, AnnouncementTemplate{
+		Method:    CommandMethodRCON,
+		Countdown: "broadcast Server {action} in {time}",
+		Aborted:   "say Server {action} is aborted",
+	}, cfg.AnnouncementTemplate("ark"))
+	assert.Equal(t, AnnouncementTemplate{
+		Method:    CommandMethodConsole,
+		Countdown: "say Server {action} in {time}",
+		Aborted:   "say Server {action} is aborted",
+	}, cfg.AnnouncementTemplate("cstrike"))
+}
+
+func TestAnnouncementLead(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.Announcements.Intervals = []time.Duration{5 * time.Minute, 10 * time.Minute, time.Minute}
+	err := cfg.Init()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 10*time.Minute, cfg.AnnouncementLead("restart"))
+	assert.Equal(t, 10*time.Minute, cfg.AnnouncementLead("stop"))
+	assert.Equal(t, time.Duration(0), cfg.AnnouncementLead("start"))
+}
+
+func TestGracefulStopCommand(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.GracefulStop.Games = map[string]GracefulStopCommand{
+		"rust": {Method: CommandMethodRCON, Command: "quit"},
+	}
+	err := cfg.Init()
+	assert.NoError(t, err)
+
+	assert.Equal(t, GracefulStopCommand{Method: CommandMethodRCON, Command: "quit"}, cfg.GracefulStopCommand("rust"))
+	assert.Equal(t, GracefulStopCommand{Method: CommandMethodConsole, Command: "stop"}, cfg.GracefulStopCommand("minecraft"))
+	assert.Equal(t, GracefulStopCommand{Method: CommandMethodConsole}, cfg.GracefulStopCommand("cstrike"))
+	assert.Equal(t, "SIGTERM", cfg.GracefulStop.Signal)
+	assert.Equal(t, 30*time.Second, cfg.GracefulStop.Timeout)
+}
+
 func givenValidConfig(t *testing.T) *Config {
 	t.Helper()
 
@@ -14,5 +14,6 @@ const (
 )
 
 const (
-	defaultProcessManager = "tmux"
+	defaultProcessManager   = "tmux"
+	defaultOfflineStoreFile = "/var/lib/gameap-daemon/daemon.db"
 )
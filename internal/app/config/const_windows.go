@@ -14,5 +14,6 @@ const (
 )
 
 const (
-	defaultProcessManager = "winsw"
+	defaultProcessManager   = "winsw"
+	defaultOfflineStoreFile = "C:\\gameap\\daemon\\daemon.db"
 )
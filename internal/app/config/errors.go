@@ -11,6 +11,19 @@ var (
 	ErrEmptyAPIHost   = errors.New("empty API Host")
 	ErrEmptyAPIKey    = errors.New("empty API Key")
 	ErrConfigNotFound = errors.New("configuration file not found")
+
+	ErrInvalidGameCacheLinkMode    = errors.New("invalid game cache link mode (game_cache.link_mode)")
+	ErrInvalidTaskTimeout          = errors.New("task timeout can't be negative (task_manager.default_timeout, task_manager.timeouts)")
+	ErrInvalidTaskWorkersCount     = errors.New("invalid task workers count (task_manager.workers_count)")
+	ErrInvalidTaskPriority         = errors.New("invalid task priority, must be high, normal or low (task_manager.priorities)")
+	ErrInvalidTaskConcurrency      = errors.New("task concurrency limit can't be negative (task_manager.concurrency)")
+	ErrInvalidAnnouncementInterval = errors.New("announcement interval must be positive (announcements.intervals)")
+	ErrInvalidAnnouncementMethod   = errors.New("invalid announcement method, must be console or rcon (announcements.method)")
+	ErrInvalidGracefulStopTimeout  = errors.New("graceful stop timeout can't be negative (graceful_stop.timeout)")
+	ErrInvalidStopSignal           = errors.New("invalid stop signal (graceful_stop.signal)")
+	ErrInvalidGracefulStopMethod   = errors.New("invalid graceful stop method, must be console or rcon (graceful_stop.method)")
+	ErrInvalidPortsRange           = errors.New("invalid ports range (ports.range_start, ports.range_end)")
+	ErrNoTrustedKeys               = errors.New("signature is required, but no trusted keys are configured (verification)")
 )
 
 type InvalidFileError struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"path/filepath"
 	"strconv"
 
 	"github.com/gameap/daemon/internal/app/contracts"
@@ -64,7 +65,13 @@ func (ncu *NodeConfigInitializer) Initialize(ctx context.Context, cfg *Config) e
 	}
 
 	cfg.WorkPath = initial.WorkPath
-	cfg.SteamCMDPath = initial.SteamCMDPath
+	if initial.SteamCMDPath != "" {
+		cfg.SteamCMDPath = initial.SteamCMDPath
+	}
+
+	if cfg.SteamCMDPath == "" {
+		cfg.SteamCMDPath = filepath.Join(cfg.ToolsPath, "steamcmd")
+	}
 
 	if cfg.Scripts.Install == "" {
 		cfg.Scripts.Install = initial.ScriptInstall
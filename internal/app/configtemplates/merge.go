@@ -0,0 +1,231 @@
+package configtemplates
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultSeparator = "="
+
+var commentPrefixes = []string{"#", ";", "//"}
+
+// mergeKeyValue replaces the values of the keys found in the patch and appends the missing ones.
+// Comments, unknown keys and the order of the lines are kept.
+// With the space separator, lines like `hostname "My server"` are matched.
+func mergeKeyValue(current, patch []byte, separator string) []byte {
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	newline := "\n"
+	if bytes.Contains(current, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	var keys []string
+	lines := make(map[string]string)
+
+	for _, line := range splitLines(patch) {
+		key, ok := lineKey(line, separator)
+		if !ok {
+			continue
+		}
+
+		if _, exists := lines[key]; !exists {
+			keys = append(keys, key)
+		}
+		lines[key] = strings.TrimSpace(line)
+	}
+
+	var result []string
+	applied := make(map[string]bool, len(keys))
+
+	for _, line := range splitLines(current) {
+		key, ok := lineKey(line, separator)
+		if ok {
+			if patched, found := lines[key]; found {
+				if applied[key] {
+					// Duplicated keys are removed, the game would use one of them anyway
+					continue
+				}
+
+				line = patched
+				applied[key] = true
+			}
+		}
+
+		result = append(result, line)
+	}
+
+	// The trailing newline of the file
+	if len(result) > 0 && result[len(result)-1] == "" {
+		result = result[:len(result)-1]
+	}
+
+	for _, key := range keys {
+		if !applied[key] {
+			result = append(result, lines[key])
+		}
+	}
+
+	if len(result) == 0 {
+		return []byte{}
+	}
+
+	return []byte(strings.Join(result, newline) + newline)
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+}
+
+func lineKey(line, separator string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false
+	}
+
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return "", false
+		}
+	}
+
+	var key string
+	if strings.TrimSpace(separator) == "" {
+		key = strings.Fields(line)[0]
+	} else {
+		i := strings.Index(line, separator)
+		if i < 0 {
+			return "", false
+		}
+		key = strings.TrimSpace(line[:i])
+	}
+
+	return key, key != ""
+}
+
+// mergeJSON merges the patch object into the current JSON document recursively.
+// Values other than objects are replaced.
+func mergeJSON(current, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	err := decodeJSON(patch, &patchValue)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid json template")
+	}
+
+	var currentValue interface{}
+	if len(bytes.TrimSpace(current)) > 0 {
+		err = decodeJSON(current, &currentValue)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid json config")
+		}
+	}
+
+	merged, err := json.MarshalIndent(mergeValues(currentValue, patchValue), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(merged, '\n'), nil
+}
+
+func decodeJSON(content []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+func mergeValues(current, patch interface{}) interface{} {
+	currentMap, ok := current.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	for key, value := range patchMap {
+		currentMap[key] = mergeValues(currentMap[key], value)
+	}
+
+	return currentMap
+}
+
+// mergeYAML merges the patch mapping into the current YAML document recursively.
+// The order of the keys and the comments of the current document are kept.
+func mergeYAML(current, patch []byte) ([]byte, error) {
+	var patchDoc yaml.Node
+	err := yaml.Unmarshal(patch, &patchDoc)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid yaml template")
+	}
+
+	var currentDoc yaml.Node
+	err = yaml.Unmarshal(current, &currentDoc)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid yaml config")
+	}
+
+	if len(patchDoc.Content) == 0 {
+		return current, nil
+	}
+
+	if len(currentDoc.Content) == 0 {
+		currentDoc = patchDoc
+	} else {
+		mergeNodes(currentDoc.Content[0], patchDoc.Content[0])
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&currentDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func mergeNodes(current, patch *yaml.Node) {
+	if current.Kind != yaml.MappingNode || patch.Kind != yaml.MappingNode {
+		*current = *patch
+		return
+	}
+
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i], patch.Content[i+1]
+
+		found := false
+		for j := 0; j+1 < len(current.Content); j += 2 {
+			if current.Content[j].Value == key.Value {
+				mergeNodes(current.Content[j+1], value)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			current.Content = append(current.Content, key, value)
+		}
+	}
+}
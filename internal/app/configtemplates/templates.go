@@ -0,0 +1,261 @@
+package configtemplates
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile lists the templates of the game, it's placed in the <config_templates.path>/<game code> directory.
+const ManifestFile = "templates.yaml"
+
+const (
+	StrategyReplace  = "replace"
+	StrategyKeyValue = "keyvalue"
+	StrategyJSON     = "json"
+	StrategyYAML     = "yaml"
+)
+
+var (
+	ErrInvalidManifest    = errors.New("invalid config templates manifest")
+	ErrUnknownStrategy    = errors.New("unknown merge strategy")
+	ErrForbiddenTarget    = errors.New("template target path is outside the game server directory")
+	errTemplateNotDefined = errors.New("template source and target are required")
+)
+
+// Template describes how the template file is merged into the game server config file.
+type Template struct {
+	// Source is the template file path relative to the game templates directory.
+	Source string `yaml:"source"`
+	// Target is the config file path relative to the game server directory.
+	Target string `yaml:"target"`
+	// Strategy is one of replace (default), keyvalue, json or yaml.
+	Strategy string `yaml:"strategy"`
+	// Separator between keys and values for the keyvalue strategy, "=" by default.
+	Separator string `yaml:"separator"`
+	// GameMods limits the template to the game mods by name, the template is applied to all game mods if empty.
+	GameMods []string `yaml:"game_mods"`
+}
+
+func (t *Template) appliesTo(gameMod string) bool {
+	if len(t.GameMods) == 0 {
+		return true
+	}
+
+	for _, name := range t.GameMods {
+		if name == gameMod {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Render renders the templates of the game server's game with the server vars and built-in shortcodes
+// ({ip}, {port}, {query_port}, {rcon_port}, {rcon_password}, {uuid} etc.)
+// and merges them into the config files in the server directory.
+// Returns the paths of the changed files.
+func Render(ctx context.Context, cfg *config.Config, server *domain.Server, out io.Writer) ([]string, error) {
+	if !cfg.ConfigTemplates.Enabled {
+		return nil, nil
+	}
+
+	templatesDir := filepath.Join(cfg.ConfigTemplates.Path, server.Game().Code)
+
+	templates, err := readManifest(filepath.Join(templatesDir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serverDir := server.WorkDir(cfg)
+	gameMod := server.GameMod().Name
+
+	var changed []string
+
+	for i := range templates {
+		t := &templates[i]
+		if !t.appliesTo(gameMod) {
+			continue
+		}
+
+		target, ok, err := renderTemplate(cfg, server, templatesDir, serverDir, t)
+		if err != nil {
+			return changed, errors.WithMessagef(err, "[configtemplates] failed to render %s", t.Source)
+		}
+
+		if ok {
+			logger.Debug(ctx, "Config "+t.Target+" rendered from template "+t.Source)
+			_, _ = out.Write([]byte("Config " + t.Target + " rendered from template " + t.Source + "\n"))
+
+			changed = append(changed, target)
+		}
+	}
+
+	return changed, nil
+}
+
+func readManifest(path string) ([]Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []Template
+	err = yaml.Unmarshal(content, &templates)
+	if err != nil {
+		return nil, errors.WithMessage(ErrInvalidManifest, err.Error())
+	}
+
+	for i := range templates {
+		if templates[i].Source == "" || templates[i].Target == "" {
+			return nil, errors.WithMessage(ErrInvalidManifest, errTemplateNotDefined.Error())
+		}
+
+		if templates[i].Strategy == "" {
+			templates[i].Strategy = StrategyReplace
+		}
+	}
+
+	return templates, nil
+}
+
+// renderTemplate writes the merged config file. The file isn't written if the content isn't changed.
+func renderTemplate(
+	cfg *config.Config,
+	server *domain.Server,
+	templatesDir, serverDir string,
+	t *Template,
+) (string, bool, error) {
+	target, err := resolveTarget(serverDir, t.Target)
+	if err != nil {
+		return "", false, err
+	}
+
+	source, err := os.ReadFile(filepath.Join(templatesDir, t.Source))
+	if err != nil {
+		return "", false, err
+	}
+
+	rendered := []byte(domain.ReplaceShortCodes(string(source), cfg, server))
+
+	perm := os.FileMode(0644)
+	current, err := os.ReadFile(target)
+	switch {
+	case err == nil:
+		if stat, statErr := os.Stat(target); statErr == nil {
+			perm = stat.Mode().Perm()
+		}
+	case errors.Is(err, os.ErrNotExist):
+		current = nil
+	default:
+		return "", false, err
+	}
+
+	merged, err := merge(t, current, rendered)
+	if err != nil {
+		return "", false, err
+	}
+
+	if current != nil && bytes.Equal(current, merged) {
+		return target, false, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = os.WriteFile(target, merged, perm)
+	if err != nil {
+		return "", false, err
+	}
+
+	return target, true, nil
+}
+
+// resolveTarget returns the target path with the symlinks resolved. The daemon writes the configs as root,
+// so the symlinks made by the game server user mustn't lead outside the game server directory.
+func resolveTarget(serverDir, target string) (string, error) {
+	path := filepath.Join(serverDir, target)
+	if !isInside(serverDir, path) {
+		return "", ErrForbiddenTarget
+	}
+
+	realServerDir, err := resolvePath(serverDir)
+	if err != nil {
+		return "", err
+	}
+
+	realPath, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !isInside(realServerDir, realPath) {
+		return "", ErrForbiddenTarget
+	}
+
+	return realPath, nil
+}
+
+// resolvePath resolves the symlinks of the deepest existing part of the path,
+// the rest of the path is created later. Dangling symlinks are forbidden, they would be followed on write.
+func resolvePath(path string) (string, error) {
+	existing := path
+	var rest []string
+
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		if _, err = os.Lstat(existing); err == nil {
+			return "", ErrForbiddenTarget
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil
+		}
+
+		rest = append([]string{filepath.Base(existing)}, rest...)
+		existing = parent
+	}
+}
+
+func isInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func merge(t *Template, current, rendered []byte) ([]byte, error) {
+	switch t.Strategy {
+	case StrategyReplace:
+		return rendered, nil
+	case StrategyKeyValue:
+		return mergeKeyValue(current, rendered, t.Separator), nil
+	case StrategyJSON:
+		return mergeJSON(current, rendered)
+	case StrategyYAML:
+		return mergeYAML(current, rendered)
+	}
+
+	return nil, errors.WithMessage(ErrUnknownStrategy, t.Strategy)
+}
@@ -0,0 +1,279 @@
+package configtemplates
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_Replace_WholeFileRendered(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.properties
+  target: server.properties
+`, map[string]string{
+		"server.properties": "server-ip={ip}\nserver-port={port}\nrcon.password={rcon_password}\nmax-players={maxplayers}\n",
+	})
+	out := &bytes.Buffer{}
+
+	changed, err := Render(context.Background(), cfg, givenServer(), out)
+
+	require.NoError(t, err)
+	assert.Len(t, changed, 1)
+	assert.Equal(
+		t,
+		"server-ip=1.3.3.7\nserver-port=1337\nrcon.password=paS$w0rD\nmax-players=32\n",
+		readServerFile(t, cfg, "server.properties"),
+	)
+	assert.Contains(t, out.String(), "Config server.properties rendered from template server.properties")
+}
+
+func TestRender_Unchanged_FileNotWritten(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.properties
+  target: server.properties
+`, map[string]string{"server.properties": "server-port={port}\n"})
+	givenServerFile(t, cfg, "server.properties", "server-port=1337\n")
+
+	changed, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestRender_KeyValue_KeysPatched(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.properties
+  target: server.properties
+  strategy: keyvalue
+`, map[string]string{"server.properties": "server-port={port}\nrcon.port={rcon_port}\n"})
+	givenServerFile(t, cfg, "server.properties", "#Minecraft server properties\r\nmotd=Hello\r\nserver-port=25565\r\n")
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"#Minecraft server properties\r\nmotd=Hello\r\nserver-port=1337\r\nrcon.port=1339\r\n",
+		readServerFile(t, cfg, "server.properties"),
+	)
+}
+
+func TestRender_KeyValue_SpaceSeparator(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: cstrike/server.cfg
+  strategy: keyvalue
+  separator: " "
+`, map[string]string{"server.cfg": "hostname \"{hostname}\"\nrcon_password \"{rcon_password}\"\n"})
+	givenServerFile(t, cfg, "cstrike/server.cfg", "// Server config\nhostname \"Counter-Strike\"\nsv_gravity 800\n")
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"// Server config\nhostname \"My Server\"\nsv_gravity 800\nrcon_password \"paS$w0rD\"\n",
+		readServerFile(t, cfg, "cstrike/server.cfg"),
+	)
+}
+
+func TestRender_JSON_Merged(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: config.json
+  target: config.json
+  strategy: json
+`, map[string]string{"config.json": `{"network": {"port": {port}}, "name": "{hostname}"}`})
+	givenServerFile(t, cfg, "config.json", `{"network": {"port": 7777, "ip": "0.0.0.0"}, "difficulty": 2.5}`)
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"network": {"port": 1337, "ip": "0.0.0.0"}, "difficulty": 2.5, "name": "My Server"}`,
+		readServerFile(t, cfg, "config.json"),
+	)
+}
+
+func TestRender_YAML_MergedKeepingOrder(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: config.yml
+  target: config.yml
+  strategy: yaml
+`, map[string]string{"config.yml": "server:\n  port: {port}\nrcon: \"{rcon_password}\"\n"})
+	givenServerFile(t, cfg, "config.yml", "# Server config\nmotd: Hello\nserver:\n  port: 7777\n  ip: 0.0.0.0\n")
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"# Server config\nmotd: Hello\nserver:\n  port: 1337\n  ip: 0.0.0.0\nrcon: \"paS$w0rD\"\n",
+		readServerFile(t, cfg, "config.yml"),
+	)
+}
+
+func TestRender_GameModFilter(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: public.cfg
+  target: public.cfg
+  game_mods: [Public]
+- source: classic.cfg
+  target: classic.cfg
+  game_mods: [Classic]
+`, map[string]string{"public.cfg": "public", "classic.cfg": "classic"})
+
+	changed, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Len(t, changed, 1)
+	assert.FileExists(t, filepath.Join(cfg.WorkPath, "server", "classic.cfg"))
+	assert.NoFileExists(t, filepath.Join(cfg.WorkPath, "server", "public.cfg"))
+}
+
+func TestRender_TargetOutsideServerDirectory_Error(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: ../server.cfg
+`, map[string]string{"server.cfg": "hostname test"})
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+}
+
+func TestRender_TargetSymlinkOutsideServerDirectory_Error(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: cfg/server.cfg
+`, map[string]string{"server.cfg": "hostname test"})
+	outside := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, os.WriteFile(outside, []byte("root:x:0:0"), 0600))
+	givenServerFile(t, cfg, "cfg/.keep", "")
+	require.NoError(t, os.Symlink(outside, filepath.Join(cfg.WorkPath, "server", "cfg", "server.cfg")))
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+	content, err := os.ReadFile(outside)
+	require.NoError(t, err)
+	assert.Equal(t, "root:x:0:0", string(content))
+}
+
+func TestRender_TargetDirectorySymlinkOutsideServerDirectory_Error(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: cfg/server.cfg
+`, map[string]string{"server.cfg": "hostname test"})
+	outside := t.TempDir()
+	givenServerFile(t, cfg, ".keep", "")
+	require.NoError(t, os.Symlink(outside, filepath.Join(cfg.WorkPath, "server", "cfg")))
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+	assert.NoFileExists(t, filepath.Join(outside, "server.cfg"))
+}
+
+func TestRender_TargetDanglingSymlink_Error(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: server.cfg
+`, map[string]string{"server.cfg": "hostname test"})
+	outside := filepath.Join(t.TempDir(), "created")
+	givenServerFile(t, cfg, ".keep", "")
+	require.NoError(t, os.Symlink(outside, filepath.Join(cfg.WorkPath, "server", "server.cfg")))
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+	assert.NoFileExists(t, outside)
+}
+
+func TestRender_UnknownStrategy_Error(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: server.cfg
+  strategy: xml
+`, map[string]string{"server.cfg": "hostname test"})
+
+	_, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestRender_NoManifest_NothingRendered(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.ConfigTemplates.Enabled = true
+	cfg.ConfigTemplates.Path = t.TempDir()
+
+	changed, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestRender_Disabled_NothingRendered(t *testing.T) {
+	cfg := givenConfig(t, `
+- source: server.cfg
+  target: server.cfg
+`, map[string]string{"server.cfg": "hostname test"})
+	cfg.ConfigTemplates.Enabled = false
+
+	changed, err := Render(context.Background(), cfg, givenServer(), &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.NoFileExists(t, filepath.Join(cfg.WorkPath, "server", "server.cfg"))
+}
+
+func givenConfig(t *testing.T, manifest string, templates map[string]string) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.ConfigTemplates.Enabled = true
+	cfg.ConfigTemplates.Path = t.TempDir()
+
+	gameDir := filepath.Join(cfg.ConfigTemplates.Path, "cstrike")
+	require.NoError(t, os.MkdirAll(gameDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, ManifestFile), []byte(manifest), 0600))
+
+	for name, content := range templates {
+		require.NoError(t, os.WriteFile(filepath.Join(gameDir, name), []byte(content), 0600))
+	}
+
+	return cfg
+}
+
+func givenServerFile(t *testing.T, cfg *config.Config, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(cfg.WorkPath, "server", name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func readServerFile(t *testing.T, cfg *config.Config, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(cfg.WorkPath, "server", name))
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func givenServer() *domain.Server {
+	return domaintest.NewServer(
+		domaintest.WithName("My Server"),
+		domaintest.WithGameMod(domain.GameMod{Name: "Classic"}),
+		domaintest.WithVars(map[string]string{"maxplayers": "32", "hostname": "My Server"}),
+	)
+}
@@ -40,6 +40,10 @@ type APIRequestMaker interface {
 	Request(ctx context.Context, request domain.APIRequest) (APIResponse, error)
 }
 
+type APIStreamer interface {
+	Stream(ctx context.Context, request domain.APIRequest) (io.ReadCloser, error)
+}
+
 type APIResponse interface {
 	Body() []byte
 	Status() string
@@ -65,6 +69,46 @@ type ProcessManager interface {
 	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
 }
 
+type SteamCMDInstaller interface {
+	EnsureInstalled(ctx context.Context, out io.Writer) error
+}
+
+type GameCache interface {
+	Enabled() bool
+	Lock(key string) func()
+	Version(key string) string
+	Restore(ctx context.Context, key, dst string) (bool, error)
+	Store(ctx context.Context, key, version, src string) error
+	Archive(ctx context.Context, source string, out io.Writer) (string, func(), error)
+}
+
+type Verifier interface {
+	Required() bool
+	Verify(ctx context.Context, source, file string, out io.Writer) error
+}
+
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type GDTaskCanceler interface {
+	CancelTask(ctx context.Context, id int) error
+}
+
+type PortAllocator interface {
+	FreePorts(ctx context.Context, ip string, from, to int) ([]domain.FreePorts, error)
+}
+
+type PlayersCounter interface {
+	OnlinePlayers(ctx context.Context, server *domain.Server) (int, error)
+}
+
+type ServerAnnouncer interface {
+	AnnounceCountdown(ctx context.Context, server *domain.Server, action string, left time.Duration) error
+	AnnounceAborted(ctx context.Context, server *domain.Server, action string) error
+}
+
 type DomainPrimitiveValidator interface {
 	Validate() error
 }
@@ -1,12 +1,20 @@
 package internal
 
 import (
+	"github.com/gameap/daemon/internal/app/announcer"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/push"
+	"github.com/gameap/daemon/internal/app/repositories"
 	"github.com/gameap/daemon/internal/app/services"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
+	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/go-resty/resty/v2"
 	"github.com/sirupsen/logrus"
 )
@@ -33,9 +41,18 @@ type ServicesContainer struct {
 	executor  contracts.Executor
 
 	gdTaskManager *gdaemonscheduler.TaskManager
+	pushClient    *push.Client
+	steamCMD      *steamcmd.SteamCMD
+	gameCache     *gamecache.Cache
+	verifier      *verification.Verifier
+	watchdog      *watchdog.Watchdog
+	announcer     *announcer.Announcer
+	migrator      *processmanager.Migrator
 }
 
 type RepositoryContainer struct {
+	localStore           *repositories.LocalStore
+	outbox               *repositories.Outbox
 	gdTaskRepository     domain.GDTaskRepository     `di:"public, set"`
 	serverRepository     domain.ServerRepository     `di:"public, set"`
 	serverTaskRepository domain.ServerTaskRepository `di:"public, set"`
@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 
+	"github.com/gameap/daemon/internal/app/announcer"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
@@ -12,7 +13,14 @@ import (
 
 	"github.com/gameap/daemon/internal/app/di/internal/definitions"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamecache"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/push"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
+	"github.com/gameap/daemon/internal/processmanager"
 )
 
 type Container struct {
@@ -57,11 +65,20 @@ type ServicesContainer struct {
 	executor       contracts.Executor
 	processManager contracts.ProcessManager
 	gdTaskManager  *gdaemonscheduler.TaskManager
+	pushClient     *push.Client
+	steamCMD       *steamcmd.SteamCMD
+	gameCache      *gamecache.Cache
+	verifier       *verification.Verifier
+	watchdog       *watchdog.Watchdog
+	announcer      *announcer.Announcer
+	migrator       *processmanager.Migrator
 }
 
 type RepositoryContainer struct {
 	*Container
 
+	localStore           *repositories.LocalStore
+	outbox               *repositories.Outbox
 	gdTaskRepository     domain.GDTaskRepository
 	serverRepository     domain.ServerRepository
 	serverTaskRepository domain.ServerTaskRepository
@@ -143,10 +160,73 @@ func (c *ServicesContainer) GdTaskManager(ctx context.Context) *gdaemonscheduler
 	return c.gdTaskManager
 }
 
+func (c *ServicesContainer) PushClient(ctx context.Context) *push.Client {
+	if c.pushClient == nil && c.err == nil {
+		c.pushClient = definitions.CreateServicesPushClient(ctx, c)
+	}
+	return c.pushClient
+}
+
+func (c *ServicesContainer) SteamCMD(ctx context.Context) *steamcmd.SteamCMD {
+	if c.steamCMD == nil && c.err == nil {
+		c.steamCMD = definitions.CreateServicesSteamCMD(ctx, c)
+	}
+	return c.steamCMD
+}
+
+func (c *ServicesContainer) GameCache(ctx context.Context) *gamecache.Cache {
+	if c.gameCache == nil && c.err == nil {
+		c.gameCache = definitions.CreateServicesGameCache(ctx, c)
+	}
+	return c.gameCache
+}
+
+func (c *ServicesContainer) Verifier(ctx context.Context) *verification.Verifier {
+	if c.verifier == nil && c.err == nil {
+		c.verifier = definitions.CreateServicesVerifier(ctx, c)
+	}
+	return c.verifier
+}
+
+func (c *ServicesContainer) Watchdog(ctx context.Context) *watchdog.Watchdog {
+	if c.watchdog == nil && c.err == nil {
+		c.watchdog = definitions.CreateServicesWatchdog(ctx, c)
+	}
+	return c.watchdog
+}
+
+func (c *ServicesContainer) Announcer(ctx context.Context) *announcer.Announcer {
+	if c.announcer == nil && c.err == nil {
+		c.announcer = definitions.CreateServicesAnnouncer(ctx, c)
+	}
+	return c.announcer
+}
+
+func (c *ServicesContainer) Migrator(ctx context.Context) *processmanager.Migrator {
+	if c.migrator == nil && c.err == nil {
+		c.migrator = definitions.CreateServicesMigrator(ctx, c)
+	}
+	return c.migrator
+}
+
 func (c *Container) Repositories() definitions.RepositoryContainer {
 	return c.repositories
 }
 
+func (c *RepositoryContainer) LocalStore(ctx context.Context) *repositories.LocalStore {
+	if c.localStore == nil && c.err == nil {
+		c.localStore = definitions.CreateRepositoriesLocalStore(ctx, c)
+	}
+	return c.localStore
+}
+
+func (c *RepositoryContainer) Outbox(ctx context.Context) *repositories.Outbox {
+	if c.outbox == nil && c.err == nil {
+		c.outbox = definitions.CreateRepositoriesOutbox(ctx, c)
+	}
+	return c.outbox
+}
+
 func (c *RepositoryContainer) GdTaskRepository(ctx context.Context) domain.GDTaskRepository {
 	if c.gdTaskRepository == nil && c.err == nil {
 		c.gdTaskRepository = definitions.CreateRepositoriesGdTaskRepository(ctx, c)
@@ -180,4 +260,8 @@ func (c *ServicesContainer) SetAPICaller(s contracts.APIRequestMaker) {
 	c.apiCaller = s
 }
 
-func (c *Container) Close() {}
+func (c *Container) Close() {
+	if c.repositories.localStore != nil {
+		_ = c.repositories.localStore.Close()
+	}
+}
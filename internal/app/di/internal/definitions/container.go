@@ -17,6 +17,15 @@ func CreateProcessRunner(ctx context.Context, c Container) *services.Runner {
 		c.Services().GdTaskManager(ctx),
 		c.Repositories().ServerRepository(ctx),
 		c.Repositories().ServerTaskRepository(ctx),
+		c.Repositories().Outbox(ctx),
+		c.Services().PushClient(ctx),
+		c.Services().Watchdog(ctx),
+		c.Services().Announcer(ctx),
+		c.Services().Migrator(ctx),
+		[]contracts.HealthChecker{
+			c.Services().SteamCMD(ctx),
+			c.Services().Watchdog(ctx),
+		},
 	)
 	if err != nil {
 		c.SetError(err)
@@ -42,5 +51,8 @@ func CreateServerCommandFactory(ctx context.Context, c Container) *gameservercom
 		c.Repositories().ServerRepository(ctx),
 		c.Services().Executor(ctx),
 		c.Services().ProcessManager(ctx),
+		c.Services().SteamCMD(ctx),
+		c.Services().GameCache(ctx),
+		c.Services().Verifier(ctx),
 	)
 }
@@ -13,8 +13,16 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/sirupsen/logrus"
 
+	"github.com/gameap/daemon/internal/app/announcer"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamecache"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/push"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
+	"github.com/gameap/daemon/internal/processmanager"
 )
 
 type Container interface {
@@ -38,9 +46,18 @@ type ServicesContainer interface {
 	ExtendableExecutor(ctx context.Context) contracts.Executor
 	GdTaskManager(ctx context.Context) *gdaemonscheduler.TaskManager
 	ProcessManager(ctx context.Context) contracts.ProcessManager
+	PushClient(ctx context.Context) *push.Client
+	SteamCMD(ctx context.Context) *steamcmd.SteamCMD
+	GameCache(ctx context.Context) *gamecache.Cache
+	Verifier(ctx context.Context) *verification.Verifier
+	Watchdog(ctx context.Context) *watchdog.Watchdog
+	Announcer(ctx context.Context) *announcer.Announcer
+	Migrator(ctx context.Context) *processmanager.Migrator
 }
 
 type RepositoryContainer interface {
+	LocalStore(ctx context.Context) *repositories.LocalStore
+	Outbox(ctx context.Context) *repositories.Outbox
 	GdTaskRepository(ctx context.Context) domain.GDTaskRepository
 	ServerRepository(ctx context.Context) domain.ServerRepository
 	ServerTaskRepository(ctx context.Context) domain.ServerTaskRepository
@@ -7,17 +7,50 @@ import (
 	"github.com/gameap/daemon/internal/app/repositories"
 )
 
+func CreateRepositoriesLocalStore(ctx context.Context, c Container) *repositories.LocalStore {
+	if !c.Cfg(ctx).Offline.Enabled {
+		return nil
+	}
+
+	store, err := repositories.NewLocalStore(c.Cfg(ctx).Offline.StoreFile)
+	if err != nil {
+		c.SetError(err)
+		return nil
+	}
+
+	return store
+}
+
+func CreateRepositoriesOutbox(ctx context.Context, c Container) *repositories.Outbox {
+	return repositories.NewOutbox(
+		c.Services().APICaller(ctx),
+		c.Repositories().LocalStore(ctx),
+	)
+}
+
 func CreateRepositoriesGdTaskRepository(ctx context.Context, c Container) domain.GDTaskRepository {
 	return repositories.NewGDTaskRepository(
 		c.Services().APICaller(ctx),
+		c.Repositories().Outbox(ctx),
 		c.Repositories().ServerRepository(ctx),
 	)
 }
 
 func CreateRepositoriesServerRepository(ctx context.Context, c Container) domain.ServerRepository {
-	return repositories.NewServerRepository(ctx, c.Services().APICaller(ctx), c.Logger(ctx))
+	return repositories.NewServerRepository(
+		ctx,
+		c.Services().APICaller(ctx),
+		c.Repositories().Outbox(ctx),
+		c.Repositories().LocalStore(ctx),
+		c.Logger(ctx),
+	)
 }
 
 func CreateRepositoriesServerTaskRepository(ctx context.Context, c Container) domain.ServerTaskRepository {
-	return repositories.NewServerTaskRepository(c.Services().APICaller(ctx), c.Repositories().ServerRepository(ctx))
+	return repositories.NewServerTaskRepository(
+		c.Services().APICaller(ctx),
+		c.Repositories().Outbox(ctx),
+		c.Repositories().LocalStore(ctx),
+		c.Repositories().ServerRepository(ctx),
+	)
 }
@@ -2,14 +2,20 @@ package definitions
 
 import (
 	"context"
-	"net/http"
 	"time"
 
+	"github.com/gameap/daemon/internal/app/announcer"
 	"github.com/gameap/daemon/internal/app/components"
 	"github.com/gameap/daemon/internal/app/components/customhandlers"
 	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/internal/app/gamequery"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/push"
 	"github.com/gameap/daemon/internal/app/services"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/go-resty/resty/v2"
 )
@@ -18,17 +24,10 @@ func CreateServicesResty(ctx context.Context, c Container) *resty.Client {
 	restyClient := resty.New()
 	restyClient.SetBaseURL(c.Cfg(ctx).APIHost)
 	restyClient.SetHeader("User-Agent", "GameAP Daemon/3.0")
-	restyClient.RetryCount = 30
-	restyClient.RetryMaxWaitTime = 10 * time.Minute
 	restyClient.SetTimeout(10 * time.Second)
 	restyClient.SetLogger(c.Logger(ctx))
 
-	restyClient.AddRetryCondition(
-		func(r *resty.Response, err error) bool {
-			return r.StatusCode() == http.StatusTooManyRequests ||
-				r.StatusCode() == http.StatusBadGateway
-		},
-	)
+	// Retries are made by the API client, see services.APIClient
 
 	return restyClient
 }
@@ -55,7 +54,7 @@ func CreateServicesExecutor(_ context.Context, _ Container) contracts.Executor {
 func CreateServiceExtendableExecutor(ctx context.Context, c Container) contracts.Executor {
 	executor := components.NewDefaultExtendableExecutor(components.NewExecutor())
 
-	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(c.Cfg(ctx)).Handle)
+	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(c.Cfg(ctx), c.Services().Verifier(ctx)).Handle)
 	executor.RegisterHandler(
 		"server-output",
 		customhandlers.NewOutputReader(
@@ -72,6 +71,16 @@ func CreateServiceExtendableExecutor(ctx context.Context, c Container) contracts
 		).Handle,
 	)
 
+	executor.RegisterHandler("cache-prune", customhandlers.NewCachePrune(c.Services().GameCache(ctx)).Handle)
+
+	executor.RegisterHandler(
+		"pm-migrate",
+		customhandlers.NewProcessManagerMigration(
+			c.Services().Migrator(ctx),
+			c.Repositories().ServerRepository(ctx),
+		).Handle,
+	)
+
 	return executor
 }
 
@@ -97,5 +106,46 @@ func CreateServicesGdTaskManager(ctx context.Context, c Container) *gdaemonsched
 		c.ServerCommandFactory(ctx),
 		c.Services().ExtendableExecutor(ctx),
 		c.Cfg(ctx),
+		c.Services().Watchdog(ctx),
+	)
+}
+
+func CreateServicesWatchdog(_ context.Context, _ Container) *watchdog.Watchdog {
+	return watchdog.NewWatchdog()
+}
+
+func CreateServicesMigrator(ctx context.Context, c Container) *processmanager.Migrator {
+	return processmanager.NewMigrator(
+		c.Cfg(ctx),
+		c.Services().ProcessManager(ctx),
+		c.Services().Executor(ctx),
+		components.NewExecutor(),
+	)
+}
+
+func CreateServicesAnnouncer(ctx context.Context, c Container) *announcer.Announcer {
+	return announcer.NewAnnouncer(
+		c.Cfg(ctx),
+		c.Services().ProcessManager(ctx),
+		gamequery.NewRCON(),
 	)
 }
+
+func CreateServicesPushClient(ctx context.Context, c Container) *push.Client {
+	// Push is unavailable if the API caller is replaced by one that can't make long-lived requests.
+	streamer, _ := c.Services().APICaller(ctx).(contracts.APIStreamer)
+
+	return push.NewClient(streamer, c.Cfg(ctx))
+}
+
+func CreateServicesSteamCMD(ctx context.Context, c Container) *steamcmd.SteamCMD {
+	return steamcmd.NewSteamCMD(c.Cfg(ctx), c.Services().Executor(ctx))
+}
+
+func CreateServicesGameCache(ctx context.Context, c Container) *gamecache.Cache {
+	return gamecache.NewCache(c.Cfg(ctx))
+}
+
+func CreateServicesVerifier(ctx context.Context, c Container) *verification.Verifier {
+	return verification.NewVerifier(c.Cfg(ctx))
+}
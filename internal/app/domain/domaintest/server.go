@@ -0,0 +1,168 @@
+// Package domaintest makes the domain entities for the tests.
+package domaintest
+
+import (
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+const defaultServerUUID = "759b875e-d910-11eb-aff7-d796d7fcf7ef"
+
+// ServerOption changes the server made by NewServer.
+type ServerOption func(params *serverParams)
+
+type serverParams struct {
+	id            int
+	name          string
+	uuid          string
+	game          domain.Game
+	gameMod       domain.GameMod
+	ip            string
+	connectPort   int
+	queryPort     int
+	rconPort      int
+	rconPassword  string
+	dir           string
+	user          string
+	startCommand  string
+	processActive bool
+	vars          map[string]string
+	settings      domain.Settings
+}
+
+// NewServer returns the installed game server, the options change its defaults.
+func NewServer(options ...ServerOption) *domain.Server {
+	params := &serverParams{
+		id:           1,
+		name:         "name",
+		uuid:         defaultServerUUID,
+		game:         domain.Game{Code: "cstrike", StartCode: "cstrike"},
+		gameMod:      domain.GameMod{Name: "public"},
+		ip:           "1.3.3.7",
+		connectPort:  1337,
+		queryPort:    1338,
+		rconPort:     1339,
+		rconPassword: "paS$w0rD",
+		dir:          "server",
+		user:         "gameap-user",
+		startCommand: "./run.sh",
+		vars:         map[string]string{},
+		settings:     map[string]string{},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	return domain.NewServer(
+		params.id,
+		true,
+		domain.ServerInstalled,
+		false,
+		params.name,
+		params.uuid,
+		params.uuid[:8],
+		params.game,
+		params.gameMod,
+		params.ip,
+		params.connectPort,
+		params.queryPort,
+		params.rconPort,
+		params.rconPassword,
+		params.dir,
+		params.user,
+		params.startCommand,
+		"",
+		"",
+		"",
+		params.processActive,
+		time.Now(),
+		params.vars,
+		params.settings,
+		time.Now(),
+	)
+}
+
+func WithID(id int) ServerOption {
+	return func(params *serverParams) {
+		params.id = id
+	}
+}
+
+func WithName(name string) ServerOption {
+	return func(params *serverParams) {
+		params.name = name
+	}
+}
+
+func WithUUID(uuid string) ServerOption {
+	return func(params *serverParams) {
+		params.uuid = uuid
+	}
+}
+
+func WithGame(game domain.Game) ServerOption {
+	return func(params *serverParams) {
+		params.game = game
+	}
+}
+
+func WithGameMod(gameMod domain.GameMod) ServerOption {
+	return func(params *serverParams) {
+		params.gameMod = gameMod
+	}
+}
+
+// WithAddress sets the IP and the ports, the zero RCON port means the server has no RCON.
+func WithAddress(ip string, connectPort, queryPort, rconPort int) ServerOption {
+	return func(params *serverParams) {
+		params.ip = ip
+		params.connectPort = connectPort
+		params.queryPort = queryPort
+		params.rconPort = rconPort
+	}
+}
+
+func WithDir(dir string) ServerOption {
+	return func(params *serverParams) {
+		params.dir = dir
+	}
+}
+
+func WithUser(user string) ServerOption {
+	return func(params *serverParams) {
+		params.user = user
+	}
+}
+
+func WithStartCommand(startCommand string) ServerOption {
+	return func(params *serverParams) {
+		params.startCommand = startCommand
+	}
+}
+
+func WithVars(vars map[string]string) ServerOption {
+	return func(params *serverParams) {
+		params.vars = vars
+	}
+}
+
+func WithSettings(settings domain.Settings) ServerOption {
+	return func(params *serverParams) {
+		params.settings = settings
+	}
+}
+
+// Active marks the game server process as running.
+func Active() ServerOption {
+	return func(params *serverParams) {
+		params.processActive = true
+	}
+}
+
+// Stopped marks the game server process as not running.
+func Stopped() ServerOption {
+	return func(params *serverParams) {
+		params.processActive = false
+	}
+}
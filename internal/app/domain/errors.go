@@ -3,8 +3,13 @@ package domain
 import (
 	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
+// ErrAPIUnavailable is returned without making a request while the API is considered down.
+var ErrAPIUnavailable = errors.New("api is temporarily unavailable")
+
 type ErrInvalidResponseFromAPI struct {
 	body []byte
 	code int
@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 type GDTaskStatus string
@@ -26,17 +27,21 @@ var GDTaskStatusNumMap = map[GDTaskStatus]uint8{
 type GDTaskCommand string
 
 const (
-	GDTaskGameServerStart     GDTaskCommand = "gsstart"
-	GDTaskGameServerPause     GDTaskCommand = "gspause" // NOT Implemented
-	GDTaskGameServerStop      GDTaskCommand = "gsstop"
-	GDTaskGameServerKill      GDTaskCommand = "gskill" // NOT Implemented
-	GDTaskGameServerRestart   GDTaskCommand = "gsrest"
-	GDTaskGameServerInstall   GDTaskCommand = "gsinst"
-	GDTaskGameServerReinstall GDTaskCommand = "gsreinst" // NOT Implemented
-	GDTaskGameServerUpdate    GDTaskCommand = "gsupd"
-	GDTaskGameServerDelete    GDTaskCommand = "gsdel"
-	GDTaskGameServerMove      GDTaskCommand = "gsdel"
-	GDTaskCommandExecute      GDTaskCommand = "cmdexec"
+	GDTaskGameServerStart       GDTaskCommand = "gsstart"
+	GDTaskGameServerPause       GDTaskCommand = "gspause" // NOT Implemented
+	GDTaskGameServerStop        GDTaskCommand = "gsstop"
+	GDTaskGameServerKill        GDTaskCommand = "gskill" // NOT Implemented
+	GDTaskGameServerRestart     GDTaskCommand = "gsrest"
+	GDTaskGameServerInstall     GDTaskCommand = "gsinst"
+	GDTaskGameServerReinstall   GDTaskCommand = "gsreinst" // NOT Implemented
+	GDTaskGameServerUpdate      GDTaskCommand = "gsupd"
+	GDTaskGameServerDelete      GDTaskCommand = "gsdel"
+	GDTaskGameServerMove        GDTaskCommand = "gsdel"
+	GDTaskGameServerWorkshop    GDTaskCommand = "gswsinst"
+	GDTaskGameServerDryRun      GDTaskCommand = "gsinstdry"
+	GDTaskCommandExecute        GDTaskCommand = "cmdexec"
+	GDTaskGameCachePrune        GDTaskCommand = "cacheprune"
+	GDTaskProcessManagerMigrate GDTaskCommand = "pmmigrate"
 )
 
 type GDTaskRepository interface {
@@ -54,6 +59,10 @@ type GDTask struct {
 	cmd         string
 	id          int
 	runAfterID  int
+	timeout     time.Duration
+
+	// installStatusBefore is the server installation status before the installation task started
+	installStatusBefore InstallationStatus
 }
 
 func NewGDTask(
@@ -103,6 +112,15 @@ func (task *GDTask) Server() *Server {
 	return task.server
 }
 
+// Timeout returns the task timeout set in the panel, zero means the timeout from the daemon config is used.
+func (task *GDTask) Timeout() time.Duration {
+	return task.timeout
+}
+
+func (task *GDTask) SetTimeout(timeout time.Duration) {
+	task.timeout = timeout
+}
+
 func (task *GDTask) SetStatus(status GDTaskStatus) error {
 	task.statusMutex.Lock()
 	defer task.statusMutex.Unlock()
@@ -114,16 +132,31 @@ func (task *GDTask) SetStatus(status GDTaskStatus) error {
 	return nil
 }
 
+// affectServer changes the server installation status by the installation task status.
+// The installation command sets the status itself if it knows the state of the server files,
+// e.g. the rolled back update leaves the server installed, then the failed task doesn't change it.
 func (task *GDTask) affectServer() {
-	if task.IsInstallation() {
-		switch task.status {
-		case GDTaskStatusError, GDTaskStatusWaiting:
+	if !task.IsInstallation() {
+		return
+	}
+
+	switch task.status {
+	case GDTaskStatusWaiting:
+		task.server.SetInstallationStatus(ServerNotInstalled)
+	case GDTaskStatusError:
+		if task.server.InstallationStatus() == ServerInstallInProcess {
 			task.server.SetInstallationStatus(ServerNotInstalled)
-		case GDTaskStatusSuccess:
-			task.server.SetInstallationStatus(ServerInstalled)
-		case GDTaskStatusWorking:
-			task.server.SetInstallationStatus(ServerInstallInProcess)
 		}
+	case GDTaskStatusCanceled:
+		// The canceled installation removes the installed files only if the server had no files before
+		if task.server.InstallationStatus() == ServerInstallInProcess {
+			task.server.SetInstallationStatus(task.installStatusBefore)
+		}
+	case GDTaskStatusSuccess:
+		task.server.SetInstallationStatus(ServerInstalled)
+	case GDTaskStatusWorking:
+		task.installStatusBefore = task.server.InstallationStatus()
+		task.server.SetInstallationStatus(ServerInstallInProcess)
 	}
 }
 
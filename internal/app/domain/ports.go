@@ -0,0 +1,13 @@
+package domain
+
+// PortRange is the inclusive range of ports.
+type PortRange struct {
+	From int
+	To   int
+}
+
+// FreePorts are the port ranges available on the node IP for both TCP and UDP.
+type FreePorts struct {
+	IP     string
+	Ranges []PortRange
+}
@@ -3,9 +3,12 @@ package domain
 import (
 	"context"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/emirpasic/gods/sets/hashset"
 )
@@ -32,12 +35,27 @@ const (
 	Install
 	Reinstall
 	Delete
+	InstallWorkshop
+	InstallDryRun
 )
 
 const autostartSettingKey = "autostart"
 const autostartCurrentSettingKey = "autostart_current"
 const updateBeforeStartSettingKey = "update_before_start"
 
+const (
+	workshopItemsSettingKey  = "workshop_items"
+	workshopAppIDSettingKey  = "workshop_app_id"
+	workshopTargetSettingKey = "workshop_target"
+)
+
+const (
+	envSettingPrefix       = "env."
+	secretEnvSettingPrefix = "secret_env."
+)
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type workDirReader interface {
 	WorkDir() string
 }
@@ -432,6 +450,75 @@ func (s *Server) UpdateBeforeStart() bool {
 	return s.readBoolSetting(s.setting(updateBeforeStartSettingKey))
 }
 
+// WorkshopItems returns the Steam Workshop item IDs listed in the server settings.
+// IDs are separated by commas or whitespaces, invalid IDs are skipped.
+func (s *Server) WorkshopItems() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fields := strings.FieldsFunc(s.setting(workshopItemsSettingKey), func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+
+	items := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if _, err := strconv.ParseUint(f, 10, 64); err != nil {
+			continue
+		}
+
+		items = append(items, f)
+	}
+
+	return items
+}
+
+// WorkshopAppID returns the Steam application ID the workshop items belong to, if it is set.
+func (s *Server) WorkshopAppID() string {
+	return s.Setting(workshopAppIDSettingKey)
+}
+
+// WorkshopTarget returns the path the workshop items are linked to, if it is set.
+func (s *Server) WorkshopTarget() string {
+	return s.Setting(workshopTargetSettingKey)
+}
+
+// Environment returns the environment variables of the game server process,
+// they are set by the "env.<NAME>" settings. Invalid names are skipped.
+func (s *Server) Environment() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.environment(envSettingPrefix)
+}
+
+// SecretEnvironment returns the environment variables set by the "secret_env.<NAME>" settings.
+// Process managers don't write them into the service files or the command line.
+func (s *Server) SecretEnvironment() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.environment(secretEnvSettingPrefix)
+}
+
+func (s *Server) environment(prefix string) map[string]string {
+	env := make(map[string]string)
+
+	for key, value := range s.settings {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(key, prefix)
+		if !envNameRegexp.MatchString(name) {
+			continue
+		}
+
+		env[name] = value
+	}
+
+	return env
+}
+
 func (s *Server) InstallationStatus() InstallationStatus {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
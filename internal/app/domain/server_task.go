@@ -32,6 +32,11 @@ type ServerTaskRepository interface {
 	Fail(ctx context.Context, task *ServerTask, output []byte) error
 }
 
+// TaskSchedule computes the next run time of the server task, e.g. from the cron expression.
+type TaskSchedule interface {
+	Next(t time.Time) time.Time
+}
+
 type ServerTask struct {
 	executeDate  time.Time
 	server       *Server
@@ -42,6 +47,11 @@ type ServerTask struct {
 	repeat       int
 	repeatPeriod time.Duration
 	counter      int
+	timeout      time.Duration
+
+	schedule            TaskSchedule
+	jitter              time.Duration
+	skipIfPlayersOnline bool
 }
 
 func NewServerTask(
@@ -97,6 +107,39 @@ func (s *ServerTask) Server() *Server {
 	return s.server
 }
 
+// Timeout returns the task timeout set in the panel, zero means the timeout from the daemon config is used.
+func (s *ServerTask) Timeout() time.Duration {
+	return s.timeout
+}
+
+func (s *ServerTask) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// Schedule returns the schedule of the task, nil means the task is repeated with the repeat period.
+func (s *ServerTask) Schedule() TaskSchedule {
+	return s.schedule
+}
+
+// Jitter returns the maximum random delay added to the scheduled run time.
+func (s *ServerTask) Jitter() time.Duration {
+	return s.jitter
+}
+
+func (s *ServerTask) SetSchedule(schedule TaskSchedule, jitter time.Duration) {
+	s.schedule = schedule
+	s.jitter = jitter
+}
+
+// SkipIfPlayersOnline returns true if the run should be skipped while players are on the game server.
+func (s *ServerTask) SkipIfPlayersOnline() bool {
+	return s.skipIfPlayersOnline
+}
+
+func (s *ServerTask) SetSkipIfPlayersOnline(skip bool) {
+	s.skipIfPlayersOnline = skip
+}
+
 func (s *ServerTask) Repeat() int {
 	return s.repeat
 }
@@ -119,11 +162,26 @@ func (s *ServerTask) Counter() int {
 	return s.counter
 }
 
-func (s *ServerTask) IncreaseCountersAndTime() {
+// Reschedule sets the next run time of the task.
+func (s *ServerTask) Reschedule(executeDate time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.executeDate = executeDate
+}
+
+// Prolong moves the next run time by the repeat period.
+func (s *ServerTask) Prolong() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	s.prolongTask()
+}
+
+func (s *ServerTask) IncreaseCounter() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.counter++
 }
 
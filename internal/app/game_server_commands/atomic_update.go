@@ -0,0 +1,284 @@
+package gameservercommands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+const (
+	stagingDirSuffix  = ".gameap-update"
+	previousDirSuffix = ".gameap-previous"
+)
+
+var errUpdateRolledBack = errors.New("update is rolled back")
+
+// atomicUpdate stages the update in the sibling directory of the server,
+// so the server files aren't changed until the update is completed.
+// The previous files are kept until the server is started after the update.
+type atomicUpdate struct {
+	dir      string
+	staging  string
+	previous string
+	output   io.Writer
+}
+
+func newAtomicUpdate(dir string, output io.Writer) *atomicUpdate {
+	parent, name := filepath.Split(filepath.Clean(dir))
+
+	return &atomicUpdate{
+		dir:      dir,
+		staging:  filepath.Join(parent, "."+name+stagingDirSuffix),
+		previous: filepath.Join(parent, "."+name+previousDirSuffix),
+		output:   output,
+	}
+}
+
+// stage copies the server files to the staging directory.
+// Files are reflinked if the filesystem supports it.
+func (u *atomicUpdate) stage(ctx context.Context) error {
+	err := u.recover(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Leftovers of the interrupted update
+	err = os.RemoveAll(u.staging)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to remove staging directory")
+	}
+	err = os.RemoveAll(u.previous)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to remove previous files")
+	}
+
+	u.writeOutput(ctx, "Staging update in "+u.staging+" ...")
+
+	_, err = gamecache.PlaceTree(u.dir, u.staging, config.GameCacheLinkModeAuto)
+	if err != nil {
+		_ = os.RemoveAll(u.staging)
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to copy server files to staging directory")
+	}
+
+	return nil
+}
+
+// recover restores the previous files if the daemon was stopped in the middle of the swap.
+func (u *atomicUpdate) recover(ctx context.Context) error {
+	if exists(u.dir) || !exists(u.previous) {
+		return nil
+	}
+
+	u.writeOutput(ctx, "Restoring server files from the interrupted update ...")
+
+	err := os.Rename(u.previous, u.dir)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to restore previous files")
+	}
+
+	return nil
+}
+
+// discard removes the staging directory, the server files are left unchanged.
+func (u *atomicUpdate) discard(ctx context.Context) {
+	err := os.RemoveAll(u.staging)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to remove staging directory"))
+	}
+
+	u.writeOutput(ctx, "Update failed, server files are left unchanged")
+}
+
+// swap replaces the server files with the updated ones. The previous files are kept for the rollback.
+func (u *atomicUpdate) swap(ctx context.Context) error {
+	err := os.Rename(u.dir, u.previous)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to move server files")
+	}
+
+	err = os.Rename(u.staging, u.dir)
+	if err != nil {
+		if restoreErr := os.Rename(u.previous, u.dir); restoreErr != nil {
+			logger.Error(ctx, restoreErr)
+		}
+
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to move updated files")
+	}
+
+	u.writeOutput(ctx, "Updated files swapped in, previous files are kept in "+u.previous)
+
+	return nil
+}
+
+// rollback restores the previous files, the updated files are removed.
+func (u *atomicUpdate) rollback(ctx context.Context) error {
+	u.writeOutput(ctx, "Rolling back the update ...")
+
+	err := os.Rename(u.dir, u.staging)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to move updated files")
+	}
+
+	err = os.Rename(u.previous, u.dir)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to restore previous files")
+	}
+
+	err = os.RemoveAll(u.staging)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to remove updated files"))
+	}
+
+	u.writeOutput(ctx, "Update rolled back, previous files restored")
+
+	return nil
+}
+
+// commit removes the previous files.
+func (u *atomicUpdate) commit(ctx context.Context) {
+	err := os.RemoveAll(u.previous)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "[game_server_commands.atomicUpdate] failed to remove previous files"))
+	}
+
+	u.writeOutput(ctx, "Update completed, previous files removed")
+}
+
+func (u *atomicUpdate) writeOutput(ctx context.Context, line string) {
+	logger.Info(ctx, line)
+
+	_, _ = u.output.Write([]byte(line + "\n"))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+// atomicUpdateUsable reports whether the update is staged and swapped in.
+// Servers without files are updated in place, there is nothing to roll back to.
+// The servers are also updated in place if the preflight checks found no space for the staging directory.
+func (cmd *installServer) atomicUpdateUsable(server *domain.Server) bool {
+	if cmd.kind != updater || cmd.cfg.Update.InPlace || cmd.inPlaceUpdate {
+		return false
+	}
+
+	stat, err := os.Stat(server.WorkDir(cmd.cfg))
+
+	return err == nil && stat.IsDir()
+}
+
+// updateAtomically installs the update to the staging directory and swaps it in on success.
+func (cmd *installServer) updateAtomically(ctx context.Context, server *domain.Server) error {
+	update := newAtomicUpdate(server.WorkDir(cmd.cfg), cmd.installOutput)
+
+	err := update.stage(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.installator.stagingDir = update.staging
+	defer func() {
+		cmd.installator.stagingDir = ""
+	}()
+
+	err = cmd.install(ctx, server)
+	if err != nil {
+		cmd.discardAtomicUpdate(ctx, server, update)
+		return err
+	}
+
+	err = update.swap(ctx)
+	if err != nil {
+		cmd.discardAtomicUpdate(ctx, server, update)
+		return err
+	}
+
+	cmd.update = update
+
+	return nil
+}
+
+// discardAtomicUpdate removes the staged update, the server stays installed with the unchanged files.
+func (cmd *installServer) discardAtomicUpdate(ctx context.Context, server *domain.Server, update *atomicUpdate) {
+	update.discard(ctx)
+	server.SetInstallationStatus(domain.ServerInstalled)
+}
+
+// completeAtomicUpdate keeps the update if the server that was active before the update
+// is running after the start grace period, otherwise the update is rolled back.
+func (cmd *installServer) completeAtomicUpdate(ctx context.Context, server *domain.Server, err error) error {
+	if err == nil && cmd.serverWasActiveBeforeInstallation {
+		err = cmd.waitServerStarted(ctx, server)
+	}
+
+	if err == nil {
+		cmd.update.commit(ctx)
+		return nil
+	}
+
+	cmd.update.writeOutput(ctx, "Server failed after the update: "+err.Error())
+
+	if cmd.serverWasActiveBeforeInstallation {
+		stopErr := cmd.stopCommand.Execute(ctx, server)
+		if stopErr != nil {
+			logger.Warn(ctx, stopErr)
+		}
+	}
+
+	cmd.SetResult(ErrorResult)
+
+	rollbackErr := cmd.update.rollback(ctx)
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+
+	// The previous files are restored, the failed update task doesn't mark the server not installed
+	server.SetInstallationStatus(domain.ServerInstalled)
+
+	if cmd.serverWasActiveBeforeInstallation {
+		startErr := cmd.startCommand.Execute(ctx, server)
+		if startErr != nil {
+			logger.Warn(ctx, startErr)
+		}
+	}
+
+	return errors.WithMessage(errUpdateRolledBack, err.Error())
+}
+
+func (cmd *installServer) waitServerStarted(ctx context.Context, server *domain.Server) error {
+	if cmd.startCommand.Result() != SuccessResult {
+		return errors.New("failed to start server")
+	}
+
+	gracePeriod := cmd.cfg.Update.StartGracePeriod
+	if gracePeriod > 0 {
+		cmd.update.writeOutput(ctx, "Waiting "+gracePeriod.String()+" for the server to start ...")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(gracePeriod):
+		}
+	}
+
+	err := cmd.statusCommand.Execute(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	if cmd.statusCommand.Result() != SuccessResult {
+		return errors.New("server is not running after the start grace period")
+	}
+
+	return nil
+}
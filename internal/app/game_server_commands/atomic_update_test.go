@@ -0,0 +1,243 @@
+package gameservercommands
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/gameap/daemon/internal/processmanager"
+	"github.com/gameap/daemon/test/mocks"
+	"github.com/gameap/daemon/test/mocks/commandmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate_Atomic_UpdatedFilesSwappedIn(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	serverDir := givenServerDirectory(t, cfg)
+	update := givenUpdateServer(cfg, commandmocks.LoadServerCommand(domain.Status))
+
+	err := update.Execute(context.Background(), givenServerForUpdate(t, givenLocalRepository(t, "new.txt")))
+
+	require.NoError(t, err)
+	assert.Equal(t, SuccessResult, update.Result())
+	assert.FileExists(t, filepath.Join(serverDir, "old.txt"))
+	assert.FileExists(t, filepath.Join(serverDir, "new.txt"))
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, ".test-server"+stagingDirSuffix))
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, ".test-server"+previousDirSuffix))
+	out := string(update.ReadOutput())
+	assert.Contains(t, out, "Updated files swapped in")
+	assert.Contains(t, out, "Update completed, previous files removed")
+}
+
+func TestUpdate_Atomic_UpdateFailed_ServerFilesUnchanged(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	serverDir := givenServerDirectory(t, cfg)
+	update := givenUpdateServer(cfg, commandmocks.LoadServerCommand(domain.Status))
+	repository := filepath.Join(t.TempDir(), "broken.tar.gz")
+	require.NoError(t, os.WriteFile(repository, []byte("not an archive"), 0600))
+
+	err := update.Execute(context.Background(), givenServerForUpdate(t, repository))
+
+	require.Error(t, err)
+	assert.Equal(t, ErrorResult, update.Result())
+	assert.FileExists(t, filepath.Join(serverDir, "old.txt"))
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, ".test-server"+stagingDirSuffix))
+	assert.Contains(t, string(update.ReadOutput()), "Update failed, server files are left unchanged")
+}
+
+func TestUpdate_Atomic_ServerNotStarted_RolledBack(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Update.StartGracePeriod = time.Millisecond
+	serverDir := givenServerDirectory(t, cfg)
+	// The server is active before the update and isn't running after the start
+	status := &sequenceCommand{results: []int{SuccessResult, ErrorResult}}
+	update := givenUpdateServer(cfg, status)
+
+	err := update.Execute(context.Background(), givenServerForUpdate(t, givenLocalRepository(t, "new.txt")))
+
+	require.ErrorIs(t, err, errUpdateRolledBack)
+	assert.Equal(t, ErrorResult, update.Result())
+	assert.FileExists(t, filepath.Join(serverDir, "old.txt"))
+	assert.NoFileExists(t, filepath.Join(serverDir, "new.txt"))
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, ".test-server"+stagingDirSuffix))
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, ".test-server"+previousDirSuffix))
+	out := string(update.ReadOutput())
+	assert.Contains(t, out, "Server failed after the update: server is not running after the start grace period")
+	assert.Contains(t, out, "Update rolled back, previous files restored")
+}
+
+func TestUpdate_Atomic_RolledBackTaskFailed_ServerStaysInstalled(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Update.StartGracePeriod = time.Millisecond
+	givenServerDirectory(t, cfg)
+	status := &sequenceCommand{results: []int{SuccessResult, ErrorResult}}
+	update := givenUpdateServer(cfg, status)
+	server := givenServerForUpdate(t, givenLocalRepository(t, "new.txt"))
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerUpdate, "", domain.GDTaskStatusWaiting)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusWorking))
+	// The running server is stopped and started again by the update only if it's marked installed
+	server.SetInstallationStatus(domain.ServerInstalled)
+
+	err := update.Execute(context.Background(), server)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusError))
+
+	require.ErrorIs(t, err, errUpdateRolledBack)
+	assert.Equal(t, domain.InstallationStatus(domain.ServerInstalled), server.InstallationStatus())
+}
+
+func TestUpdate_Atomic_UpdateFailedTask_ServerStaysInstalled(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	givenServerDirectory(t, cfg)
+	update := givenUpdateServer(cfg, commandmocks.LoadServerCommand(domain.Status))
+	repository := filepath.Join(t.TempDir(), "broken.tar.gz")
+	require.NoError(t, os.WriteFile(repository, []byte("not an archive"), 0600))
+	server := givenServerForUpdate(t, repository)
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerUpdate, "", domain.GDTaskStatusWaiting)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusWorking))
+
+	err := update.Execute(context.Background(), server)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusError))
+
+	require.Error(t, err)
+	assert.Equal(t, domain.InstallationStatus(domain.ServerInstalled), server.InstallationStatus())
+}
+
+func TestUpdate_InPlace_NotStaged(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Update.InPlace = true
+	serverDir := givenServerDirectory(t, cfg)
+	update := givenUpdateServer(cfg, commandmocks.LoadServerCommand(domain.Status))
+
+	err := update.Execute(context.Background(), givenServerForUpdate(t, givenLocalRepository(t, "new.txt")))
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(serverDir, "new.txt"))
+	assert.NotContains(t, string(update.ReadOutput()), "Staging update")
+}
+
+func TestUpdate_InPlace_Canceled_ServerStaysInstalled(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Update.InPlace = true
+	serverDir := givenServerDirectory(t, cfg)
+	update := givenUpdateServer(cfg, commandmocks.LoadServerCommand(domain.Status))
+	repository := filepath.Join(t.TempDir(), "broken.tar.gz")
+	require.NoError(t, os.WriteFile(repository, []byte("not an archive"), 0600))
+	server := givenServerForUpdate(t, repository)
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerUpdate, "", domain.GDTaskStatusWaiting)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusWorking))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := update.Execute(ctx, server)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusCanceled))
+
+	require.Error(t, err)
+	assert.FileExists(t, filepath.Join(serverDir, "old.txt"))
+	assert.Equal(t, domain.InstallationStatus(domain.ServerInstalled), server.InstallationStatus())
+}
+
+func TestAtomicUpdate_InterruptedSwap_PreviousFilesRecovered(t *testing.T) {
+	workPath := t.TempDir()
+	update := newAtomicUpdate(filepath.Join(workPath, "test-server"), components.NewSafeBuffer())
+	require.NoError(t, os.MkdirAll(update.previous, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(update.previous, "old.txt"), []byte("old"), 0600))
+
+	err := update.stage(context.Background())
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(workPath, "test-server", "old.txt"))
+	assert.FileExists(t, filepath.Join(update.staging, "old.txt"))
+	assert.NoDirExists(t, update.previous)
+}
+
+func givenServerDirectory(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+
+	dir := filepath.Join(cfg.WorkPath, "test-server")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0600))
+
+	return dir
+}
+
+func givenLocalRepository(t *testing.T, fileName string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName), []byte("new"), 0600))
+
+	return dir
+}
+
+func givenUpdateServer(cfg *config.Config, statusCommand contracts.GameServerCommand) *installServer {
+	return newUpdateServer(
+		cfg,
+		components.NewExecutor(),
+		processmanager.NewSimple(cfg, components.NewExecutor(), components.NewExecutor()),
+		mocks.NewServerRepository(),
+		nil,
+		nil,
+		nil,
+		statusCommand,
+		commandmocks.LoadServerCommand(domain.Stop),
+		commandmocks.LoadServerCommand(domain.Start),
+	)
+}
+
+// givenServerForUpdate returns the server owned by the current user, so files can be chowned.
+func givenServerForUpdate(t *testing.T, localRepository string) *domain.Server {
+	t.Helper()
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	return givenServer(
+		t,
+		domain.Game{StartCode: "test", LocalRepository: localRepository},
+		domain.GameMod{},
+		domaintest.WithUser(currentUser.Username),
+	)
+}
+
+// sequenceCommand returns the results in order, the last result is repeated.
+type sequenceCommand struct {
+	results  []int
+	executed int
+	complete bool
+}
+
+func (c *sequenceCommand) Execute(_ context.Context, _ *domain.Server) error {
+	c.executed++
+	c.complete = true
+
+	return nil
+}
+
+func (c *sequenceCommand) Result() int {
+	if c.executed == 0 {
+		return UnknownResult
+	}
+
+	if c.executed > len(c.results) {
+		return c.results[len(c.results)-1]
+	}
+
+	return c.results[c.executed-1]
+}
+
+func (c *sequenceCommand) IsComplete() bool {
+	return c.complete
+}
+
+func (c *sequenceCommand) ReadOutput() []byte {
+	return nil
+}
@@ -27,6 +27,9 @@ type ServerCommandFactory struct {
 	serverRepo     domain.ServerRepository
 	executor       contracts.Executor
 	processManager contracts.ProcessManager
+	steamCMD       contracts.SteamCMDInstaller
+	gameCache      contracts.GameCache
+	verifier       contracts.Verifier
 }
 
 func NewFactory(
@@ -34,12 +37,18 @@ func NewFactory(
 	serverRepo domain.ServerRepository,
 	executor contracts.Executor,
 	processManager contracts.ProcessManager,
+	steamCMD contracts.SteamCMDInstaller,
+	gameCache contracts.GameCache,
+	verifier contracts.Verifier,
 ) *ServerCommandFactory {
 	return &ServerCommandFactory{
 		cfg,
 		serverRepo,
 		executor,
 		processManager,
+		steamCMD,
+		gameCache,
+		verifier,
 	}
 }
 
@@ -64,6 +73,10 @@ func (factory *ServerCommandFactory) LoadServerCommand(
 		return factory.makeReinstallCommand(server)
 	case domain.Delete:
 		return factory.makeDeleteCommand(server)
+	case domain.InstallWorkshop:
+		return newInstallWorkshop(factory.cfg, factory.executor, factory.processManager, factory.steamCMD)
+	case domain.InstallDryRun:
+		return newInstallDryRun(factory.cfg, factory.executor, factory.processManager, factory.steamCMD)
 	case domain.Pause:
 	case domain.Unpause:
 		return newNotImplementedCommand(factory.cfg, factory.executor, factory.processManager)
@@ -104,6 +117,9 @@ func (factory *ServerCommandFactory) makeInstallCommand(server *domain.Server) c
 		factory.executor,
 		factory.processManager,
 		factory.serverRepo,
+		factory.steamCMD,
+		factory.gameCache,
+		factory.verifier,
 		factory.makeStatusCommand(server),
 		factory.makeStopCommand(server),
 		factory.makeStartCommand(server, nilLoadServerCommandFunc),
@@ -116,6 +132,9 @@ func (factory *ServerCommandFactory) makeUpdateCommand(server *domain.Server) co
 		factory.executor,
 		factory.processManager,
 		factory.serverRepo,
+		factory.steamCMD,
+		factory.gameCache,
+		factory.verifier,
 		factory.makeStatusCommand(server),
 		factory.makeStopCommand(server),
 		factory.makeStartCommand(server, nilLoadServerCommandFunc),
@@ -130,6 +149,9 @@ func (factory *ServerCommandFactory) makeReinstallCommand(server *domain.Server)
 			factory.executor,
 			factory.processManager,
 			factory.serverRepo,
+			factory.steamCMD,
+			factory.gameCache,
+			factory.verifier,
 			factory.makeStatusCommand(server),
 			factory.makeStopCommand(server),
 			factory.makeStartCommand(server, nilLoadServerCommandFunc),
@@ -0,0 +1,50 @@
+package gameservercommands
+
+import (
+	"context"
+	"io"
+	"os/user"
+	"strconv"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/configtemplates"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+// renderConfigTemplates renders the game config templates before the server start.
+// The changed files are owned by the server user if the daemon runs as root.
+func renderConfigTemplates(ctx context.Context, cfg *config.Config, server *domain.Server, out io.Writer) error {
+	changed, err := configtemplates.Render(ctx, cfg, server, out)
+	if err != nil {
+		_, _ = out.Write([]byte(err.Error() + "\n"))
+		return errors.WithMessage(err, "[game_server_commands] failed to render config templates")
+	}
+
+	if len(changed) == 0 || server.User() == "" || !isRootUser() {
+		return nil
+	}
+
+	systemUser, err := user.Lookup(server.User())
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands] failed to lookup user")
+	}
+
+	uid, err := strconv.Atoi(systemUser.Uid)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands] invalid user uid")
+	}
+	gid, err := strconv.Atoi(systemUser.Gid)
+	if err != nil {
+		return errors.WithMessage(err, "[game_server_commands] invalid user gid")
+	}
+
+	for _, path := range changed {
+		err = chownR(path, uid, gid)
+		if err != nil {
+			return errors.WithMessage(err, "[game_server_commands] failed to change config owner")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package gameservercommands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+var steamBuildIDRegexp = regexp.MustCompile(`"buildid"\s+"(\d+)"`)
+
+func (in *installator) gameCacheEnabled() bool {
+	return in.gameCache != nil && in.gameCache.Enabled()
+}
+
+// cachedArchive returns the remote repository archive from the game cache,
+// so the archive is downloaded once and revalidated for the next installations.
+func (in *installator) cachedArchive(ctx context.Context, source string) (string, func(), bool) {
+	if !in.gameCacheEnabled() {
+		return "", nil, false
+	}
+
+	archive, release, err := in.gameCache.Archive(ctx, source, in.output)
+	if errors.Is(err, gamecache.ErrNotCacheable) {
+		return "", nil, false
+	}
+	if err != nil {
+		in.writeOutput(ctx, "Failed to get the archive from the game cache: "+err.Error())
+		return "", nil, false
+	}
+
+	return archive, release, true
+}
+
+// steamCacheUsable reports whether the game files from the game cache can be placed into the server directory.
+// Only new installations are cached, the directories of installed servers contain configs and user data.
+func (in *installator) steamCacheUsable(server *domain.Server) bool {
+	if !in.gameCacheEnabled() || in.kind != installer {
+		return false
+	}
+
+	entries, err := os.ReadDir(server.WorkDir(in.cfg))
+
+	return errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0)
+}
+
+// installFromSteamWithCache places the cached game files into the server directory,
+// so SteamCMD only downloads the changes since the cached build.
+// The first installation of the game populates the cache, concurrent installations wait for it.
+func (in *installator) installFromSteamWithCache(
+	ctx context.Context,
+	output io.Writer,
+	server *domain.Server,
+	source string,
+	execCmd string,
+	executorOptions contracts.ExecutorOptions,
+) error {
+	dst := server.WorkDir(in.cfg)
+	key := "steam:" + source
+
+	unlock := in.gameCache.Lock(key)
+
+	restored, err := in.gameCache.Restore(ctx, key, dst)
+	if err != nil {
+		in.writeOutput(ctx, "Failed to restore game files from the game cache: "+err.Error())
+		restored = false
+
+		err = clearDirectory(dst)
+		if err != nil {
+			unlock()
+			return errors.WithMessage(err, "[game_server_commands.installator] failed to clear server directory")
+		}
+	}
+
+	if restored {
+		unlock()
+		in.writeOutput(ctx, "Game files restored from the game cache, updating ...")
+	} else {
+		defer unlock()
+	}
+
+	err = in.runSteamCMD(ctx, output, execCmd, executorOptions)
+	if err != nil {
+		return err
+	}
+
+	buildID := readSteamBuildID(dst, strings.Fields(source)[0])
+	if buildID == "" || buildID == in.gameCache.Version(key) {
+		return nil
+	}
+
+	in.writeOutput(ctx, "Saving game files of build "+buildID+" to the game cache ...")
+
+	err = in.gameCache.Store(ctx, key, buildID, dst)
+	if err != nil {
+		// The installation is successful anyway
+		logger.Warn(ctx, errors.WithMessage(err, "[game_server_commands.installator] failed to save game files to cache"))
+		in.writeOutput(ctx, "Failed to save game files to the game cache: "+err.Error())
+	}
+
+	return nil
+}
+
+// readSteamBuildID returns the installed build ID from the SteamCMD application manifest.
+func readSteamBuildID(dir, appID string) string {
+	content, err := os.ReadFile(filepath.Join(dir, "steamapps", "appmanifest_"+appID+".acf"))
+	if err != nil {
+		return ""
+	}
+
+	matches := steamBuildIDRegexp.FindSubmatch(content)
+	if matches == nil {
+		return ""
+	}
+
+	return string(matches[1])
+}
+
+func clearDirectory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package gameservercommands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallBySteam_GameCache_PopulatedAndRestored(t *testing.T) {
+	cachePath := t.TempDir()
+	cache := gamecache.NewCache(givenGameCacheConfig(t, cachePath))
+	executor := &steamAppExecutor{t: t, buildID: "1337"}
+
+	first := newInstallator(givenGameCacheConfig(t, cachePath), executor, nil, cache, nil, &bytes.Buffer{})
+	err := first.installFromSteam(context.Background(), first.cfg, io.Discard, givenSteamServer(t), "90")
+	require.NoError(t, err)
+	assert.False(t, executor.restored)
+	assert.Equal(t, "1337", cache.Version("steam:90"))
+
+	out := &bytes.Buffer{}
+	second := newInstallator(givenGameCacheConfig(t, cachePath), executor, nil, cache, nil, out)
+	err = second.installFromSteam(context.Background(), second.cfg, io.Discard, givenSteamServer(t), "90")
+	require.NoError(t, err)
+	assert.True(t, executor.restored)
+	assert.Contains(t, out.String(), "Game files restored from the game cache")
+	assert.NotContains(t, out.String(), "Saving game files")
+	assert.FileExists(t, filepath.Join(second.cfg.WorkPath, "test-server", "game.bin"))
+}
+
+func TestInstallBySteam_GameCache_NewBuildStored(t *testing.T) {
+	cachePath := t.TempDir()
+	cache := gamecache.NewCache(givenGameCacheConfig(t, cachePath))
+	executor := &steamAppExecutor{t: t, buildID: "1337"}
+
+	inst := newInstallator(givenGameCacheConfig(t, cachePath), executor, nil, cache, nil, &bytes.Buffer{})
+	require.NoError(t, inst.installFromSteam(context.Background(), inst.cfg, io.Discard, givenSteamServer(t), "90"))
+
+	executor.buildID = "1338"
+	inst = newInstallator(givenGameCacheConfig(t, cachePath), executor, nil, cache, nil, &bytes.Buffer{})
+	require.NoError(t, inst.installFromSteam(context.Background(), inst.cfg, io.Discard, givenSteamServer(t), "90"))
+
+	assert.Equal(t, "1338", cache.Version("steam:90"))
+}
+
+func TestInstallBySteam_GameCache_NotUsedForUpdates(t *testing.T) {
+	cachePath := t.TempDir()
+	cache := gamecache.NewCache(givenGameCacheConfig(t, cachePath))
+	executor := &steamAppExecutor{t: t, buildID: "1337"}
+
+	inst := newUpdater(givenGameCacheConfig(t, cachePath), executor, nil, cache, nil, &bytes.Buffer{})
+	require.NoError(t, inst.installFromSteam(context.Background(), inst.cfg, io.Discard, givenSteamServer(t), "90"))
+
+	assert.Empty(t, cache.Version("steam:90"))
+}
+
+// givenGameCacheConfig returns a config with an own work path and the shared cache path.
+func givenGameCacheConfig(t *testing.T, cachePath string) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.GameCache.Enabled = true
+	cfg.GameCache.Path = cachePath
+	cfg.GameCache.LinkMode = config.GameCacheLinkModeAuto
+
+	return cfg
+}
+
+// steamAppExecutor imitates the SteamCMD application installation.
+// It reports whether the game files were in the install directory before the installation.
+type steamAppExecutor struct {
+	t        *testing.T
+	buildID  string
+	restored bool
+}
+
+func (ex *steamAppExecutor) Exec(_ context.Context, _ string, _ contracts.ExecutorOptions) ([]byte, int, error) {
+	return nil, 0, nil
+}
+
+func (ex *steamAppExecutor) ExecWithWriter(
+	_ context.Context, command string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	installDir := installDirRegexp.FindStringSubmatch(command)[1]
+
+	_, err := os.Stat(filepath.Join(installDir, "game.bin"))
+	ex.restored = err == nil
+
+	require.NoError(ex.t, os.MkdirAll(filepath.Join(installDir, "steamapps"), 0755))
+	require.NoError(ex.t, os.WriteFile(filepath.Join(installDir, "game.bin"), []byte("game"), 0600))
+	require.NoError(ex.t, os.WriteFile(
+		filepath.Join(installDir, "steamapps", "appmanifest_90.acf"),
+		[]byte("\"AppState\"\n{\n\t\"appid\"\t\t\"90\"\n\t\"buildid\"\t\t\""+ex.buildID+"\"\n}\n"),
+		0600,
+	))
+
+	writeTranscript(ex.t, out, "install_success.txt")
+
+	return SuccessResult, nil
+}
@@ -0,0 +1,515 @@
+package gameservercommands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+const (
+	preflightRequestTimeout = 15 * time.Second
+
+	// archiveUnpackRatio is the assumed ratio of the unpacked files size to the archive size.
+	archiveUnpackRatio = 2
+)
+
+var errPreflightChecksFailed = errors.New("preflight checks failed")
+
+type preflightStatus string
+
+const (
+	preflightPassed  preflightStatus = "ok"
+	preflightWarning preflightStatus = "warning"
+	preflightFailed  preflightStatus = "failed"
+)
+
+type preflightCheck struct {
+	Name    string
+	Status  preflightStatus
+	Message string
+}
+
+type preflightReport struct {
+	Checks []preflightCheck
+	// InPlaceUpdate is set if there is no space to stage the update, so the server is updated in place.
+	InPlaceUpdate bool
+}
+
+func (r *preflightReport) add(name string, status preflightStatus, message string) {
+	r.Checks = append(r.Checks, preflightCheck{Name: name, Status: status, Message: message})
+}
+
+func (r *preflightReport) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == preflightFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *preflightReport) write(out io.Writer) {
+	for _, check := range r.Checks {
+		_, _ = fmt.Fprintf(out, "[%s] %s: %s\n", check.Status, check.Name, check.Message)
+	}
+}
+
+// installationPlan is the set of installation rules for the game server.
+type installationPlan struct {
+	GameRules    []*installationRule
+	GameModRules []*installationRule
+	// Staged is set if the update is installed into the copy of the server directory.
+	Staged bool
+}
+
+func defineInstallationPlan(server *domain.Server, kind installatorKind) installationPlan {
+	sd := installationRulesDefiner{}
+
+	game := server.Game()
+	gameMod := server.GameMod()
+
+	plan := installationPlan{
+		GameRules: sd.DefineGameRules(&game),
+	}
+
+	if kind != updater {
+		plan.GameModRules = sd.DefineGameModRules(&gameMod)
+	}
+
+	return plan
+}
+
+func (p installationPlan) write(out io.Writer) {
+	writeRules := func(title string, rules []*installationRule) {
+		_, _ = fmt.Fprintf(out, "%s:\n", title)
+
+		if len(rules) == 0 {
+			_, _ = out.Write([]byte("  none\n"))
+			return
+		}
+
+		for i, rule := range rules {
+			_, _ = fmt.Fprintf(out, "  %d. %s %s\n", i+1, rule.Action, rule.SourceValue)
+		}
+	}
+
+	writeRules("Game installation rules", p.GameRules)
+	writeRules("Game mod installation rules", p.GameModRules)
+}
+
+func (a installAction) String() string {
+	switch a {
+	case downloadAnUnpackFromRemoteRepository:
+		return "download and unpack from remote repository"
+	case unpackFromLocalRepository:
+		return "unpack from local repository"
+	case copyDirectoryFromLocalRepository:
+		return "copy directory from local repository"
+	case installFromSteam:
+		return "install from steam"
+	case unknownAction:
+	}
+
+	return "unknown"
+}
+
+// sourceProbe is the result of the installation source check.
+type sourceProbe struct {
+	rule *installationRule
+	// size is the expected disk space needed by the installation from the source, -1 if unknown.
+	size int64
+}
+
+// preflight checks that the installation can be completed before any files are changed:
+// the installation sources are reachable, the server directory has enough free space,
+// the system user exists and the required tools are present.
+// Unreachable sources fail the checks only if strictSources is set, the mirrors may reject the probe
+// or be unavailable for a moment, so the installation tries them anyway.
+func (in *installator) preflight(
+	ctx context.Context,
+	server *domain.Server,
+	plan installationPlan,
+	strictSources bool,
+) *preflightReport {
+	report := &preflightReport{}
+
+	in.checkUser(server, report)
+
+	var probes []sourceProbe
+
+	if probe, ok := in.checkSources(ctx, "game", plan.GameRules, strictSources, report); ok {
+		probes = append(probes, probe)
+	}
+
+	if len(plan.GameModRules) > 0 {
+		if probe, ok := in.checkSources(ctx, "game mod", plan.GameModRules, strictSources, report); ok {
+			probes = append(probes, probe)
+		}
+	}
+
+	in.checkTools(probes, server, report)
+	in.checkDiskSpace(server, probes, plan.Staged, report)
+
+	return report
+}
+
+func (in *installator) checkUser(server *domain.Server, report *preflightReport) {
+	if server.User() == "" {
+		return
+	}
+
+	_, err := user.Lookup(server.User())
+	if err == nil {
+		report.add("user", preflightPassed, "user "+server.User()+" exists")
+		return
+	}
+
+	// Files are owned by the user only if the daemon runs as root
+	if isRootUser() {
+		report.add("user", preflightFailed, "user "+server.User()+" doesn't exist: "+err.Error())
+	} else {
+		report.add("user", preflightWarning, "user "+server.User()+" doesn't exist: "+err.Error())
+	}
+}
+
+// checkSources probes the rules in the installation order and returns the first usable source.
+func (in *installator) checkSources(
+	ctx context.Context,
+	name string,
+	rules []*installationRule,
+	strict bool,
+	report *preflightReport,
+) (sourceProbe, bool) {
+	checkName := name + " source"
+
+	if len(rules) == 0 {
+		report.add(checkName, preflightFailed, ErrDefinedNoGameInstallationRulesError.Error())
+		return sourceProbe{}, false
+	}
+
+	for _, rule := range rules {
+		probe, err := in.probeSource(ctx, rule)
+		if err != nil {
+			report.add(checkName, preflightWarning, rule.SourceValue+" is not available: "+err.Error())
+			continue
+		}
+
+		message := rule.SourceValue + " is available"
+		if probe.size >= 0 {
+			message += ", expected size " + formatSize(probe.size)
+		}
+		report.add(checkName, preflightPassed, message)
+
+		return probe, true
+	}
+
+	if strict {
+		report.add(checkName, preflightFailed, "no available installation sources")
+	} else {
+		report.add(checkName, preflightWarning, "no available installation sources, trying to install anyway")
+	}
+
+	return sourceProbe{}, false
+}
+
+func (in *installator) probeSource(ctx context.Context, rule *installationRule) (sourceProbe, error) {
+	probe := sourceProbe{rule: rule, size: -1}
+
+	switch rule.Action {
+	case unpackFromLocalRepository:
+		stat, err := os.Stat(rule.SourceValue)
+		if err != nil {
+			return probe, err
+		}
+		probe.size = stat.Size() * archiveUnpackRatio
+	case copyDirectoryFromLocalRepository:
+		size, err := directorySize(rule.SourceValue)
+		if err != nil {
+			return probe, err
+		}
+		probe.size = size
+	case downloadAnUnpackFromRemoteRepository:
+		size, err := in.probeRemoteSource(ctx, rule.SourceValue)
+		if err != nil {
+			return probe, err
+		}
+		if size >= 0 {
+			// The archive is downloaded before unpacking
+			probe.size = size + size*archiveUnpackRatio
+		}
+	case installFromSteam:
+		// The application size is known to SteamCMD only after the login
+	case unknownAction:
+		return probe, errors.New("unknown installation action")
+	}
+
+	return probe, nil
+}
+
+// probeRemoteSource sends the HEAD request to the remote repository and returns the archive size
+// from the Content-Length header, -1 if the size is unknown.
+// Sources that aren't HTTP(S) URLs, e.g. git repositories, aren't checked.
+func (in *installator) probeRemoteSource(ctx context.Context, source string) (int64, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return -1, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return -1, nil
+	}
+
+	// The go-getter parameters and the subdirectory aren't a part of the file URL
+	u.RawQuery = ""
+	if i := strings.Index(u.Path, "//"); i >= 0 {
+		u.Path = u.Path[:i]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preflightRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return -1, errors.Errorf("%s responded with %s", u.Host, resp.Status)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return -1, errors.Errorf("%s responded with %s", u.Host, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		// Some servers don't allow HEAD requests, the file may be available anyway
+		return -1, nil
+	}
+
+	return resp.ContentLength, nil
+}
+
+func (in *installator) checkTools(probes []sourceProbe, server *domain.Server, report *preflightReport) {
+	steamCMDRequired := len(server.WorkshopItems()) > 0
+
+	for _, probe := range probes {
+		switch probe.rule.Action {
+		case installFromSteam:
+			steamCMDRequired = true
+		case unpackFromLocalRepository, downloadAnUnpackFromRemoteRepository:
+			if !isKnownArchive(probe.rule.SourceValue) {
+				report.add(
+					"tools",
+					preflightWarning,
+					"archive format of "+probe.rule.SourceValue+" is not recognized, the file will be copied as is",
+				)
+			}
+		case copyDirectoryFromLocalRepository, unknownAction:
+		}
+	}
+
+	if !steamCMDRequired {
+		return
+	}
+
+	if in.steamCMD != nil {
+		report.add("tools", preflightPassed, "steamcmd is installed on demand")
+		return
+	}
+
+	steamCMD := filepath.Join(in.cfg.SteamCMDPath, config.SteamCMDExecutableFile)
+	if _, err := os.Stat(steamCMD); err != nil {
+		report.add("tools", preflightFailed, "steamcmd is not found: "+err.Error())
+		return
+	}
+
+	report.add("tools", preflightPassed, "steamcmd is found")
+}
+
+// checkDiskSpace checks the free space for the installation. The staged update also needs the space
+// for the copy of the server directory, the server is updated in place if only this space is missing.
+func (in *installator) checkDiskSpace(
+	server *domain.Server,
+	probes []sourceProbe,
+	staged bool,
+	report *preflightReport,
+) {
+	var required int64
+	for _, probe := range probes {
+		if probe.size < 0 {
+			report.add("disk space", preflightWarning, "expected size of "+probe.rule.SourceValue+" is unknown")
+			continue
+		}
+		required += probe.size
+	}
+
+	var staging int64
+	if staged {
+		size, err := directorySize(server.WorkDir(in.cfg))
+		if err != nil {
+			report.add("disk space", preflightWarning, "failed to get server directory size: "+err.Error())
+		} else {
+			staging = size
+		}
+	}
+
+	dir := existingParent(server.WorkDir(in.cfg))
+
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		report.add("disk space", preflightWarning, "failed to get free disk space: "+err.Error())
+		return
+	}
+
+	message := formatSize(int64(free)) + " free in " + dir + ", " + formatSize(required) + " required"
+	if staging > 0 {
+		message += " and " + formatSize(staging) + " to stage the update"
+	}
+
+	switch {
+	case int64(free) < required:
+		report.add("disk space", preflightFailed, "not enough disk space: "+message)
+	case int64(free) < required+staging:
+		report.add("disk space", preflightWarning, "not enough disk space to stage the update, updating in place: "+message)
+		report.InPlaceUpdate = true
+	default:
+		report.add("disk space", preflightPassed, message)
+	}
+}
+
+func isKnownArchive(source string) bool {
+	u, err := url.Parse(source)
+	if err == nil && u.Query().Get("archive") != "" {
+		return true
+	}
+
+	name := source
+	if err == nil && u.Path != "" {
+		name = u.Path
+	}
+
+	for ext := range getter.Decompressors {
+		if strings.HasSuffix(name, "."+ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func directorySize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// existingParent returns the path or its nearest existing parent directory.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// installDryRun prints the installation rules and the preflight check results
+// without changing anything.
+type installDryRun struct {
+	bufCommand
+	baseCommand
+
+	installator *installator
+}
+
+func newInstallDryRun(
+	cfg *config.Config,
+	executor contracts.Executor,
+	processManager contracts.ProcessManager,
+	steamCMD contracts.SteamCMDInstaller,
+) *installDryRun {
+	buffer := components.NewSafeBuffer()
+
+	return &installDryRun{
+		baseCommand: newBaseCommand(cfg, executor, processManager),
+		bufCommand:  bufCommand{output: buffer},
+		installator: newInstallator(cfg, executor, steamCMD, nil, nil, buffer),
+	}
+}
+
+func (cmd *installDryRun) Execute(ctx context.Context, server *domain.Server) error {
+	defer func() {
+		cmd.SetComplete()
+	}()
+
+	if cmd.cfg.Scripts.Install != "" {
+		_, _ = cmd.output.Write([]byte("Game server is installed by the install script: " + cmd.cfg.Scripts.Install + "\n"))
+	}
+
+	plan := defineInstallationPlan(server, installer)
+	plan.write(cmd.output)
+
+	_, _ = cmd.output.Write([]byte("Preflight checks:\n"))
+
+	report := cmd.installator.preflight(ctx, server, plan, true)
+	report.write(cmd.output)
+
+	if report.Failed() {
+		cmd.SetResult(ErrorResult)
+		return nil
+	}
+
+	cmd.SetResult(SuccessResult)
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package gameservercommands
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/test/mocks/commandmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallDryRun_RulesAndChecksPrinted_NothingChanged(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	server := givenLocalInstallationServer(t)
+	cmd := newInstallDryRun(cfg, &testExecutor{}, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.True(t, cmd.IsComplete())
+	out := string(cmd.ReadOutput())
+	assert.Contains(t, out, "Game installation rules:\n  1. unpack from local repository "+server.Game().LocalRepository+"\n")
+	assert.Contains(t, out, "  2. download and unpack from remote repository https://files.gameap.ru/test/test.tar.xz\n")
+	assert.Contains(t, out, "Game mod installation rules:\n  1. copy directory from local repository")
+	assert.Contains(t, out, "[ok] game source: "+server.Game().LocalRepository+" is available, expected size")
+	assert.Contains(t, out, "[ok] disk space: ")
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, "test-server"))
+}
+
+func TestInstallDryRun_NoAvailableSources_Failed(t *testing.T) {
+	repository := httptest.NewServer(http.NotFoundHandler())
+	defer repository.Close()
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cmd := newInstallDryRun(cfg, &testExecutor{}, nil, nil)
+
+	err := cmd.Execute(context.Background(), givenServer(t, domain.Game{
+		StartCode:        "test",
+		RemoteRepository: repository.URL + "/game.tar.gz",
+	}, domain.GameMod{}))
+
+	require.NoError(t, err)
+	assert.Equal(t, ErrorResult, cmd.Result())
+	out := string(cmd.ReadOutput())
+	assert.Contains(t, out, "[warning] game source: "+repository.URL+"/game.tar.gz is not available")
+	assert.Contains(t, out, "[failed] game source: no available installation sources\n")
+}
+
+func TestInstall_PreflightFailed_InstallationRefused(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir(), SteamCMDPath: t.TempDir()}
+	install := newInstallServer(
+		cfg,
+		&testExecutor{},
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		commandmocks.LoadServerCommand(domain.Status),
+		commandmocks.LoadServerCommand(domain.Stop),
+		commandmocks.LoadServerCommand(domain.Start),
+	)
+
+	err := install.Execute(context.Background(), givenServer(t, domain.Game{
+		StartCode:  "test",
+		SteamAppID: 90,
+	}, domain.GameMod{}))
+
+	require.ErrorIs(t, err, errPreflightChecksFailed)
+	assert.Equal(t, ErrorResult, install.Result())
+	assert.Contains(t, string(install.ReadOutput()), "[failed] tools: steamcmd is not found")
+	assert.NoDirExists(t, filepath.Join(cfg.WorkPath, "test-server"))
+}
+
+func TestInstall_NoAvailableSources_InstallationTried(t *testing.T) {
+	repository := httptest.NewServer(http.NotFoundHandler())
+	defer repository.Close()
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	install := newInstallServer(
+		cfg,
+		&testExecutor{},
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		commandmocks.LoadServerCommand(domain.Status),
+		commandmocks.LoadServerCommand(domain.Stop),
+		commandmocks.LoadServerCommand(domain.Start),
+	)
+
+	err := install.Execute(context.Background(), givenServer(t, domain.Game{
+		StartCode:        "test",
+		RemoteRepository: repository.URL + "/game.tar.gz",
+	}, domain.GameMod{}))
+
+	require.Error(t, err)
+	out := string(install.ReadOutput())
+	assert.Contains(t, out, "[warning] game source: no available installation sources, trying to install anyway")
+	assert.NotContains(t, out, "[failed] game source")
+}
+
+func TestPreflight_RemoteSource_SizeFromContentLength(t *testing.T) {
+	repository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, "/game.tar.gz", r.URL.Path)
+		assert.Empty(t, r.URL.RawQuery)
+
+		_, _ = w.Write([]byte(strings.Repeat("a", 1024)))
+	}))
+	defer repository.Close()
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, nil, &bytes.Buffer{})
+	server := givenServer(t, domain.Game{
+		RemoteRepository: repository.URL + "/game.tar.gz?checksum=sha256:" + fileTarGzChecksum,
+	}, domain.GameMod{})
+
+	report := inst.preflight(context.Background(), server, defineInstallationPlan(server, updater), false)
+
+	assert.Contains(t, report.Checks, preflightCheck{
+		Name:    "game source",
+		Status:  preflightPassed,
+		Message: server.Game().RemoteRepository + " is available, expected size 3.0 KiB",
+	})
+}
+
+func TestPreflight_SteamCMDNotFound_Failed(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir(), SteamCMDPath: t.TempDir()}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, nil, &bytes.Buffer{})
+	server := givenServer(t, domain.Game{SteamAppID: 90}, domain.GameMod{})
+
+	report := inst.preflight(context.Background(), server, defineInstallationPlan(server, updater), false)
+
+	require.True(t, report.Failed())
+	assert.Contains(t, report.Checks, preflightCheck{
+		Name:    "disk space",
+		Status:  preflightWarning,
+		Message: "expected size of 90 is unknown",
+	})
+	requireCheck(t, report, "tools", preflightFailed, "steamcmd is not found")
+}
+
+func TestPreflight_NotEnoughDiskSpace_Failed(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, nil, &bytes.Buffer{})
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	report := &preflightReport{}
+
+	inst.checkDiskSpace(server, []sourceProbe{{
+		rule: &installationRule{SourceValue: "huge.tar.gz", Action: unpackFromLocalRepository},
+		size: 1 << 62,
+	}}, false, report)
+
+	requireCheck(t, report, "disk space", preflightFailed, "not enough disk space: ")
+	assert.Contains(t, report.Checks[0].Message, "in "+cfg.WorkPath+", 4.0 EiB required")
+}
+
+func TestPreflight_NoSpaceToStageUpdate_InPlaceUpdate(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, nil, &bytes.Buffer{})
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	serverDir := server.WorkDir(cfg)
+	require.NoError(t, os.MkdirAll(serverDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(serverDir, "game.pak"), make([]byte, 64<<20), 0600))
+	free, err := freeDiskSpace(serverDir)
+	require.NoError(t, err)
+	report := &preflightReport{}
+
+	// The update fits, but the copy of the server directory doesn't
+	inst.checkDiskSpace(server, []sourceProbe{{
+		rule: &installationRule{SourceValue: "update.tar.gz", Action: unpackFromLocalRepository},
+		size: int64(free) - 32<<20,
+	}}, true, report)
+
+	require.False(t, report.Failed())
+	assert.True(t, report.InPlaceUpdate)
+	requireCheck(t, report, "disk space", preflightWarning, "not enough disk space to stage the update, updating in place: ")
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "10.0 GiB", formatSize(10<<30))
+}
+
+func requireCheck(t *testing.T, report *preflightReport, name string, status preflightStatus, message string) {
+	t.Helper()
+
+	for _, check := range report.Checks {
+		if check.Name == name && check.Status == status && strings.HasPrefix(check.Message, message) {
+			return
+		}
+	}
+
+	t.Fatalf("check %s [%s] %s not found in %v", name, status, message, report.Checks)
+}
@@ -7,16 +7,19 @@ import (
 	"os"
 	"os/exec"
 	"os/user"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/emirpasic/gods/sets/hashset"
 	"github.com/gameap/daemon/internal/app/components"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/verification"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/hashicorp/go-getter"
 	"github.com/otiai10/copy"
@@ -42,7 +45,11 @@ const (
 	updater
 )
 
-const maxSteamCMDInstallTries = 20
+const (
+	maxSteamCMDInstallTries = 20
+
+	defaultSteamCMDRateLimitDelay = 30 * time.Second
+)
 
 var repeatableSteamCMDInstallResults = hashset.New(7, 8)
 
@@ -66,9 +73,12 @@ type installServer struct {
 	stopCommand   contracts.GameServerCommand
 	startCommand  contracts.GameServerCommand
 	installator   *installator
+	update        *atomicUpdate
 	baseCommand
 	kind                              installatorKind
 	serverWasActiveBeforeInstallation bool
+	// inPlaceUpdate is set by the preflight checks if there is no space to stage the update.
+	inPlaceUpdate bool
 }
 
 func newUpdateServer(
@@ -76,12 +86,15 @@ func newUpdateServer(
 	executor contracts.Executor,
 	processManager contracts.ProcessManager,
 	serverRepo domain.ServerRepository,
+	steamCMD contracts.SteamCMDInstaller,
+	gameCache contracts.GameCache,
+	verifier contracts.Verifier,
 	statusCommand contracts.GameServerCommand,
 	stopCommand contracts.GameServerCommand,
 	startCommand contracts.GameServerCommand,
 ) *installServer {
 	buffer := components.NewSafeBuffer()
-	inst := newUpdater(cfg, executor, buffer)
+	inst := newUpdater(cfg, executor, steamCMD, gameCache, verifier, buffer)
 
 	return &installServer{
 		baseCommand:   newBaseCommand(cfg, executor, processManager),
@@ -100,12 +113,15 @@ func newInstallServer(
 	executor contracts.Executor,
 	processManager contracts.ProcessManager,
 	serverRepo domain.ServerRepository,
+	steamCMD contracts.SteamCMDInstaller,
+	gameCache contracts.GameCache,
+	verifier contracts.Verifier,
 	statusCommand contracts.GameServerCommand,
 	stopCommand contracts.GameServerCommand,
 	startCommand contracts.GameServerCommand,
 ) *installServer {
 	buffer := components.NewSafeBuffer()
-	inst := newInstallator(cfg, executor, buffer)
+	inst := newInstallator(cfg, executor, steamCMD, gameCache, verifier, buffer)
 
 	return &installServer{
 		baseCommand:   newBaseCommand(cfg, executor, processManager),
@@ -128,15 +144,31 @@ func (cmd *installServer) Execute(ctx context.Context, server *domain.Server) er
 
 	var err error
 
+	if cmd.cfg.Scripts.Install == "" {
+		err = cmd.preflight(ctx, server)
+		if err != nil {
+			cmd.SetResult(ErrorResult)
+			return err
+		}
+	}
+
 	err = cmd.stopServerIfNeeded(ctx, server)
 	if err != nil {
 		return err
 	}
 
-	if cmd.cfg.Scripts.Install != "" {
+	switch {
+	case cmd.cfg.Scripts.Install != "":
 		err = cmd.installByScript(ctx, server)
-	} else {
+	case cmd.atomicUpdateUsable(server):
+		err = cmd.updateAtomically(ctx, server)
+	default:
+		freshInstall := isDirEmpty(server.WorkDir(cmd.cfg))
+
 		err = cmd.install(ctx, server)
+		if err != nil && ctx.Err() != nil && freshInstall {
+			cmd.removeCanceledInstallation(ctx, server)
+		}
 	}
 
 	if err != nil {
@@ -145,12 +177,43 @@ func (cmd *installServer) Execute(ctx context.Context, server *domain.Server) er
 	}
 
 	_, err = cmd.processManager.Install(ctx, server, cmd.installOutput)
+	if err != nil {
+		err = errors.WithMessage(err, "failed to execute process manager install")
+	} else {
+		err = cmd.startServerIfNeeded(ctx, server)
+	}
+
+	if cmd.update != nil {
+		return cmd.completeAtomicUpdate(ctx, server, err)
+	}
+
 	if err != nil {
 		cmd.SetResult(ErrorResult)
-		return errors.WithMessage(err, "failed to execute process manager install")
 	}
 
-	return cmd.startServerIfNeeded(ctx, server)
+	return err
+}
+
+// removeCanceledInstallation removes the half-installed files of the canceled installation.
+// It's called only if the server directory was empty before the installation.
+func (cmd *installServer) removeCanceledInstallation(ctx context.Context, server *domain.Server) {
+	_, _ = cmd.installOutput.Write([]byte("Installation canceled, removing installed files ...\n"))
+
+	err := os.RemoveAll(server.WorkDir(cmd.cfg))
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Error("failed to remove files of canceled installation")
+	}
+
+	server.SetInstallationStatus(domain.ServerNotInstalled)
+}
+
+func isDirEmpty(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+
+	return len(entries) == 0
 }
 
 func (cmd *installServer) ReadOutput() []byte {
@@ -177,6 +240,29 @@ func (cmd *installServer) ReadOutput() []byte {
 	return out
 }
 
+// preflight refuses the installation before the server is stopped and any files are changed
+// if the installation can't be completed.
+func (cmd *installServer) preflight(ctx context.Context, server *domain.Server) error {
+	plan := defineInstallationPlan(server, cmd.kind)
+	if len(plan.GameRules) == 0 {
+		return ErrDefinedNoGameInstallationRulesError
+	}
+	plan.Staged = cmd.atomicUpdateUsable(server)
+
+	_, _ = cmd.installOutput.Write([]byte("Running preflight checks ...\n"))
+
+	report := cmd.installator.preflight(ctx, server, plan, false)
+	report.write(cmd.installOutput)
+
+	if report.Failed() {
+		return errors.WithMessage(errPreflightChecksFailed, "[game_server_commands.installServer] installation refused")
+	}
+
+	cmd.inPlaceUpdate = report.InPlaceUpdate
+
+	return nil
+}
+
 func (cmd *installServer) stopServerIfNeeded(ctx context.Context, server *domain.Server) error {
 	if server.InstallationStatus() != domain.ServerInstalled {
 		return nil
@@ -250,39 +336,37 @@ func (cmd *installServer) installByScript(ctx context.Context, server *domain.Se
 }
 
 func (cmd *installServer) install(ctx context.Context, server *domain.Server) error {
-	sd := installationRulesDefiner{}
-
-	game := server.Game()
-	gameMod := server.GameMod()
+	plan := defineInstallationPlan(server, cmd.kind)
 
-	gameRules := sd.DefineGameRules(&game)
-	if len(gameRules) == 0 {
+	if len(plan.GameRules) == 0 {
 		return ErrDefinedNoGameInstallationRulesError
 	}
 
 	_, _ = cmd.installOutput.Write([]byte("Installing game files ...\n"))
 
-	err := cmd.installator.Install(ctx, server, gameRules)
+	err := cmd.installator.Install(ctx, server, plan.GameRules)
 	if err != nil {
 		cmd.SetResult(ErrorResult)
 		return err
 	}
 
-	if cmd.kind != updater {
-		gameModRules := sd.DefineGameModRules(&gameMod)
-
-		if len(gameModRules) > 0 {
-			_, _ = cmd.installOutput.Write([]byte("\n\n"))
-			_, _ = cmd.installOutput.Write([]byte("Installing game mod files ...\n"))
+	if len(plan.GameModRules) > 0 {
+		_, _ = cmd.installOutput.Write([]byte("\n\n"))
+		_, _ = cmd.installOutput.Write([]byte("Installing game mod files ...\n"))
 
-			err = cmd.installator.Install(ctx, server, gameModRules)
-			if err != nil {
-				cmd.SetResult(ErrorResult)
-				return err
-			}
+		err = cmd.installator.Install(ctx, server, plan.GameModRules)
+		if err != nil {
+			cmd.SetResult(ErrorResult)
+			return err
 		}
 	}
 
+	err = cmd.installator.installWorkshopItems(ctx, server)
+	if err != nil {
+		cmd.SetResult(ErrorResult)
+		return err
+	}
+
 	cmd.SetResult(SuccessResult)
 
 	return nil
@@ -383,30 +467,71 @@ func (d *installationRulesDefiner) DefineGameModRules(gameMod *domain.GameMod) [
 }
 
 type installator struct {
-	cfg      *config.Config
-	executor contracts.Executor
-	output   io.ReadWriter
-	kind     installatorKind
+	cfg       *config.Config
+	executor  contracts.Executor
+	steamCMD  contracts.SteamCMDInstaller
+	gameCache contracts.GameCache
+	verifier  contracts.Verifier
+	output    io.ReadWriter
+	kind      installatorKind
+
+	// stagingDir is the directory the update is installed to instead of the server directory.
+	stagingDir string
+
+	steamCMDRateLimitDelay time.Duration
 }
 
-func newInstallator(cfg *config.Config, executor contracts.Executor, output io.ReadWriter) *installator {
+func newInstallator(
+	cfg *config.Config,
+	executor contracts.Executor,
+	steamCMD contracts.SteamCMDInstaller,
+	gameCache contracts.GameCache,
+	verifier contracts.Verifier,
+	output io.ReadWriter,
+) *installator {
 	return &installator{
-		cfg:      cfg,
-		executor: executor,
-		output:   output,
-		kind:     installer,
+		cfg:       cfg,
+		executor:  executor,
+		steamCMD:  steamCMD,
+		gameCache: gameCache,
+		verifier:  verifier,
+		output:    output,
+		kind:      installer,
+
+		steamCMDRateLimitDelay: defaultSteamCMDRateLimitDelay,
 	}
 }
 
-func newUpdater(cfg *config.Config, executor contracts.Executor, output io.ReadWriter) *installator {
+func newUpdater(
+	cfg *config.Config,
+	executor contracts.Executor,
+	steamCMD contracts.SteamCMDInstaller,
+	gameCache contracts.GameCache,
+	verifier contracts.Verifier,
+	output io.ReadWriter,
+) *installator {
 	return &installator{
-		cfg:      cfg,
-		executor: executor,
-		output:   output,
-		kind:     updater,
+		cfg:       cfg,
+		executor:  executor,
+		steamCMD:  steamCMD,
+		gameCache: gameCache,
+		verifier:  verifier,
+		output:    output,
+		kind:      updater,
+
+		steamCMDRateLimitDelay: defaultSteamCMDRateLimitDelay,
 	}
 }
 
+// dir returns the directory the game server files are installed to.
+func (in *installator) dir(server *domain.Server) string {
+	if in.stagingDir != "" {
+		return in.stagingDir
+	}
+
+	return server.WorkDir(in.cfg)
+}
+
 func (in *installator) Install(ctx context.Context, server *domain.Server, rules []*installationRule) error {
 	var err error
 	var success bool
@@ -418,6 +543,10 @@ func (in *installator) Install(ctx context.Context, server *domain.Server, rules
 		}
 
 		err = in.install(ctx, server, *rule)
+		if err != nil && ctx.Err() != nil {
+			// The installation is canceled, other rules shouldn't be tried
+			return ctx.Err()
+		}
 		if err != nil {
 			_, _ = in.output.Write([]byte(err.Error() + "\n"))
 			log.Error(err)
@@ -440,12 +569,14 @@ func (in *installator) Install(ctx context.Context, server *domain.Server, rules
 }
 
 func (in *installator) install(ctx context.Context, server *domain.Server, rule installationRule) error {
-	dst := server.WorkDir(in.cfg)
+	dst := in.dir(server)
 
 	var err error
 	switch rule.Action {
-	case downloadAnUnpackFromRemoteRepository, unpackFromLocalRepository:
-		err = in.getAndUnpackFiles(ctx, dst, rule.SourceValue)
+	case downloadAnUnpackFromRemoteRepository:
+		err = in.downloadAndUnpackFiles(ctx, dst, rule.SourceValue)
+	case unpackFromLocalRepository:
+		err = in.verifyAndUnpackFiles(ctx, dst, rule.SourceValue)
 	case copyDirectoryFromLocalRepository:
 		err = in.copyDirectoryFromLocalRepository(ctx, dst, rule.SourceValue)
 	case installFromSteam:
@@ -496,6 +627,110 @@ func (in *installator) getAndUnpackFiles(
 	return nil
 }
 
+// downloadAndUnpackFiles downloads the remote repository archive, verifies and unpacks it.
+// Sources that can't be downloaded as a single file, e.g. git repositories, are passed to go-getter as is.
+func (in *installator) downloadAndUnpackFiles(ctx context.Context, dst string, source string) error {
+	if !verification.IsVerifiableSource(source) {
+		if in.verifier != nil && in.verifier.Required() {
+			err := errors.WithMessage(verification.ErrUnverifiable, "[game_server_commands.installator] installation refused")
+			in.writeOutput(ctx, err.Error())
+			return err
+		}
+
+		return in.getAndUnpackFiles(ctx, dst, source)
+	}
+
+	archive, release, ok := in.cachedArchive(ctx, source)
+	if !ok {
+		var err error
+		archive, release, err = in.downloadArchive(ctx, source)
+		if err != nil {
+			return err
+		}
+	}
+	defer release()
+
+	err := in.verifyFile(ctx, source, archive)
+	if err != nil {
+		return err
+	}
+
+	return in.getAndUnpackFiles(ctx, dst, archive)
+}
+
+// downloadArchive downloads the archive without unpacking to the temporary directory.
+// The returned function removes the directory.
+func (in *installator) downloadArchive(ctx context.Context, source string) (string, func(), error) {
+	tmp, err := os.MkdirTemp("", "gameap-download")
+	if err != nil {
+		return "", nil, errors.WithMessage(err, "[game_server_commands.installator] failed to create temporary directory")
+	}
+	release := func() {
+		_ = os.RemoveAll(tmp)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		release()
+		return "", nil, errors.WithMessage(err, "[game_server_commands.installator] invalid source")
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = "archive"
+	}
+	archive := filepath.Join(tmp, name)
+
+	query := u.Query()
+	query.Set("archive", "false")
+	u.RawQuery = query.Encode()
+
+	c := getter.Client{
+		Ctx:  ctx,
+		Src:  u.String(),
+		Dst:  archive,
+		Mode: getter.ClientModeFile,
+	}
+
+	in.writeOutput(ctx, "Downloading from "+source+" ...")
+
+	err = c.Get()
+	if err != nil {
+		release()
+		err = errors.WithMessage(err, "[game_server_commands.installator] failed to download files")
+		in.writeOutput(ctx, err.Error())
+		return "", nil, err
+	}
+
+	return archive, release, nil
+}
+
+func (in *installator) verifyAndUnpackFiles(ctx context.Context, dst string, source string) error {
+	err := in.verifyFile(ctx, source, source)
+	if err != nil {
+		return err
+	}
+
+	return in.getAndUnpackFiles(ctx, dst, source)
+}
+
+// verifyFile checks the checksum and the signature of the file downloaded from the source.
+// The installation is refused on mismatch.
+func (in *installator) verifyFile(ctx context.Context, source, file string) error {
+	if in.verifier == nil {
+		return nil
+	}
+
+	err := in.verifier.Verify(ctx, source, file, in.output)
+	if err != nil {
+		err = errors.WithMessage(err, "[game_server_commands.installator] verification failed, installation refused")
+		in.writeOutput(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
 func (in *installator) copyDirectoryFromLocalRepository(
 	ctx context.Context,
 	dst string,
@@ -522,12 +757,50 @@ func (in *installator) installFromSteam(
 	server *domain.Server,
 	source string,
 ) error {
+	err := in.ensureSteamCMD(ctx, output)
+	if err != nil {
+		return err
+	}
+
 	execCmd := in.makeSteamCMDCommand(source, server)
 
 	in.writeOutput(ctx, "Installing from steam ...")
 
+	executorOptions, err := in.steamCMDExecutorOptions(ctx, cfg.WorkPath, server)
+	if err != nil {
+		return err
+	}
+
+	if in.steamCacheUsable(server) {
+		return in.installFromSteamWithCache(ctx, output, server, source, execCmd, executorOptions)
+	}
+
+	return in.runSteamCMD(ctx, output, execCmd, executorOptions)
+}
+
+func (in *installator) ensureSteamCMD(ctx context.Context, output io.Writer) error {
+	if in.steamCMD == nil {
+		return nil
+	}
+
+	err := in.steamCMD.EnsureInstalled(ctx, output)
+	if err != nil {
+		err = errors.WithMessage(err, "[game_server_commands.installator] steamcmd is not available")
+		in.writeOutput(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// steamCMDExecutorOptions runs SteamCMD as the game server user if the daemon runs as root.
+func (in *installator) steamCMDExecutorOptions(
+	ctx context.Context,
+	workDir string,
+	server *domain.Server,
+) (contracts.ExecutorOptions, error) {
 	executorOptions := contracts.ExecutorOptions{
-		WorkDir: cfg.WorkPath,
+		WorkDir: workDir,
 	}
 
 	if isRootUser() && server.User() != "" {
@@ -535,23 +808,41 @@ func (in *installator) installFromSteam(
 		if err != nil {
 			err = errors.WithMessage(err, "[game_server_commands.installator] failed to lookup user")
 			in.writeOutput(ctx, err.Error())
-			return err
+			return executorOptions, err
 		}
 
 		executorOptions.UID = systemUser.Uid
 		executorOptions.GID = systemUser.Gid
 	}
 
-	installTries := maxSteamCMDInstallTries
+	return executorOptions, nil
+}
+
+// runSteamCMD executes SteamCMD and repeats the execution on transient failures.
+func (in *installator) runSteamCMD(
+	ctx context.Context,
+	output io.Writer,
+	execCmd string,
+	executorOptions contracts.ExecutorOptions,
+) error {
 	var result int
 	var err error
-	for installTries > 0 {
+	var failure steamCMDError
+	for attempt := 1; ; attempt++ {
+		parser := newSteamCMDOutputParser(output)
+
 		result, err = in.executor.ExecWithWriter(
 			ctx,
 			execCmd,
-			output,
+			parser,
 			executorOptions,
 		)
+		parser.Flush()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		var exitErr *exec.ExitError
 		if err != nil && !errors.As(err, &exitErr) {
 			return errors.WithMessage(err, "failed to execute steamcmd")
@@ -560,22 +851,62 @@ func (in *installator) installFromSteam(
 			result = exitErr.ExitCode()
 		}
 
-		if result == SuccessResult {
-			break
+		var failed bool
+		failure, failed = parser.Failure()
+
+		// SteamCMD may exit with a zero code even if the update job failed
+		if result == SuccessResult && (!failed || parser.Succeeded()) {
+			return nil
 		}
 
-		if !repeatableSteamCMDInstallResults.Contains(result) {
+		if attempt >= maxSteamCMDInstallTries || !isRepeatableSteamCMDFailure(result, failure, failed) {
 			break
 		}
 
-		installTries--
+		_, _ = output.Write(formatSteamCMDEvent(steamCMDRetryEvent{
+			Event:       steamCMDEventRetry,
+			Attempt:     attempt + 1,
+			MaxAttempts: maxSteamCMDInstallTries,
+			Error:       failure,
+		}))
+
+		if failure == steamCMDErrorRateLimited {
+			err = in.waitSteamCMDRateLimit(ctx)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	if result != SuccessResult {
-		return errInstallViaSteamCMDFailed
+	if failure != "" {
+		err = errors.WithMessage(errInstallViaSteamCMDFailed, failure.Description())
+		in.writeOutput(ctx, err.Error())
+
+		return err
 	}
 
-	return nil
+	return errInstallViaSteamCMDFailed
+}
+
+// isRepeatableSteamCMDFailure returns true if the SteamCMD failure is known as transient.
+// If no error was recognized in the output, the exit code decides.
+func isRepeatableSteamCMDFailure(result int, failure steamCMDError, failed bool) bool {
+	if failed {
+		return failure.Transient()
+	}
+
+	return repeatableSteamCMDInstallResults.Contains(result)
+}
+
+func (in *installator) waitSteamCMDRateLimit(ctx context.Context) error {
+	in.writeOutput(ctx, "Steam login rate limit exceeded, waiting "+in.steamCMDRateLimitDelay.String()+" ...")
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(in.steamCMDRateLimitDelay):
+		return nil
+	}
 }
 
 func (in *installator) makeSteamCMDCommand(appID string, server *domain.Server) string {
@@ -584,17 +915,10 @@ func (in *installator) makeSteamCMDCommand(appID string, server *domain.Server)
 	execCmd.WriteString(filepath.Join(in.cfg.SteamCMDPath, config.SteamCMDExecutableFile))
 
 	execCmd.WriteString(" +force_install_dir \"")
-	execCmd.WriteString(server.WorkDir(in.cfg))
+	execCmd.WriteString(in.dir(server))
 	execCmd.WriteString("\"")
 
-	if in.cfg.SteamConfig.Login != "" && in.cfg.SteamConfig.Password != "" {
-		execCmd.WriteString(" +login ")
-		execCmd.WriteString(in.cfg.SteamConfig.Login)
-		execCmd.WriteString(" ")
-		execCmd.WriteString(in.cfg.SteamConfig.Password)
-	} else {
-		execCmd.WriteString(" +login anonymous")
-	}
+	execCmd.WriteString(in.steamCMDLogin())
 
 	execCmd.WriteString(" +app_update ")
 	execCmd.WriteString(appID)
@@ -608,6 +932,14 @@ func (in *installator) makeSteamCMDCommand(appID string, server *domain.Server)
 	return execCmd.String()
 }
 
+func (in *installator) steamCMDLogin() string {
+	if in.cfg.SteamConfig.Login != "" && in.cfg.SteamConfig.Password != "" {
+		return " +login " + in.cfg.SteamConfig.Login + " " + in.cfg.SteamConfig.Password
+	}
+
+	return " +login anonymous"
+}
+
 func (in *installator) chown(ctx context.Context, dst string, userName string) error {
 	if !isRootUser() {
 		return nil
@@ -628,7 +960,12 @@ func (in *installator) chown(ctx context.Context, dst string, userName string) e
 	if err != nil {
 		return errors.WithMessage(err, "[game_server_commands.installator] invalid user gid")
 	}
-	err = chownR(dst, uid, gid)
+
+	if in.gameCacheEnabled() && in.cfg.GameCache.LinkMode == config.GameCacheLinkModeHardlink {
+		err = chownRExceptHardlinked(dst, uid, gid)
+	} else {
+		err = chownR(dst, uid, gid)
+	}
 	if err != nil {
 		return err
 	}
@@ -8,12 +8,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
-	"time"
 
 	"github.com/gameap/daemon/internal/app/components"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/test/mocks"
 	"github.com/gameap/daemon/test/mocks/commandmocks"
@@ -209,6 +209,9 @@ func TestInstallation_ServerInstalledFromRemoterRepository(t *testing.T) {
 		components.NewExecutor(),
 		processmanager.NewSimple(cfg, components.NewExecutor(), components.NewExecutor()),
 		mocks.NewServerRepository(),
+		nil,
+		nil,
+		nil,
 		commandmocks.LoadServerCommand(domain.Status),
 		commandmocks.LoadServerCommand(domain.Stop),
 		commandmocks.LoadServerCommand(domain.Start),
@@ -240,6 +243,9 @@ func TestInstallation_ServerInstalledFromLocalRepository(t *testing.T) {
 		components.NewExecutor(),
 		processmanager.NewSimple(cfg, components.NewExecutor(), components.NewExecutor()),
 		mocks.NewServerRepository(),
+		nil,
+		nil,
+		nil,
 		commandmocks.LoadServerCommand(domain.Status),
 		commandmocks.LoadServerCommand(domain.Stop),
 		commandmocks.LoadServerCommand(domain.Start),
@@ -272,6 +278,9 @@ func TestInstallation_RunAfterInstallScript(t *testing.T) {
 		components.NewExecutor(),
 		processmanager.NewSimple(cfg, components.NewExecutor(), components.NewExecutor()),
 		mocks.NewServerRepository(),
+		nil,
+		nil,
+		nil,
 		commandmocks.LoadServerCommand(domain.Status),
 		commandmocks.LoadServerCommand(domain.Stop),
 		commandmocks.LoadServerCommand(domain.Start),
@@ -288,7 +297,7 @@ func TestUpdateBySteam_SteamCommandWithoutValidate(t *testing.T) {
 		WorkPath: "/",
 	}
 	executor := &testExecutor{}
-	updater := newUpdater(cfg, executor, &bytes.Buffer{})
+	updater := newUpdater(cfg, executor, nil, nil, nil, &bytes.Buffer{})
 	server := givenLocalInstallationServer(t)
 	rules := []*installationRule{
 		{SourceValue: "90", Action: installFromSteam},
@@ -309,7 +318,7 @@ func TestUpdateBySteam_SteamCommandWithSetConfig(t *testing.T) {
 		WorkPath: "/work-path",
 	}
 	executor := &testExecutor{}
-	updater := newUpdater(cfg, executor, &bytes.Buffer{})
+	updater := newUpdater(cfg, executor, nil, nil, nil, &bytes.Buffer{})
 	server := givenLocalInstallationServer(t)
 	rules := []*installationRule{
 		{SourceValue: "90 mod czero", Action: installFromSteam},
@@ -326,7 +335,7 @@ func TestInstallBySteam_SteamCommandWithValidate(t *testing.T) {
 		WorkPath: "/",
 	}
 	executor := &testExecutor{}
-	updater := newInstallator(cfg, executor, &bytes.Buffer{})
+	updater := newInstallator(cfg, executor, nil, nil, nil, &bytes.Buffer{})
 	server := givenLocalInstallationServer(t)
 	rules := []*installationRule{
 		{SourceValue: "90", Action: installFromSteam},
@@ -409,36 +418,16 @@ func givenLocalInstallationServerWithAfterInstallScript(t *testing.T) *domain.Se
 	return givenServer(t, game, gameMod)
 }
 
-func givenServer(t *testing.T, game domain.Game, gameMod domain.GameMod) *domain.Server {
+func givenServer(
+	t *testing.T, game domain.Game, gameMod domain.GameMod, options ...domaintest.ServerOption,
+) *domain.Server {
 	t.Helper()
 
-	return domain.NewServer(
-		1,
-		true,
-		domain.ServerInstalled,
-		false,
-		"name",
-		"759b875e-d910-11eb-aff7-d796d7fcf7ef",
-		"759b875e",
-		game,
-		gameMod,
-		"1.3.3.7",
-		1337,
-		1338,
-		1339,
-		"paS$w0rD",
-		"test-server",
-		"gameap-user",
-		"./run.sh",
-		"",
-		"",
-		"",
-		false,
-		time.Now(),
-		map[string]string{},
-		map[string]string{},
-		time.Now(),
-	)
+	return domaintest.NewServer(append([]domaintest.ServerOption{
+		domaintest.WithGame(game),
+		domaintest.WithGameMod(gameMod),
+		domaintest.WithDir("test-server"),
+	}, options...)...)
 }
 
 type testExecutor struct {
@@ -464,3 +453,34 @@ func (ex *testExecutor) AssertCommand(t *testing.T, expected string) {
 
 	assert.Equal(t, expected, ex.command)
 }
+
+func TestInstallation_CanceledFreshInstallationRemoved_ServerNotInstalled(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	// The installation is interrupted by the cancellation, the broken archive fails it the same way
+	repository := filepath.Join(t.TempDir(), "broken.tar.gz")
+	require.NoError(t, os.WriteFile(repository, []byte("not an archive"), 0600))
+	server := givenServerForUpdate(t, repository)
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerInstall, "", domain.GDTaskStatusWaiting)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusWorking))
+	install := newInstallServer(
+		cfg,
+		components.NewExecutor(),
+		processmanager.NewSimple(cfg, components.NewExecutor(), components.NewExecutor()),
+		mocks.NewServerRepository(),
+		nil,
+		nil,
+		nil,
+		commandmocks.LoadServerCommand(domain.Status),
+		commandmocks.LoadServerCommand(domain.Stop),
+		commandmocks.LoadServerCommand(domain.Start),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := install.Execute(ctx, server)
+	require.NoError(t, task.SetStatus(domain.GDTaskStatusCanceled))
+
+	require.Error(t, err)
+	assert.NoDirExists(t, server.WorkDir(cfg))
+	assert.Equal(t, domain.InstallationStatus(domain.ServerNotInstalled), server.InstallationStatus())
+}
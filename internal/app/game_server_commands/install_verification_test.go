@@ -0,0 +1,86 @@
+package gameservercommands
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fileTarGzChecksum = "c04b4e3d485f04f64d4427c77b7eafd0d06a1bcfb3f4f741ae60af8ae76b4537"
+	wrongChecksum     = "6d5a0a0e2a8d1bb2d1be4a5ce5f2ee2ed4c2f1d8b3b7f1c0e3c1c6bbcf0cbb52"
+)
+
+func TestInstallFromRemoteRepository_ChecksumVerified(t *testing.T) {
+	repository := givenArchiveRepository(t, fileTarGzChecksum+"  file.tar.gz\n")
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Verification.RequireChecksum = true
+	out := &bytes.Buffer{}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, verification.NewVerifier(cfg), out)
+	dst := filepath.Join(cfg.WorkPath, "test-server")
+
+	err := inst.downloadAndUnpackFiles(context.Background(), dst, repository.URL+"/file.tar.gz")
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dst, "file.txt"))
+	assert.Contains(t, out.String(), "verified")
+}
+
+func TestInstallFromRemoteRepository_ChecksumMismatch_Refused(t *testing.T) {
+	repository := givenArchiveRepository(t, wrongChecksum+"  file.tar.gz\n")
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Verification.RequireChecksum = true
+	out := &bytes.Buffer{}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, verification.NewVerifier(cfg), out)
+	dst := filepath.Join(cfg.WorkPath, "test-server")
+
+	err := inst.downloadAndUnpackFiles(context.Background(), dst, repository.URL+"/file.tar.gz")
+
+	require.ErrorIs(t, err, verification.ErrChecksumMismatch)
+	assert.NoFileExists(t, filepath.Join(dst, "file.txt"))
+	assert.Contains(t, out.String(), "installation refused: expected sha256 "+wrongChecksum+", got "+fileTarGzChecksum)
+}
+
+func TestInstallFromRemoteRepository_ChecksumRequiredButMissing_Refused(t *testing.T) {
+	repository := givenArchiveRepository(t, "")
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Verification.RequireChecksum = true
+	out := &bytes.Buffer{}
+	inst := newInstallator(cfg, &testExecutor{}, nil, nil, verification.NewVerifier(cfg), out)
+	dst := filepath.Join(cfg.WorkPath, "test-server")
+
+	err := inst.downloadAndUnpackFiles(context.Background(), dst, repository.URL+"/file.tar.gz")
+
+	require.ErrorIs(t, err, verification.ErrChecksumMissing)
+	assert.NoFileExists(t, filepath.Join(dst, "file.txt"))
+}
+
+// givenArchiveRepository serves test/files/file.tar.gz and the checksum sidecar if it isn't empty.
+func givenArchiveRepository(t *testing.T, checksum string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/file.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../../../test/files/file.tar.gz")
+	})
+	mux.HandleFunc("/file.tar.gz.sha256", func(w http.ResponseWriter, _ *http.Request) {
+		if checksum == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(checksum))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
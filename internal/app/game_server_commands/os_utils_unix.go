@@ -7,18 +7,36 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
 // https://github.com/gutengo/fil/blob/6109b2e0b5cfdefdef3a254cc1a3eaa35bc89284/file.go#L27
 func chownR(path string, uid, gid int) error {
+	return chownRWalk(path, uid, gid, false)
+}
+
+// chownRExceptHardlinked changes the owner like chownR, but skips the files with several hard links.
+// They are shared with the game cache and the other game servers, so the owner must stay the same.
+func chownRExceptHardlinked(path string, uid, gid int) error {
+	return chownRWalk(path, uid, gid, true)
+}
+
+func chownRWalk(path string, uid, gid int, skipHardlinked bool) error {
 	return filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
 		if err != nil {
 			// Ignore invalid
 			return nil
 		}
 
+		if skipHardlinked && info.Mode().IsRegular() {
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Nlink > 1 {
+				return nil
+			}
+		}
+
 		if info.Mode()&os.ModeSymlink != 0 {
 			symlinkFile, err := os.Readlink(name)
 			if err != nil {
@@ -44,3 +62,15 @@ func isRootUser() bool {
 	}
 	return currentUser.Username == "root"
 }
+
+// freeDiskSpace returns the disk space available to unprivileged users in bytes.
+func freeDiskSpace(path string) (uint64, error) {
+	var stat unix.Statfs_t
+
+	err := unix.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -7,12 +7,17 @@ import (
 	"os/user"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
 )
 
 func chownR(_ string, _, _ int) error {
 	return nil
 }
 
+func chownRExceptHardlinked(_ string, _, _ int) error {
+	return nil
+}
+
 func isRootUser() bool {
 	currentUser, err := user.Current()
 	if err != nil {
@@ -21,3 +26,19 @@ func isRootUser() bool {
 	}
 	return currentUser.Username == "System" || currentUser.Username == "Administrator"
 }
+
+// freeDiskSpace returns the disk space available to the current user in bytes.
+func freeDiskSpace(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytes uint64
+	err = windows.GetDiskFreeSpaceEx(pathPtr, &freeBytes, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return freeBytes, nil
+}
@@ -45,6 +45,13 @@ func (cmd *defaultRestartServer) Execute(ctx context.Context, server *domain.Ser
 		return cmd.restartViaStopStart(ctx, server)
 	}
 
+	err := renderConfigTemplates(ctx, cmd.cfg, server, cmd.output)
+	if err != nil {
+		cmd.SetResult(ErrorResult)
+		cmd.SetComplete()
+		return err
+	}
+
 	result, err := cmd.processManager.Restart(ctx, server, cmd.output)
 	cmd.SetResult(int(result))
 	cmd.SetComplete()
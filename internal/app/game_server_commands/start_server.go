@@ -8,6 +8,7 @@ import (
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/ports"
 	"github.com/pkg/errors"
 )
 
@@ -49,6 +50,25 @@ func (cmd *defaultStartServer) Execute(ctx context.Context, server *domain.Serve
 		}
 	}
 
+	err := renderConfigTemplates(ctx, cmd.cfg, server, cmd.startOutput)
+	if err != nil {
+		cmd.SetResult(ErrorResult)
+		cmd.SetComplete()
+		return err
+	}
+
+	// Ports of the running server are taken by the server itself
+	if !cmd.cfg.Ports.SkipStartCheck && !server.IsActive() {
+		err = ports.CheckServer(server)
+		if err != nil {
+			_, _ = cmd.startOutput.Write([]byte(err.Error() + "\n"))
+			cmd.SetResult(ErrorResult)
+			cmd.SetComplete()
+
+			return errors.WithMessage(err, "[game_server_commands.defaultStartServer] server ports are not available")
+		}
+	}
+
 	result, err := cmd.processManager.Start(ctx, server, cmd.startOutput)
 	cmd.SetResult(int(result))
 	cmd.SetComplete()
@@ -2,14 +2,23 @@ package gameservercommands_test
 
 import (
 	"context"
+	"net"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/components"
 	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/configtemplates"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/internal/app/ports"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/test/mocks"
 	"github.com/stretchr/testify/assert"
@@ -87,6 +96,54 @@ func TestStartServer_ReadOutput(t *testing.T) {
 	cancel()
 }
 
+func TestStartServer_ConfigTemplateFailed_ServerNotStarted(t *testing.T) {
+	cfg := &config.Config{
+		WorkPath: "../../../test/servers",
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	cfg.ConfigTemplates.Enabled = true
+	cfg.ConfigTemplates.Path = t.TempDir()
+	require.NoError(t, os.WriteFile(
+		filepath.Join(cfg.ConfigTemplates.Path, configtemplates.ManifestFile),
+		[]byte("- source: server.cfg\n  target: ../server.cfg\n"),
+		0600,
+	))
+	server := givenServerWithStartCommand(t, "./run.sh")
+	startServerCommand := givenCommandFactory(t, cfg).LoadServerCommand(domain.Start, server)
+
+	err := startServerCommand.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, configtemplates.ErrForbiddenTarget)
+	assert.Equal(t, gameservercommands.ErrorResult, startServerCommand.Result())
+	assert.NotContains(t, string(startServerCommand.ReadOutput()), "Server started")
+}
+
+func TestStartServer_PortInUse_ServerNotStarted(t *testing.T) {
+	cfg := &config.Config{
+		WorkPath: "../../../test/servers",
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	server := givenServerWithStartCommand(
+		t, "./run.sh",
+		domaintest.WithAddress("127.0.0.1", port, port+1, 1339), domaintest.Stopped(),
+	)
+	startServerCommand := givenCommandFactory(t, cfg).LoadServerCommand(domain.Start, server)
+
+	err = startServerCommand.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, ports.ErrPortInUse)
+	assert.Equal(t, gameservercommands.ErrorResult, startServerCommand.Result())
+	assert.Contains(t, string(startServerCommand.ReadOutput()), "/tcp on 127.0.0.1: port is already in use")
+}
+
 func givenCommandFactory(t *testing.T, cfg *config.Config) *gameservercommands.ServerCommandFactory {
 	t.Helper()
 
@@ -97,44 +154,26 @@ func givenCommandFactory(t *testing.T, cfg *config.Config) *gameservercommands.S
 		mocks.NewServerRepository(),
 		executor,
 		processmanager.NewSimple(cfg, executor, executor),
+		steamcmd.NewSteamCMD(cfg, executor),
+		gamecache.NewCache(cfg),
+		verification.NewVerifier(cfg),
 	)
 }
 
-func givenServerWithStartCommand(t *testing.T, startCommand string) *domain.Server {
+func givenServerWithStartCommand(
+	t *testing.T, startCommand string, options ...domaintest.ServerOption,
+) *domain.Server {
 	t.Helper()
 
-	return domain.NewServer(
-		1337,
-		true,
-		domain.ServerInstalled,
-		false,
-		"name",
-		"759b875e-d910-11eb-aff7-d796d7fcf7ef",
-		"759b875e",
-		domain.Game{
-			StartCode: "cstrike",
-		},
-		domain.GameMod{
-			Name: "public",
-		},
-		"1.3.3.7",
-		1337,
-		1338,
-		1339,
-		"paS$w0rD",
-		"simple",
-		"gameap-user",
-		startCommand,
-		"",
-		"",
-		"",
-		true,
-		time.Now(),
-		map[string]string{
+	return domaintest.NewServer(append([]domaintest.ServerOption{
+		domaintest.WithID(1337),
+		domaintest.WithGame(domain.Game{StartCode: "cstrike"}),
+		domaintest.WithDir("simple"),
+		domaintest.WithStartCommand(startCommand),
+		domaintest.WithVars(map[string]string{
 			"default_map": "de_dust2",
 			"tickrate":    "1000",
-		},
-		map[string]string{},
-		time.Now(),
-	)
+		}),
+		domaintest.Active(),
+	}, options...)...)
 }
@@ -0,0 +1,399 @@
+package gameservercommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// steamCMDEventPrefix marks structured SteamCMD events in the task output.
+// Each event is a single line: the prefix followed by a JSON object.
+const steamCMDEventPrefix = "[steamcmd] "
+
+const (
+	steamCMDEventProgress = "progress"
+	steamCMDEventError    = "error"
+	steamCMDEventRetry    = "retry"
+	steamCMDEventSuccess  = "success"
+)
+
+const (
+	steamCMDPhaseDownloading   = "downloading"
+	steamCMDPhaseValidating    = "validating"
+	steamCMDPhaseCommitting    = "committing"
+	steamCMDPhasePreallocating = "preallocating"
+	steamCMDPhaseReconfiguring = "reconfiguring"
+)
+
+type steamCMDError string
+
+const (
+	steamCMDErrorNoSubscription       steamCMDError = "no_subscription"
+	steamCMDErrorDiskFull             steamCMDError = "disk_full"
+	steamCMDErrorDiskWriteFailure     steamCMDError = "disk_write_failure"
+	steamCMDErrorInvalidPassword      steamCMDError = "invalid_password"
+	steamCMDErrorSteamGuard           steamCMDError = "steam_guard"
+	steamCMDErrorRateLimited          steamCMDError = "rate_limited"
+	steamCMDErrorNoConnection         steamCMDError = "no_connection"
+	steamCMDErrorTimeout              steamCMDError = "timeout"
+	steamCMDErrorMissingConfiguration steamCMDError = "missing_configuration"
+	steamCMDErrorUpdateFailed         steamCMDError = "update_failed"
+	steamCMDErrorAccessDenied         steamCMDError = "access_denied"
+	steamCMDErrorItemNotFound         steamCMDError = "item_not_found"
+)
+
+// Transient returns true if SteamCMD may succeed when it is run again.
+func (e steamCMDError) Transient() bool {
+	switch e {
+	case steamCMDErrorRateLimited,
+		steamCMDErrorNoConnection,
+		steamCMDErrorTimeout,
+		steamCMDErrorMissingConfiguration,
+		steamCMDErrorUpdateFailed:
+		return true
+	case steamCMDErrorNoSubscription,
+		steamCMDErrorDiskFull,
+		steamCMDErrorDiskWriteFailure,
+		steamCMDErrorInvalidPassword,
+		steamCMDErrorSteamGuard,
+		steamCMDErrorAccessDenied,
+		steamCMDErrorItemNotFound:
+		return false
+	}
+
+	return false
+}
+
+func (e steamCMDError) Description() string {
+	switch e {
+	case steamCMDErrorNoSubscription:
+		return "no subscription to the application"
+	case steamCMDErrorDiskFull:
+		return "not enough disk space"
+	case steamCMDErrorDiskWriteFailure:
+		return "disk write failure"
+	case steamCMDErrorInvalidPassword:
+		return "invalid steam login or password"
+	case steamCMDErrorSteamGuard:
+		return "steam guard code required"
+	case steamCMDErrorRateLimited:
+		return "steam login rate limit exceeded"
+	case steamCMDErrorNoConnection:
+		return "no connection to steam"
+	case steamCMDErrorTimeout:
+		return "steam timed out"
+	case steamCMDErrorMissingConfiguration:
+		return "missing application configuration"
+	case steamCMDErrorUpdateFailed:
+		return "update job failed"
+	case steamCMDErrorAccessDenied:
+		return "access to the workshop item denied"
+	case steamCMDErrorItemNotFound:
+		return "workshop item not found"
+	}
+
+	return string(e)
+}
+
+type steamCMDProgressEvent struct {
+	Event      string  `json:"event"`
+	Phase      string  `json:"phase"`
+	Percent    float64 `json:"percent"`
+	Bytes      uint64  `json:"bytes"`
+	TotalBytes uint64  `json:"total_bytes"`
+}
+
+type steamCMDErrorEvent struct {
+	Event     string        `json:"event"`
+	Error     steamCMDError `json:"error"`
+	Transient bool          `json:"transient"`
+	Message   string        `json:"message"`
+}
+
+type steamCMDRetryEvent struct {
+	Event       string        `json:"event"`
+	Attempt     int           `json:"attempt"`
+	MaxAttempts int           `json:"max_attempts"`
+	Error       steamCMDError `json:"error,omitempty"`
+}
+
+type steamCMDSuccessEvent struct {
+	Event string `json:"event"`
+}
+
+var (
+	steamCMDProgressRegexp = regexp.MustCompile(
+		`Update state \(0x[0-9a-fA-F]+\) ([a-zA-Z ]+), progress: ([0-9.]+) \((\d+) / (\d+)\)`,
+	)
+	steamCMDAppStateRegexp = regexp.MustCompile(`Error! App '[^']*' state is (0x[0-9a-fA-F]+) after update job`)
+	steamCMDSuccessRegexp  = regexp.MustCompile(
+		`Success! App '[^']*' (fully installed|already up to date)|Success\. Downloaded item \d+`,
+	)
+	steamCMDItemFailedRegexp = regexp.MustCompile(`Download item \d+ failed`)
+)
+
+// steamCMDErrorPatterns maps SteamCMD messages to known errors.
+// The order matters, the first matched pattern wins.
+var steamCMDErrorPatterns = []struct {
+	substring string
+	err       steamCMDError
+}{
+	{"no subscription", steamCMDErrorNoSubscription},
+	{"disk space", steamCMDErrorDiskFull},
+	{"disk write failure", steamCMDErrorDiskWriteFailure},
+	{"invalid password", steamCMDErrorInvalidPassword},
+	{"account logon denied", steamCMDErrorSteamGuard},
+	{"two-factor code mismatch", steamCMDErrorSteamGuard},
+	{"steam guard", steamCMDErrorSteamGuard},
+	{"rate limit exceeded", steamCMDErrorRateLimited},
+	{"no connection", steamCMDErrorNoConnection},
+	{"timed out", steamCMDErrorTimeout},
+	{"timeout", steamCMDErrorTimeout},
+	{"missing configuration", steamCMDErrorMissingConfiguration},
+	{"access denied", steamCMDErrorAccessDenied},
+	{"file not found", steamCMDErrorItemNotFound},
+}
+
+// steamCMDAppStates maps app states reported after a failed update job to known errors.
+var steamCMDAppStates = map[string]steamCMDError{
+	"0x202": steamCMDErrorDiskFull,
+	"0x402": steamCMDErrorNoConnection,
+	"0x602": steamCMDErrorUpdateFailed,
+}
+
+type steamCMDProgress struct {
+	Phase      string
+	Percent    float64
+	Bytes      uint64
+	TotalBytes uint64
+}
+
+func parseSteamCMDProgress(line string) (steamCMDProgress, bool) {
+	matches := steamCMDProgressRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return steamCMDProgress{}, false
+	}
+
+	percent, err := strconv.ParseFloat(matches[2], 64)
+	if err != nil {
+		return steamCMDProgress{}, false
+	}
+
+	bytesDone, err := strconv.ParseUint(matches[3], 10, 64)
+	if err != nil {
+		return steamCMDProgress{}, false
+	}
+
+	totalBytes, err := strconv.ParseUint(matches[4], 10, 64)
+	if err != nil {
+		return steamCMDProgress{}, false
+	}
+
+	return steamCMDProgress{
+		Phase:      steamCMDPhase(matches[1]),
+		Percent:    percent,
+		Bytes:      bytesDone,
+		TotalBytes: totalBytes,
+	}, true
+}
+
+func steamCMDPhase(state string) string {
+	state = strings.ToLower(strings.TrimSpace(state))
+
+	switch {
+	case strings.HasPrefix(state, "downloading"), strings.HasPrefix(state, "staging"):
+		return steamCMDPhaseDownloading
+	case strings.HasPrefix(state, "verifying"), strings.HasPrefix(state, "validating"):
+		return steamCMDPhaseValidating
+	case strings.HasPrefix(state, "committing"):
+		return steamCMDPhaseCommitting
+	case strings.HasPrefix(state, "preallocating"):
+		return steamCMDPhasePreallocating
+	case strings.HasPrefix(state, "reconfiguring"):
+		return steamCMDPhaseReconfiguring
+	}
+
+	return strings.ReplaceAll(state, " ", "_")
+}
+
+func parseSteamCMDError(line string) (steamCMDError, bool) {
+	if matches := steamCMDAppStateRegexp.FindStringSubmatch(line); matches != nil {
+		if err, ok := steamCMDAppStates[strings.ToLower(matches[1])]; ok {
+			return err, true
+		}
+
+		return steamCMDErrorUpdateFailed, true
+	}
+
+	lower := strings.ToLower(line)
+	if !strings.Contains(lower, "error") && !strings.Contains(lower, "failed") {
+		return "", false
+	}
+
+	for _, p := range steamCMDErrorPatterns {
+		if strings.Contains(lower, p.substring) {
+			return p.err, true
+		}
+	}
+
+	if steamCMDItemFailedRegexp.MatchString(line) {
+		return steamCMDErrorUpdateFailed, true
+	}
+
+	return "", false
+}
+
+// steamCMDOutputParser passes SteamCMD output through to the underlying writer
+// and appends structured events for the recognized lines: progress, errors and success.
+type steamCMDOutputParser struct {
+	out io.Writer
+
+	mu          sync.Mutex
+	line        []byte
+	lastPhase   string
+	lastPercent int
+	errors      []steamCMDError
+	succeeded   bool
+}
+
+func newSteamCMDOutputParser(out io.Writer) *steamCMDOutputParser {
+	return &steamCMDOutputParser{
+		out:         out,
+		lastPercent: -1,
+	}
+}
+
+func (p *steamCMDOutputParser) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Output is passed through line by line, so events are never written in the middle of a line.
+	p.line = append(p.line, b...)
+	for {
+		i := bytes.IndexAny(p.line, "\r\n")
+		if i < 0 {
+			break
+		}
+
+		_, err := p.out.Write(p.line[:i+1])
+		if err != nil {
+			return 0, err
+		}
+
+		p.processLine(string(p.line[:i]))
+		p.line = p.line[i+1:]
+	}
+
+	return len(b), nil
+}
+
+// Flush writes and processes the last line if it isn't terminated by a newline.
+func (p *steamCMDOutputParser) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.line) == 0 {
+		return
+	}
+
+	_, _ = p.out.Write(append(p.line, '\n'))
+	p.processLine(string(p.line))
+	p.line = nil
+}
+
+// Failure returns the error that caused SteamCMD to fail.
+// Permanent errors take precedence over transient ones.
+func (p *steamCMDOutputParser) Failure() (steamCMDError, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.errors) == 0 {
+		return "", false
+	}
+
+	for _, e := range p.errors {
+		if !e.Transient() {
+			return e, true
+		}
+	}
+
+	return p.errors[len(p.errors)-1], true
+}
+
+func (p *steamCMDOutputParser) Succeeded() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.succeeded
+}
+
+func (p *steamCMDOutputParser) processLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	if progress, ok := parseSteamCMDProgress(line); ok {
+		p.processProgress(progress)
+		return
+	}
+
+	if steamCMDSuccessRegexp.MatchString(line) {
+		p.succeeded = true
+		p.writeEvent(steamCMDSuccessEvent{Event: steamCMDEventSuccess})
+		return
+	}
+
+	if e, ok := parseSteamCMDError(line); ok {
+		p.errors = append(p.errors, e)
+		p.writeEvent(steamCMDErrorEvent{
+			Event:     steamCMDEventError,
+			Error:     e,
+			Transient: e.Transient(),
+			Message:   line,
+		})
+	}
+}
+
+// processProgress writes a progress event when the phase changes or
+// the progress advances by at least one percent.
+func (p *steamCMDOutputParser) processProgress(progress steamCMDProgress) {
+	percent := int(math.Floor(progress.Percent))
+	if progress.Phase == p.lastPhase && percent <= p.lastPercent {
+		return
+	}
+
+	p.lastPhase = progress.Phase
+	p.lastPercent = percent
+
+	p.writeEvent(steamCMDProgressEvent{
+		Event:      steamCMDEventProgress,
+		Phase:      progress.Phase,
+		Percent:    progress.Percent,
+		Bytes:      progress.Bytes,
+		TotalBytes: progress.TotalBytes,
+	})
+}
+
+func (p *steamCMDOutputParser) writeEvent(event interface{}) {
+	_, _ = p.out.Write(formatSteamCMDEvent(event))
+}
+
+func formatSteamCMDEvent(event interface{}) []byte {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil
+	}
+
+	line := make([]byte, 0, len(steamCMDEventPrefix)+len(b)+1)
+	line = append(line, steamCMDEventPrefix...)
+	line = append(line, b...)
+	line = append(line, '\n')
+
+	return line
+}
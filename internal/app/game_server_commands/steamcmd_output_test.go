@@ -0,0 +1,202 @@
+package gameservercommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSteamCMDOutputParser_InstallSuccess(t *testing.T) {
+	out := &bytes.Buffer{}
+	parser := newSteamCMDOutputParser(out)
+
+	writeTranscript(t, parser, "install_success.txt")
+	parser.Flush()
+
+	events := readSteamCMDEvents(t, out.String())
+	require.Len(t, events, 7)
+	assert.Equal(t, map[string]interface{}{
+		"event": "progress", "phase": "reconfiguring", "percent": float64(0), "bytes": float64(0), "total_bytes": float64(0),
+	}, events[0])
+	assert.Equal(t, map[string]interface{}{
+		"event": "progress", "phase": "downloading", "percent": 0.52, "bytes": float64(1048576), "total_bytes": float64(201326592),
+	}, events[1])
+	assert.Equal(t, 45.23, events[2]["percent"])
+	assert.Equal(t, 99.41, events[3]["percent"])
+	assert.Equal(t, "validating", events[4]["phase"])
+	assert.Equal(t, "committing", events[5]["phase"])
+	assert.Equal(t, map[string]interface{}{"event": "success"}, events[6])
+	assert.True(t, parser.Succeeded())
+	_, failed := parser.Failure()
+	assert.False(t, failed)
+	assert.Contains(t, out.String(), "Success! App '90' fully installed.\n")
+}
+
+func TestSteamCMDOutputParser_Errors(t *testing.T) {
+	tests := []struct {
+		transcript string
+		expected   steamCMDError
+		transient  bool
+	}{
+		{"no_subscription.txt", steamCMDErrorNoSubscription, false},
+		{"disk_full.txt", steamCMDErrorDiskFull, false},
+		{"rate_limited.txt", steamCMDErrorRateLimited, true},
+		{"update_failed.txt", steamCMDErrorUpdateFailed, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.transcript, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			parser := newSteamCMDOutputParser(out)
+
+			writeTranscript(t, parser, test.transcript)
+			parser.Flush()
+
+			failure, failed := parser.Failure()
+			require.True(t, failed)
+			assert.Equal(t, test.expected, failure)
+			assert.Equal(t, test.transient, failure.Transient())
+			assert.False(t, parser.Succeeded())
+			assert.Contains(t, out.String(), `"event":"error","error":"`+string(test.expected)+`"`)
+		})
+	}
+}
+
+func TestSteamCMDOutputParser_PartialLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	parser := newSteamCMDOutputParser(out)
+
+	_, _ = parser.Write([]byte(" Update state (0x61) downloading, progress: 45.23 (9105"))
+	_, _ = parser.Write([]byte("8790 / 201326592)\nWaiting"))
+	parser.Flush()
+
+	assert.Equal(t,
+		" Update state (0x61) downloading, progress: 45.23 (91058790 / 201326592)\n"+
+			`[steamcmd] {"event":"progress","phase":"downloading","percent":45.23,"bytes":91058790,"total_bytes":201326592}`+"\n"+
+			"Waiting\n",
+		out.String(),
+	)
+}
+
+func TestInstallBySteam_TransientError_Retried(t *testing.T) {
+	executor := &steamCMDExecutor{t: t, transcripts: []string{"update_failed.txt", "install_success.txt"}}
+	out := &bytes.Buffer{}
+	inst := newInstallator(&config.Config{WorkPath: "/"}, executor, nil, nil, nil, out)
+
+	err := inst.installFromSteam(context.Background(), inst.cfg, out, givenSteamServer(t), "90")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, executor.calls)
+	assert.Contains(t, out.String(), `[steamcmd] {"event":"retry","attempt":2,"max_attempts":20,"error":"update_failed"}`)
+}
+
+func TestInstallBySteam_RateLimited_RetriedAfterDelay(t *testing.T) {
+	executor := &steamCMDExecutor{t: t, transcripts: []string{"rate_limited.txt", "install_success.txt"}}
+	out := &bytes.Buffer{}
+	inst := newInstallator(&config.Config{WorkPath: "/"}, executor, nil, nil, nil, out)
+	inst.steamCMDRateLimitDelay = 0
+
+	err := inst.installFromSteam(context.Background(), inst.cfg, out, givenSteamServer(t), "90")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, executor.calls)
+	assert.Contains(t, out.String(), "Steam login rate limit exceeded, waiting")
+}
+
+func TestInstallBySteam_PermanentError_NotRetried(t *testing.T) {
+	executor := &steamCMDExecutor{t: t, transcripts: []string{"no_subscription.txt", "install_success.txt"}}
+	out := &bytes.Buffer{}
+	inst := newInstallator(&config.Config{WorkPath: "/"}, executor, nil, nil, nil, out)
+
+	err := inst.installFromSteam(context.Background(), inst.cfg, out, givenSteamServer(t), "4020")
+
+	require.ErrorIs(t, err, errInstallViaSteamCMDFailed)
+	assert.Contains(t, err.Error(), "no subscription to the application")
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestInstallBySteam_ZeroExitCodeWithError_Failed(t *testing.T) {
+	executor := &steamCMDExecutor{t: t, transcripts: []string{"disk_full.txt"}, zeroExitCode: true}
+	out := &bytes.Buffer{}
+	inst := newInstallator(&config.Config{WorkPath: "/"}, executor, nil, nil, nil, out)
+
+	err := inst.installFromSteam(context.Background(), inst.cfg, out, givenSteamServer(t), "740")
+
+	require.ErrorIs(t, err, errInstallViaSteamCMDFailed)
+	assert.Equal(t, 1, executor.calls)
+}
+
+// steamCMDExecutor replays recorded SteamCMD transcripts, one per execution.
+// Transcripts with errors finish with the failure exit code unless zeroExitCode is set.
+type steamCMDExecutor struct {
+	t            *testing.T
+	transcripts  []string
+	zeroExitCode bool
+	calls        int
+}
+
+func (ex *steamCMDExecutor) Exec(_ context.Context, _ string, _ contracts.ExecutorOptions) ([]byte, int, error) {
+	return nil, 0, nil
+}
+
+func (ex *steamCMDExecutor) ExecWithWriter(
+	_ context.Context, _ string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	transcript := ex.transcripts[ex.calls]
+	ex.calls++
+
+	writeTranscript(ex.t, out, transcript)
+
+	if transcript == "install_success.txt" {
+		return SuccessResult, nil
+	}
+	if ex.zeroExitCode {
+		return SuccessResult, nil
+	}
+
+	return 8, nil
+}
+
+func givenSteamServer(t *testing.T) *domain.Server {
+	t.Helper()
+
+	return givenServer(t, domain.Game{StartCode: "cstrike", SteamAppID: 90}, domain.GameMod{Name: "classic"}, domaintest.WithUser(""))
+}
+
+func writeTranscript(t *testing.T, w io.Writer, name string) {
+	t.Helper()
+
+	content, err := os.ReadFile("../../../test/files/steamcmd/" + name)
+	require.NoError(t, err)
+
+	_, err = w.Write(content)
+	require.NoError(t, err)
+}
+
+func readSteamCMDEvents(t *testing.T, output string) []map[string]interface{} {
+	t.Helper()
+
+	var events []map[string]interface{}
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, steamCMDEventPrefix) {
+			continue
+		}
+
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, steamCMDEventPrefix)), &event))
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,245 @@
+package gameservercommands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+)
+
+const workshopItemIDPlaceholder = "{id}"
+
+var (
+	errUnknownWorkshopAppID      = errors.New("unknown steam workshop application, set the workshop_app_id server setting")
+	errInvalidWorkshopAppID      = errors.New("invalid workshop_app_id server setting, should be positive integer")
+	errWorkshopItemNotDownloaded = errors.New("workshop item is not downloaded")
+	errForbiddenWorkshopTarget   = errors.New("workshop target path is outside the game server directory")
+)
+
+// workshopLayout describes where the workshop items of the game are placed.
+// If Target contains {id}, each item directory is linked to Target with the item ID.
+// Otherwise, item files are linked into the Target directory.
+// An empty Target means the game server loads items from the SteamCMD download directory.
+type workshopLayout struct {
+	AppID  string
+	Target string
+}
+
+// workshopLayouts are the known layouts by the dedicated server Steam application ID.
+var workshopLayouts = map[domain.SteamAppID]workshopLayout{
+	4020:   {AppID: "4000", Target: "garrysmod/addons"},    // Garry's Mod
+	222860: {AppID: "550", Target: "left4dead2/addons"},    // Left 4 Dead 2
+	233780: {AppID: "107410", Target: "@{id}"},             // Arma 3
+	380870: {AppID: "108600", Target: ""},                  // Project Zomboid
+	443030: {AppID: "440900", Target: "ConanSandbox/Mods"}, // Conan Exiles
+}
+
+func defineWorkshopLayout(server *domain.Server) (workshopLayout, error) {
+	layout := workshopLayouts[server.Game().SteamAppID]
+
+	if appID := server.WorkshopAppID(); appID != "" {
+		// The application ID is put into the SteamCMD command line, so nothing but the number is allowed
+		id, err := strconv.ParseUint(appID, 10, 32)
+		if err != nil || id == 0 {
+			return layout, errInvalidWorkshopAppID
+		}
+
+		layout.AppID = strconv.FormatUint(id, 10)
+	}
+
+	if target := server.WorkshopTarget(); target != "" {
+		layout.Target = target
+	}
+
+	if layout.AppID == "" {
+		return layout, errUnknownWorkshopAppID
+	}
+
+	return layout, nil
+}
+
+// installWorkshopItems downloads the workshop items listed in the server settings
+// and links them into the game server directory. Items already downloaded are updated.
+func (in *installator) installWorkshopItems(ctx context.Context, server *domain.Server) error {
+	items := server.WorkshopItems()
+	if len(items) == 0 {
+		return nil
+	}
+
+	layout, err := defineWorkshopLayout(server)
+	if err != nil {
+		in.writeOutput(ctx, err.Error())
+		return err
+	}
+
+	err = in.ensureSteamCMD(ctx, in.output)
+	if err != nil {
+		return err
+	}
+
+	in.writeOutput(ctx, "Installing workshop items "+strings.Join(items, ", ")+" ...")
+
+	executorOptions, err := in.steamCMDExecutorOptions(ctx, in.cfg.WorkPath, server)
+	if err != nil {
+		return err
+	}
+
+	err = in.runSteamCMD(ctx, in.output, in.makeWorkshopCommand(layout.AppID, items, server), executorOptions)
+	if err != nil {
+		return err
+	}
+
+	dir := in.dir(server)
+	for _, item := range items {
+		source := filepath.Join(dir, "steamapps", "workshop", "content", layout.AppID, item)
+
+		err = placeWorkshopItem(dir, source, layout.Target, item)
+		if err != nil {
+			err = errors.WithMessagef(err, "[game_server_commands.installator] failed to install workshop item %s", item)
+			in.writeOutput(ctx, err.Error())
+			return err
+		}
+	}
+
+	in.writeOutput(ctx, "Workshop items successfully installed")
+
+	return nil
+}
+
+func (in *installator) makeWorkshopCommand(appID string, items []string, server *domain.Server) string {
+	execCmd := strings.Builder{}
+
+	execCmd.WriteString(filepath.Join(in.cfg.SteamCMDPath, config.SteamCMDExecutableFile))
+
+	execCmd.WriteString(" +force_install_dir \"")
+	execCmd.WriteString(in.dir(server))
+	execCmd.WriteString("\"")
+
+	execCmd.WriteString(in.steamCMDLogin())
+
+	for _, item := range items {
+		execCmd.WriteString(" +workshop_download_item ")
+		execCmd.WriteString(appID)
+		execCmd.WriteString(" ")
+		execCmd.WriteString(item)
+	}
+
+	execCmd.WriteString(" +quit")
+
+	return execCmd.String()
+}
+
+func placeWorkshopItem(dir, source, target, item string) error {
+	stat, err := os.Stat(source)
+	if err != nil || !stat.IsDir() {
+		return errWorkshopItemNotDownloaded
+	}
+
+	if target == "" {
+		return nil
+	}
+
+	perItem := strings.Contains(target, workshopItemIDPlaceholder)
+	target = filepath.Join(dir, strings.ReplaceAll(target, workshopItemIDPlaceholder, item))
+
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return errForbiddenWorkshopTarget
+	}
+
+	if perItem {
+		return linkOrCopy(source, target)
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = linkOrCopy(filepath.Join(source, entry.Name()), filepath.Join(target, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkOrCopy replaces the target with a relative symbolic link to the source.
+// If the link can't be created, e.g. on Windows without privileges, the source is copied.
+func linkOrCopy(source, target string) error {
+	err := os.RemoveAll(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	linkSource, err := filepath.Rel(filepath.Dir(target), source)
+	if err != nil {
+		linkSource = source
+	}
+
+	err = os.Symlink(linkSource, target)
+	if err == nil {
+		return nil
+	}
+
+	return copy.Copy(source, target)
+}
+
+type installWorkshop struct {
+	bufCommand
+	baseCommand
+
+	installator *installator
+}
+
+func newInstallWorkshop(
+	cfg *config.Config,
+	executor contracts.Executor,
+	processManager contracts.ProcessManager,
+	steamCMD contracts.SteamCMDInstaller,
+) *installWorkshop {
+	buffer := components.NewSafeBuffer()
+
+	return &installWorkshop{
+		baseCommand: newBaseCommand(cfg, executor, processManager),
+		bufCommand:  bufCommand{output: buffer},
+		installator: newUpdater(cfg, executor, steamCMD, nil, nil, buffer),
+	}
+}
+
+func (cmd *installWorkshop) Execute(ctx context.Context, server *domain.Server) error {
+	defer func() {
+		cmd.SetComplete()
+	}()
+
+	if len(server.WorkshopItems()) == 0 {
+		_, _ = cmd.output.Write([]byte("No workshop items in the game server settings\n"))
+		cmd.SetResult(SuccessResult)
+		return nil
+	}
+
+	err := cmd.installator.installWorkshopItems(ctx, server)
+	if err != nil {
+		cmd.SetResult(ErrorResult)
+		return errors.WithMessage(err, "[game_server_commands.installWorkshop] failed to install workshop items")
+	}
+
+	cmd.SetResult(SuccessResult)
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package gameservercommands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallWorkshop_Arma_ItemDirectoriesLinked(t *testing.T) {
+	workPath := t.TempDir()
+	cfg := &config.Config{WorkPath: workPath, SteamCMDPath: "/opt/steamcmd"}
+	executor := &workshopExecutor{t: t, transcript: "workshop_download.txt"}
+	server := givenWorkshopServer(t, 233780, domain.Settings{"workshop_items": "450814997, 463939057 invalid"})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Equal(t, SuccessResult, cmd.Result())
+	assert.Equal(t,
+		"/opt/steamcmd/steamcmd.sh +force_install_dir \""+filepath.Join(workPath, "test-server")+"\" +login anonymous"+
+			" +workshop_download_item 107410 450814997 +workshop_download_item 107410 463939057 +quit",
+		executor.command,
+	)
+	link, err := os.Readlink(filepath.Join(workPath, "test-server", "@450814997"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("steamapps", "workshop", "content", "107410", "450814997"), link)
+	assert.FileExists(t, filepath.Join(workPath, "test-server", "@463939057", "mod.cpp"))
+}
+
+func TestInstallWorkshop_GarrysMod_ItemFilesLinked(t *testing.T) {
+	workPath := t.TempDir()
+	cfg := &config.Config{WorkPath: workPath}
+	executor := &workshopExecutor{t: t, transcript: "workshop_download.txt"}
+	server := givenWorkshopServer(t, 4020, domain.Settings{"workshop_items": "450814997"})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Contains(t, executor.command, "+workshop_download_item 4000 450814997")
+	assert.FileExists(t, filepath.Join(workPath, "test-server", "garrysmod", "addons", "mod.cpp"))
+}
+
+func TestInstallWorkshop_CustomTargetOutsideServerDirectory_Error(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	executor := &workshopExecutor{t: t, transcript: "workshop_download.txt"}
+	server := givenWorkshopServer(t, 233780, domain.Settings{
+		"workshop_items":  "450814997",
+		"workshop_target": "../mods/{id}",
+	})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, errForbiddenWorkshopTarget)
+	assert.Equal(t, ErrorResult, cmd.Result())
+}
+
+func TestInstallWorkshop_UnknownGame_Error(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	executor := &workshopExecutor{t: t, transcript: "workshop_download.txt"}
+	server := givenWorkshopServer(t, 90, domain.Settings{"workshop_items": "450814997"})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, errUnknownWorkshopAppID)
+	assert.Empty(t, executor.command)
+}
+
+func TestInstallWorkshop_InvalidAppID_Error(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	executor := &workshopExecutor{t: t, transcript: "workshop_download.txt"}
+	server := givenWorkshopServer(t, 4020, domain.Settings{
+		"workshop_items":  "450814997",
+		"workshop_app_id": "4000 +runscript x",
+	})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, errInvalidWorkshopAppID)
+	assert.Empty(t, executor.command)
+}
+
+func TestInstallWorkshop_AccessDenied_NotRetried(t *testing.T) {
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	executor := &workshopExecutor{t: t, transcript: "workshop_access_denied.txt", exitCode: 10}
+	server := givenWorkshopServer(t, 233780, domain.Settings{"workshop_items": "1234567"})
+	cmd := newInstallWorkshop(cfg, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, errInstallViaSteamCMDFailed)
+	assert.Contains(t, err.Error(), "access to the workshop item denied")
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestInstallWorkshop_NoItems_Success(t *testing.T) {
+	executor := &workshopExecutor{t: t}
+	cmd := newInstallWorkshop(&config.Config{}, executor, nil, nil)
+
+	err := cmd.Execute(context.Background(), givenWorkshopServer(t, 233780, domain.Settings{}))
+
+	require.NoError(t, err)
+	assert.Equal(t, SuccessResult, cmd.Result())
+	assert.Equal(t, 0, executor.calls)
+}
+
+var (
+	installDirRegexp     = regexp.MustCompile(`\+force_install_dir "([^"]+)"`)
+	downloadItemRegexp   = regexp.MustCompile(`\+workshop_download_item (\d+) (\d+)`)
+	downloadedItemRegexp = regexp.MustCompile(`Downloaded item (\d+) `)
+)
+
+// workshopExecutor replays the SteamCMD transcript and creates directories
+// of the requested items reported as downloaded in the transcript.
+type workshopExecutor struct {
+	t          *testing.T
+	transcript string
+	exitCode   int
+	command    string
+	calls      int
+}
+
+func (ex *workshopExecutor) Exec(_ context.Context, _ string, _ contracts.ExecutorOptions) ([]byte, int, error) {
+	return nil, 0, nil
+}
+
+func (ex *workshopExecutor) ExecWithWriter(
+	_ context.Context, command string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	ex.command = command
+	ex.calls++
+
+	writeTranscript(ex.t, out, ex.transcript)
+
+	content, err := os.ReadFile("../../../test/files/steamcmd/" + ex.transcript)
+	require.NoError(ex.t, err)
+	downloaded := map[string]bool{}
+	for _, m := range downloadedItemRegexp.FindAllStringSubmatch(string(content), -1) {
+		downloaded[m[1]] = true
+	}
+
+	installDir := installDirRegexp.FindStringSubmatch(command)[1]
+	for _, m := range downloadItemRegexp.FindAllStringSubmatch(command, -1) {
+		if !downloaded[m[2]] {
+			continue
+		}
+
+		itemDir := filepath.Join(installDir, "steamapps", "workshop", "content", m[1], m[2])
+		require.NoError(ex.t, os.MkdirAll(itemDir, 0755))
+		require.NoError(ex.t, os.WriteFile(filepath.Join(itemDir, "mod.cpp"), []byte("name = \"mod\";"), 0600))
+	}
+
+	return ex.exitCode, nil
+}
+
+func givenWorkshopServer(t *testing.T, appID domain.SteamAppID, settings domain.Settings) *domain.Server {
+	t.Helper()
+
+	return givenServer(
+		t,
+		domain.Game{StartCode: "game", SteamAppID: appID},
+		domain.GameMod{Name: "default"},
+		domaintest.WithUser(""),
+		domaintest.WithSettings(settings),
+	)
+}
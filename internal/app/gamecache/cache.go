@@ -0,0 +1,495 @@
+package gamecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	metaFile    = "meta.json"
+	contentName = "content"
+	tmpInfix    = ".tmp-"
+
+	megabyte = 1024 * 1024
+)
+
+var ErrNotCacheable = errors.New("source is not cacheable")
+
+// entryMeta is stored next to the cached content.
+type entryMeta struct {
+	Key          string    `json:"key"`
+	Version      string    `json:"version"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	File         string    `json:"file,omitempty"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+type entryLocks struct {
+	// fill serializes the population of the entry, so the first installation
+	// fills the cache and the concurrent ones wait for it.
+	fill sync.Mutex
+	// data guards the entry content while it is read or replaced.
+	data sync.RWMutex
+}
+
+// PruneResult describes the evicted entries.
+type PruneResult struct {
+	Removed []string
+	Freed   int64
+	Skipped int
+}
+
+// Cache is a content-addressed cache of game files shared by game server installations.
+// Entries are keyed by the installation source, e.g. the remote repository URL
+// or the Steam application ID, and keep the version (ETag or build ID) of the content.
+type Cache struct {
+	cfg        *config.Config
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	locks map[string]*entryLocks
+}
+
+func NewCache(cfg *config.Config) *Cache {
+	return &Cache{
+		cfg:        cfg,
+		httpClient: &http.Client{},
+		locks:      make(map[string]*entryLocks),
+	}
+}
+
+func (c *Cache) Enabled() bool {
+	return c.cfg.GameCache.Enabled
+}
+
+// Lock prevents concurrent population of the entry.
+// The returned function releases the lock.
+func (c *Cache) Lock(key string) func() {
+	l := c.entryLocks(key)
+	l.fill.Lock()
+
+	return l.fill.Unlock
+}
+
+// Version returns the version of the cached content or an empty string if the key isn't cached.
+func (c *Cache) Version(key string) string {
+	meta, err := c.readMeta(c.entryPath(key))
+	if err != nil {
+		return ""
+	}
+
+	return meta.Version
+}
+
+// Restore places the cached directory into dst according to the configured link mode.
+// Returns false if the key isn't cached.
+func (c *Cache) Restore(_ context.Context, key, dst string) (bool, error) {
+	l := c.entryLocks(key)
+	l.data.RLock()
+	defer l.data.RUnlock()
+
+	entryPath := c.entryPath(key)
+
+	meta, err := c.readMeta(entryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = PlaceTree(filepath.Join(entryPath, contentName), dst, c.cfg.GameCache.LinkMode)
+	if err != nil {
+		return false, errors.WithMessage(err, "[gamecache.Cache] failed to restore cached files")
+	}
+
+	c.touch(entryPath, meta)
+
+	return true, nil
+}
+
+// Store replaces the cached content of the key with the src directory.
+// Files are copied (or cloned if possible), so later changes in src don't affect the cache.
+func (c *Cache) Store(_ context.Context, key, version, src string) error {
+	tmp, err := c.makeTempEntry(key)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	mode := c.cfg.GameCache.LinkMode
+	if mode == config.GameCacheLinkModeHardlink {
+		mode = config.GameCacheLinkModeAuto
+	}
+
+	size, err := PlaceTree(src, filepath.Join(tmp, contentName), mode)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to copy files to cache")
+	}
+
+	now := time.Now()
+
+	return c.replace(key, tmp, &entryMeta{
+		Key:        key,
+		Version:    version,
+		Size:       size,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+}
+
+// Archive returns the path of the cached archive downloaded from the source URL.
+// The cached archive is revalidated by the ETag and Last-Modified headers and downloaded
+// again only if it has changed. If the source is unreachable, the cached archive is used.
+// The release function must be called after the archive is no longer used.
+func (c *Cache) Archive(ctx context.Context, source string, out io.Writer) (string, func(), error) {
+	if !IsCacheableSource(source) {
+		return "", nil, ErrNotCacheable
+	}
+
+	key := "remote:" + source
+	entryPath := c.entryPath(key)
+	l := c.entryLocks(key)
+
+	l.fill.Lock()
+	defer l.fill.Unlock()
+
+	meta, err := c.readMeta(entryPath)
+	if err != nil {
+		meta = nil
+	}
+
+	err = c.download(ctx, key, source, meta, out)
+	if err != nil {
+		if meta == nil {
+			return "", nil, err
+		}
+
+		writeLine(out, "Failed to revalidate the cached archive, using the cached one: "+err.Error())
+	}
+
+	l.data.RLock()
+
+	meta, err = c.readMeta(entryPath)
+	if err != nil {
+		l.data.RUnlock()
+		return "", nil, err
+	}
+
+	c.touch(entryPath, meta)
+
+	return filepath.Join(entryPath, contentName, meta.File), l.data.RUnlock, nil
+}
+
+// IsCacheableSource reports whether the archive from the source can be cached.
+// Only plain HTTP(S) URLs are cached. URLs with go-getter parameters
+// or subdirectories are downloaded directly.
+func IsCacheableSource(source string) bool {
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	return u.RawQuery == "" && !strings.Contains(u.Path, "//")
+}
+
+func (c *Cache) download(ctx context.Context, key, source string, meta *entryMeta, out io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to create request")
+	}
+
+	if meta != nil && meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	if meta != nil && meta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.LastModified)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to download archive")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && meta != nil {
+		writeLine(out, "Cached archive of "+source+" is up to date")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("[gamecache.Cache] failed to download archive, unexpected status code %d", resp.StatusCode)
+	}
+
+	writeLine(out, "Downloading "+source+" to the game cache ...")
+
+	tmp, err := c.makeTempEntry(key)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	fileName := archiveFileName(source)
+
+	err = os.MkdirAll(filepath.Join(tmp, contentName), 0755)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to create cache directory")
+	}
+
+	file, err := os.Create(filepath.Join(tmp, contentName, fileName))
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to create archive file")
+	}
+
+	size, err := io.Copy(file, resp.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to download archive")
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	version := etag
+	if version == "" {
+		version = lastModified
+	}
+
+	now := time.Now()
+
+	return c.replace(key, tmp, &entryMeta{
+		Key:          key,
+		Version:      version,
+		ETag:         etag,
+		LastModified: lastModified,
+		File:         fileName,
+		Size:         size,
+		CreatedAt:    now,
+		LastUsedAt:   now,
+	})
+}
+
+// Prune evicts entries unused longer than game_cache.max_age, then the least recently used
+// entries until the cache fits game_cache.max_size. If no limits are configured, all entries are evicted.
+// Entries in use are skipped.
+func (c *Cache) Prune(_ context.Context) (PruneResult, error) {
+	result := PruneResult{}
+
+	dirEntries, err := os.ReadDir(c.cfg.GameCache.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, errors.WithMessage(err, "[gamecache.Cache] failed to read cache directory")
+	}
+
+	maxAge := c.cfg.GameCache.MaxAge
+	maxSize := int64(c.cfg.GameCache.MaxSize) * megabyte
+	noLimits := maxAge == 0 && maxSize == 0
+
+	now := time.Now()
+	var kept []*entryMeta
+	var total int64
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.Contains(dirEntry.Name(), tmpInfix) {
+			continue
+		}
+
+		entryPath := filepath.Join(c.cfg.GameCache.Path, dirEntry.Name())
+
+		meta, err := c.readMeta(entryPath)
+		if err != nil {
+			// Broken entries are removed
+			_ = os.RemoveAll(entryPath)
+			continue
+		}
+
+		if noLimits || (maxAge > 0 && now.Sub(meta.LastUsedAt) > maxAge) {
+			c.evict(meta, &result)
+			continue
+		}
+
+		kept = append(kept, meta)
+		total += meta.Size
+	}
+
+	if maxSize == 0 {
+		return result, nil
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].LastUsedAt.Before(kept[j].LastUsedAt)
+	})
+
+	for _, meta := range kept {
+		if total <= maxSize {
+			break
+		}
+
+		if c.evict(meta, &result) {
+			total -= meta.Size
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Cache) evict(meta *entryMeta, result *PruneResult) bool {
+	l := c.entryLocks(meta.Key)
+	if !l.data.TryLock() {
+		result.Skipped++
+		return false
+	}
+	defer l.data.Unlock()
+
+	err := os.RemoveAll(c.entryPath(meta.Key))
+	if err != nil {
+		result.Skipped++
+		return false
+	}
+
+	result.Removed = append(result.Removed, meta.Key)
+	result.Freed += meta.Size
+
+	return true
+}
+
+func (c *Cache) entryLocks(key string) *entryLocks {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.locks[key]
+	if !ok {
+		l = &entryLocks{}
+		c.locks[key] = l
+	}
+
+	return l
+}
+
+func (c *Cache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(c.cfg.GameCache.Path, hex.EncodeToString(sum[:]))
+}
+
+func (c *Cache) makeTempEntry(key string) (string, error) {
+	err := os.MkdirAll(c.cfg.GameCache.Path, 0755)
+	if err != nil {
+		return "", errors.WithMessage(err, "[gamecache.Cache] failed to create cache directory")
+	}
+
+	tmp, err := os.MkdirTemp(c.cfg.GameCache.Path, filepath.Base(c.entryPath(key))+tmpInfix)
+	if err != nil {
+		return "", errors.WithMessage(err, "[gamecache.Cache] failed to create temporary directory")
+	}
+
+	return tmp, nil
+}
+
+// replace writes the meta file to the prepared temporary entry and puts it in place of the current entry.
+func (c *Cache) replace(key, tmp string, meta *entryMeta) error {
+	err := c.writeMeta(tmp, meta)
+	if err != nil {
+		return err
+	}
+
+	l := c.entryLocks(key)
+	l.data.Lock()
+	defer l.data.Unlock()
+
+	entryPath := c.entryPath(key)
+
+	err = os.RemoveAll(entryPath)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to remove outdated entry")
+	}
+
+	err = os.Rename(tmp, entryPath)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to move entry")
+	}
+
+	return nil
+}
+
+func (c *Cache) readMeta(entryPath string) (*entryMeta, error) {
+	content, err := os.ReadFile(filepath.Join(entryPath, metaFile))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &entryMeta{}
+	err = json.Unmarshal(content, meta)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[gamecache.Cache] failed to decode entry meta")
+	}
+
+	return meta, nil
+}
+
+func (c *Cache) writeMeta(entryPath string, meta *entryMeta) error {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to encode entry meta")
+	}
+
+	err = os.WriteFile(filepath.Join(entryPath, metaFile), content, 0600)
+	if err != nil {
+		return errors.WithMessage(err, "[gamecache.Cache] failed to write entry meta")
+	}
+
+	return nil
+}
+
+// touch updates the last usage time used by the cache pruning.
+func (c *Cache) touch(entryPath string, meta *entryMeta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta.LastUsedAt = time.Now()
+	_ = c.writeMeta(entryPath, meta)
+}
+
+func archiveFileName(source string) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "archive"
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "archive"
+	}
+
+	return name
+}
+
+func writeLine(out io.Writer, line string) {
+	_, _ = out.Write([]byte(line + "\n"))
+}
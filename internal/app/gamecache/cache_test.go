@@ -0,0 +1,197 @@
+package gamecache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRestore_Hardlink_FilesLinkedFromCache(t *testing.T) {
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeHardlink))
+	src := givenGameDirectory(t)
+	dst := filepath.Join(t.TempDir(), "server")
+
+	require.NoError(t, cache.Store(context.Background(), "steam:90", "1337", src))
+	restored, err := cache.Restore(context.Background(), "steam:90", dst)
+
+	require.NoError(t, err)
+	assert.True(t, restored)
+	assert.Equal(t, "1337", cache.Version("steam:90"))
+	content, err := os.ReadFile(filepath.Join(dst, "cstrike", "liblist.gam"))
+	require.NoError(t, err)
+	assert.Equal(t, "game \"Counter-Strike\"", string(content))
+	link, err := os.Readlink(filepath.Join(dst, "hlds"))
+	require.NoError(t, err)
+	assert.Equal(t, "hlds_run", link)
+
+	// The cache copies files, so the source changes don't affect the cache
+	require.NoError(t, os.WriteFile(filepath.Join(src, "cstrike", "liblist.gam"), []byte("changed"), 0600))
+	stat, err := os.Stat(filepath.Join(dst, "cstrike", "liblist.gam"))
+	require.NoError(t, err)
+	assert.EqualValues(t, len("game \"Counter-Strike\""), stat.Size())
+}
+
+func TestRestore_NotCached(t *testing.T) {
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeAuto))
+
+	restored, err := cache.Restore(context.Background(), "steam:90", t.TempDir())
+
+	require.NoError(t, err)
+	assert.False(t, restored)
+	assert.Empty(t, cache.Version("steam:90"))
+}
+
+func TestArchive_NotModified_DownloadedOnce(t *testing.T) {
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&downloads, 1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("archive"))
+	}))
+	defer server.Close()
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeAuto))
+	out := &bytes.Buffer{}
+
+	path, release, err := cache.Archive(context.Background(), server.URL+"/games/cstrike.tar.gz", out)
+	require.NoError(t, err)
+	release()
+	path2, release, err := cache.Archive(context.Background(), server.URL+"/games/cstrike.tar.gz", out)
+	require.NoError(t, err)
+	release()
+
+	assert.Equal(t, path, path2)
+	assert.Equal(t, "cstrike.tar.gz", filepath.Base(path))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&downloads))
+	assert.Contains(t, out.String(), "is up to date")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "archive", string(content))
+}
+
+func TestArchive_SourceUnavailable_CachedArchiveUsed(t *testing.T) {
+	var unavailable atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if unavailable.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("archive"))
+	}))
+	defer server.Close()
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeAuto))
+	_, release, err := cache.Archive(context.Background(), server.URL+"/cstrike.zip", io.Discard)
+	require.NoError(t, err)
+	release()
+	unavailable.Store(true)
+	out := &bytes.Buffer{}
+
+	path, release, err := cache.Archive(context.Background(), server.URL+"/cstrike.zip", out)
+
+	require.NoError(t, err)
+	release()
+	assert.FileExists(t, path)
+	assert.Contains(t, out.String(), "using the cached one")
+}
+
+func TestArchive_NotCacheableSource(t *testing.T) {
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeAuto))
+
+	_, _, err := cache.Archive(context.Background(), "https://example.com/cstrike.zip?archive=zip", io.Discard)
+
+	assert.ErrorIs(t, err, ErrNotCacheable)
+}
+
+func TestPrune_MaxSize_LeastRecentlyUsedEvicted(t *testing.T) {
+	cfg := givenConfig(t, config.GameCacheLinkModeAuto)
+	cfg.GameCache.MaxSize = 1
+	cache := NewCache(cfg)
+	givenEntry(t, cache, "steam:90", 700*1024, time.Now().Add(-2*time.Hour))
+	givenEntry(t, cache, "steam:740", 700*1024, time.Now().Add(-1*time.Hour))
+
+	result, err := cache.Prune(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"steam:90"}, result.Removed)
+	assert.Empty(t, cache.Version("steam:90"))
+	assert.NotEmpty(t, cache.Version("steam:740"))
+}
+
+func TestPrune_MaxAge_UnusedEvicted(t *testing.T) {
+	cfg := givenConfig(t, config.GameCacheLinkModeAuto)
+	cfg.GameCache.MaxAge = 24 * time.Hour
+	cache := NewCache(cfg)
+	givenEntry(t, cache, "steam:90", 10, time.Now().Add(-48*time.Hour))
+	givenEntry(t, cache, "steam:740", 10, time.Now())
+
+	result, err := cache.Prune(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"steam:90"}, result.Removed)
+}
+
+func TestPrune_NoLimits_AllEvictedExceptInUse(t *testing.T) {
+	cache := NewCache(givenConfig(t, config.GameCacheLinkModeAuto))
+	givenEntry(t, cache, "steam:90", 10, time.Now())
+	givenEntry(t, cache, "steam:740", 10, time.Now())
+	l := cache.entryLocks("steam:740")
+	l.data.RLock()
+	defer l.data.RUnlock()
+
+	result, err := cache.Prune(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"steam:90"}, result.Removed)
+	assert.Equal(t, 1, result.Skipped)
+}
+
+func givenConfig(t *testing.T, linkMode string) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.GameCache.Enabled = true
+	cfg.GameCache.Path = filepath.Join(t.TempDir(), "cache")
+	cfg.GameCache.LinkMode = linkMode
+
+	return cfg
+}
+
+func givenGameDirectory(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "cstrike"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cstrike", "liblist.gam"), []byte("game \"Counter-Strike\""), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hlds_run"), []byte("#!/bin/sh"), 0700))
+	require.NoError(t, os.Symlink("hlds_run", filepath.Join(dir, "hlds")))
+
+	return dir
+}
+
+func givenEntry(t *testing.T, cache *Cache, key string, size int, lastUsedAt time.Time) {
+	t.Helper()
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file"), make([]byte, size), 0600))
+	require.NoError(t, cache.Store(context.Background(), key, "1", src))
+
+	entryPath := cache.entryPath(key)
+	meta, err := cache.readMeta(entryPath)
+	require.NoError(t, err)
+	meta.LastUsedAt = lastUsedAt
+	require.NoError(t, cache.writeMeta(entryPath, meta))
+}
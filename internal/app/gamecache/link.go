@@ -0,0 +1,99 @@
+package gamecache
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+)
+
+// PlaceTree recreates the src directory tree in dst, files are placed according to the link mode.
+// Existing files in dst are replaced. Returns the total size of the placed files.
+func PlaceTree(src, dst, mode string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			size += info.Size()
+
+			return placeFile(p, target, info.Mode().Perm(), mode)
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+func placeFile(src, dst string, perm fs.FileMode, mode string) error {
+	err := os.Remove(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	switch mode {
+	case config.GameCacheLinkModeHardlink:
+		// Hard links are impossible between filesystems
+		if os.Link(src, dst) == nil {
+			return nil
+		}
+	case config.GameCacheLinkModeReflink:
+		return reflinkFile(src, dst, perm)
+	case config.GameCacheLinkModeAuto:
+		if reflinkFile(src, dst, perm) == nil {
+			return nil
+		}
+	}
+
+	return copyFile(src, dst, perm)
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package gamecache
+
+import (
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+var errReflinkNotSupported = errors.New("reflinks are not supported")
+
+func reflinkFile(_, _ string, _ fs.FileMode) error {
+	return errReflinkNotSupported
+}
@@ -0,0 +1,130 @@
+package gamequery
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout = 3 * time.Second
+
+	maxPacketSize = 1400
+
+	a2sInfoResponseHeader = 'I'
+	a2sChallengeHeader    = 'A'
+	packetHeaderSize      = 4
+)
+
+var a2sInfoRequest = append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'T'}, []byte("Source Engine Query\x00")...)
+
+var (
+	ErrInvalidResponse = errors.New("invalid query response")
+	ErrNoQueryPort     = errors.New("game server has no query port")
+)
+
+// PlayersCounter queries the game servers with the Source A2S_INFO query.
+// Most of the Steam games (Source, GoldSrc, Rust, ARK, etc.) answer it.
+type PlayersCounter struct {
+	timeout time.Duration
+}
+
+func NewPlayersCounter() *PlayersCounter {
+	return &PlayersCounter{timeout: defaultTimeout}
+}
+
+// OnlinePlayers returns the number of players on the game server.
+// The query port is used, the connect port is used if the query port isn't set.
+func (c *PlayersCounter) OnlinePlayers(ctx context.Context, server *domain.Server) (int, error) {
+	port := server.QueryPort()
+	if port == 0 {
+		port = server.ConnectPort()
+	}
+	if port == 0 {
+		return 0, ErrNoQueryPort
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return queryPlayers(ctx, net.JoinHostPort(server.IP(), strconv.Itoa(port)))
+}
+
+func queryPlayers(ctx context.Context, address string) (int, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, errors.WithMessage(err, "[gamequery] failed to connect")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	response, err := exchange(conn, a2sInfoRequest)
+	if err != nil {
+		return 0, err
+	}
+
+	// The server may require the challenge number to be sent with the request
+	if response[0] == a2sChallengeHeader && len(response) >= 5 {
+		request := append(append([]byte{}, a2sInfoRequest...), response[1:5]...)
+
+		response, err = exchange(conn, request)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return parseInfoPlayers(response)
+}
+
+func exchange(conn net.Conn, request []byte) ([]byte, error) {
+	_, err := conn.Write(request)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[gamequery] failed to send query")
+	}
+
+	buf := make([]byte, maxPacketSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[gamequery] failed to read query response")
+	}
+
+	if n <= packetHeaderSize || !bytes.Equal(buf[:packetHeaderSize], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+		return nil, ErrInvalidResponse
+	}
+
+	return buf[packetHeaderSize:n], nil
+}
+
+// parseInfoPlayers reads the players count from the A2S_INFO response:
+// header, protocol, name, map, folder, game, app id (2 bytes), players.
+func parseInfoPlayers(response []byte) (int, error) {
+	if len(response) < 2 || response[0] != a2sInfoResponseHeader {
+		return 0, ErrInvalidResponse
+	}
+
+	rest := response[2:]
+	for i := 0; i < 4; i++ {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return 0, ErrInvalidResponse
+		}
+		rest = rest[end+1:]
+	}
+
+	if len(rest) < 3 {
+		return 0, ErrInvalidResponse
+	}
+
+	return int(rest[2]), nil
+}
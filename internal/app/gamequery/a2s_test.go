@@ -0,0 +1,52 @@
+package gamequery
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPlayers_WithChallenge(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	challenge := []byte{0x01, 0x02, 0x03, 0x04}
+	info := append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'I', 17}, []byte("My server\x00de_dust2\x00cstrike\x00Counter-Strike\x00")...)
+	info = append(info, 0x0A, 0x00, 7, 32, 0)
+
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if bytes.HasSuffix(buf[:n], challenge) {
+				_, _ = conn.WriteTo(info, addr)
+			} else {
+				_, _ = conn.WriteTo(append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'A'}, challenge...), addr)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	players, err := queryPlayers(ctx, conn.LocalAddr().String())
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, players)
+}
+
+func TestParseInfoPlayers_InvalidResponse(t *testing.T) {
+	_, err := parseInfoPlayers([]byte{'I', 17, 'n', 0})
+
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+}
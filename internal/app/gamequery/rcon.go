@@ -0,0 +1,158 @@
+package gamequery
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+// Source RCON packet types.
+const (
+	rconExecCommand   int32 = 2
+	rconAuth          int32 = 3
+	rconAuthResponse  int32 = 2
+	rconResponseValue int32 = 0
+
+	rconRequestID int32 = 1
+
+	// Minimal packet: ID, type and two null bytes.
+	rconMinPacketSize = 10
+	rconMaxPacketSize = 4096 + rconMinPacketSize
+)
+
+var (
+	ErrNoRCONPassword   = errors.New("game server has no RCON password")
+	ErrRCONAuthFailed   = errors.New("RCON authentication failed")
+	ErrInvalidRCONReply = errors.New("invalid RCON response")
+)
+
+// RCON executes the commands on the game servers with the Source RCON protocol.
+// Source games, Minecraft, Rust, ARK and many others support it.
+type RCON struct {
+	timeout time.Duration
+}
+
+func NewRCON() *RCON {
+	return &RCON{timeout: defaultTimeout}
+}
+
+// Exec executes the command and returns its response.
+// The RCON port is used, the connect port is used if the RCON port isn't set.
+func (r *RCON) Exec(ctx context.Context, server *domain.Server, command string) (string, error) {
+	if server.RCONPassword() == "" {
+		return "", ErrNoRCONPassword
+	}
+
+	port := server.RCONPort()
+	if port == 0 {
+		port = server.ConnectPort()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return rconExec(ctx, net.JoinHostPort(server.IP(), strconv.Itoa(port)), server.RCONPassword(), command)
+}
+
+func rconExec(ctx context.Context, address, password, command string) (string, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", errors.WithMessage(err, "[gamequery.RCON] failed to connect")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	err = writeRCONPacket(conn, rconRequestID, rconAuth, password)
+	if err != nil {
+		return "", err
+	}
+
+	// The server may send the empty response value before the auth response
+	for {
+		id, packetType, _, err := readRCONPacket(conn)
+		if err != nil {
+			return "", err
+		}
+
+		if packetType != rconAuthResponse {
+			continue
+		}
+
+		if id != rconRequestID {
+			return "", ErrRCONAuthFailed
+		}
+
+		break
+	}
+
+	err = writeRCONPacket(conn, rconRequestID, rconExecCommand, command)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		_, packetType, body, err := readRCONPacket(conn)
+		if err != nil {
+			return "", err
+		}
+
+		if packetType == rconResponseValue {
+			return body, nil
+		}
+	}
+}
+
+func writeRCONPacket(w io.Writer, id, packetType int32, body string) error {
+	buf := bytes.Buffer{}
+	size := int32(len(body) + rconMinPacketSize)
+
+	_ = binary.Write(&buf, binary.LittleEndian, size)
+	_ = binary.Write(&buf, binary.LittleEndian, id)
+	_ = binary.Write(&buf, binary.LittleEndian, packetType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		return errors.WithMessage(err, "[gamequery.RCON] failed to send packet")
+	}
+
+	return nil
+}
+
+func readRCONPacket(r io.Reader) (int32, int32, string, error) {
+	var size int32
+	err := binary.Read(r, binary.LittleEndian, &size)
+	if err != nil {
+		return 0, 0, "", errors.WithMessage(err, "[gamequery.RCON] failed to read packet")
+	}
+
+	if size < rconMinPacketSize || size > rconMaxPacketSize {
+		return 0, 0, "", ErrInvalidRCONReply
+	}
+
+	packet := make([]byte, size)
+	_, err = io.ReadFull(r, packet)
+	if err != nil {
+		return 0, 0, "", errors.WithMessage(err, "[gamequery.RCON] failed to read packet")
+	}
+
+	id := int32(binary.LittleEndian.Uint32(packet[0:4]))
+	packetType := int32(binary.LittleEndian.Uint32(packet[4:8]))
+	body := bytes.TrimRight(packet[8:], "\x00")
+
+	return id, packetType, string(body), nil
+}
@@ -0,0 +1,73 @@
+package gamequery
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenRCONServer(t *testing.T, password string) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	commands := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		id, _, body, err := readRCONPacket(conn)
+		if err != nil {
+			return
+		}
+
+		_ = writeRCONPacket(conn, id, rconResponseValue, "")
+		if body != password {
+			_ = writeRCONPacket(conn, -1, rconAuthResponse, "")
+			return
+		}
+		_ = writeRCONPacket(conn, id, rconAuthResponse, "")
+
+		id, _, body, err = readRCONPacket(conn)
+		if err != nil {
+			return
+		}
+
+		commands <- body
+		_ = writeRCONPacket(conn, id, rconResponseValue, "ok")
+	}()
+
+	return listener.Addr().String(), commands
+}
+
+func TestRCONExec(t *testing.T) {
+	address, commands := givenRCONServer(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := rconExec(ctx, address, "secret", "say Server restart in 5 minutes")
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, "say Server restart in 5 minutes", <-commands)
+}
+
+func TestRCONExec_InvalidPassword(t *testing.T) {
+	address, _ := givenRCONServer(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := rconExec(ctx, address, "invalid", "status")
+
+	assert.ErrorIs(t, err, ErrRCONAuthFailed)
+}
@@ -2,4 +2,7 @@ package gdaemonscheduler
 
 import "errors"
 
-var ErrInvalidTaskError = errors.New("invalid task")
+var (
+	ErrInvalidTaskError = errors.New("invalid task")
+	ErrTaskNotFound     = errors.New("task not found in the queue")
+)
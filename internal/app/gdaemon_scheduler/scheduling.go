@@ -0,0 +1,216 @@
+package gdaemonscheduler
+
+import (
+	"sort"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+type priority int
+
+const (
+	priorityLow priority = iota + 1
+	priorityNormal
+	priorityHigh
+)
+
+var priorityNames = map[string]priority{
+	config.TaskPriorityLow:    priorityLow,
+	config.TaskPriorityNormal: priorityNormal,
+	config.TaskPriorityHigh:   priorityHigh,
+}
+
+// defaultPriorities puts the game server lifecycle commands ahead of the long installations and updates.
+// Other commands have the normal priority.
+var defaultPriorities = map[domain.GDTaskCommand]priority{
+	domain.GDTaskGameServerStart:     priorityHigh,
+	domain.GDTaskGameServerPause:     priorityHigh,
+	domain.GDTaskGameServerStop:      priorityHigh,
+	domain.GDTaskGameServerKill:      priorityHigh,
+	domain.GDTaskGameServerRestart:   priorityHigh,
+	domain.GDTaskGameServerInstall:   priorityLow,
+	domain.GDTaskGameServerReinstall: priorityLow,
+	domain.GDTaskGameServerUpdate:    priorityLow,
+	domain.GDTaskGameServerWorkshop:  priorityLow,
+	domain.GDTaskGameCachePrune:      priorityLow,
+}
+
+// steamCMDLimitKey is the concurrency limit of the tasks running SteamCMD:
+// installations and updates of the Steam games and workshop items installations.
+const steamCMDLimitKey = "steamcmd"
+
+// scheduling decides which waiting tasks can be started now. The rules are:
+//   - the task waits for the task from its run_after_id;
+//   - tasks of the same game server never run concurrently and are started in the queue order;
+//   - tasks with the higher priority are started first, the queue order is kept within the same priority;
+//   - the number of working tasks is limited by task_manager.workers_count, high priority tasks aren't limited;
+//   - the number of working tasks by command is limited by task_manager.concurrency;
+//   - the exclusive task runs alone, it waits for the working tasks and the tasks queued after it wait for it.
+type scheduling struct {
+	cfg              *config.Config
+	queue            *taskQueue
+	working          int
+	exclusiveWorking bool
+	busyServers      map[int]struct{}
+	running          map[string]int // limit key => working tasks
+}
+
+func (manager *TaskManager) newScheduling() *scheduling {
+	s := &scheduling{
+		cfg:         manager.config,
+		queue:       manager.queue,
+		busyServers: make(map[int]struct{}),
+		running:     make(map[string]int),
+	}
+
+	_, working := manager.queue.WorkingTasks()
+	for _, task := range working {
+		s.take(task)
+	}
+
+	return s
+}
+
+// Select returns the waiting tasks to start in the starting order.
+func (s *scheduling) Select(waiting []*domain.GDTask) []*domain.GDTask {
+	if s.exclusiveWorking {
+		return nil
+	}
+
+	candidates := make([]*domain.GDTask, 0, len(waiting))
+	claimedServers := make(map[int]struct{})
+
+	for _, task := range waiting {
+		if isExclusive(task) {
+			if s.working == 0 && len(candidates) == 0 && !s.waitsForAnotherTask(task) {
+				s.take(task)
+
+				return []*domain.GDTask{task}
+			}
+
+			break
+		}
+
+		if s.waitsForAnotherTask(task) {
+			continue
+		}
+
+		if task.Server() != nil {
+			serverID := task.Server().ID()
+
+			if _, busy := s.busyServers[serverID]; busy {
+				continue
+			}
+
+			// Only the first task of the server in the queue order can be started
+			if _, claimed := claimedServers[serverID]; claimed {
+				continue
+			}
+			claimedServers[serverID] = struct{}{}
+		}
+
+		candidates = append(candidates, task)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return s.priority(candidates[i]) > s.priority(candidates[j])
+	})
+
+	selected := make([]*domain.GDTask, 0, len(candidates))
+	for _, task := range candidates {
+		if !s.allowed(task) {
+			continue
+		}
+
+		s.take(task)
+		selected = append(selected, task)
+	}
+
+	return selected
+}
+
+func (s *scheduling) waitsForAnotherTask(task *domain.GDTask) bool {
+	if task.RunAfterID() == 0 {
+		return false
+	}
+
+	t := s.queue.FindByID(task.RunAfterID())
+
+	return t != nil && !t.IsComplete()
+}
+
+func (s *scheduling) allowed(task *domain.GDTask) bool {
+	workersCount := s.cfg.TaskManager.WorkersCount
+	if workersCount > 0 && s.working >= workersCount && s.priority(task) < priorityHigh {
+		return false
+	}
+
+	for _, key := range limitKeys(task) {
+		limit, ok := s.cfg.TaskManager.Concurrency[key]
+		if ok && limit > 0 && s.running[key] >= limit {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *scheduling) take(task *domain.GDTask) {
+	s.working++
+
+	if isExclusive(task) {
+		s.exclusiveWorking = true
+	}
+
+	if task.Server() != nil {
+		s.busyServers[task.Server().ID()] = struct{}{}
+	}
+
+	for _, key := range limitKeys(task) {
+		s.running[key]++
+	}
+}
+
+func (s *scheduling) priority(task *domain.GDTask) priority {
+	if name, ok := s.cfg.TaskManager.Priorities[string(task.Task())]; ok {
+		if p, ok := priorityNames[name]; ok {
+			return p
+		}
+	}
+
+	if p, ok := defaultPriorities[task.Task()]; ok {
+		return p
+	}
+
+	return priorityNormal
+}
+
+// isExclusive reports whether the task must run alone.
+// The node-wide process manager migration stops and starts all game servers,
+// so the lifecycle tasks running alongside it would race with it.
+func isExclusive(task *domain.GDTask) bool {
+	return task.Task() == domain.GDTaskProcessManagerMigrate && task.Server() == nil
+}
+
+// limitKeys returns the keys of task_manager.concurrency limiting the task.
+func limitKeys(task *domain.GDTask) []string {
+	keys := []string{string(task.Task())}
+
+	if usesSteamCMD(task) {
+		keys = append(keys, steamCMDLimitKey)
+	}
+
+	return keys
+}
+
+func usesSteamCMD(task *domain.GDTask) bool {
+	switch task.Task() {
+	case domain.GDTaskGameServerWorkshop:
+		return true
+	case domain.GDTaskGameServerInstall, domain.GDTaskGameServerReinstall, domain.GDTaskGameServerUpdate:
+		return task.Server() != nil && task.Server().Game().SteamAppID != 0
+	}
+
+	return false
+}
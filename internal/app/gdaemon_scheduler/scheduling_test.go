@@ -0,0 +1,189 @@
+package gdaemonscheduler
+
+import (
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduling_Select(t *testing.T) {
+	server1 := givenServer(1, 0)
+	server2 := givenServer(2, 0)
+	steamServer1 := givenServer(3, 90)
+	steamServer2 := givenServer(4, 90)
+	steamServer3 := givenServer(5, 90)
+
+	tests := []struct {
+		name     string
+		setup    func(cfg *config.Config)
+		working  []*domain.GDTask
+		waiting  []*domain.GDTask
+		expected []int
+	}{
+		{
+			name: "lifecycle tasks are ahead of installations",
+			waiting: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerInstall),
+				givenTask(2, 0, nil, domain.GDTaskCommandExecute),
+				givenTask(3, 0, server2, domain.GDTaskGameServerStop),
+			},
+			expected: []int{3, 2, 1},
+		},
+		{
+			name: "configured priority",
+			setup: func(cfg *config.Config) {
+				cfg.TaskManager.Priorities = map[string]string{"cmdexec": config.TaskPriorityLow}
+			},
+			waiting: []*domain.GDTask{
+				givenTask(1, 0, nil, domain.GDTaskCommandExecute),
+				givenTask(2, 0, server1, domain.GDTaskGameServerDelete),
+			},
+			expected: []int{2, 1},
+		},
+		{
+			name: "tasks of the same server are not started concurrently",
+			working: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerInstall),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, server1, domain.GDTaskGameServerStart),
+				givenTask(3, 0, server2, domain.GDTaskGameServerStart),
+			},
+			expected: []int{3},
+		},
+		{
+			name: "tasks of the same server are started in the queue order",
+			waiting: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerInstall),
+				givenTask(2, 0, server1, domain.GDTaskGameServerStart),
+			},
+			expected: []int{1},
+		},
+		{
+			name: "task waits for run after task",
+			working: []*domain.GDTask{
+				givenTask(1, 0, nil, domain.GDTaskCommandExecute),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 1, server1, domain.GDTaskGameServerStop),
+				givenTask(3, 0, server1, domain.GDTaskGameServerStart),
+			},
+			expected: []int{3},
+		},
+		{
+			name: "workers count doesn't limit high priority tasks",
+			setup: func(cfg *config.Config) {
+				cfg.TaskManager.WorkersCount = 1
+			},
+			working: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerInstall),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, nil, domain.GDTaskCommandExecute),
+				givenTask(3, 0, server2, domain.GDTaskGameServerRestart),
+			},
+			expected: []int{3},
+		},
+		{
+			name: "steamcmd concurrency",
+			setup: func(cfg *config.Config) {
+				cfg.TaskManager.Concurrency = map[string]int{"steamcmd": 2}
+			},
+			working: []*domain.GDTask{
+				givenTask(1, 0, steamServer1, domain.GDTaskGameServerUpdate),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, steamServer2, domain.GDTaskGameServerInstall),
+				givenTask(3, 0, steamServer3, domain.GDTaskGameServerInstall),
+				givenTask(4, 0, server1, domain.GDTaskGameServerInstall),
+			},
+			expected: []int{2, 4},
+		},
+		{
+			name: "command concurrency",
+			setup: func(cfg *config.Config) {
+				cfg.TaskManager.Concurrency = map[string]int{"cmdexec": 1}
+			},
+			waiting: []*domain.GDTask{
+				givenTask(1, 0, nil, domain.GDTaskCommandExecute),
+				givenTask(2, 0, nil, domain.GDTaskCommandExecute),
+			},
+			expected: []int{1},
+		},
+		{
+			name: "node migration waits for working tasks and blocks tasks queued after it",
+			working: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerStart),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, server2, domain.GDTaskGameServerStop),
+				givenTask(3, 0, nil, domain.GDTaskProcessManagerMigrate),
+				givenTask(4, 0, steamServer1, domain.GDTaskGameServerStart),
+			},
+			expected: []int{2},
+		},
+		{
+			name: "node migration runs alone",
+			waiting: []*domain.GDTask{
+				givenTask(1, 0, nil, domain.GDTaskProcessManagerMigrate),
+				givenTask(2, 0, server1, domain.GDTaskGameServerStart),
+			},
+			expected: []int{1},
+		},
+		{
+			name: "nothing is started while node migration is working",
+			working: []*domain.GDTask{
+				givenTask(1, 0, nil, domain.GDTaskProcessManagerMigrate),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, server1, domain.GDTaskGameServerStart),
+			},
+			expected: []int{},
+		},
+		{
+			name: "game server migration isn't exclusive",
+			working: []*domain.GDTask{
+				givenTask(1, 0, server1, domain.GDTaskGameServerStart),
+			},
+			waiting: []*domain.GDTask{
+				givenTask(2, 0, server2, domain.GDTaskProcessManagerMigrate),
+			},
+			expected: []int{2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			if test.setup != nil {
+				test.setup(cfg)
+			}
+
+			manager := NewTaskManager(nil, nil, nil, nil, cfg, nil)
+			for _, task := range test.working {
+				_ = task.SetStatus(domain.GDTaskStatusWorking)
+			}
+			manager.queue.Insert(test.working)
+			manager.queue.Insert(test.waiting)
+
+			selected := manager.newScheduling().Select(test.waiting)
+
+			ids := make([]int, 0, len(selected))
+			for _, task := range selected {
+				ids = append(ids, task.ID())
+			}
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
+
+func givenTask(id, runAfterID int, server *domain.Server, command domain.GDTaskCommand) *domain.GDTask {
+	return domain.NewGDTask(id, runAfterID, server, command, "", domain.GDTaskStatusWaiting)
+}
+
+func givenServer(id int, steamAppID domain.SteamAppID) *domain.Server {
+	return domaintest.NewServer(domaintest.WithID(id), domaintest.WithGame(domain.Game{SteamAppID: steamAppID}))
+}
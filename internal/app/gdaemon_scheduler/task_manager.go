@@ -3,8 +3,10 @@ package gdaemonscheduler
 import (
 	"context"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/components"
@@ -12,12 +14,20 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/watchdog"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 )
 
 var updateTimeout = 5 * time.Second
 
+// cachePruneCommand is handled by the extendable executor, see customhandlers.CachePrune.
+const cachePruneCommand = "cache-prune"
+
+// processManagerMigrateCommand is handled by the extendable executor, see customhandlers.ProcessManagerMigration.
+const processManagerMigrateCommand = "pm-migrate"
+
 var taskServerCommandMap = map[domain.GDTaskCommand]domain.ServerCommand{
 	domain.GDTaskGameServerStart:     domain.Start,
 	domain.GDTaskGameServerPause:     domain.Pause,
@@ -28,6 +38,8 @@ var taskServerCommandMap = map[domain.GDTaskCommand]domain.ServerCommand{
 	domain.GDTaskGameServerReinstall: domain.Reinstall,
 	domain.GDTaskGameServerUpdate:    domain.Update,
 	domain.GDTaskGameServerDelete:    domain.Delete,
+	domain.GDTaskGameServerWorkshop:  domain.InstallWorkshop,
+	domain.GDTaskGameServerDryRun:    domain.InstallDryRun,
 }
 
 type TaskManager struct {
@@ -37,9 +49,16 @@ type TaskManager struct {
 	cache                contracts.Cache
 	config               *config.Config
 	serverCommandFactory *gameservercommands.ServerCommandFactory
+	watchdog             *watchdog.Watchdog
 	mutex                *sync.Mutex
 	queue                *taskQueue
 	commandsInProgress   sync.Map
+	startedAt            sync.Map // [int]time.Time (taskID => start time)
+	cancels              sync.Map // [int]context.CancelFunc (taskID => cancel of the task context)
+	canceled             sync.Map // [int]struct{} (taskIDs with requested cancellation)
+	timedOut             sync.Map // [int]struct{} (taskIDs killed by the timeout)
+	refresh              chan struct{}
+	pushConnected        atomic.Bool
 }
 
 func NewTaskManager(
@@ -48,15 +67,18 @@ func NewTaskManager(
 	serverCommandFactory *gameservercommands.ServerCommandFactory,
 	executor contracts.Executor,
 	config *config.Config,
+	watchdog *watchdog.Watchdog,
 ) *TaskManager {
 	return &TaskManager{
 		config:               config,
+		watchdog:             watchdog,
 		repository:           repository,
 		cache:                cache,
 		queue:                newTaskQueue(),
 		serverCommandFactory: serverCommandFactory,
 		mutex:                &sync.Mutex{},
 		executor:             executor,
+		refresh:              make(chan struct{}, 1),
 	}
 }
 
@@ -64,9 +86,7 @@ func (manager *TaskManager) Run(ctx context.Context) error {
 	manager.failWorkingTaskAfterRestart(ctx)
 
 	err := manager.updateTasksIfNeeded(ctx)
-	if err != nil {
-		logger.Logger(ctx).Error(err)
-	}
+	manager.logUpdateError(ctx, err)
 
 	go manager.RunWorker(ctx)
 
@@ -74,15 +94,98 @@ func (manager *TaskManager) Run(ctx context.Context) error {
 		select {
 		case <-(ctx).Done():
 			return nil
-		default:
-			time.Sleep(manager.config.TaskManager.UpdatePeriod)
-
+		case <-manager.refresh:
+			err = manager.updateTasks(ctx)
+		case <-time.After(manager.updatePeriod()):
 			err = manager.updateTasksIfNeeded(ctx)
-			if err != nil {
-				logger.Logger(ctx).Error(err)
-			}
 		}
+
+		manager.logUpdateError(ctx, err)
+	}
+}
+
+func (manager *TaskManager) logUpdateError(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, domain.ErrAPIUnavailable):
+		logger.Debug(ctx, "API is unavailable, tasks update is paused")
+	default:
+		logger.Logger(ctx).Error(err)
+	}
+}
+
+// Refresh requests the tasks to be reloaded from the API without waiting for the update period.
+func (manager *TaskManager) Refresh() {
+	select {
+	case manager.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// SetPushConnected switches the task manager to the fallback polling period
+// while tasks are delivered through the push channel.
+func (manager *TaskManager) SetPushConnected(connected bool) {
+	manager.pushConnected.Store(connected)
+}
+
+// AddTaskByID loads the task from the API and puts it to the queue if it is waiting.
+func (manager *TaskManager) AddTaskByID(ctx context.Context, id int) error {
+	task, err := manager.repository.FindByID(ctx, id)
+	if err != nil {
+		return errors.WithMessage(err, "[gdaemon_scheduler.TaskManager] failed to find task")
+	}
+
+	if task == nil {
+		return nil
+	}
+
+	if task.Status() == domain.GDTaskStatusCanceled {
+		return manager.cancelQueuedTask(ctx, id)
+	}
+
+	if !task.IsWaiting() {
+		return nil
+	}
+
+	manager.queue.Insert([]*domain.GDTask{task})
+
+	return nil
+}
+
+// CancelTask requests cancellation of the waiting or working task.
+// The context of the working task is canceled, so the running commands are killed with their child processes.
+// The task is completed with the canceled status on the next worker iteration.
+func (manager *TaskManager) CancelTask(ctx context.Context, id int) error {
+	task := manager.queue.FindByID(id)
+	if task == nil || task.IsComplete() {
+		return ErrTaskNotFound
+	}
+
+	logger.WithField(ctx, "gdTaskID", id).Info("Task cancellation requested")
+
+	manager.canceled.Store(id, struct{}{})
+
+	if cancel, ok := manager.cancels.Load(id); ok {
+		cancel.(context.CancelFunc)()
+	}
+
+	return nil
+}
+
+func (manager *TaskManager) cancelQueuedTask(ctx context.Context, id int) error {
+	err := manager.CancelTask(ctx, id)
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil
 	}
+
+	return err
+}
+
+func (manager *TaskManager) isCancellationRequested(task *domain.GDTask) bool {
+	_, ok := manager.canceled.Load(task.ID())
+
+	return ok
 }
 
 func (manager *TaskManager) RunWorker(ctx context.Context) {
@@ -94,7 +197,7 @@ func (manager *TaskManager) RunWorker(ctx context.Context) {
 			return
 		case <-ticker.C:
 			if manager.queue.Len() > 0 {
-				manager.runNext(ctx)
+				manager.runTasks(ctx)
 			}
 		}
 	}
@@ -134,26 +237,43 @@ func (manager *TaskManager) failWorkingTaskAfterRestart(ctx context.Context) {
 	}
 }
 
-func (manager *TaskManager) runNext(ctx context.Context) {
-	task := manager.queue.Next()
-	if task == nil {
+// runTasks proceeds the working tasks and starts the waiting ones allowed by the scheduling rules.
+func (manager *TaskManager) runTasks(ctx context.Context) {
+	var waiting []*domain.GDTask
+
+	for _, task := range manager.queue.Tasks() {
+		if task.IsWaiting() && !manager.isCancellationRequested(task) {
+			waiting = append(waiting, task)
+			continue
+		}
+
+		manager.runTask(ctx, task)
+	}
+
+	if len(waiting) == 0 {
 		return
 	}
 
+	for _, task := range manager.newScheduling().Select(waiting) {
+		manager.runTask(ctx, task)
+	}
+}
+
+func (manager *TaskManager) runTask(ctx context.Context, task *domain.GDTask) {
 	ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithField("gdTaskID", task.ID()))
 
 	if task.Server() != nil {
 		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithField("gameServerID", task.Server().ID()))
 	}
 
-	if manager.shouldTaskWaitForAnotherToComplete(task) {
-		return
-	}
-
 	var err error
-	if task.IsWaiting() {
+	if task.IsWaiting() && manager.isCancellationRequested(task) {
+		go manager.appendTaskOutput(ctx, task, []byte("Task canceled"))
+		manager.failTask(ctx, task)
+	} else if task.IsWaiting() {
 		err = manager.executeTask(ctx, task)
 	} else if task.IsWorking() {
+		manager.checkTimeout(ctx, task)
 		err = manager.proceedTask(ctx, task)
 	}
 
@@ -167,6 +287,16 @@ func (manager *TaskManager) runNext(ctx context.Context) {
 	if task.IsComplete() {
 		logger.Debug(ctx, "Task completed")
 
+		if cancel, ok := manager.cancels.LoadAndDelete(task.ID()); ok {
+			cancel.(context.CancelFunc)()
+		}
+		manager.canceled.Delete(task.ID())
+		manager.timedOut.Delete(task.ID())
+
+		if startedAt, ok := manager.startedAt.LoadAndDelete(task.ID()); ok {
+			metrics.ObserveGDTask(task.Task(), task.Status(), time.Since(startedAt.(time.Time)))
+		}
+
 		if task.Server() != nil {
 			task.Server().NoticeTaskCompleted()
 		}
@@ -181,42 +311,57 @@ func (manager *TaskManager) runNext(ctx context.Context) {
 	}
 }
 
-func (manager *TaskManager) shouldTaskWaitForAnotherToComplete(task *domain.GDTask) bool {
-	if task.RunAfterID() > 0 {
-		t := manager.queue.FindByID(task.RunAfterID())
-
-		if t == nil {
-			return false
-		}
-
-		if !t.IsComplete() {
-			return true
-		}
-	}
-
-	return false
-}
-
 func (manager *TaskManager) executeTask(ctx context.Context, task *domain.GDTask) error {
 	err := task.SetStatus(domain.GDTaskStatusWorking)
 	if err != nil {
 		return err
 	}
 
+	manager.startedAt.Store(task.ID(), time.Now())
+
 	err = manager.repository.Save(ctx, task)
 	if err != nil {
 		err = errors.WithMessage(err, "[gdaemon_scheduler.TaskManager] failed to save task")
 		logger.Error(ctx, err)
 	}
 
-	if task.Task() == domain.GDTaskCommandExecute {
-		return manager.executeCommand(ctx, task)
+	// The task context is canceled on the task cancellation or when the task is completed
+	taskCtx, cancel := context.WithCancel(ctx)
+	manager.cancels.Store(task.ID(), cancel)
+
+	if manager.isCancellationRequested(task) {
+		cancel()
+	}
+
+	switch task.Task() {
+	case domain.GDTaskCommandExecute:
+		return manager.executeCommand(taskCtx, task, task.Command())
+	case domain.GDTaskGameCachePrune:
+		return manager.executeCommand(taskCtx, task, cachePruneCommand)
+	case domain.GDTaskProcessManagerMigrate:
+		return manager.executeCommand(taskCtx, task, processManagerMigrateTaskCommand(task))
 	}
 
-	return manager.executeGameCommand(ctx, task)
+	return manager.executeGameCommand(taskCtx, task)
 }
 
-func (manager *TaskManager) executeCommand(ctx context.Context, task *domain.GDTask) error {
+// processManagerMigrateTaskCommand migrates the task game server or all game servers of the node
+// if the task has no game server. The task command holds the migration arguments, e.g. "--dry-run".
+func processManagerMigrateTaskCommand(task *domain.GDTask) string {
+	command := processManagerMigrateCommand
+
+	if task.Server() != nil {
+		command += " --server=" + strconv.Itoa(task.Server().ID())
+	}
+
+	if task.Command() != "" {
+		command += " " + task.Command()
+	}
+
+	return command
+}
+
+func (manager *TaskManager) executeCommand(ctx context.Context, task *domain.GDTask, command string) error {
 	cmd := newExecuteCommand(manager.config, manager.executor)
 
 	manager.commandsInProgress.Store(*task, cmd)
@@ -224,7 +369,9 @@ func (manager *TaskManager) executeCommand(ctx context.Context, task *domain.GDT
 	logger.Debug(ctx, "Running task command")
 
 	go func() {
-		err := cmd.Execute(ctx, task.Command(), contracts.ExecutorOptions{
+		defer manager.watchdog.Release(stuckTaskName(task))
+
+		err := cmd.Execute(ctx, command, contracts.ExecutorOptions{
 			WorkDir: manager.config.WorkDir(),
 		})
 
@@ -252,6 +399,8 @@ func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain
 	logger.Debug(ctx, "Running task command")
 
 	go func() {
+		defer manager.watchdog.Release(stuckTaskName(task))
+
 		err := cmdFunc.Execute(ctx, task.Server())
 		if err != nil {
 			logger.Warn(ctx, err)
@@ -267,6 +416,74 @@ func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain
 	return nil
 }
 
+// checkTimeout kills the overdue task. If the task isn't stopped during the watchdog.KillGracePeriod,
+// it is failed without waiting and its worker is reported as stuck.
+func (manager *TaskManager) checkTimeout(ctx context.Context, task *domain.GDTask) {
+	timeout := manager.taskTimeout(task)
+	if timeout <= 0 {
+		return
+	}
+
+	startedAt, ok := manager.startedAt.Load(task.ID())
+	if !ok {
+		return
+	}
+
+	overdue := time.Since(startedAt.(time.Time)) - timeout
+	if overdue < 0 {
+		return
+	}
+
+	if _, killed := manager.timedOut.LoadOrStore(task.ID(), struct{}{}); !killed {
+		logger.Logger(ctx).WithField("timeout", timeout.String()).Warn("Task timed out, killing it")
+		go manager.appendTaskOutput(ctx, task, []byte(watchdog.NewTimeoutError(timeout).Error()))
+
+		if cancel, ok := manager.cancels.Load(task.ID()); ok {
+			cancel.(context.CancelFunc)()
+		}
+
+		return
+	}
+
+	if overdue < watchdog.KillGracePeriod {
+		return
+	}
+
+	c, ok := manager.commandsInProgress.Load(*task)
+	if !ok {
+		return
+	}
+
+	cmd := c.(contracts.CommandResultReader)
+	if cmd.IsComplete() {
+		return
+	}
+
+	logger.Logger(ctx).Error("Task isn't stopped after the timeout, the worker is stuck")
+	go manager.appendTaskOutput(ctx, task, []byte("Task isn't stopped after the timeout, the worker is stuck"))
+
+	manager.watchdog.Stuck(stuckTaskName(task))
+	if cmd.IsComplete() {
+		// The worker is released right before it was marked as stuck
+		manager.watchdog.Release(stuckTaskName(task))
+	}
+
+	manager.commandsInProgress.Delete(*task)
+	manager.failTask(ctx, task)
+}
+
+func (manager *TaskManager) taskTimeout(task *domain.GDTask) time.Duration {
+	if task.Timeout() > 0 {
+		return task.Timeout()
+	}
+
+	return manager.config.TaskTimeout(string(task.Task()))
+}
+
+func stuckTaskName(task *domain.GDTask) string {
+	return "gdtask #" + strconv.Itoa(task.ID()) + " (" + string(task.Task()) + ")"
+}
+
 func (manager *TaskManager) proceedTask(ctx context.Context, task *domain.GDTask) error {
 	c, ok := manager.commandsInProgress.Load(*task)
 	if !ok {
@@ -294,7 +511,12 @@ func (manager *TaskManager) proceedTask(ctx context.Context, task *domain.GDTask
 }
 
 func (manager *TaskManager) failTask(ctx context.Context, task *domain.GDTask) {
-	err := task.SetStatus(domain.GDTaskStatusError)
+	status := domain.GDTaskStatusError
+	if manager.isCancellationRequested(task) {
+		status = domain.GDTaskStatusCanceled
+	}
+
+	err := task.SetStatus(status)
 	if err != nil {
 		logger.Error(ctx, err)
 	}
@@ -305,12 +527,22 @@ func (manager *TaskManager) appendTaskOutput(ctx context.Context, task *domain.G
 		return
 	}
 
+	logger.TaskOutput(ctx, task.ID(), output)
+
 	err := manager.repository.AppendOutput(ctx, task, output)
 	if err != nil {
 		logger.Logger(ctx).Error(err)
 	}
 }
 
+func (manager *TaskManager) updatePeriod() time.Duration {
+	if manager.pushConnected.Load() {
+		return manager.config.Push.FallbackPeriod
+	}
+
+	return manager.config.TaskManager.UpdatePeriod
+}
+
 func (manager *TaskManager) updateTasksIfNeeded(ctx context.Context) error {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
@@ -319,6 +551,17 @@ func (manager *TaskManager) updateTasksIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
+	return manager.loadWaitingTasks(ctx)
+}
+
+func (manager *TaskManager) updateTasks(ctx context.Context) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	return manager.loadWaitingTasks(ctx)
+}
+
+func (manager *TaskManager) loadWaitingTasks(ctx context.Context) error {
 	tasks, err := manager.repository.FindByStatus(ctx, domain.GDTaskStatusWaiting)
 	if err != nil {
 		return err
@@ -328,11 +571,52 @@ func (manager *TaskManager) updateTasksIfNeeded(ctx context.Context) error {
 		manager.queue.Insert(tasks)
 	}
 
+	manager.syncCanceledTasks(ctx)
+
 	manager.lastUpdated = time.Now()
 
 	return nil
 }
 
+// syncCanceledTasks cancels the queued tasks that were canceled in the panel.
+// The canceled tasks are requested once for the whole queue. While the event stream is connected
+// the cancellations are delivered by the push events.
+func (manager *TaskManager) syncCanceledTasks(ctx context.Context) {
+	if manager.pushConnected.Load() {
+		return
+	}
+
+	pending := make(map[int]struct{})
+	for _, queued := range manager.queue.Tasks() {
+		if queued.IsComplete() || manager.isCancellationRequested(queued) {
+			continue
+		}
+
+		pending[queued.ID()] = struct{}{}
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	canceled, err := manager.repository.FindByStatus(ctx, domain.GDTaskStatusCanceled)
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Warn("failed to find canceled tasks")
+		return
+	}
+
+	for _, task := range canceled {
+		if _, ok := pending[task.ID()]; !ok {
+			continue
+		}
+
+		err = manager.cancelQueuedTask(ctx, task.ID())
+		if err != nil {
+			logger.Logger(ctx).Error(err)
+		}
+	}
+}
+
 type taskQueue struct {
 	tasks []*domain.GDTask
 	mutex sync.RWMutex
@@ -400,10 +684,10 @@ func (q *taskQueue) Remove(task *domain.GDTask) {
 		return
 	}
 
+	// The order is kept, it is the order of the tasks of the same priority
 	for i := range q.tasks {
 		if q.tasks[i].ID() == task.ID() {
-			q.tasks[i] = q.tasks[len(q.tasks)-1]
-			q.tasks = q.tasks[:len(q.tasks)-1]
+			q.tasks = append(q.tasks[:i], q.tasks[i+1:]...)
 			break
 		}
 	}
@@ -426,6 +710,16 @@ func (q *taskQueue) findByID(id int) *domain.GDTask {
 	return nil
 }
 
+func (q *taskQueue) Tasks() []*domain.GDTask {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	tasks := make([]*domain.GDTask, len(q.tasks))
+	copy(tasks, q.tasks)
+
+	return tasks
+}
+
 func (q *taskQueue) WorkingTasks() ([]int, []*domain.GDTask) {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
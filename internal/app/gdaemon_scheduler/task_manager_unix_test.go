@@ -0,0 +1,109 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package gdaemonscheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/watchdog"
+	"github.com/gameap/daemon/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskManager_CancelTask(t *testing.T) {
+	repository := mocks.NewGDTaskRepository()
+	working := domain.NewGDTask(1, 0, nil, domain.GDTaskCommandExecute, "sh -c 'sleep 30 & sleep 30'", domain.GDTaskStatusWaiting)
+	waiting := domain.NewGDTask(2, 1, nil, domain.GDTaskCommandExecute, "sleep 30", domain.GDTaskStatusWaiting)
+	repository.Set([]*domain.GDTask{working, waiting})
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.TaskManager.UpdatePeriod = time.Minute
+
+	manager := NewTaskManager(repository, nil, nil, components.NewCleanExecutor(), cfg, watchdog.NewWatchdog())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = manager.Run(ctx)
+	}()
+
+	require.Eventually(t, working.IsWorking, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, manager.CancelTask(ctx, waiting.ID()))
+	require.NoError(t, manager.CancelTask(ctx, working.ID()))
+
+	require.Eventually(t, func() bool {
+		return working.IsComplete() && waiting.IsComplete()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, domain.GDTaskStatusCanceled, working.Status())
+	assert.Equal(t, domain.GDTaskStatusCanceled, waiting.Status())
+
+	require.Eventually(t, func() bool {
+		return manager.queue.Len() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, manager.CancelTask(ctx, working.ID()), ErrTaskNotFound)
+}
+
+func TestTaskManager_TaskCanceledInPanel(t *testing.T) {
+	tests := []struct {
+		name          string
+		pushConnected bool
+		wantCanceled  bool
+	}{
+		{"polling", false, true},
+		{"push connected", true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := mocks.NewGDTaskRepository()
+			task := domain.NewGDTask(1, 0, nil, domain.GDTaskCommandExecute, "sleep 30", domain.GDTaskStatusWaiting)
+			other := domain.NewGDTask(2, 0, nil, domain.GDTaskCommandExecute, "sleep 30", domain.GDTaskStatusWaiting)
+			repository.Set([]*domain.GDTask{task, other})
+			manager := NewTaskManager(
+				repository, nil, nil, components.NewCleanExecutor(), &config.Config{}, watchdog.NewWatchdog(),
+			)
+			manager.SetPushConnected(test.pushConnected)
+			require.NoError(t, manager.updateTasks(context.Background()))
+
+			repository.Set([]*domain.GDTask{
+				domain.NewGDTask(1, 0, nil, domain.GDTaskCommandExecute, "sleep 30", domain.GDTaskStatusCanceled),
+			})
+			require.NoError(t, manager.updateTasks(context.Background()))
+
+			assert.Equal(t, test.wantCanceled, manager.isCancellationRequested(task))
+			assert.False(t, manager.isCancellationRequested(other))
+		})
+	}
+}
+
+func TestTaskManager_TaskTimedOut(t *testing.T) {
+	repository := mocks.NewGDTaskRepository()
+	task := domain.NewGDTask(1, 0, nil, domain.GDTaskCommandExecute, "sh -c 'sleep 30 & sleep 30'", domain.GDTaskStatusWaiting)
+	task.SetTimeout(200 * time.Millisecond)
+	repository.Set([]*domain.GDTask{task})
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.TaskManager.UpdatePeriod = time.Minute
+	cfg.TaskManager.DefaultTimeout = time.Hour
+
+	manager := NewTaskManager(repository, nil, nil, components.NewCleanExecutor(), cfg, watchdog.NewWatchdog())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = manager.Run(ctx)
+	}()
+
+	require.Eventually(t, task.IsComplete, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, domain.GDTaskStatusError, task.Status())
+}
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "gameap_daemon"
+
+// Registry contains all daemon metrics. It is exposed by the metrics server.
+var Registry = prometheus.NewRegistry()
+
+var (
+	gdTaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gdtask_duration_seconds",
+		Help:      "Duration of completed GameAP Daemon tasks.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200},
+	}, []string{"task", "status"})
+
+	serverTaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "server_task_duration_seconds",
+		Help:      "Duration of executed game server tasks.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+	}, []string{"command", "result"})
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Number of API request attempts. Code is 0 if there is no response.",
+	}, []string{"endpoint", "code"})
+
+	apiRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_retries_total",
+		Help:      "Number of repeated API requests.",
+	}, []string{"endpoint"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of API request attempts.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	apiCircuitOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "api_circuit_open",
+		Help:      "Whether API requests are paused because the API is unavailable.",
+	})
+
+	binnConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "binn_connections",
+		Help:      "Number of open connections to the daemon server.",
+	}, []string{"mode"})
+
+	binnConnectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "binn_connections_total",
+		Help:      "Number of accepted connections to the daemon server.",
+	}, []string{"mode"})
+
+	serverUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "server_up",
+		Help:      "Whether the game server process is active.",
+	}, []string{"server_id"})
+
+	serverCrashes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "server_crashes_total",
+		Help:      "Number of times the game server process stopped without a stop command.",
+	}, []string{"server_id"})
+
+	resources = newServerResourcesCollector()
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		gdTaskDuration,
+		serverTaskDuration,
+		apiRequests,
+		apiRetries,
+		apiRequestDuration,
+		apiCircuitOpen,
+		binnConnections,
+		binnConnectionsTotal,
+		serverUp,
+		serverCrashes,
+		resources,
+	)
+}
+
+// RegisterGDTaskQueue exposes the number of waiting and working GameAP Daemon tasks.
+func RegisterGDTaskQueue(reader domain.GDTaskStatsReader) {
+	register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gdtasks_waiting",
+		Help:      "Number of GameAP Daemon tasks waiting in the queue.",
+	}, func() float64 {
+		return float64(reader.Stats().WaitingCount)
+	}))
+
+	register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gdtasks_working",
+		Help:      "Number of running GameAP Daemon tasks.",
+	}, func() float64 {
+		return float64(reader.Stats().WorkingCount)
+	}))
+}
+
+// RegisterServerTaskQueue exposes the number of scheduled game server tasks.
+func RegisterServerTaskQueue(queueLen func() int) {
+	register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "server_tasks_scheduled",
+		Help:      "Number of game server tasks in the scheduler queue.",
+	}, func() float64 {
+		return float64(queueLen())
+	}))
+}
+
+func ObserveGDTask(task domain.GDTaskCommand, status domain.GDTaskStatus, duration time.Duration) {
+	gdTaskDuration.WithLabelValues(string(task), string(status)).Observe(duration.Seconds())
+}
+
+func ObserveServerTask(command domain.ServerTaskCommand, success bool, duration time.Duration) {
+	serverTaskDuration.WithLabelValues(string(command), resultLabel(success)).Observe(duration.Seconds())
+}
+
+func ObserveAPIRequest(endpoint string, code int, retry bool, duration time.Duration) {
+	apiRequests.WithLabelValues(endpoint, strconv.Itoa(code)).Inc()
+	apiRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+
+	if retry {
+		apiRetries.WithLabelValues(endpoint).Inc()
+	}
+}
+
+func SetAPICircuitOpen(open bool) {
+	if open {
+		apiCircuitOpen.Set(1)
+	} else {
+		apiCircuitOpen.Set(0)
+	}
+}
+
+// BinnConnectionOpened returns the function to call when the connection is closed.
+func BinnConnectionOpened(mode string) func() {
+	binnConnectionsTotal.WithLabelValues(mode).Inc()
+	binnConnections.WithLabelValues(mode).Inc()
+
+	return func() {
+		binnConnections.WithLabelValues(mode).Dec()
+	}
+}
+
+// ObserveServerStatus records the game server status. The workDir is used to find
+// the game server processes for resource metrics.
+func ObserveServerStatus(id int, workDir string, active bool, crashed bool) {
+	label := strconv.Itoa(id)
+
+	if active {
+		serverUp.WithLabelValues(label).Set(1)
+	} else {
+		serverUp.WithLabelValues(label).Set(0)
+	}
+
+	if crashed {
+		serverCrashes.WithLabelValues(label).Inc()
+	}
+
+	resources.SetServer(id, workDir)
+}
+
+func register(c prometheus.Collector) {
+	err := Registry.Register(c)
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		Registry.Unregister(alreadyRegistered.ExistingCollector)
+		err = Registry.Register(c)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func resultLabel(success bool) string {
+	if success {
+		return "success"
+	}
+
+	return "error"
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type taskStatsReader struct {
+	stats domain.GDTaskStats
+}
+
+func (r taskStatsReader) Stats() domain.GDTaskStats {
+	return r.stats
+}
+
+func TestRegisterGDTaskQueue_RegisteredTwice_LastReaderUsed(t *testing.T) {
+	RegisterGDTaskQueue(taskStatsReader{stats: domain.GDTaskStats{WaitingCount: 1}})
+	RegisterGDTaskQueue(taskStatsReader{stats: domain.GDTaskStats{WaitingCount: 5, WorkingCount: 2}})
+
+	err := testutil.GatherAndCompare(Registry, strings.NewReader(`
+# HELP gameap_daemon_gdtasks_waiting Number of GameAP Daemon tasks waiting in the queue.
+# TYPE gameap_daemon_gdtasks_waiting gauge
+gameap_daemon_gdtasks_waiting 5
+# HELP gameap_daemon_gdtasks_working Number of running GameAP Daemon tasks.
+# TYPE gameap_daemon_gdtasks_working gauge
+gameap_daemon_gdtasks_working 2
+`), "gameap_daemon_gdtasks_waiting", "gameap_daemon_gdtasks_working")
+
+	require.NoError(t, err)
+}
+
+func TestBinnConnectionOpened(t *testing.T) {
+	closeFirst := BinnConnectionOpened("files")
+	closeSecond := BinnConnectionOpened("files")
+	closeFirst()
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(binnConnections.WithLabelValues("files")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(binnConnectionsTotal.WithLabelValues("files")))
+
+	closeSecond()
+	assert.Equal(t, float64(0), testutil.ToFloat64(binnConnections.WithLabelValues("files")))
+}
+
+func TestObserveServerStatus(t *testing.T) {
+	ObserveServerStatus(1001, "/srv/gameap/servers/1001", true, false)
+	ObserveServerStatus(1001, "/srv/gameap/servers/1001", false, true)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(serverUp.WithLabelValues("1001")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(serverCrashes.WithLabelValues("1001")))
+}
+
+func TestObserveAPIRequest(t *testing.T) {
+	ObserveAPIRequest("GET /gdaemon_api/servers/{id}", 502, true, 100*time.Millisecond)
+	ObserveAPIRequest("GET /gdaemon_api/servers/{id}", 200, false, 50*time.Millisecond)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiRequests.WithLabelValues("GET /gdaemon_api/servers/{id}", "502")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiRequests.WithLabelValues("GET /gdaemon_api/servers/{id}", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiRetries.WithLabelValues("GET /gdaemon_api/servers/{id}")))
+}
+
+func TestServerResourcesCollector(t *testing.T) {
+	collector := newServerResourcesCollector()
+	collector.readProcesses = func() []processStats {
+		return []processStats{
+			{cwd: "/srv/gameap/servers/1", rssBytes: 100, cpuSeconds: 1.5},
+			{cwd: "/srv/gameap/servers/1/bin", rssBytes: 50, cpuSeconds: 0.5},
+			{cwd: "/srv/gameap/servers/10", rssBytes: 1000, cpuSeconds: 10},
+		}
+	}
+	collector.SetServer(1, "/srv/gameap/servers/1/")
+	collector.SetServer(2, "/srv/gameap/servers/2")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP gameap_daemon_server_cpu_seconds_total User and system CPU time spent by the running game server processes.
+# TYPE gameap_daemon_server_cpu_seconds_total counter
+gameap_daemon_server_cpu_seconds_total{server_id="1"} 2
+gameap_daemon_server_cpu_seconds_total{server_id="2"} 0
+# HELP gameap_daemon_server_memory_bytes Resident memory size of the game server processes.
+# TYPE gameap_daemon_server_memory_bytes gauge
+gameap_daemon_server_memory_bytes{server_id="1"} 150
+gameap_daemon_server_memory_bytes{server_id="2"} 0
+# HELP gameap_daemon_server_processes Number of the game server processes.
+# TYPE gameap_daemon_server_processes gauge
+gameap_daemon_server_processes{server_id="1"} 2
+gameap_daemon_server_processes{server_id="2"} 0
+`))
+
+	require.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server exposes the metrics in Prometheus format on /metrics.
+type Server struct {
+	address string
+}
+
+func NewServer(address string) *Server {
+	return &Server{
+		address: address,
+	}
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:              s.address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Failed to shutdown metrics server")
+		}
+	}()
+
+	logger.Infof(ctx, "Metrics server listening at: %s", s.address)
+
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.WithMessage(err, "[metrics.Server] failed to listen")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processStats contains resource usage of the single OS process.
+type processStats struct {
+	cwd        string
+	rssBytes   uint64
+	cpuSeconds float64
+}
+
+// serverResourcesCollector collects resource usage of game server processes.
+// Processes are matched to the game servers by their working directory.
+type serverResourcesCollector struct {
+	memory    *prometheus.Desc
+	cpu       *prometheus.Desc
+	processes *prometheus.Desc
+
+	mu      sync.RWMutex
+	servers map[int]string // server ID => work dir
+
+	readProcesses func() []processStats
+}
+
+func newServerResourcesCollector() *serverResourcesCollector {
+	return &serverResourcesCollector{
+		memory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "server", "memory_bytes"),
+			"Resident memory size of the game server processes.",
+			[]string{"server_id"}, nil,
+		),
+		cpu: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "server", "cpu_seconds_total"),
+			"User and system CPU time spent by the running game server processes.",
+			[]string{"server_id"}, nil,
+		),
+		processes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "server", "processes"),
+			"Number of the game server processes.",
+			[]string{"server_id"}, nil,
+		),
+		servers:       map[int]string{},
+		readProcesses: readProcesses,
+	}
+}
+
+func (c *serverResourcesCollector) SetServer(id int, workDir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.servers[id] = filepath.Clean(workDir)
+}
+
+func (c *serverResourcesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.memory
+	ch <- c.cpu
+	ch <- c.processes
+}
+
+func (c *serverResourcesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	servers := make(map[int]string, len(c.servers))
+	for id, dir := range c.servers {
+		servers[id] = dir
+	}
+	c.mu.RUnlock()
+
+	if len(servers) == 0 {
+		return
+	}
+
+	processes := c.readProcesses()
+
+	for id, dir := range servers {
+		var rss uint64
+		var cpu float64
+		var count int
+
+		for _, p := range processes {
+			if p.cwd != dir && !strings.HasPrefix(p.cwd, dir+string(filepath.Separator)) {
+				continue
+			}
+
+			rss += p.rssBytes
+			cpu += p.cpuSeconds
+			count++
+		}
+
+		label := strconv.Itoa(id)
+		ch <- prometheus.MustNewConstMetric(c.memory, prometheus.GaugeValue, float64(rss), label)
+		ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, cpu, label)
+		ch <- prometheus.MustNewConstMetric(c.processes, prometheus.GaugeValue, float64(count), label)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// clockTicks is the USER_HZ value, it is 100 on all supported architectures.
+const clockTicks = 100
+
+func readProcesses() []processStats {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	result := make([]processStats, 0, len(entries))
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+
+		dir := filepath.Join("/proc", entry.Name())
+
+		cwd, err := os.Readlink(filepath.Join(dir, "cwd"))
+		if err != nil {
+			continue
+		}
+
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+
+		p, ok := parseProcStat(string(stat), pageSize)
+		if !ok {
+			continue
+		}
+
+		p.cwd = cwd
+		result = append(result, p)
+	}
+
+	return result
+}
+
+// parseProcStat parses /proc/[pid]/stat content, see proc(5).
+func parseProcStat(stat string, pageSize uint64) (processStats, bool) {
+	// The process name may contain spaces and parentheses
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 || i+2 > len(stat) {
+		return processStats{}, false
+	}
+
+	// Fields starting from the 3rd one (state)
+	fields := strings.Fields(stat[i+2:])
+	if len(fields) < 22 {
+		return processStats{}, false
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return processStats{}, false
+	}
+
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return processStats{}, false
+	}
+
+	rss, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil || rss < 0 {
+		return processStats{}, false
+	}
+
+	return processStats{
+		rssBytes:   uint64(rss) * pageSize,
+		cpuSeconds: float64(utime+stime) / clockTicks,
+	}, true
+}
@@ -0,0 +1,44 @@
+//go:build linux
+// +build linux
+
+package metrics
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcStat(t *testing.T) {
+	stat := "4242 (srcds (linux)) S 1 4242 4242 0 -1 4194560 5306 0 0 0 250 150 0 0 20 0 " +
+		"12 0 1234567 987654321 2048 18446744073709551615 1 1 0 0 0 0 0 4096 0 0 0 0 17 3 0 0 0 0 0"
+
+	p, ok := parseProcStat(stat, 4096)
+
+	require.True(t, ok)
+	assert.Equal(t, uint64(2048*4096), p.rssBytes)
+	assert.InDelta(t, 4.0, p.cpuSeconds, 0.001)
+}
+
+func TestParseProcStat_Invalid(t *testing.T) {
+	_, ok := parseProcStat("4242 (srcds) S 1", 4096)
+
+	assert.False(t, ok)
+}
+
+func TestReadProcesses_CurrentProcessFound(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	processes := readProcesses()
+
+	found := false
+	for _, p := range processes {
+		if p.cwd == cwd && p.rssBytes > 0 {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package metrics
+
+// readProcesses isn't supported, resource metrics are reported as zero.
+func readProcesses() []processStats {
+	return nil
+}
@@ -0,0 +1,152 @@
+package ports
+
+import (
+	"context"
+	"net"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidRange = errors.New("invalid ports range")
+
+// Allocator reports the free ports on the node IPs, so the panel can assign them to new servers.
+type Allocator struct {
+	cfg        *config.Config
+	serverRepo domain.ServerRepository
+	nodeIPs    func() ([]string, error)
+}
+
+func NewAllocator(cfg *config.Config, serverRepo domain.ServerRepository) *Allocator {
+	return &Allocator{
+		cfg:        cfg,
+		serverRepo: serverRepo,
+		nodeIPs:    NodeIPs,
+	}
+}
+
+// FreePorts returns the ranges of ports free for both TCP and UDP.
+// The ports of the node servers are not free even if the servers are stopped.
+// All node IPs are checked if the ip is empty, the ports.range_start and ports.range_end
+// from the config are used if from and to are zero.
+func (a *Allocator) FreePorts(ctx context.Context, ip string, from, to int) ([]domain.FreePorts, error) {
+	if from == 0 && to == 0 {
+		from, to = a.cfg.Ports.RangeStart, a.cfg.Ports.RangeEnd
+	}
+	if from < 1 || to > 65535 || from > to {
+		return nil, errors.WithMessagef(ErrInvalidRange, "[ports.Allocator] %d-%d", from, to)
+	}
+
+	ips := []string{ip}
+	if ip == "" {
+		var err error
+		ips, err = a.nodeIPs()
+		if err != nil {
+			return nil, errors.WithMessage(err, "[ports.Allocator] failed to get node IPs")
+		}
+	}
+
+	reserved, err := a.reservedPorts(ctx, ips)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.FreePorts, 0, len(ips))
+
+	for _, nodeIP := range ips {
+		free := domain.FreePorts{IP: nodeIP, Ranges: []domain.PortRange{}}
+
+		for port := from; port <= to; port++ {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			if reserved[nodeIP][port] {
+				continue
+			}
+
+			err = Check(nodeIP, port)
+			if errors.Is(err, ErrPortInUse) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			free.Ranges = appendPort(free.Ranges, port)
+		}
+
+		result = append(result, free)
+	}
+
+	return result, nil
+}
+
+// reservedPorts returns the ports of the node servers by IP.
+// Ports of the servers with IPs not assigned to the node (e.g. NAT) are reserved on all IPs.
+func (a *Allocator) reservedPorts(ctx context.Context, ips []string) (map[string]map[int]bool, error) {
+	reserved := make(map[string]map[int]bool, len(ips))
+	for _, ip := range ips {
+		reserved[ip] = map[int]bool{}
+	}
+
+	ids, err := a.serverRepo.IDs(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[ports.Allocator] failed to get servers")
+	}
+
+	for _, id := range ids {
+		server, err := a.serverRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "[ports.Allocator] failed to get server")
+		}
+		if server == nil {
+			continue
+		}
+
+		serverIPs := ips
+		if _, ok := reserved[server.IP()]; ok {
+			serverIPs = []string{server.IP()}
+		}
+
+		for _, ip := range serverIPs {
+			for _, port := range serverPorts(server) {
+				reserved[ip][port] = true
+			}
+		}
+	}
+
+	return reserved, nil
+}
+
+func appendPort(ranges []domain.PortRange, port int) []domain.PortRange {
+	if len(ranges) > 0 && ranges[len(ranges)-1].To == port-1 {
+		ranges[len(ranges)-1].To = port
+
+		return ranges
+	}
+
+	return append(ranges, domain.PortRange{From: port, To: port})
+}
+
+// NodeIPs returns the global unicast IPs of the node network interfaces.
+func NodeIPs() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(addrs))
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+
+		ips = append(ips, ipNet.IP.String())
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package ports
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+func isAddrNotAvailable(err error) bool {
+	return errors.Is(err, syscall.EADDRNOTAVAIL)
+}
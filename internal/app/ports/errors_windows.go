@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package ports
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, windows.WSAEADDRINUSE)
+}
+
+func isAddrNotAvailable(err error) bool {
+	return errors.Is(err, windows.WSAEADDRNOTAVAIL)
+}
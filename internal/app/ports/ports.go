@@ -0,0 +1,111 @@
+package ports
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+var (
+	ErrPortInUse          = errors.New("port is already in use by another process")
+	errAddressUnavailable = errors.New("address is not available on the node")
+)
+
+// CheckServer checks that the server ports are not taken by other processes.
+// The ports are probed on the wildcard address if the server IP isn't assigned to the node (e.g. NAT).
+func CheckServer(server *domain.Server) error {
+	for _, port := range serverPorts(server) {
+		err := Check(server.IP(), port)
+		if errors.Is(err, errAddressUnavailable) {
+			err = Check("", port)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check checks that the port can be bound for both TCP and UDP.
+func Check(ip string, port int) error {
+	for _, protocol := range []string{ProtocolTCP, ProtocolUDP} {
+		err := probe(protocol, ip, port)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func probe(protocol, ip string, port int) error {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	var err error
+
+	switch protocol {
+	case ProtocolTCP:
+		var listener net.Listener
+		listener, err = net.Listen(protocol, address)
+		if err == nil {
+			return listener.Close()
+		}
+	case ProtocolUDP:
+		var conn net.PacketConn
+		conn, err = net.ListenPacket(protocol, address)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+
+	switch {
+	case isAddrInUse(err):
+		return errors.WithMessagef(ErrPortInUse, "[ports] %d/%s on %s", port, protocol, displayIP(ip))
+	case isAddrNotAvailable(err):
+		return errors.WithMessagef(errAddressUnavailable, "[ports] %s", displayIP(ip))
+	}
+
+	// Privileged ports and other errors don't mean the port is taken
+	return nil
+}
+
+// serverPorts returns the distinct non-zero ports of the server.
+func serverPorts(server *domain.Server) []int {
+	ports := make([]int, 0, 3)
+
+	for _, port := range []int{server.ConnectPort(), server.QueryPort(), server.RCONPort()} {
+		if port <= 0 || containsPort(ports, port) {
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func displayIP(ip string) string {
+	if ip == "" {
+		return "all addresses"
+	}
+
+	return ip
+}
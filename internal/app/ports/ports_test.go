@@ -0,0 +1,125 @@
+package ports
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/gameap/daemon/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckServer_TCPPortInUse(t *testing.T) {
+	port := givenTCPListener(t)
+
+	err := CheckServer(givenServer("127.0.0.1", port))
+
+	require.ErrorIs(t, err, ErrPortInUse)
+	assert.Contains(t, err.Error(), "/tcp on 127.0.0.1")
+}
+
+func TestCheckServer_UDPPortInUse(t *testing.T) {
+	port := givenUDPConn(t)
+
+	err := CheckServer(givenServer("127.0.0.1", port))
+
+	require.ErrorIs(t, err, ErrPortInUse)
+	assert.Contains(t, err.Error(), "/udp on 127.0.0.1")
+}
+
+func TestCheckServer_PortsFree(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	err = CheckServer(givenServer("127.0.0.1", port))
+
+	require.NoError(t, err)
+}
+
+func TestCheckServer_IPNotAssignedToNode_WildcardAddressChecked(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// TEST-NET-1 address isn't assigned to the node
+	err = CheckServer(givenServer("192.0.2.1", port))
+
+	require.ErrorIs(t, err, ErrPortInUse)
+	assert.Contains(t, err.Error(), "on all addresses")
+}
+
+func TestAllocator_FreePorts(t *testing.T) {
+	port := givenTCPListener(t)
+	serverRepo := mocks.NewServerRepository()
+	serverRepo.Set([]*domain.Server{givenServer("127.0.0.1", port+2)})
+	allocator := NewAllocator(&config.Config{}, serverRepo)
+	allocator.nodeIPs = func() ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	}
+
+	result, err := allocator.FreePorts(context.Background(), "", port, port+3)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FreePorts{
+		{
+			IP: "127.0.0.1",
+			Ranges: []domain.PortRange{
+				{From: port + 1, To: port + 1},
+				{From: port + 3, To: port + 3},
+			},
+		},
+	}, result)
+}
+
+func TestAllocator_FreePorts_InvalidRange(t *testing.T) {
+	allocator := NewAllocator(&config.Config{}, mocks.NewServerRepository())
+
+	_, err := allocator.FreePorts(context.Background(), "127.0.0.1", 27999, 27015)
+
+	require.ErrorIs(t, err, ErrInvalidRange)
+}
+
+func TestAppendPort(t *testing.T) {
+	var ranges []domain.PortRange
+
+	for _, port := range []int{27015, 27016, 27017, 27020, 27022, 27023} {
+		ranges = appendPort(ranges, port)
+	}
+
+	assert.Equal(t, []domain.PortRange{
+		{From: 27015, To: 27017},
+		{From: 27020, To: 27020},
+		{From: 27022, To: 27023},
+	}, ranges)
+}
+
+func givenTCPListener(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func givenUDPConn(t *testing.T) int {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func givenServer(ip string, port int) *domain.Server {
+	return domaintest.NewServer(domaintest.WithAddress(ip, port, port, 0))
+}
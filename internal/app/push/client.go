@@ -0,0 +1,156 @@
+package push
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var errStreamClosed = errors.New("event stream closed by the API")
+
+type Handler func(ctx context.Context, event Event)
+
+type StateHandler func(connected bool)
+
+// Client listens to the panel event stream and notifies subscribers about changes.
+// Subscribers should keep polling the API as a fallback while the client is disconnected.
+type Client struct {
+	streamer contracts.APIStreamer
+	cfg      *config.Config
+
+	mu            sync.RWMutex
+	handlers      map[string][]Handler
+	stateHandlers []StateHandler
+	connected     atomic.Bool
+}
+
+// NewClient creates the push client. The client is disabled if the streamer is nil.
+func NewClient(streamer contracts.APIStreamer, cfg *config.Config) *Client {
+	return &Client{
+		streamer: streamer,
+		cfg:      cfg,
+		handlers: map[string][]Handler{},
+	}
+}
+
+// Subscribe registers handler for the named event.
+func (c *Client) Subscribe(name string, handler Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers[name] = append(c.handlers[name], handler)
+}
+
+// OnStateChanged registers handler called after the client connects or disconnects.
+// The handler is called immediately if the client is already connected.
+func (c *Client) OnStateChanged(handler StateHandler) {
+	c.mu.Lock()
+	c.stateHandlers = append(c.stateHandlers, handler)
+	c.mu.Unlock()
+
+	if c.Connected() {
+		handler(true)
+	}
+}
+
+func (c *Client) Connected() bool {
+	return c.connected.Load()
+}
+
+func (c *Client) Enabled() bool {
+	return c.streamer != nil && c.cfg.Push.Enabled
+}
+
+// Run listens to the event stream and reconnects until the context is done.
+func (c *Client) Run(ctx context.Context) error {
+	if !c.Enabled() {
+		<-ctx.Done()
+		return nil
+	}
+
+	for {
+		err := c.listen(ctx)
+		c.setConnected(false)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		logger.WithError(ctx, err).Warn("Push channel is disconnected, falling back to polling")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.cfg.Push.ReconnectPeriod):
+		}
+	}
+}
+
+func (c *Client) listen(ctx context.Context) error {
+	body, err := c.streamer.Stream(ctx, domain.APIRequest{
+		Method: http.MethodGet,
+		URL:    c.cfg.Push.URL,
+		Header: http.Header{
+			"Accept": []string{"text/event-stream"},
+		},
+	})
+	if err != nil {
+		return errors.WithMessage(err, "[push.Client] failed to connect to event stream")
+	}
+	defer body.Close()
+
+	logger.Info(ctx, "Push channel is connected")
+	c.setConnected(true)
+
+	err = readEvents(body, func(event Event) {
+		c.dispatch(ctx, event)
+	})
+	if err != nil {
+		return errors.WithMessage(err, "[push.Client] failed to read event stream")
+	}
+
+	return errStreamClosed
+}
+
+func (c *Client) dispatch(ctx context.Context, event Event) {
+	c.mu.RLock()
+	handlers := c.handlers[event.Name]
+	c.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		logger.WithField(ctx, "event", event.Name).Debug("Unsupported push event")
+		return
+	}
+
+	logger.WithFields(ctx, log.Fields{
+		"event": event.Name,
+		"data":  string(event.Data),
+	}).Debug("Push event received")
+
+	for _, h := range handlers {
+		h(ctx, event)
+	}
+}
+
+func (c *Client) setConnected(connected bool) {
+	if !c.connected.CompareAndSwap(!connected, connected) {
+		return
+	}
+
+	c.mu.RLock()
+	handlers := c.stateHandlers
+	c.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(connected)
+	}
+}
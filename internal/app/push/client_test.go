@@ -0,0 +1,143 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// httpStreamer is a stand-in for the API client making long-lived requests to the test server.
+type httpStreamer struct {
+	baseURL string
+}
+
+func (s *httpStreamer) Stream(ctx context.Context, request domain.APIRequest) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+request.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, domain.NewErrInvalidResponseFromAPI(resp.StatusCode, nil)
+	}
+
+	return resp.Body, nil
+}
+
+// givenEventServer starts a stand-in for the panel event stream.
+// Each connection receives the given events, then the stream is closed.
+func givenEventServer(t *testing.T, events ...string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	connections := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gdaemon_api/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		connections.Add(1)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			_, _ = fmt.Fprint(w, e)
+		}
+		w.(http.Flusher).Flush()
+	}))
+	t.Cleanup(server.Close)
+
+	return server, connections
+}
+
+func givenConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Push.Enabled = true
+	cfg.Push.URL = "/gdaemon_api/events"
+	cfg.Push.ReconnectPeriod = 10 * time.Millisecond
+
+	return cfg
+}
+
+func TestClient_Run_EventsDispatchedToSubscribers(t *testing.T) {
+	server, _ := givenEventServer(t,
+		"event: gdtasks\ndata: {\"id\": 7}\n\n",
+		"event: unknown\n\n",
+	)
+	client := NewClient(&httpStreamer{baseURL: server.URL}, givenConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan Event, 10)
+	client.Subscribe(EventGDTasks, func(_ context.Context, event Event) {
+		received <- event
+	})
+
+	go func() {
+		_ = client.Run(ctx)
+	}()
+
+	select {
+	case event := <-received:
+		id, ok := event.ID()
+		assert.True(t, ok)
+		assert.Equal(t, 7, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event is not received")
+	}
+}
+
+func TestClient_Run_ReconnectsAndNotifiesStateChanges(t *testing.T) {
+	server, connections := givenEventServer(t)
+	client := NewClient(&httpStreamer{baseURL: server.URL}, givenConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	states := make(chan bool, 100)
+	client.OnStateChanged(func(connected bool) {
+		states <- connected
+	})
+
+	go func() {
+		_ = client.Run(ctx)
+	}()
+
+	expected := []bool{true, false, true, false}
+	for i := range expected {
+		select {
+		case state := <-states:
+			assert.Equal(t, expected[i], state)
+		case <-time.After(5 * time.Second):
+			t.Fatal("state change is not received")
+		}
+	}
+	cancel()
+
+	assert.GreaterOrEqual(t, connections.Load(), int32(2))
+}
+
+func TestClient_Run_Disabled(t *testing.T) {
+	cfg := givenConfig()
+	cfg.Push.Enabled = false
+	client := NewClient(&httpStreamer{}, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := client.Run(ctx)
+
+	require.NoError(t, err)
+	assert.False(t, client.Connected())
+}
@@ -0,0 +1,88 @@
+package push
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const (
+	EventGDTasks     = "gdtasks"
+	EventServerTasks = "server_tasks"
+	EventServers     = "servers"
+)
+
+const maxEventSize = 1024 * 1024
+
+// Event is a server-sent event received from the panel.
+type Event struct {
+	Name string
+	Data []byte
+}
+
+// ID returns the identifier of the changed entity.
+// It returns false if the event is not related to a single entity.
+func (e Event) ID() (int, bool) {
+	if len(e.Data) == 0 {
+		return 0, false
+	}
+
+	var data struct {
+		ID int `json:"id"`
+	}
+
+	err := json.Unmarshal(e.Data, &data)
+	if err != nil || data.ID <= 0 {
+		return 0, false
+	}
+
+	return data.ID, true
+}
+
+// readEvents reads the text/event-stream and calls handle for each dispatched event.
+// It returns nil when the stream ends.
+func readEvents(r io.Reader, handle func(event Event)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxEventSize)
+
+	var name string
+	var data bytes.Buffer
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if data.Len() > 0 || name != "" {
+				handle(Event{
+					Name: name,
+					Data: bytes.TrimSuffix(data.Bytes(), []byte("\n")),
+				})
+			}
+
+			name = ""
+			data = bytes.Buffer{}
+
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comment, used by the panel as a keepalive.
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		}
+	}
+
+	return scanner.Err()
+}
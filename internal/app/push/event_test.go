@@ -0,0 +1,58 @@
+package push
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadEvents(t *testing.T) {
+	stream := ": keepalive\n" +
+		"\n" +
+		"event: gdtasks\n" +
+		"data: {\"id\": 12}\n" +
+		"\n" +
+		"event: server_tasks\n" +
+		"\n" +
+		"event: servers\n" +
+		"data: {\"id\":\n" +
+		"data: 3}\n" +
+		"\n"
+	var events []Event
+
+	err := readEvents(strings.NewReader(stream), func(event Event) {
+		events = append(events, event)
+	})
+
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, Event{Name: EventGDTasks, Data: []byte(`{"id": 12}`)}, events[0])
+	assert.Equal(t, EventServerTasks, events[1].Name)
+	assert.Empty(t, events[1].Data)
+	assert.Equal(t, Event{Name: EventServers, Data: []byte("{\"id\":\n3}")}, events[2])
+}
+
+func TestEvent_ID(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		wantID int
+		wantOK bool
+	}{
+		{"with id", `{"id": 5}`, 5, true},
+		{"empty data", ``, 0, false},
+		{"without id", `{"status": "waiting"}`, 0, false},
+		{"invalid json", `five`, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, ok := Event{Data: []byte(test.data)}.ID()
+
+			assert.Equal(t, test.wantID, id)
+			assert.Equal(t, test.wantOK, ok)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
@@ -15,6 +16,7 @@ import (
 
 type GDTaskRepository struct {
 	client           contracts.APIRequestMaker
+	outbox           *Outbox
 	serverRepository domain.ServerRepository
 }
 
@@ -25,14 +27,17 @@ type task struct {
 	ID         int    `json:"id"`
 	RunAfterID int    `json:"run_after_id"`
 	Server     int    `json:"server_id"`
+	Timeout    int    `json:"timeout"` // In seconds
 }
 
 func NewGDTaskRepository(
 	client contracts.APIRequestMaker,
+	outbox *Outbox,
 	serverRepository domain.ServerRepository,
 ) *GDTaskRepository {
 	return &GDTaskRepository{
 		client:           client,
+		outbox:           outbox,
 		serverRepository: serverRepository,
 	}
 }
@@ -93,6 +98,7 @@ func (repository *GDTaskRepository) FindByStatus(
 			items[i].Cmd,
 			domain.GDTaskStatus(items[i].Status),
 		)
+		gdTask.SetTimeout(time.Duration(items[i].Timeout) * time.Second)
 
 		tasks = append(tasks, gdTask)
 	}
@@ -128,14 +134,17 @@ func (repository *GDTaskRepository) FindByID(ctx context.Context, id int) (*doma
 		}
 	}
 
-	return domain.NewGDTask(
+	gdTask := domain.NewGDTask(
 		tsk.ID,
 		tsk.RunAfterID,
 		server,
 		domain.GDTaskCommand(tsk.Task),
 		tsk.Cmd,
 		domain.GDTaskStatus(tsk.Status),
-	), nil
+	)
+	gdTask.SetTimeout(time.Duration(tsk.Timeout) * time.Second)
+
+	return gdTask, nil
 }
 
 func (repository *GDTaskRepository) Save(ctx context.Context, gdtask *domain.GDTask) error {
@@ -146,7 +155,7 @@ func (repository *GDTaskRepository) Save(ctx context.Context, gdtask *domain.GDT
 		return errors.WithMessage(err, "[repositories.GDTaskRepository] failed to marshal gd task")
 	}
 
-	resp, err := repository.client.Request(ctx, domain.APIRequest{
+	err = repository.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPut,
 		URL:    "/gdaemon_api/tasks/{id}",
 		Body:   marshalled,
@@ -155,14 +164,7 @@ func (repository *GDTaskRepository) Save(ctx context.Context, gdtask *domain.GDT
 		},
 	})
 	if err != nil {
-		return errors.WithMessage(err, "failed to save gameap daemon task")
-	}
-
-	if resp.StatusCode() != http.StatusOK {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.GDTaskRepository] failed to save gameap daemon task",
-		)
+		return errors.WithMessage(err, "[repositories.GDTaskRepository] failed to save gameap daemon task")
 	}
 
 	if gdtask.Server() != nil {
@@ -183,7 +185,7 @@ func (repository *GDTaskRepository) AppendOutput(ctx context.Context, gdtask *do
 		return errors.WithMessage(err, "[repositories.GDTaskRepository] failed to marshal output")
 	}
 
-	resp, err := repository.client.Request(ctx, domain.APIRequest{
+	err = repository.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPut,
 		URL:    "/gdaemon_api/tasks/{id}/output",
 		Body:   marshalled,
@@ -195,12 +197,5 @@ func (repository *GDTaskRepository) AppendOutput(ctx context.Context, gdtask *do
 		return errors.WithMessage(err, "[repositories.GDTaskRepository] failed to append output of gameap daemon task")
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.GDTaskRepository] failed to save gameap daemon task",
-		)
-	}
-
 	return nil
 }
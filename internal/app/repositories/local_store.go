@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	serversBucket     = "servers"
+	serverTasksBucket = "server_tasks"
+	outboxBucket      = "outbox"
+	deadLettersBucket = "outbox_dead_letters"
+
+	serverTasksKey = "all"
+)
+
+var localStoreBuckets = []string{
+	serversBucket,
+	serverTasksBucket,
+	outboxBucket,
+	deadLettersBucket,
+}
+
+// LocalStore keeps the last known API state and outgoing requests on disk,
+// so the daemon can keep working while the panel is unreachable.
+type LocalStore struct {
+	db *bolt.DB
+}
+
+func NewLocalStore(path string) (*LocalStore, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[repositories.LocalStore] failed to create store directory")
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.WithMessage(err, "[repositories.LocalStore] failed to open store")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range localStoreBuckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.WithMessage(err, "[repositories.LocalStore] failed to initialize store")
+	}
+
+	return &LocalStore{db: db}, nil
+}
+
+func (s *LocalStore) Put(bucket string, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put([]byte(key), value)
+	})
+}
+
+// Get returns nil if the key doesn't exist.
+func (s *LocalStore) Get(bucket string, key string) ([]byte, error) {
+	var value []byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(bucket)).Get([]byte(key))
+		if v != nil {
+			value = make([]byte, len(v))
+			copy(value, v)
+		}
+
+		return nil
+	})
+
+	return value, err
+}
+
+func (s *LocalStore) Keys(bucket string) ([]string, error) {
+	var keys []string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+func (s *LocalStore) Delete(bucket string, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Delete([]byte(key))
+	})
+}
+
+// Push appends value to the end of the ordered bucket.
+func (s *LocalStore) Push(bucket string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		return b.Put(sequenceKey(seq), value)
+	})
+}
+
+// First returns the oldest value of the ordered bucket and its sequence number.
+// It returns nil value if the bucket is empty.
+func (s *LocalStore) First(bucket string) (uint64, []byte, error) {
+	var seq uint64
+	var value []byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, v := tx.Bucket([]byte(bucket)).Cursor().First()
+		if k == nil {
+			return nil
+		}
+
+		seq = binary.BigEndian.Uint64(k)
+		value = make([]byte, len(v))
+		copy(value, v)
+
+		return nil
+	})
+
+	return seq, value, err
+}
+
+// Replace replaces the value of the ordered bucket, the value keeps its place.
+func (s *LocalStore) Replace(bucket string, seq uint64, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put(sequenceKey(seq), value)
+	})
+}
+
+// Move moves the value from one ordered bucket to the end of another one.
+func (s *LocalStore) Move(from string, seq uint64, to string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(from)).Get(sequenceKey(seq))
+		if v == nil {
+			return nil
+		}
+
+		// The value is valid only until the bucket is changed
+		value := make([]byte, len(v))
+		copy(value, v)
+
+		b := tx.Bucket([]byte(to))
+
+		next, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		err = b.Put(sequenceKey(next), value)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket([]byte(from)).Delete(sequenceKey(seq))
+	})
+}
+
+func (s *LocalStore) Remove(bucket string, seq uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Delete(sequenceKey(seq))
+	})
+}
+
+func (s *LocalStore) Len(bucket string) (int, error) {
+	var n int
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket([]byte(bucket)).Stats().KeyN
+		return nil
+	})
+
+	return n, err
+}
+
+func (s *LocalStore) Close() error {
+	return s.db.Close()
+}
+
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	return key
+}
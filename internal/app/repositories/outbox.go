@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// outboxMaxAttempts is the number of replays of the queued request failed by the reachable API,
+// then the request is moved to the dead letters, so it doesn't block the requests queued after it.
+const outboxMaxAttempts = 10
+
+var errAPIUnavailable = errors.New("api is unavailable")
+
+// outboxEntry is the queued request.
+type outboxEntry struct {
+	Request  domain.APIRequest `json:"request"`
+	QueuedAt time.Time         `json:"queued_at"`
+	Attempts int               `json:"attempts"`
+}
+
+// Outbox delivers state changing requests to the API.
+// If the local store is configured, requests that cannot be delivered
+// because the API is unavailable are queued and replayed later in the same order.
+type Outbox struct {
+	client contracts.APIRequestMaker
+	store  *LocalStore
+	mu     sync.Mutex
+}
+
+func NewOutbox(client contracts.APIRequestMaker, store *LocalStore) *Outbox {
+	return &Outbox{
+		client: client,
+		store:  store,
+	}
+}
+
+// Send sends request to the API. Without local store it fails if the response status
+// is not one of the successCodes (http.StatusOK by default).
+// With local store the request is queued if the API is unavailable
+// or there are earlier requests which are not delivered yet.
+func (o *Outbox) Send(ctx context.Context, request domain.APIRequest, successCodes ...int) error {
+	if len(successCodes) == 0 {
+		successCodes = []int{http.StatusOK}
+	}
+
+	if o.store == nil {
+		resp, err := o.client.Request(ctx, request)
+
+		return checkResponse(resp, err, successCodes)
+	}
+
+	queued, err := o.enqueueIfPending(request)
+	if err != nil || queued {
+		return err
+	}
+
+	resp, err := o.client.Request(ctx, request)
+	if !isAPIUnavailable(resp, err) {
+		return checkResponse(resp, err, successCodes)
+	}
+
+	logger.WithFields(ctx, log.Fields{
+		"method": request.Method,
+		"url":    request.URL,
+	}).Warn("API is unavailable, request is queued")
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.enqueue(request)
+}
+
+// Pending returns the number of queued requests.
+func (o *Outbox) Pending() int {
+	if o.store == nil {
+		return 0
+	}
+
+	n, err := o.store.Len(outboxBucket)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// Run replays queued requests until the context is done.
+func (o *Outbox) Run(ctx context.Context, period time.Duration) error {
+	if o.store == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := o.Flush(ctx)
+			if err != nil {
+				logger.WithError(ctx, err).Debug("Failed to replay queued API requests")
+			}
+		}
+	}
+}
+
+// DeadLetters returns the number of queued requests failed too many times.
+func (o *Outbox) DeadLetters() int {
+	if o.store == nil {
+		return 0
+	}
+
+	n, err := o.store.Len(deadLettersBucket)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// Flush replays queued requests in order. It stops on the first request
+// that cannot be delivered because the API is still unavailable.
+// The request failed by the reachable API outboxMaxAttempts times is moved to the dead letters.
+func (o *Outbox) Flush(ctx context.Context) error {
+	if o.store == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for {
+		seq, raw, err := o.store.First(outboxBucket)
+		if err != nil {
+			return errors.WithMessage(err, "[repositories.Outbox] failed to read queued request")
+		}
+		if raw == nil {
+			return nil
+		}
+
+		var entry outboxEntry
+		err = json.Unmarshal(raw, &entry)
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Invalid queued API request, dropping it")
+
+			err = o.store.Remove(outboxBucket, seq)
+			if err != nil {
+				return errors.WithMessage(err, "[repositories.Outbox] failed to remove replayed request")
+			}
+
+			continue
+		}
+
+		err = o.replay(ctx, seq, entry)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// replay sends the queued request and removes it from the queue. The request failed by the API
+// is left in the queue until it fails outboxMaxAttempts times, then it's moved to the dead letters.
+func (o *Outbox) replay(ctx context.Context, seq uint64, entry outboxEntry) error {
+	fields := log.Fields{
+		"method": entry.Request.Method,
+		"url":    entry.Request.URL,
+	}
+
+	resp, err := o.client.Request(ctx, entry.Request)
+	if !isAPIUnavailable(resp, err) {
+		if resp.StatusCode() < http.StatusOK || resp.StatusCode() >= http.StatusMultipleChoices {
+			fields["responseStatus"] = resp.StatusCode()
+			logger.WithFields(ctx, fields).Warn("Queued API request was rejected, dropping it")
+		}
+
+		err = o.store.Remove(outboxBucket, seq)
+		if err != nil {
+			return errors.WithMessage(err, "[repositories.Outbox] failed to remove replayed request")
+		}
+
+		return nil
+	}
+
+	// The unreachable API doesn't count, the requests wait for it as long as needed
+	if !isRequestFailing(resp, err) {
+		return errAPIUnavailable
+	}
+
+	entry.Attempts++
+
+	err = o.replace(seq, entry)
+	if err != nil {
+		return err
+	}
+
+	if entry.Attempts < outboxMaxAttempts {
+		return errAPIUnavailable
+	}
+
+	fields["responseStatus"] = resp.StatusCode()
+	fields["attempts"] = entry.Attempts
+	fields["queuedAt"] = entry.QueuedAt
+	logger.WithFields(ctx, fields).Error("Queued API request failed too many times, moving it to dead letters")
+
+	err = o.store.Move(outboxBucket, seq, deadLettersBucket)
+	if err != nil {
+		return errors.WithMessage(err, "[repositories.Outbox] failed to move request to dead letters")
+	}
+
+	return nil
+}
+
+func (o *Outbox) replace(seq uint64, entry outboxEntry) error {
+	marshalled, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithMessage(err, "[repositories.Outbox] failed to marshal request")
+	}
+
+	err = o.store.Replace(outboxBucket, seq, marshalled)
+	if err != nil {
+		return errors.WithMessage(err, "[repositories.Outbox] failed to update queued request")
+	}
+
+	return nil
+}
+
+func (o *Outbox) enqueueIfPending(request domain.APIRequest) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n, err := o.store.Len(outboxBucket)
+	if err != nil {
+		return false, errors.WithMessage(err, "[repositories.Outbox] failed to read queue length")
+	}
+
+	if n == 0 {
+		return false, nil
+	}
+
+	return true, o.enqueue(request)
+}
+
+func (o *Outbox) enqueue(request domain.APIRequest) error {
+	marshalled, err := json.Marshal(outboxEntry{Request: request, QueuedAt: time.Now()})
+	if err != nil {
+		return errors.WithMessage(err, "[repositories.Outbox] failed to marshal request")
+	}
+
+	err = o.store.Push(outboxBucket, marshalled)
+	if err != nil {
+		return errors.WithMessage(err, "[repositories.Outbox] failed to queue request")
+	}
+
+	return nil
+}
+
+func checkResponse(resp contracts.APIResponse, err error, successCodes []int) error {
+	if err != nil {
+		return err
+	}
+
+	for _, code := range successCodes {
+		if resp.StatusCode() == code {
+			return nil
+		}
+	}
+
+	return domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body())
+}
+
+// isAPIUnavailable reports whether the request failed because of the API availability
+// (network errors, timeouts and server side errors), so it makes sense to repeat it later.
+func isAPIUnavailable(resp contracts.APIResponse, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode() >= http.StatusInternalServerError
+}
+
+// isRequestFailing reports whether the reachable API fails the request, e.g. the request breaks the panel.
+// The gateway errors mean the API is unreachable.
+func isRequestFailing(resp contracts.APIResponse, err error) bool {
+	if err != nil {
+		return false
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return false
+	default:
+		return resp.StatusCode() >= http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiResponse struct {
+	body []byte
+	code int
+}
+
+func (r *apiResponse) Body() []byte       { return r.body }
+func (r *apiResponse) Status() string     { return http.StatusText(r.code) }
+func (r *apiResponse) StatusCode() int    { return r.code }
+func (r *apiResponse) Error() interface{} { return nil }
+
+type fakeAPIClient struct {
+	mu        sync.Mutex
+	available bool
+	requests  []domain.APIRequest
+	// failing are the URLs of the requests failed by the API
+	failing map[string]bool
+}
+
+func (c *fakeAPIClient) Request(_ context.Context, request domain.APIRequest) (contracts.APIResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.available {
+		return nil, errors.New("connection refused")
+	}
+
+	if c.failing[request.URL] {
+		return &apiResponse{code: http.StatusInternalServerError}, nil
+	}
+
+	c.requests = append(c.requests, request)
+
+	return &apiResponse{code: http.StatusOK}, nil
+}
+
+func (c *fakeAPIClient) SetAvailable(available bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.available = available
+}
+
+func givenLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "daemon.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	return store
+}
+
+func TestOutbox_APIUnavailable_RequestsQueuedAndReplayedInOrder(t *testing.T) {
+	client := &fakeAPIClient{}
+	outbox := NewOutbox(client, givenLocalStore(t))
+	ctx := context.Background()
+
+	err := outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/first"})
+	require.NoError(t, err)
+	client.SetAvailable(true)
+	err = outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/second"})
+	require.NoError(t, err)
+
+	assert.Empty(t, client.requests)
+	assert.Equal(t, 2, outbox.Pending())
+
+	err = outbox.Flush(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, client.requests, 2)
+	assert.Equal(t, "/first", client.requests[0].URL)
+	assert.Equal(t, "/second", client.requests[1].URL)
+	assert.Equal(t, 0, outbox.Pending())
+}
+
+func TestOutbox_FlushWhileAPIUnavailable_RequestsKept(t *testing.T) {
+	client := &fakeAPIClient{}
+	outbox := NewOutbox(client, givenLocalStore(t))
+	ctx := context.Background()
+
+	err := outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/first"})
+	require.NoError(t, err)
+
+	err = outbox.Flush(ctx)
+
+	assert.ErrorIs(t, err, errAPIUnavailable)
+	assert.Equal(t, 1, outbox.Pending())
+}
+
+func TestOutbox_FirstRequestFailing_MovedToDeadLettersAndQueueDrained(t *testing.T) {
+	client := &fakeAPIClient{failing: map[string]bool{"/failing": true}}
+	outbox := NewOutbox(client, givenLocalStore(t))
+	ctx := context.Background()
+	require.NoError(t, outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/failing"}))
+	require.NoError(t, outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/second"}))
+	client.SetAvailable(true)
+
+	for i := 1; i < outboxMaxAttempts; i++ {
+		err := outbox.Flush(ctx)
+
+		require.ErrorIs(t, err, errAPIUnavailable)
+		assert.Equal(t, 2, outbox.Pending())
+	}
+	err := outbox.Flush(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, outbox.Pending())
+	assert.Equal(t, 1, outbox.DeadLetters())
+	require.Len(t, client.requests, 1)
+	assert.Equal(t, "/second", client.requests[0].URL)
+}
+
+func TestOutbox_APIUnreachable_AttemptsNotCounted(t *testing.T) {
+	client := &fakeAPIClient{}
+	outbox := NewOutbox(client, givenLocalStore(t))
+	ctx := context.Background()
+	require.NoError(t, outbox.Send(ctx, domain.APIRequest{Method: http.MethodPut, URL: "/first"}))
+
+	for i := 0; i < outboxMaxAttempts; i++ {
+		assert.ErrorIs(t, outbox.Flush(ctx), errAPIUnavailable)
+	}
+
+	assert.Equal(t, 1, outbox.Pending())
+	assert.Equal(t, 0, outbox.DeadLetters())
+}
+
+func TestOutbox_WithoutStore_ErrorReturned(t *testing.T) {
+	client := &fakeAPIClient{}
+	outbox := NewOutbox(client, nil)
+
+	err := outbox.Send(context.Background(), domain.APIRequest{Method: http.MethodPut, URL: "/first"})
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, outbox.Pending())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/pkg/limiter"
+	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
@@ -33,10 +34,18 @@ type ServerRepository struct {
 	mu             sync.Mutex
 }
 
-func NewServerRepository(ctx context.Context, client contracts.APIRequestMaker, logger *log.Logger) *ServerRepository {
+func NewServerRepository(
+	ctx context.Context,
+	client contracts.APIRequestMaker,
+	outbox *Outbox,
+	store *LocalStore,
+	logger *log.Logger,
+) *ServerRepository {
 	serverRepo := &ServerRepository{
 		innerRepo: apiServerRepo{
 			client: client,
+			outbox: outbox,
+			store:  store,
 		},
 	}
 
@@ -128,6 +137,11 @@ func (repo *ServerRepository) FindByID(ctx context.Context, id int) (*domain.Ser
 	return server, nil
 }
 
+// Invalidate marks the cached server as outdated, so it will be reloaded from the API on the next FindByID call.
+func (repo *ServerRepository) Invalidate(id int) {
+	repo.lastUpdated.Store(id, time.Time{})
+}
+
 func (repo *ServerRepository) Save(_ context.Context, server *domain.Server) error {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
@@ -168,6 +182,8 @@ type serverStruct struct {
 
 type apiServerRepo struct {
 	client contracts.APIRequestMaker
+	outbox *Outbox
+	store  *LocalStore
 
 	servers sync.Map // [int]*domain.Server  (serverID => server)
 }
@@ -178,6 +194,12 @@ func (apiRepo *apiServerRepo) IDs(ctx context.Context) ([]int, error) {
 		URL:    "/gdaemon_api/servers",
 	})
 
+	if apiRepo.store != nil && isAPIUnavailable(response, err) {
+		logger.Debug(ctx, "API is unavailable, using last known game servers")
+
+		return apiRepo.storedIDs()
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -200,11 +222,55 @@ func (apiRepo *apiServerRepo) IDs(ctx context.Context) ([]int, error) {
 		ids = append(ids, v.ID)
 	}
 
+	if apiRepo.store != nil {
+		apiRepo.removeStaleStored(ctx, ids)
+	}
+
 	return ids, nil
 }
 
-//nolint:funlen
-func (apiRepo *apiServerRepo) FindByID(ctx context.Context, id int) (*domain.Server, error) {
+func (apiRepo *apiServerRepo) storedIDs() ([]int, error) {
+	keys, err := apiRepo.store.Keys(serversBucket)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[repositories.apiServerRepo] failed to read stored servers")
+	}
+
+	ids := make([]int, 0, len(keys))
+	for _, key := range keys {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (apiRepo *apiServerRepo) removeStaleStored(ctx context.Context, ids []int) {
+	storedIDs, err := apiRepo.storedIDs()
+	if err != nil {
+		logger.WithError(ctx, err).Warn("Failed to read stored game servers")
+		return
+	}
+
+	for _, storedID := range storedIDs {
+		if lo.Contains(ids, storedID) {
+			continue
+		}
+
+		err = apiRepo.store.Delete(serversBucket, strconv.Itoa(storedID))
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Failed to remove stored game server")
+		}
+	}
+}
+
+// load returns the raw game server data. If the API is unavailable the last known data is loaded
+// from the local store, in that case fromStore is true.
+// It returns nil body if the server is not found.
+func (apiRepo *apiServerRepo) load(ctx context.Context, id int) (body []byte, fromStore bool, err error) {
 	response, err := apiRepo.client.Request(ctx, domain.APIRequest{
 		Method: http.MethodGet,
 		URL:    "/gdaemon_api/servers/{id}",
@@ -213,22 +279,56 @@ func (apiRepo *apiServerRepo) FindByID(ctx context.Context, id int) (*domain.Ser
 		},
 	})
 
+	if apiRepo.store != nil && isAPIUnavailable(response, err) {
+		body, storeErr := apiRepo.store.Get(serversBucket, strconv.Itoa(id))
+		if storeErr != nil || body == nil {
+			return nil, false, errAPIUnavailable
+		}
+
+		return body, true, nil
+	}
+
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if response.StatusCode() == http.StatusNotFound {
-		return nil, nil
+		return nil, false, nil
 	}
 	if response.StatusCode() != http.StatusOK {
-		return nil, errors.WithMessage(
+		return nil, false, errors.WithMessage(
 			domain.NewErrInvalidResponseFromAPI(response.StatusCode(), response.Body()),
 			"[repositories.apiServerRepo] failed find game server",
 		)
 	}
 
+	if apiRepo.store != nil {
+		err = apiRepo.store.Put(serversBucket, strconv.Itoa(id), response.Body())
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Failed to store game server")
+		}
+	}
+
+	return response.Body(), false, nil
+}
+
+//nolint:funlen
+func (apiRepo *apiServerRepo) FindByID(ctx context.Context, id int) (*domain.Server, error) {
+	body, fromStore, err := apiRepo.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, nil
+	}
+
+	if item, exists := apiRepo.servers.Load(id); exists && fromStore {
+		// The daemon state is newer than the stored one.
+		return item.(*domain.Server), nil
+	}
+
 	var srv serverStruct
-	err = json.Unmarshal(response.Body(), &srv)
+	err = json.Unmarshal(body, &srv)
 	if err != nil {
 		return nil, err
 	}
@@ -393,7 +493,7 @@ func (apiRepo *apiServerRepo) Save(ctx context.Context, server *domain.Server) e
 		return errors.WithMessage(err, "[repositories.apiServerRepo] failed to marshal server")
 	}
 
-	resp, err := apiRepo.client.Request(ctx, domain.APIRequest{
+	err = apiRepo.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPut,
 		URL:    "/gdaemon_api/servers/{id}",
 		Body:   marshalled,
@@ -405,13 +505,6 @@ func (apiRepo *apiServerRepo) Save(ctx context.Context, server *domain.Server) e
 		return errors.WithMessage(err, "[repositories.apiServerRepo] failed to saving server")
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.apiServerRepo] failed to saving server",
-		)
-	}
-
 	return nil
 }
 
@@ -427,7 +520,7 @@ func (apiRepo *apiServerRepo) SaveBulk(ctx context.Context, servers []*domain.Se
 		return errors.WithMessage(err, "[repositories.apiServerRepo] failed to marshal servers")
 	}
 
-	resp, err := apiRepo.client.Request(ctx, domain.APIRequest{
+	err = apiRepo.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPatch,
 		URL:    "/gdaemon_api/servers",
 		Body:   marshalled,
@@ -436,12 +529,5 @@ func (apiRepo *apiServerRepo) SaveBulk(ctx context.Context, servers []*domain.Se
 		return errors.WithMessage(err, "[repositories.apiServerRepo] failed to bulk saving servers")
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.apiServerRepo] failed to bulk saving servers",
-		)
-	}
-
 	return nil
 }
@@ -3,27 +3,49 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"math/rand"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/cron"
+	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 )
 
 type ServerTaskRepository struct {
 	client           contracts.APIRequestMaker
+	outbox           *Outbox
+	store            *LocalStore
 	serverRepository domain.ServerRepository
+
+	// The next run dates of the cron tasks, the panel doesn't send them
+	// until the task is executed and saved for the first time
+	cronDatesMu sync.Mutex
+	cronDates   map[int]cronDate
+}
+
+type cronDate struct {
+	cron     string
+	timezone string
+	date     time.Time
 }
 
 func NewServerTaskRepository(
 	client contracts.APIRequestMaker,
+	outbox *Outbox,
+	store *LocalStore,
 	serverRepository domain.ServerRepository,
 ) *ServerTaskRepository {
 	return &ServerTaskRepository{
 		client:           client,
+		outbox:           outbox,
+		store:            store,
 		serverRepository: serverRepository,
+		cronDates:        make(map[int]cronDate),
 	}
 }
 
@@ -35,14 +57,170 @@ type serverTask struct {
 	Repeat       int    `json:"repeat"`
 	RepeatPeriod int    `json:"repeat_period"`
 	Counter      int    `json:"counter"`
+	Timeout      int    `json:"timeout"` // In seconds
+
+	Cron                string `json:"cron,omitempty"`
+	Timezone            string `json:"timezone,omitempty"`
+	Jitter              int    `json:"jitter,omitempty"` // In seconds
+	SkipIfPlayersOnline bool   `json:"skip_if_players_online,omitempty"`
 }
 
 func (repo *ServerTaskRepository) Find(ctx context.Context) ([]*domain.ServerTask, error) {
+	items, err := repo.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*domain.ServerTask, 0, len(items))
+	for i := range items {
+		server, err := repo.serverRepository.FindByID(ctx, items[i].ServerID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to join server to server task")
+		}
+		if server == nil {
+			return nil, errInvalidServerID
+		}
+
+		var schedule *cron.Schedule
+		if items[i].Cron != "" {
+			schedule, err = parseSchedule(items[i].Cron, items[i].Timezone)
+			if err != nil {
+				logger.Logger(ctx).
+					WithError(err).
+					WithField("serverTaskID", items[i].ID).
+					Warn("Invalid server task schedule, the task is skipped")
+
+				continue
+			}
+		}
+
+		var executeDate time.Time
+		if items[i].ExecuteDate == "" && schedule != nil {
+			executeDate = repo.cronExecuteDate(items[i], schedule)
+		} else {
+			executeDate, err = time.Parse("2006-01-02 15:04:05", items[i].ExecuteDate)
+			if err != nil {
+				return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to parse server task execute date")
+			}
+		}
+
+		task := domain.NewServerTask(
+			items[i].ID,
+			domain.ServerTaskCommand(items[i].Command),
+			server,
+			items[i].Repeat,
+			time.Duration(items[i].RepeatPeriod)*time.Second,
+			items[i].Counter,
+			executeDate,
+		)
+		task.SetTimeout(time.Duration(items[i].Timeout) * time.Second)
+		task.SetSkipIfPlayersOnline(items[i].SkipIfPlayersOnline)
+
+		if schedule != nil {
+			task.SetSchedule(schedule, time.Duration(items[i].Jitter)*time.Second)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	repo.forgetDeletedCronDates(items)
+
+	return tasks, nil
+}
+
+// cronExecuteDate returns the known next run date of the cron task,
+// it's computed again only if the task is new or its schedule is changed.
+// Otherwise the task would be rescheduled on every reload and lose its jitter.
+func (repo *ServerTaskRepository) cronExecuteDate(item serverTask, schedule *cron.Schedule) time.Time {
+	repo.cronDatesMu.Lock()
+	defer repo.cronDatesMu.Unlock()
+
+	known, ok := repo.cronDates[item.ID]
+	if ok && known.cron == item.Cron && known.timezone == item.Timezone {
+		return known.date
+	}
+
+	// The first date gets the jitter, like the dates the scheduler reschedules the task to
+	date := schedule.Next(time.Now()).Add(randomJitter(time.Duration(item.Jitter) * time.Second))
+	repo.cronDates[item.ID] = cronDate{
+		cron:     item.Cron,
+		timezone: item.Timezone,
+		date:     date,
+	}
+
+	return date
+}
+
+// rememberCronDate keeps the date the cron task is rescheduled to.
+func (repo *ServerTaskRepository) rememberCronDate(task *domain.ServerTask) {
+	repo.cronDatesMu.Lock()
+	defer repo.cronDatesMu.Unlock()
+
+	known, ok := repo.cronDates[task.ID()]
+	if !ok {
+		return
+	}
+
+	known.date = task.ExecuteDate()
+	repo.cronDates[task.ID()] = known
+}
+
+// randomJitter returns the random delay up to the jitter.
+func randomJitter(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+
+	//nolint:gosec
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+func (repo *ServerTaskRepository) forgetDeletedCronDates(items []serverTask) {
+	actual := make(map[int]struct{}, len(items))
+	for i := range items {
+		actual[items[i].ID] = struct{}{}
+	}
+
+	repo.cronDatesMu.Lock()
+	defer repo.cronDatesMu.Unlock()
+
+	for id := range repo.cronDates {
+		if _, ok := actual[id]; !ok {
+			delete(repo.cronDates, id)
+		}
+	}
+}
+
+func parseSchedule(expression, timezone string) (*cron.Schedule, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to load server task timezone")
+		}
+	}
+
+	schedule, err := cron.Parse(expression, location)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to parse server task cron expression")
+	}
+
+	return schedule, nil
+}
+
+func (repo *ServerTaskRepository) load(ctx context.Context) ([]serverTask, error) {
 	resp, err := repo.client.Request(ctx, domain.APIRequest{
 		Method: http.MethodGet,
 		URL:    "/gdaemon_api/servers_tasks",
 	})
 
+	if repo.store != nil && isAPIUnavailable(resp, err) {
+		logger.Debug(ctx, "API is unavailable, using last known game server tasks")
+
+		return repo.loadFromStore()
+	}
+
 	if err != nil {
 		return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to find game server tasks")
 	}
@@ -60,35 +238,101 @@ func (repo *ServerTaskRepository) Find(ctx context.Context) ([]*domain.ServerTas
 		return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to unmarshal server tasks")
 	}
 
-	tasks := make([]*domain.ServerTask, 0, len(items))
-	for i := range items {
-		server, err := repo.serverRepository.FindByID(ctx, items[i].ServerID)
+	if repo.store != nil {
+		err = repo.saveToStore(items)
 		if err != nil {
-			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to join server to server task")
+			logger.WithError(ctx, err).Warn("Failed to store game server tasks")
 		}
-		if server == nil {
-			return nil, errInvalidServerID
+	}
+
+	return items, nil
+}
+
+func (repo *ServerTaskRepository) loadFromStore() ([]serverTask, error) {
+	keys, err := repo.store.Keys(serverTasksBucket)
+	if err != nil {
+		return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to read stored server tasks")
+	}
+
+	items := make([]serverTask, 0, len(keys))
+	for _, key := range keys {
+		raw, err := repo.store.Get(serverTasksBucket, key)
+		if err != nil {
+			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to read stored server task")
 		}
 
-		executeDate, err := time.Parse("2006-01-02 15:04:05", items[i].ExecuteDate)
+		var item serverTask
+		err = json.Unmarshal(raw, &item)
 		if err != nil {
-			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to parse server task execute date")
+			return nil, errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to unmarshal stored server task")
 		}
 
-		task := domain.NewServerTask(
-			items[i].ID,
-			domain.ServerTaskCommand(items[i].Command),
-			server,
-			items[i].Repeat,
-			time.Duration(items[i].RepeatPeriod)*time.Second,
-			items[i].Counter,
-			executeDate,
-		)
+		items = append(items, item)
+	}
 
-		tasks = append(tasks, task)
+	return items, nil
+}
+
+func (repo *ServerTaskRepository) saveToStore(items []serverTask) error {
+	actual := make(map[string]struct{}, len(items))
+
+	for i := range items {
+		key := strconv.Itoa(items[i].ID)
+		actual[key] = struct{}{}
+
+		marshalled, err := json.Marshal(items[i])
+		if err != nil {
+			return err
+		}
+
+		err = repo.store.Put(serverTasksBucket, key, marshalled)
+		if err != nil {
+			return err
+		}
 	}
 
-	return tasks, nil
+	keys, err := repo.store.Keys(serverTasksBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, ok := actual[key]; !ok {
+			err = repo.store.Delete(serverTasksBucket, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateStored keeps the stored task in sync with changes made by the daemon,
+// so the last known tasks are not executed again after the daemon loads them from the store.
+func (repo *ServerTaskRepository) updateStored(task *domain.ServerTask) error {
+	key := strconv.Itoa(task.ID())
+
+	raw, err := repo.store.Get(serverTasksBucket, key)
+	if err != nil || raw == nil {
+		return err
+	}
+
+	var item serverTask
+	err = json.Unmarshal(raw, &item)
+	if err != nil {
+		return err
+	}
+
+	item.ExecuteDate = task.ExecuteDate().Format("2006-01-02 15:04:05")
+	item.Counter = task.Counter()
+
+	marshalled, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return repo.store.Put(serverTasksBucket, key, marshalled)
 }
 
 func (repo *ServerTaskRepository) Save(ctx context.Context, task *domain.ServerTask) error {
@@ -97,7 +341,16 @@ func (repo *ServerTaskRepository) Save(ctx context.Context, task *domain.ServerT
 		return errors.WithMessage(err, "failed to marshal server task")
 	}
 
-	resp, err := repo.client.Request(ctx, domain.APIRequest{
+	repo.rememberCronDate(task)
+
+	if repo.store != nil {
+		err = repo.updateStored(task)
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Failed to update stored game server task")
+		}
+	}
+
+	err = repo.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPut,
 		URL:    "/gdaemon_api/servers_tasks/{id}",
 		Body:   marshalled,
@@ -109,13 +362,6 @@ func (repo *ServerTaskRepository) Save(ctx context.Context, task *domain.ServerT
 		return errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to save server task")
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.ServerTaskRepository] failed to save server task",
-		)
-	}
-
 	return nil
 }
 
@@ -129,24 +375,17 @@ func (repo *ServerTaskRepository) Fail(ctx context.Context, task *domain.ServerT
 		return errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to marshal server task output")
 	}
 
-	resp, err := repo.client.Request(ctx, domain.APIRequest{
+	err = repo.outbox.Send(ctx, domain.APIRequest{
 		Method: http.MethodPost,
 		URL:    "/gdaemon_api/servers_tasks/{id}/fail",
 		Body:   marshalled,
 		PathParams: map[string]string{
 			"id": strconv.Itoa(task.ID()),
 		},
-	})
+	}, http.StatusOK, http.StatusCreated)
 	if err != nil {
 		return errors.WithMessage(err, "[repositories.ServerTaskRepository] failed to save server task fail info")
 	}
 
-	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusCreated {
-		return errors.WithMessage(
-			domain.NewErrInvalidResponseFromAPI(resp.StatusCode(), resp.Body()),
-			"[repositories.ServerTaskRepository] failed to save server task fail info",
-		)
-	}
-
 	return nil
 }
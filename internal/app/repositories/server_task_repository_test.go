@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/gameap/daemon/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type serverTasksAPIClient struct {
+	tasks string
+}
+
+func (c *serverTasksAPIClient) Request(_ context.Context, request domain.APIRequest) (contracts.APIResponse, error) {
+	if request.Method == http.MethodGet {
+		return &apiResponse{code: http.StatusOK, body: []byte(c.tasks)}, nil
+	}
+
+	return &apiResponse{code: http.StatusOK}, nil
+}
+
+func TestServerTaskRepository_CronTaskWithoutExecuteDate_DateKeptBetweenReloads(t *testing.T) {
+	client := &serverTasksAPIClient{
+		tasks: `[{"id": 1, "server_id": 1, "command": "restart", "cron": "0 4 * * *"}]`,
+	}
+	repo := givenServerTaskRepository(t, client)
+	ctx := context.Background()
+
+	tasks, err := repo.Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, 4, tasks[0].ExecuteDate().Hour())
+
+	// The scheduler adds the jitter to the next run date
+	rescheduled := tasks[0].ExecuteDate().Add(42 * time.Second)
+	tasks[0].Reschedule(rescheduled)
+	require.NoError(t, repo.Save(ctx, tasks[0]))
+
+	tasks, err = repo.Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, rescheduled, tasks[0].ExecuteDate())
+
+	client.tasks = `[{"id": 1, "server_id": 1, "command": "restart", "cron": "30 5 * * *"}]`
+
+	tasks, err = repo.Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, 5, tasks[0].ExecuteDate().Hour())
+	assert.Equal(t, 30, tasks[0].ExecuteDate().Minute())
+}
+
+func TestServerTaskRepository_CronTaskWithJitter_FirstDateJittered(t *testing.T) {
+	client := &serverTasksAPIClient{
+		tasks: `[{"id": 1, "server_id": 1, "command": "restart", "cron": "0 4 * * *", "jitter": 3600}]`,
+	}
+	repo := givenServerTaskRepository(t, client)
+	schedule, err := parseSchedule("0 4 * * *", "")
+	require.NoError(t, err)
+	earliest := schedule.Next(time.Now())
+
+	tasks, err := repo.Find(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.False(t, tasks[0].ExecuteDate().Before(earliest))
+	assert.True(t, tasks[0].ExecuteDate().Before(earliest.Add(time.Hour)))
+}
+
+func givenServerTaskRepository(t *testing.T, client contracts.APIRequestMaker) *ServerTaskRepository {
+	t.Helper()
+
+	serverRepository := mocks.NewServerRepository()
+	require.NoError(t, serverRepository.Save(context.Background(), domaintest.NewServer()))
+
+	return NewServerTaskRepository(client, NewOutbox(client, nil), nil, serverRepository)
+}
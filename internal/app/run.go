@@ -64,6 +64,7 @@ func initialize(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	defer container.Close()
 
 	processRunner, err := container.ProcessRunner(ctx)
 	if err != nil {
@@ -81,6 +82,9 @@ func initialize(c *cli.Context) error {
 	group.Go(processRunner.RunGDaemonTaskScheduler(ctx, cfg))
 	group.Go(processRunner.RunServersLoop(ctx, cfg))
 	group.Go(processRunner.RunServerScheduler(ctx, cfg))
+	group.Go(processRunner.RunOutbox(ctx, cfg))
+	group.Go(processRunner.RunPushClient(ctx, cfg))
+	group.Go(processRunner.RunMetricsServer(ctx, cfg))
 
 	err = group.Wait()
 	if err != nil {
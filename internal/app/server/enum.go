@@ -11,3 +11,20 @@ const (
 
 	ModeUnknown = -1
 )
+
+func (m Mode) String() string {
+	switch m {
+	case ModeNoAuth:
+		return "noauth"
+	case ModeAuth:
+		return "auth"
+	case ModeCommands:
+		return "commands"
+	case ModeFiles:
+		return "files"
+	case ModeStatus:
+		return "status"
+	default:
+		return "unknown"
+	}
+}
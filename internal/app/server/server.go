@@ -12,6 +12,7 @@ import (
 	"github.com/et-nik/binngo/decode"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/metrics"
 	"github.com/gameap/daemon/internal/app/server/commands"
 	"github.com/gameap/daemon/internal/app/server/files"
 	"github.com/gameap/daemon/internal/app/server/response"
@@ -34,6 +35,9 @@ type Server struct {
 	listener        net.Listener
 	executor        contracts.Executor
 	taskStatsReader domain.GDTaskStatsReader
+	taskCanceler    contracts.GDTaskCanceler
+	portAllocator   contracts.PortAllocator
+	healthCheckers  []contracts.HealthChecker
 
 	quit chan struct{}
 
@@ -58,6 +62,9 @@ func NewServer(
 	credConfig CredentialsConfig,
 	executor contracts.Executor,
 	taskStatsReader domain.GDTaskStatsReader,
+	taskCanceler contracts.GDTaskCanceler,
+	portAllocator contracts.PortAllocator,
+	healthCheckers ...contracts.HealthChecker,
 ) (*Server, error) {
 	return &Server{
 		ip:              ip,
@@ -69,6 +76,9 @@ func NewServer(
 		connTimeout:     5 * time.Second,
 		executor:        executor,
 		taskStatsReader: taskStatsReader,
+		taskCanceler:    taskCanceler,
+		portAllocator:   portAllocator,
+		healthCheckers:  healthCheckers,
 	}, nil
 }
 
@@ -214,7 +224,12 @@ func (srv *Server) serveComponent(ctx context.Context, conn net.Conn, m Mode) er
 	case ModeFiles:
 		handler = files.NewFiles()
 	case ModeStatus:
-		handler = status.NewStatus(srv.taskStatsReader)
+		handler = status.NewStatus(
+			srv.taskStatsReader,
+			srv.taskCanceler,
+			srv.portAllocator,
+			srv.healthCheckers...,
+		)
 	default:
 		err := response.WriteResponse(conn, response.Response{
 			Code: response.StatusError,
@@ -228,6 +243,9 @@ func (srv *Server) serveComponent(ctx context.Context, conn net.Conn, m Mode) er
 		return errInvalidMode
 	}
 
+	connectionClosed := metrics.BinnConnectionOpened(m.String())
+	defer connectionClosed()
+
 	for {
 		select {
 		case <-srv.quit:
@@ -11,12 +11,24 @@ const (
 	Version       Operation = 1
 	StatusBase    Operation = 2
 	StatusDetails Operation = 3
+	FreePorts     Operation = 4
+	CancelTask    Operation = 5
 )
 
-var errInvalidOperationMessage = errors.New("unknown binn value, cannot be presented as operation")
+var (
+	errInvalidOperationMessage  = errors.New("unknown binn value, cannot be presented as operation")
+	errInvalidFreePortsMessage  = errors.New("unknown binn value, cannot be presented as free ports request")
+	errInvalidCancelTaskMessage = errors.New("unknown binn value, cannot be presented as cancel task request")
+)
+
+// request is the operation followed by its arguments.
+type request struct {
+	Operation Operation
+	Args      []interface{}
+}
 
-func (o *Operation) UnmarshalBINN(bytes []byte) error {
-	var v []uint8
+func (r *request) UnmarshalBINN(bytes []byte) error {
+	var v []interface{}
 
 	err := decode.Unmarshal(bytes, &v)
 	if err != nil {
@@ -26,7 +38,89 @@ func (o *Operation) UnmarshalBINN(bytes []byte) error {
 		return errInvalidOperationMessage
 	}
 
-	*o = Operation(v[0])
+	operation, ok := toInt(v[0])
+	if !ok {
+		return errInvalidOperationMessage
+	}
+
+	r.Operation = Operation(operation)
+	r.Args = v[1:]
 
 	return nil
 }
+
+// freePortsRequest is [ip, from, to], all arguments are optional.
+// The empty IP means all node IPs, zero ports mean the configured range.
+type freePortsRequest struct {
+	IP   string
+	From int
+	To   int
+}
+
+func newFreePortsRequest(args []interface{}) (freePortsRequest, error) {
+	var r freePortsRequest
+	var ok bool
+
+	if len(args) > 0 {
+		r.IP, ok = args[0].(string)
+		if !ok {
+			return r, errInvalidFreePortsMessage
+		}
+	}
+
+	if len(args) > 1 {
+		r.From, ok = toInt(args[1])
+		if !ok {
+			return r, errInvalidFreePortsMessage
+		}
+	}
+
+	if len(args) > 2 {
+		r.To, ok = toInt(args[2])
+		if !ok {
+			return r, errInvalidFreePortsMessage
+		}
+	}
+
+	return r, nil
+}
+
+// newCancelTaskRequest parses [task id].
+func newCancelTaskRequest(args []interface{}) (int, error) {
+	if len(args) < 1 {
+		return 0, errInvalidCancelTaskMessage
+	}
+
+	taskID, ok := toInt(args[0])
+	if !ok || taskID <= 0 {
+		return 0, errInvalidCancelTaskMessage
+	}
+
+	return taskID, nil
+}
+
+// toInt converts the binn number, its type depends on the value and the client.
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case int:
+		return n, true
+	}
+
+	return 0, false
+}
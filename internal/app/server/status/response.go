@@ -2,6 +2,7 @@ package status
 
 import (
 	"github.com/et-nik/binngo"
+	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/internal/app/server/response"
 )
 
@@ -36,3 +37,57 @@ func (r *infoBaseResponse) MarshalBINN() ([]byte, error) {
 	}
 	return binngo.Marshal(&resp)
 }
+
+const healthOK = "ok"
+
+type healthCheckResult struct {
+	Name   string
+	Status string
+}
+
+// infoDetailsResponse extends the base info with health checks.
+// Each check is presented as a [name, status] pair, the status is "ok" or an error message.
+type infoDetailsResponse struct {
+	infoBaseResponse
+	Health []healthCheckResult
+}
+
+func (r *infoDetailsResponse) MarshalBINN() ([]byte, error) {
+	health := make([]interface{}, 0, len(r.Health))
+	for _, h := range r.Health {
+		health = append(health, []interface{}{h.Name, h.Status})
+	}
+
+	resp := []interface{}{
+		response.StatusOK,
+		r.Uptime,
+		r.WorkingTasks,
+		r.WaitingTasks,
+		r.OnlineServers,
+		health,
+	}
+	return binngo.Marshal(&resp)
+}
+
+// freePortsResponse presents each node IP as an [ip, [[from, to], ...]] pair.
+type freePortsResponse struct {
+	Ports []domain.FreePorts
+}
+
+func (r *freePortsResponse) MarshalBINN() ([]byte, error) {
+	ips := make([]interface{}, 0, len(r.Ports))
+	for _, p := range r.Ports {
+		ranges := make([]interface{}, 0, len(p.Ranges))
+		for _, portRange := range p.Ranges {
+			ranges = append(ranges, []interface{}{portRange.From, portRange.To})
+		}
+
+		ips = append(ips, []interface{}{p.IP, ranges})
+	}
+
+	resp := []interface{}{
+		response.StatusOK,
+		ips,
+	}
+	return binngo.Marshal(&resp)
+}
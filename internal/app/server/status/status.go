@@ -8,36 +8,50 @@ import (
 
 	"github.com/et-nik/binngo/decode"
 	"github.com/gameap/daemon/internal/app/build"
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/internal/app/server/response"
 	"github.com/pkg/errors"
 )
 
-type operationHandlerFunc func(readWriter io.ReadWriter) error
+type operationHandlerFunc func(ctx context.Context, readWriter io.ReadWriter, args []interface{}) error
 
 type Status struct {
 	gdTaskStatsReader domain.GDTaskStatsReader
+	gdTaskCanceler    contracts.GDTaskCanceler
+	portAllocator     contracts.PortAllocator
+	healthCheckers    []contracts.HealthChecker
 	handlers          map[Operation]operationHandlerFunc
 }
 
-func NewStatus(gdTaskStatsReader domain.GDTaskStatsReader) *Status {
+func NewStatus(
+	gdTaskStatsReader domain.GDTaskStatsReader,
+	gdTaskCanceler contracts.GDTaskCanceler,
+	portAllocator contracts.PortAllocator,
+	healthCheckers ...contracts.HealthChecker,
+) *Status {
 	status := &Status{
 		gdTaskStatsReader: gdTaskStatsReader,
+		gdTaskCanceler:    gdTaskCanceler,
+		portAllocator:     portAllocator,
+		healthCheckers:    healthCheckers,
 	}
 
 	status.handlers = map[Operation]operationHandlerFunc{
 		Version:       status.version,
 		StatusBase:    status.statusBase,
 		StatusDetails: status.statusDetails,
+		FreePorts:     status.freePorts,
+		CancelTask:    status.cancelTask,
 	}
 
 	return status
 }
 
-func (s *Status) Handle(_ context.Context, readWriter io.ReadWriter) error {
-	var operation Operation
+func (s *Status) Handle(ctx context.Context, readWriter io.ReadWriter) error {
+	var req request
 	decoder := decode.NewDecoder(readWriter)
-	err := decoder.Decode(&operation)
+	err := decoder.Decode(&req)
 	if errors.Is(err, io.EOF) {
 		return io.EOF
 	}
@@ -48,7 +62,7 @@ func (s *Status) Handle(_ context.Context, readWriter io.ReadWriter) error {
 		})
 	}
 
-	handler, ok := s.handlers[operation]
+	handler, ok := s.handlers[req.Operation]
 	if !ok {
 		return response.WriteResponse(readWriter, response.Response{
 			Code: response.StatusError,
@@ -56,17 +70,17 @@ func (s *Status) Handle(_ context.Context, readWriter io.ReadWriter) error {
 		})
 	}
 
-	return handler(readWriter)
+	return handler(ctx, readWriter, req.Args)
 }
 
-func (s *Status) version(readWriter io.ReadWriter) error {
+func (s *Status) version(_ context.Context, readWriter io.ReadWriter, _ []interface{}) error {
 	return response.WriteResponse(readWriter, &versionResponse{
 		build.Version,
 		build.BuildDate,
 	})
 }
 
-func (s *Status) statusBase(readWriter io.ReadWriter) error {
+func (s *Status) statusBase(_ context.Context, readWriter io.ReadWriter, _ []interface{}) error {
 	stats := s.gdTaskStatsReader.Stats()
 
 	return response.WriteResponse(readWriter, &infoBaseResponse{
@@ -77,9 +91,71 @@ func (s *Status) statusBase(readWriter io.ReadWriter) error {
 	})
 }
 
-func (s *Status) statusDetails(readWriter io.ReadWriter) error {
+func (s *Status) statusDetails(ctx context.Context, readWriter io.ReadWriter, _ []interface{}) error {
+	stats := s.gdTaskStatsReader.Stats()
+
+	health := make([]healthCheckResult, 0, len(s.healthCheckers))
+	for _, checker := range s.healthCheckers {
+		result := healthCheckResult{Name: checker.Name(), Status: healthOK}
+
+		err := checker.Check(ctx)
+		if err != nil {
+			result.Status = err.Error()
+		}
+
+		health = append(health, result)
+	}
+
+	return response.WriteResponse(readWriter, &infoDetailsResponse{
+		infoBaseResponse: infoBaseResponse{
+			Uptime:        time.Since(domain.StartTime).Truncate(1 * time.Second).String(),
+			WorkingTasks:  strconv.Itoa(stats.WorkingCount),
+			WaitingTasks:  strconv.Itoa(stats.WaitingCount),
+			OnlineServers: "-",
+		},
+		Health: health,
+	})
+}
+
+func (s *Status) freePorts(ctx context.Context, readWriter io.ReadWriter, args []interface{}) error {
+	req, err := newFreePortsRequest(args)
+	if err != nil {
+		return response.WriteResponse(readWriter, response.Response{
+			Code: response.StatusError,
+			Info: "Failed to decode message",
+		})
+	}
+
+	ports, err := s.portAllocator.FreePorts(ctx, req.IP, req.From, req.To)
+	if err != nil {
+		return response.WriteResponse(readWriter, response.Response{
+			Code: response.StatusError,
+			Info: err.Error(),
+		})
+	}
+
+	return response.WriteResponse(readWriter, &freePortsResponse{Ports: ports})
+}
+
+func (s *Status) cancelTask(ctx context.Context, readWriter io.ReadWriter, args []interface{}) error {
+	taskID, err := newCancelTaskRequest(args)
+	if err != nil {
+		return response.WriteResponse(readWriter, response.Response{
+			Code: response.StatusError,
+			Info: "Failed to decode message",
+		})
+	}
+
+	err = s.gdTaskCanceler.CancelTask(ctx, taskID)
+	if err != nil {
+		return response.WriteResponse(readWriter, response.Response{
+			Code: response.StatusError,
+			Info: err.Error(),
+		})
+	}
+
 	return response.WriteResponse(readWriter, response.Response{
-		Code: response.StatusError,
-		Info: "Not implemented",
+		Code: response.StatusOK,
+		Info: "Task cancellation requested",
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/domain"
 	commands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/metrics"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -30,25 +31,38 @@ const (
 	skipMaxCount = 20
 )
 
+type runningServersMigrator interface {
+	MigrateRunning(ctx context.Context, servers []*domain.Server) map[int]struct{}
+}
+
 type ServersLoop struct {
 	cfg                  *config.Config
 	serverRepo           domain.ServerRepository
 	serverCommandFactory *commands.ServerCommandFactory
+	migrator             runningServersMigrator
 
 	skipCounter skipCounter
+
+	// The game servers are migrated from the previous process managers before the first autostart
+	migrated bool
+	// The game servers left running under the previous process managers, they aren't started again
+	leftRunning map[int]struct{}
 }
 
 func NewServersLoop(
 	serverRepo domain.ServerRepository,
 	serverCommandFactory *commands.ServerCommandFactory,
+	migrator runningServersMigrator,
 	cfg *config.Config,
 ) *ServersLoop {
 	return &ServersLoop{
 		cfg:                  cfg,
 		serverRepo:           serverRepo,
 		serverCommandFactory: serverCommandFactory,
+		migrator:             migrator,
 
 		skipCounter: skipCounter{},
+		leftRunning: map[int]struct{}{},
 	}
 }
 
@@ -71,11 +85,23 @@ func (l *ServersLoop) loop(ctx context.Context) error {
 
 func (l *ServersLoop) tick(ctx context.Context) {
 	ids, err := l.serverRepo.IDs(ctx)
+	if errors.Is(err, domain.ErrAPIUnavailable) {
+		logger.Debug(ctx, "API is unavailable, servers loop is paused")
+		return
+	}
 	if err != nil {
 		log.Error(err)
 		return
 	}
 
+	if !l.migrated {
+		err = l.migrateRunning(ctx, ids)
+		if err != nil {
+			logger.Error(ctx, err)
+			return
+		}
+	}
+
 	for i := range ids {
 		ctxWithServer := logger.WithLogger(ctx, logger.WithField(ctx, "gameServerID", ids[i]))
 
@@ -101,6 +127,35 @@ func (l *ServersLoop) tick(ctx context.Context) {
 	}
 }
 
+// migrateRunning moves the game servers running under the previous process managers to the current one,
+// otherwise the current process manager doesn't find them and autostart starts the second instances.
+func (l *ServersLoop) migrateRunning(ctx context.Context, ids []int) error {
+	if l.migrator == nil {
+		l.migrated = true
+
+		return nil
+	}
+
+	servers := make([]*domain.Server, 0, len(ids))
+	for _, id := range ids {
+		server, err := l.serverRepo.FindByID(ctx, id)
+		if err != nil {
+			return errors.WithMessage(err, "failed to find game server to migrate")
+		}
+
+		if server == nil || server.InstallationStatus() != domain.ServerInstalled {
+			continue
+		}
+
+		servers = append(servers, server)
+	}
+
+	l.leftRunning = l.migrator.MigrateRunning(ctx, servers)
+	l.migrated = true
+
+	return nil
+}
+
 type pipelineHandler func(ctx context.Context, server *domain.Server) error
 
 func (l *ServersLoop) pipeline(ctx context.Context, server *domain.Server, handlers []pipelineHandler) error {
@@ -129,7 +184,28 @@ func (l *ServersLoop) checkStatus(ctx context.Context, server *domain.Server) er
 		return errors.WithMessage(err, "failed to execute status command")
 	}
 
-	server.SetStatus(statusCmd.Result() == commands.SuccessResult)
+	wasActive := server.IsActive()
+	active := statusCmd.Result() == commands.SuccessResult
+
+	if _, ok := l.leftRunning[server.ID()]; ok {
+		if active {
+			// The game server is migrated by the pmmigrate task
+			delete(l.leftRunning, server.ID())
+		} else {
+			// The game server is running under the previous process manager
+			active = true
+		}
+	}
+
+	server.SetStatus(active)
+
+	// Stop commands turn off the current autostart, so a stopped server with enabled autostart has crashed
+	crashed := wasActive && !active && server.AutoStart()
+	if crashed {
+		logger.Warn(ctx, "Game server process is not active")
+	}
+
+	metrics.ObserveServerStatus(server.ID(), server.WorkDir(l.cfg), active, crashed)
 
 	return nil
 }
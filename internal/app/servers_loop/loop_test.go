@@ -0,0 +1,150 @@
+package serversloop
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProcessManager is the current process manager (systemd), it keeps the running game servers by ID.
+type fakeProcessManager struct {
+	running map[int]bool
+	started []int
+}
+
+func (pm *fakeProcessManager) Install(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Uninstall(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Start(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.started = append(pm.started, server.ID())
+	pm.running[server.ID()] = true
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Stop(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.running[server.ID()] = false
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Restart(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Status(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	if pm.running[server.ID()] {
+		return domain.SuccessResult, nil
+	}
+
+	return domain.ErrorResult, nil
+}
+
+func (pm *fakeProcessManager) GetOutput(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) SendInput(
+	_ context.Context, _ string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+// fakeMigrator moves the game servers running under tmux to the current process manager,
+// the stuck game servers fail to migrate and are left under tmux.
+type fakeMigrator struct {
+	target *fakeProcessManager
+	tmux   map[int]bool
+	stuck  map[int]bool
+}
+
+func (m *fakeMigrator) MigrateRunning(ctx context.Context, servers []*domain.Server) map[int]struct{} {
+	left := map[int]struct{}{}
+	for _, server := range servers {
+		if !m.tmux[server.ID()] {
+			continue
+		}
+
+		if m.stuck[server.ID()] {
+			left[server.ID()] = struct{}{}
+
+			continue
+		}
+
+		m.tmux[server.ID()] = false
+		_, _ = m.target.Start(ctx, server, io.Discard)
+	}
+
+	return left
+}
+
+func TestServersLoop_ServersRunningUnderTmux_NotStartedAgainUnderSystemd(t *testing.T) {
+	migrated := givenAutostartServer(1)
+	stuck := givenAutostartServer(2)
+	stopped := givenAutostartServer(3)
+	systemd := &fakeProcessManager{running: map[int]bool{}}
+	migrator := &fakeMigrator{
+		target: systemd,
+		tmux:   map[int]bool{migrated.ID(): true, stuck.ID(): true},
+		stuck:  map[int]bool{stuck.ID(): true},
+	}
+	loop := givenServersLoop(t, systemd, migrator, migrated, stuck, stopped)
+
+	loop.tick(context.Background())
+	loop.tick(context.Background())
+
+	assert.ElementsMatch(t, []int{migrated.ID(), stopped.ID()}, systemd.started)
+	assert.True(t, migrated.IsActive())
+	assert.True(t, stuck.IsActive())
+	assert.True(t, stopped.IsActive())
+}
+
+func givenAutostartServer(id int) *domain.Server {
+	return domaintest.NewServer(
+		domaintest.WithID(id),
+		domaintest.WithSettings(map[string]string{"autostart": "1"}),
+	)
+}
+
+func givenServersLoop(
+	t *testing.T,
+	processManager *fakeProcessManager,
+	migrator runningServersMigrator,
+	servers ...*domain.Server,
+) *ServersLoop {
+	t.Helper()
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.Ports.SkipStartCheck = true
+	executor := components.NewExecutor()
+	serverRepo := mocks.NewServerRepository()
+	serverRepo.Set(servers)
+
+	factory := gameservercommands.NewFactory(
+		cfg,
+		serverRepo,
+		executor,
+		processManager,
+		steamcmd.NewSteamCMD(cfg, executor),
+		gamecache.NewCache(cfg),
+		verification.NewVerifier(cfg),
+	)
+
+	return NewServersLoop(serverRepo, factory, migrator, cfg)
+}
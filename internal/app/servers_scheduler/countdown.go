@@ -0,0 +1,146 @@
+package serversscheduler
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+)
+
+// abortAnnouncementTimeout limits the aborted announcement sent after the scheduler is stopped.
+const abortAnnouncementTimeout = 5 * time.Second
+
+type countdown struct {
+	executeDate time.Time
+	cancel      context.CancelFunc
+	// The countdown is ended and the task is executing, it can't be aborted anymore
+	ended atomic.Bool
+}
+
+// announcementLead returns the longest announcement interval of the task, zero means the task isn't announced.
+// The tasks skipped with the players online aren't announced, they're executed only on the empty game servers.
+func (s *Scheduler) announcementLead(task *domain.ServerTask) time.Duration {
+	if s.announcer == nil || task.SkipIfPlayersOnline() {
+		return 0
+	}
+
+	return s.config.AnnouncementLead(string(task.Command()))
+}
+
+// startCountdown announces the task at the configured intervals and executes it when the countdown ends.
+func (s *Scheduler) startCountdown(ctx context.Context, task *domain.ServerTask) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c := &countdown{
+		executeDate: task.ExecuteDate(),
+		cancel:      cancel,
+	}
+	s.countdowns.Store(task.ID(), c)
+
+	go func() {
+		defer s.countdowns.Delete(task.ID())
+		defer cancel()
+
+		if !s.countdown(ctx, task) {
+			logger.Info(ctx, "Server task countdown is aborted")
+			s.announceAborted(ctx, task)
+			return
+		}
+
+		c.ended.Store(true)
+		s.runTask(ctx, task)
+	}()
+}
+
+// countdown waits until the execute date and announces the remaining time at each interval.
+// If the countdown is started late, the task is postponed until the whole countdown ends.
+// It returns false if the countdown is aborted.
+func (s *Scheduler) countdown(ctx context.Context, task *domain.ServerTask) bool {
+	intervals := append([]time.Duration{}, s.config.Announcements.Intervals...)
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i] > intervals[j]
+	})
+
+	deadline := task.ExecuteDate()
+	if earliest := time.Now().Add(longestInterval(intervals)); deadline.Before(earliest) {
+		deadline = earliest
+	}
+
+	for _, interval := range intervals {
+		if !sleepUntil(ctx, deadline.Add(-interval)) {
+			return false
+		}
+
+		err := s.announcer.AnnounceCountdown(ctx, task.Server(), string(task.Command()), interval)
+		if err != nil {
+			logger.Logger(ctx).WithError(err).Warn("Failed to announce server task")
+		}
+	}
+
+	return sleepUntil(ctx, deadline)
+}
+
+func (s *Scheduler) announceAborted(ctx context.Context, task *domain.ServerTask) {
+	// The scheduler context may be done already
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortAnnouncementTimeout)
+	defer cancel()
+
+	err := s.announcer.AnnounceAborted(ctx, task.Server(), string(task.Command()))
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Warn("Failed to announce aborted server task")
+	}
+}
+
+// abortChangedCountdowns aborts the countdowns of the tasks removed or rescheduled in the panel.
+// It returns the IDs of the tasks with the countdowns in progress.
+func (s *Scheduler) abortChangedCountdowns(tasks []*domain.ServerTask) map[int]struct{} {
+	loaded := make(map[int]*domain.ServerTask, len(tasks))
+	for _, t := range tasks {
+		loaded[t.ID()] = t
+	}
+
+	inProgress := map[int]struct{}{}
+
+	s.countdowns.Range(func(key, value any) bool {
+		id := key.(int)
+		c := value.(*countdown)
+
+		t, ok := loaded[id]
+		if !c.ended.Load() && (!ok || !t.ExecuteDate().Equal(c.executeDate)) {
+			c.cancel()
+			return true
+		}
+
+		inProgress[id] = struct{}{}
+
+		return true
+	})
+
+	return inProgress
+}
+
+func longestInterval(intervals []time.Duration) time.Duration {
+	var longest time.Duration
+	for _, interval := range intervals {
+		if interval > longest {
+			longest = interval
+		}
+	}
+
+	return longest
+}
+
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -2,12 +2,35 @@ package serversscheduler
 
 import (
 	"sync"
+	"time"
 
 	"github.com/emirpasic/gods/trees/btree"
 	"github.com/emirpasic/gods/utils"
 	"github.com/gameap/daemon/internal/app/domain"
 )
 
+// taskKey orders the tasks by the execute date, the tasks with the same date
+// (e.g. the same cron expression for several servers) are ordered by ID.
+type taskKey struct {
+	executeDate time.Time
+	id          int
+}
+
+func taskKeyComparator(a, b interface{}) int {
+	ka := a.(taskKey)
+	kb := b.(taskKey)
+
+	if c := utils.TimeComparator(ka.executeDate, kb.executeDate); c != 0 {
+		return c
+	}
+
+	return utils.IntComparator(ka.id, kb.id)
+}
+
+func keyOf(task *domain.ServerTask) taskKey {
+	return taskKey{executeDate: task.ExecuteDate(), id: task.ID()}
+}
+
 type taskQueue struct {
 	tree  *btree.Tree
 	mutex *sync.Mutex
@@ -15,7 +38,7 @@ type taskQueue struct {
 
 func newTaskQueue() *taskQueue {
 	return &taskQueue{
-		tree:  btree.NewWith(3, utils.TimeComparator),
+		tree:  btree.NewWith(3, taskKeyComparator),
 		mutex: &sync.Mutex{},
 	}
 }
@@ -24,28 +47,36 @@ func (q *taskQueue) Exists(task *domain.ServerTask) bool {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	_, found := q.tree.Get(task.ExecuteDate())
-	q.tree.Values()
-	return found
+	return q.find(task.ID()) != nil
 }
 
 func (q *taskQueue) Replace(task *domain.ServerTask) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if t := q.find(task.ID()); t != nil {
+		q.tree.Remove(keyOf(t))
+		q.tree.Put(keyOf(task), task)
+	}
+}
+
+func (q *taskQueue) find(id int) *domain.ServerTask {
 	for _, v := range q.tree.Values() {
 		t := v.(*domain.ServerTask)
 
-		if t.ID() == task.ID() {
-			q.Remove(t)
-			q.Put(task)
-			return
+		if t.ID() == id {
+			return t
 		}
 	}
+
+	return nil
 }
 
 func (q *taskQueue) Put(task *domain.ServerTask) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	q.tree.Put(task.ExecuteDate(), task)
+	q.tree.Put(keyOf(task), task)
 }
 
 func (q *taskQueue) Pop() *domain.ServerTask {
@@ -62,11 +93,29 @@ func (q *taskQueue) Pop() *domain.ServerTask {
 	return task
 }
 
+// Due returns the tasks to be executed before the time in the execution order.
+func (q *taskQueue) Due(before time.Time) []*domain.ServerTask {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var tasks []*domain.ServerTask
+	for it := q.tree.Iterator(); it.Next(); {
+		task := it.Value().(*domain.ServerTask)
+		if !task.ExecuteDate().Before(before) {
+			break
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
 func (q *taskQueue) Remove(task *domain.ServerTask) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	q.tree.Remove(task.ExecuteDate())
+	q.tree.Remove(keyOf(task))
 }
 
 func (q *taskQueue) Empty() bool {
@@ -75,3 +124,10 @@ func (q *taskQueue) Empty() bool {
 
 	return q.tree.Empty()
 }
+
+func (q *taskQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.tree.Size()
+}
@@ -89,3 +89,19 @@ func TestPriorityQueue_EmptyValue_ExpectNil(t *testing.T) {
 
 	assert.Nil(t, task)
 }
+
+func TestPriorityQueue_Due(t *testing.T) {
+	executeDate := time.Now().Add(time.Minute)
+	task1 := domain.NewServerTask(1, domain.ServerTaskRestart, nil, 0, time.Hour, 0, executeDate)
+	task2 := domain.NewServerTask(2, domain.ServerTaskRestart, nil, 0, time.Hour, 0, executeDate)
+	task3 := domain.NewServerTask(3, domain.ServerTaskRestart, nil, 0, time.Hour, 0, executeDate.Add(time.Hour))
+	q := newTaskQueue()
+	q.Put(task3)
+	q.Put(task2)
+	q.Put(task1)
+
+	tasks := q.Due(executeDate.Add(time.Second))
+
+	assert.Equal(t, []*domain.ServerTask{task1, task2}, tasks)
+	assert.Equal(t, 3, q.Len())
+}
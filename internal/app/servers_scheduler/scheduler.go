@@ -2,12 +2,18 @@ package serversscheduler
 
 import (
 	"context"
+	"math/rand"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/watchdog"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -19,76 +25,169 @@ type Scheduler struct {
 	config               *config.Config
 	repository           domain.ServerTaskRepository
 	serverCommandFactory *gameservercommands.ServerCommandFactory
+	watchdog             *watchdog.Watchdog
+	playersCounter       contracts.PlayersCounter
+	announcer            contracts.ServerAnnouncer
 
 	// Runtime, state
-	mutex       *sync.Mutex
-	lastUpdated time.Time
-	queue       *taskQueue
+	mutex         *sync.Mutex
+	lastUpdated   time.Time
+	queue         *taskQueue
+	refresh       chan struct{}
+	pushConnected atomic.Bool
+	countdowns    sync.Map // Task ID => *countdown
+	serverLocks   sync.Map // Game server ID => *sync.Mutex
 }
 
 func NewScheduler(
 	config *config.Config,
 	repository domain.ServerTaskRepository,
 	serverCommandFactory *gameservercommands.ServerCommandFactory,
+	watchdog *watchdog.Watchdog,
+	playersCounter contracts.PlayersCounter,
+	announcer contracts.ServerAnnouncer,
 ) *Scheduler {
 	return &Scheduler{
 		config:               config,
 		repository:           repository,
 		serverCommandFactory: serverCommandFactory,
+		watchdog:             watchdog,
+		playersCounter:       playersCounter,
+		announcer:            announcer,
 		mutex:                &sync.Mutex{},
 		queue:                newTaskQueue(),
+		refresh:              make(chan struct{}, 1),
 	}
 }
 
 func (s *Scheduler) Run(ctx context.Context) error {
 	err := s.updateTasksIfNeeded(ctx)
-	if err != nil {
-		logger.Logger(ctx).WithError(err).Warn("Failed to update game server tasks")
-	}
+	s.logUpdateError(ctx, err)
 
 	for {
+		s.runDueTasks(ctx)
+
 		select {
 		case <-(ctx).Done():
 			return nil
-		default:
-			s.runNext(ctx)
-
+		case <-s.refresh:
+			err = s.updateTasks(ctx)
+		case <-time.After(updateTimeout):
 			err = s.updateTasksIfNeeded(ctx)
-			if err != nil {
-				logger.Logger(ctx).WithError(err).Warn("Failed to update game server tasks")
-			}
-
-			time.Sleep(updateTimeout)
 		}
+
+		s.logUpdateError(ctx, err)
 	}
 }
 
-func (s *Scheduler) runNext(ctx context.Context) {
-	task := s.queue.Pop()
-	if task == nil {
+func (s *Scheduler) logUpdateError(ctx context.Context, err error) {
+	switch {
+	case err == nil:
 		return
+	case errors.Is(err, domain.ErrAPIUnavailable):
+		logger.Debug(ctx, "API is unavailable, game server tasks update is paused")
+	default:
+		logger.Logger(ctx).WithError(err).Warn("Failed to update game server tasks")
 	}
+}
 
-	ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
-		"serverTaskID": task.ID(),
-		"gameServerID": task.Server().ID(),
-	}))
+// Refresh requests the tasks to be reloaded from the API without waiting for the update period.
+func (s *Scheduler) Refresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// SetPushConnected switches the scheduler to the fallback polling period
+// while task changes are delivered through the push channel.
+func (s *Scheduler) SetPushConnected(connected bool) {
+	s.pushConnected.Store(connected)
+}
+
+// runDueTasks executes the due tasks. The announced tasks are taken from the queue
+// before the countdown, they are executed when the countdown ends.
+func (s *Scheduler) runDueTasks(ctx context.Context) {
+	now := time.Now()
+
+	for _, task := range s.queue.Due(now.Add(longestInterval(s.config.Announcements.Intervals) + updateTimeout)) {
+		taskCtx := logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
+			"serverTaskID": task.ID(),
+			"gameServerID": task.Server().ID(),
+		}))
+
+		lead := s.announcementLead(task)
+		if lead > 0 {
+			// The countdown is started a bit earlier, so the first announcement isn't late
+			lead += updateTimeout
+		}
+
+		if !task.ExecuteDate().Add(-lead).Before(now) {
+			continue
+		}
 
-	if task.ExecuteDate().Before(time.Now()) {
 		s.queue.Remove(task)
 
-		if task.CanExecute() {
-			s.executeTask(ctx, task)
-			s.prolongTask(ctx, task)
+		if !task.CanExecute() {
+			continue
+		}
+
+		if lead > 0 {
+			s.startCountdown(taskCtx, task)
+			continue
 		}
+
+		s.runTask(taskCtx, task)
+	}
+}
+
+func (s *Scheduler) runTask(ctx context.Context, task *domain.ServerTask) {
+	executed := false
+	if !s.playersOnline(ctx, task) {
+		s.executeTask(ctx, task)
+		executed = true
 	}
+
+	s.prolongTask(ctx, task, executed)
+}
+
+// playersOnline checks if the task should be skipped because of the players on the game server.
+// The task isn't skipped if the game server doesn't answer the query.
+func (s *Scheduler) playersOnline(ctx context.Context, task *domain.ServerTask) bool {
+	if !task.SkipIfPlayersOnline() || s.playersCounter == nil {
+		return false
+	}
+
+	players, err := s.playersCounter.OnlinePlayers(ctx, task.Server())
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Debug("Failed to query game server players, executing server task")
+		return false
+	}
+
+	if players > 0 {
+		logger.Logger(ctx).WithField("players", players).Info("Players are online, server task is skipped")
+		return true
+	}
+
+	return false
 }
 
 func (s *Scheduler) executeTask(ctx context.Context, task *domain.ServerTask) {
+	// The tasks with the countdown are executed in their own goroutines,
+	// the tasks of the same game server must not be executed concurrently
+	lock := s.serverLock(task.Server().ID())
+	lock.Lock()
+	defer lock.Unlock()
+
 	cmd := s.serverCommandFactory.LoadServerCommand(taskCommandToServerCommand(task.Command()), task.Server())
 
-	err := cmd.Execute(ctx, task.Server())
+	start := time.Now()
+
+	err := s.watchdog.Run(ctx, stuckTaskName(task), s.taskTimeout(task), func(ctx context.Context) error {
+		return cmd.Execute(ctx, task.Server())
+	})
 	if err != nil {
+		metrics.ObserveServerTask(task.Command(), false, time.Since(start))
 		logger.Logger(ctx).WithError(err).Warn("Failed to execute server task")
 		s.saveFailInfo(ctx, task, err.Error())
 		return
@@ -97,14 +196,54 @@ func (s *Scheduler) executeTask(ctx context.Context, task *domain.ServerTask) {
 	task.Server().NoticeTaskCompleted()
 
 	result := cmd.Result()
+	metrics.ObserveServerTask(task.Command(), result != gameservercommands.ErrorResult, time.Since(start))
+
 	if result == gameservercommands.ErrorResult {
 		s.saveFailInfo(ctx, task, string(cmd.ReadOutput()))
 		return
 	}
 }
 
-func (s *Scheduler) prolongTask(ctx context.Context, task *domain.ServerTask) {
-	task.IncreaseCountersAndTime()
+func (s *Scheduler) serverLock(serverID int) *sync.Mutex {
+	lock, _ := s.serverLocks.LoadOrStore(serverID, &sync.Mutex{})
+
+	return lock.(*sync.Mutex)
+}
+
+func (s *Scheduler) taskTimeout(task *domain.ServerTask) time.Duration {
+	if task.Timeout() > 0 {
+		return task.Timeout()
+	}
+
+	return s.config.TaskTimeout(string(timeoutCommandMap[task.Command()]))
+}
+
+func stuckTaskName(task *domain.ServerTask) string {
+	return "server task #" + strconv.Itoa(task.ID()) + " (" + string(task.Command()) + ")"
+}
+
+// QueueLen returns the number of scheduled tasks.
+func (s *Scheduler) QueueLen() int {
+	return s.queue.Len()
+}
+
+func (s *Scheduler) prolongTask(ctx context.Context, task *domain.ServerTask, executed bool) {
+	// The skipped runs aren't counted
+	if executed {
+		task.IncreaseCounter()
+	}
+
+	if schedule := task.Schedule(); schedule != nil {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Warn(ctx, "Server task schedule never matches, the task won't be executed anymore")
+			return
+		}
+
+		task.Reschedule(next.Add(randomJitter(task.Jitter())))
+	} else {
+		task.Prolong()
+	}
 
 	err := s.repository.Save(ctx, task)
 	if err != nil {
@@ -114,6 +253,17 @@ func (s *Scheduler) prolongTask(ctx context.Context, task *domain.ServerTask) {
 	s.queue.Put(task)
 }
 
+// randomJitter returns the random delay up to the jitter,
+// so the tasks with the same schedule don't hit the host at the same moment.
+func randomJitter(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+
+	//nolint:gosec
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
 func (s *Scheduler) saveFailInfo(ctx context.Context, task *domain.ServerTask, errorText string) {
 	err := s.repository.Fail(ctx, task, []byte(errorText))
 	if err != nil {
@@ -121,24 +271,49 @@ func (s *Scheduler) saveFailInfo(ctx context.Context, task *domain.ServerTask, e
 	}
 }
 
+func (s *Scheduler) updatePeriod() time.Duration {
+	if s.pushConnected.Load() {
+		return s.config.Push.FallbackPeriod
+	}
+
+	return updateTimeout
+}
+
 func (s *Scheduler) updateTasksIfNeeded(ctx context.Context) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if time.Since(s.lastUpdated) <= updateTimeout {
+	if time.Since(s.lastUpdated) <= s.updatePeriod() {
 		return nil
 	}
 
+	return s.loadTasks(ctx)
+}
+
+func (s *Scheduler) updateTasks(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.loadTasks(ctx)
+}
+
+func (s *Scheduler) loadTasks(ctx context.Context) error {
 	tasks, err := s.repository.Find(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "failed to get server tasks")
 	}
 
+	inCountdown := s.abortChangedCountdowns(tasks)
+
 	for _, t := range tasks {
 		if !t.CanExecute() {
 			continue
 		}
 
+		if _, ok := inCountdown[t.ID()]; ok {
+			continue
+		}
+
 		if s.queue.Exists(t) {
 			s.queue.Replace(t)
 		}
@@ -159,6 +334,16 @@ var commandMap = map[domain.ServerTaskCommand]domain.ServerCommand{
 	domain.ServerTaskReinstall: domain.Reinstall,
 }
 
+// timeoutCommandMap maps the server task commands to the gdtask commands,
+// the timeouts are configured by the gdtask commands.
+var timeoutCommandMap = map[domain.ServerTaskCommand]domain.GDTaskCommand{
+	domain.ServerTaskStart:     domain.GDTaskGameServerStart,
+	domain.ServerTaskStop:      domain.GDTaskGameServerStop,
+	domain.ServerTaskRestart:   domain.GDTaskGameServerRestart,
+	domain.ServerTaskUpdate:    domain.GDTaskGameServerUpdate,
+	domain.ServerTaskReinstall: domain.GDTaskGameServerReinstall,
+}
+
 func taskCommandToServerCommand(cmd domain.ServerTaskCommand) domain.ServerCommand {
 	return commandMap[cmd]
 }
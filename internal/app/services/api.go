@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/metrics"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
@@ -28,6 +34,12 @@ type APIClient struct {
 	innerClient *resty.Client
 	cfg         *config.Config
 
+	// streamClient is used for long-lived requests, it has no timeout
+	streamClient *http.Client
+
+	breaker *circuitBreaker
+	stats   *apiStats
+
 	// runtime
 	tokenMutex    *lock.CASMutex
 	apiServerTime time.Time
@@ -39,6 +51,14 @@ func NewAPICaller(ctx context.Context, cfg *config.Config, client *resty.Client)
 		innerClient: client,
 		cfg:         cfg,
 		tokenMutex:  lock.NewCASMutex(),
+		streamClient: &http.Client{
+			Transport: client.GetClient().Transport,
+		},
+		breaker: newCircuitBreaker(
+			cfg.API.CircuitBreaker.FailureThreshold,
+			cfg.API.CircuitBreaker.OpenTimeout,
+		),
+		stats: newAPIStats(),
 	}
 
 	err := api.actualizeToken(ctx)
@@ -50,12 +70,117 @@ func (c *APIClient) Request(ctx context.Context, request domain.APIRequest) (con
 	return c.request(ctx, request, 0)
 }
 
-//nolint:funlen
+// Available reports whether API requests are not paused by the circuit breaker.
+func (c *APIClient) Available() bool {
+	return !c.breaker.IsOpen()
+}
+
+// Stats returns request statistics per API endpoint.
+func (c *APIClient) Stats() []APIEndpointStats {
+	return c.stats.Snapshot()
+}
+
 func (c *APIClient) request(
 	ctx context.Context,
 	request domain.APIRequest,
 	deep uint8,
 ) (contracts.APIResponse, error) {
+	if !c.breaker.Allow() {
+		return nil, domain.ErrAPIUnavailable
+	}
+
+	response, err := c.requestWithRetries(ctx, request)
+
+	if isTransientFailure(response, err) {
+		if c.breaker.Failure() {
+			logger.Warn(ctx, "API is unavailable, API requests are paused")
+			metrics.SetAPICircuitOpen(true)
+		}
+	} else if c.breaker.Success() {
+		logger.Info(ctx, "API is available, API requests are resumed")
+		metrics.SetAPICircuitOpen(false)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode() == http.StatusUnauthorized && deep < maxActualizeCount {
+		logger.Warn(ctx, "invalid token, actualizing token")
+		err = c.actualizeToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.request(ctx, request, deep+1)
+	}
+
+	return response, nil
+}
+
+func (c *APIClient) requestWithRetries(ctx context.Context, request domain.APIRequest) (*resty.Response, error) {
+	endpoint := request.Method + " " + request.URL
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+
+		response, err := c.do(ctx, request)
+
+		retry := attempt < c.cfg.API.RetryCount &&
+			ctx.Err() == nil &&
+			isRetryable(request.Method, response, err)
+
+		c.stats.Observe(endpoint, response.StatusCode(), isTransientFailure(response, err), retry, time.Since(start))
+
+		if !retry {
+			return response, err
+		}
+
+		wait := c.retryWaitTime(attempt, response)
+
+		logger.WithFields(ctx, logrus.Fields{
+			"method":         request.Method,
+			"url":            request.URL,
+			"responseStatus": response.StatusCode(),
+			"attempt":        attempt + 1,
+			"wait":           wait,
+		}).Debug("retrying api request")
+
+		select {
+		case <-ctx.Done():
+			return response, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryWaitTime returns the exponential backoff with jitter
+// or the Retry-After header value if the API sets it.
+func (c *APIClient) retryWaitTime(attempt int, response *resty.Response) time.Duration {
+	maxWait := c.cfg.API.RetryMaxWaitTime
+
+	if retryAfter, ok := parseRetryAfter(response.Header().Get("Retry-After")); ok {
+		if maxWait > 0 && retryAfter > maxWait {
+			return maxWait
+		}
+
+		return retryAfter
+	}
+
+	wait := c.cfg.API.RetryWaitTime << attempt
+	if wait <= 0 || (maxWait > 0 && wait > maxWait) {
+		wait = maxWait
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+
+	//nolint:gosec
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+//nolint:funlen
+func (c *APIClient) do(ctx context.Context, request domain.APIRequest) (*resty.Response, error) {
 	restyRequest := c.innerClient.R()
 
 	restyRequest.SetHeader("Content-Type", "application/json")
@@ -117,7 +242,11 @@ func (c *APIClient) request(
 
 		response, err = restyRequest.Patch(request.URL)
 	default:
-		return nil, errInvalidRequestMethod
+		return &resty.Response{}, errInvalidRequestMethod
+	}
+
+	if response == nil {
+		response = &resty.Response{}
 	}
 
 	l.WithFields(logrus.Fields{
@@ -126,20 +255,142 @@ func (c *APIClient) request(
 		"responseTime":   time.Since(start),
 	}).Debug("api request")
 
+	return response, err
+}
+
+// isRetryable reports whether the request may be repeated. Requests to non-idempotent methods are repeated
+// only if the API explicitly reports that the request was not processed.
+func isRetryable(method string, response *resty.Response, err error) bool {
+	if errors.Is(err, errInvalidRequestMethod) {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch response.StatusCode() {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method == http.MethodGet || method == http.MethodPut
+	}
+
+	return false
+}
+
+// isTransientFailure reports whether the request failed because the API is down or overloaded.
+func isTransientFailure(response *resty.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, errInvalidRequestMethod)
+	}
+
+	return response.StatusCode() == http.StatusTooManyRequests ||
+		response.StatusCode() >= http.StatusInternalServerError
+}
+
+// parseRetryAfter parses Retry-After header value in seconds or HTTP date format.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait, true
+}
+
+// Stream makes a long-lived GET request to the API and returns the response body.
+// The caller must close the returned body.
+func (c *APIClient) Stream(ctx context.Context, request domain.APIRequest) (io.ReadCloser, error) {
+	return c.stream(ctx, request, 0)
+}
+
+func (c *APIClient) stream(
+	ctx context.Context,
+	request domain.APIRequest,
+	deep uint8,
+) (io.ReadCloser, error) {
+	if request.Method != "" && request.Method != http.MethodGet {
+		return nil, errInvalidRequestMethod
+	}
+
+	requestURL := request.URL
+	for key, value := range request.PathParams {
+		requestURL = strings.ReplaceAll(requestURL, "{"+key+"}", url.PathEscape(value))
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, c.innerClient.BaseURL+requestURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.StatusCode() == http.StatusUnauthorized && deep < maxActualizeCount {
+	if len(request.QueryParams) > 0 {
+		query := httpRequest.URL.Query()
+		for key, value := range request.QueryParams {
+			query.Set(key, value)
+		}
+		httpRequest.URL.RawQuery = query.Encode()
+	}
+
+	for key, values := range c.innerClient.Header {
+		for _, v := range values {
+			httpRequest.Header.Add(key, v)
+		}
+	}
+	for key, values := range request.Header {
+		for _, v := range values {
+			httpRequest.Header.Add(key, v)
+		}
+	}
+	httpRequest.Header.Set("X-Auth-Token", c.token)
+
+	response, err := c.streamClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger(ctx).WithFields(logrus.Fields{
+		"requestURL":     httpRequest.URL.String(),
+		"responseStatus": response.StatusCode,
+	}).Debug("api stream request")
+
+	if response.StatusCode == http.StatusUnauthorized && deep < maxActualizeCount {
+		_ = response.Body.Close()
+
 		logger.Warn(ctx, "invalid token, actualizing token")
 		err = c.actualizeToken(ctx)
 		if err != nil {
 			return nil, err
 		}
-		return c.request(ctx, request, deep+1)
+		return c.stream(ctx, request, deep+1)
 	}
 
-	return response, nil
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return nil, domain.NewErrInvalidResponseFromAPI(response.StatusCode, body)
+	}
+
+	return response.Body, nil
 }
 
 func (c *APIClient) actualizeToken(ctx context.Context) error {
@@ -156,7 +407,24 @@ func (c *APIClient) actualizeToken(ctx context.Context) error {
 	request.SetHeader("Content-Type", "application/json")
 	request.SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.cfg.APIKey))
 
-	response, err := request.Get("/gdaemon_api/get_token")
+	var response *resty.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		response, err = request.Get("/gdaemon_api/get_token")
+		if response == nil {
+			response = &resty.Response{}
+		}
+
+		if attempt >= c.cfg.API.RetryCount || ctx.Err() != nil || !isRetryable(http.MethodGet, response, err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.retryWaitTime(attempt, response)):
+		}
+	}
 	if err != nil {
 		return errors.WithMessage(err, "failed to get gdaemon API token")
 	}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/metrics"
+)
+
+// APIEndpointStats contains the request statistics of the single API endpoint.
+// The endpoint is the request method and the URL template, e.g. "GET /gdaemon_api/servers/{id}".
+type APIEndpointStats struct {
+	StatusCodes   map[int]uint64
+	Endpoint      string
+	Requests      uint64
+	Errors        uint64
+	Retries       uint64
+	TotalDuration time.Duration
+}
+
+type apiStats struct {
+	mu        sync.Mutex
+	endpoints map[string]*APIEndpointStats
+}
+
+func newAPIStats() *apiStats {
+	return &apiStats{
+		endpoints: map[string]*APIEndpointStats{},
+	}
+}
+
+// Observe records the result of the request attempt. Status code is 0 if the request failed without response.
+func (s *apiStats) Observe(endpoint string, statusCode int, failed bool, retry bool, duration time.Duration) {
+	metrics.ObserveAPIRequest(endpoint, statusCode, retry, duration)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.endpoints[endpoint]
+	if !ok {
+		stats = &APIEndpointStats{
+			Endpoint:    endpoint,
+			StatusCodes: map[int]uint64{},
+		}
+		s.endpoints[endpoint] = stats
+	}
+
+	stats.Requests++
+	stats.TotalDuration += duration
+	stats.StatusCodes[statusCode]++
+
+	if failed {
+		stats.Errors++
+	}
+
+	if retry {
+		stats.Retries++
+	}
+}
+
+func (s *apiStats) Snapshot() []APIEndpointStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]APIEndpointStats, 0, len(s.endpoints))
+
+	for _, stats := range s.endpoints {
+		codes := make(map[int]uint64, len(stats.StatusCodes))
+		for code, n := range stats.StatusCodes {
+			codes[code] = n
+		}
+
+		snapshot := *stats
+		snapshot.StatusCodes = codes
+
+		result = append(result, snapshot)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Endpoint < result[j].Endpoint
+	})
+
+	return result
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingAPI is a stand-in API server responding with the given status codes before answering successfully.
+type failingAPI struct {
+	failures   []int
+	retryAfter string
+	calls      atomic.Int32
+}
+
+func (api *failingAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/gdaemon_api/get_token" {
+		_, _ = w.Write([]byte(`{"token":"token","timestamp":1700000000}`))
+		return
+	}
+
+	call := int(api.calls.Add(1)) - 1
+
+	if call < len(api.failures) {
+		if api.retryAfter != "" {
+			w.Header().Set("Retry-After", api.retryAfter)
+		}
+		w.WriteHeader(api.failures[call])
+		return
+	}
+
+	_, _ = w.Write([]byte(`[]`))
+}
+
+func givenAPIConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.API.RetryCount = 3
+	cfg.API.RetryWaitTime = 10 * time.Millisecond
+	cfg.API.RetryMaxWaitTime = 3 * time.Second
+	cfg.API.CircuitBreaker.FailureThreshold = 2
+	cfg.API.CircuitBreaker.OpenTimeout = 200 * time.Millisecond
+
+	return cfg
+}
+
+func givenAPIClient(t *testing.T, api http.Handler, cfg *config.Config) *APIClient {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client, err := NewAPICaller(context.Background(), cfg, resty.New().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestAPIClient_Request_TransientErrorsRetried(t *testing.T) {
+	api := &failingAPI{failures: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	client := givenAPIClient(t, api, givenAPIConfig())
+
+	resp, err := client.Request(context.Background(), domain.APIRequest{
+		Method: http.MethodGet,
+		URL:    "/gdaemon_api/servers",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(3), api.calls.Load())
+	stats := client.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "GET /gdaemon_api/servers", stats[0].Endpoint)
+	assert.Equal(t, uint64(3), stats[0].Requests)
+	assert.Equal(t, uint64(2), stats[0].Retries)
+	assert.Equal(t, uint64(2), stats[0].Errors)
+	assert.Equal(t, uint64(1), stats[0].StatusCodes[http.StatusOK])
+}
+
+func TestAPIClient_Request_RetryAfterHonored(t *testing.T) {
+	api := &failingAPI{failures: []int{http.StatusTooManyRequests}, retryAfter: "1"}
+	client := givenAPIClient(t, api, givenAPIConfig())
+	start := time.Now()
+
+	resp, err := client.Request(context.Background(), domain.APIRequest{
+		Method: http.MethodGet,
+		URL:    "/gdaemon_api/servers",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.GreaterOrEqual(t, time.Since(start), 1*time.Second)
+}
+
+func TestAPIClient_Request_NonIdempotentRequestNotRetriedOnServerError(t *testing.T) {
+	api := &failingAPI{failures: []int{http.StatusInternalServerError}}
+	client := givenAPIClient(t, api, givenAPIConfig())
+
+	resp, err := client.Request(context.Background(), domain.APIRequest{
+		Method: http.MethodPost,
+		URL:    "/gdaemon_api/servers_tasks/1/fail",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+	assert.Equal(t, int32(1), api.calls.Load())
+}
+
+func TestAPIClient_Request_CircuitBreakerPausesRequests(t *testing.T) {
+	cfg := givenAPIConfig()
+	cfg.API.RetryCount = 0
+	api := &failingAPI{failures: []int{
+		http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable,
+	}}
+	client := givenAPIClient(t, api, cfg)
+	request := domain.APIRequest{Method: http.MethodGet, URL: "/gdaemon_api/servers"}
+	ctx := context.Background()
+
+	// Failures open the circuit
+	_, _ = client.Request(ctx, request)
+	_, _ = client.Request(ctx, request)
+	_, err := client.Request(ctx, request)
+
+	assert.ErrorIs(t, err, domain.ErrAPIUnavailable)
+	assert.False(t, client.Available())
+	assert.Equal(t, int32(2), api.calls.Load())
+
+	// Failed trial request reopens the circuit
+	time.Sleep(cfg.API.CircuitBreaker.OpenTimeout)
+	resp, err := client.Request(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	_, err = client.Request(ctx, request)
+	assert.ErrorIs(t, err, domain.ErrAPIUnavailable)
+
+	// Successful trial request closes the circuit
+	time.Sleep(cfg.API.CircuitBreaker.OpenTimeout)
+	resp, err = client.Request(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.True(t, client.Available())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	wait, ok := parseRetryAfter("120")
+	assert.True(t, ok)
+	assert.Equal(t, 120*time.Second, wait)
+
+	wait, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, wait, float64(2*time.Second))
+
+	_, ok = parseRetryAfter("")
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+}
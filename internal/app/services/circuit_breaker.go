@@ -0,0 +1,102 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+type circuitState uint8
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker stops API requests after a number of consecutive failures.
+// After the open timeout a single trial request is allowed, its result closes or reopens the circuit.
+type circuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// Allow reports whether a request can be made.
+func (b *circuitBreaker) Allow() bool {
+	if b.failureThreshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+
+		b.state = circuitHalfOpen
+
+		return true
+	case circuitHalfOpen:
+		// Only the trial request is allowed
+		return false
+	default:
+		return true
+	}
+}
+
+// Success returns true if the circuit was closed by this call.
+func (b *circuitBreaker) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.state != circuitClosed
+
+	b.state = circuitClosed
+	b.failures = 0
+
+	return wasOpen
+}
+
+// Failure returns true if the circuit was opened by this call.
+func (b *circuitBreaker) Failure() bool {
+	if b.failureThreshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.failureThreshold) {
+		wasClosed := b.state == circuitClosed
+
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+
+		return wasClosed
+	}
+
+	return false
+}
+
+func (b *circuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state != circuitClosed
+}
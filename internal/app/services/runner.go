@@ -7,10 +7,17 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamequery"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/ports"
+	"github.com/gameap/daemon/internal/app/push"
+	"github.com/gameap/daemon/internal/app/repositories"
 	"github.com/gameap/daemon/internal/app/server"
 	serversloop "github.com/gameap/daemon/internal/app/servers_loop"
 	serversscheduler "github.com/gameap/daemon/internal/app/servers_scheduler"
+	"github.com/gameap/daemon/internal/app/watchdog"
+	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -25,6 +32,12 @@ type Runner struct {
 	gdTaskManager        *gdaemonscheduler.TaskManager
 	serverRepository     domain.ServerRepository
 	serverTaskRepository domain.ServerTaskRepository
+	outbox               *repositories.Outbox
+	pushClient           *push.Client
+	watchdog             *watchdog.Watchdog
+	announcer            contracts.ServerAnnouncer
+	migrator             *processmanager.Migrator
+	healthCheckers       []contracts.HealthChecker
 }
 
 func NewProcessRunner(
@@ -35,6 +48,12 @@ func NewProcessRunner(
 	gdTaskManager *gdaemonscheduler.TaskManager,
 	serverRepository domain.ServerRepository,
 	serverTaskRepository domain.ServerTaskRepository,
+	outbox *repositories.Outbox,
+	pushClient *push.Client,
+	watchdog *watchdog.Watchdog,
+	announcer contracts.ServerAnnouncer,
+	migrator *processmanager.Migrator,
+	healthCheckers []contracts.HealthChecker,
 ) (*Runner, error) {
 	return &Runner{
 		cfg:                  cfg,
@@ -44,6 +63,12 @@ func NewProcessRunner(
 		gdTaskManager:        gdTaskManager,
 		serverRepository:     serverRepository,
 		serverTaskRepository: serverTaskRepository,
+		outbox:               outbox,
+		pushClient:           pushClient,
+		watchdog:             watchdog,
+		announcer:            announcer,
+		migrator:             migrator,
+		healthCheckers:       healthCheckers,
 	}, nil
 }
 
@@ -75,6 +100,9 @@ func (r *Runner) RunGDaemonServer(ctx context.Context, cfg *config.Config) func(
 			},
 			r.executor,
 			r.gdTaskManager,
+			r.gdTaskManager,
+			ports.NewAllocator(cfg, r.serverRepository),
+			r.healthCheckers...,
 		)
 		if err != nil {
 			return err
@@ -95,6 +123,9 @@ func (r *Runner) RunGDaemonTaskScheduler(ctx context.Context, _ *config.Config)
 			"service": "gdtask scheduler",
 		}))
 
+		r.subscribeTaskManager()
+		metrics.RegisterGDTaskQueue(r.gdTaskManager)
+
 		log.Trace("Running gdtask scheduler...")
 		return runService(ctx, r.gdTaskManager.Run)
 	}
@@ -102,7 +133,9 @@ func (r *Runner) RunGDaemonTaskScheduler(ctx context.Context, _ *config.Config)
 
 func (r *Runner) RunServersLoop(ctx context.Context, cfg *config.Config) func() error {
 	return func() error {
-		loop := serversloop.NewServersLoop(r.serverRepository, r.commandFactory, cfg)
+		loop := serversloop.NewServersLoop(r.serverRepository, r.commandFactory, r.migrator, cfg)
+
+		r.subscribeServerRepository()
 
 		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
 			"service": "servers loop",
@@ -119,8 +152,14 @@ func (r *Runner) RunServerScheduler(ctx context.Context, cfg *config.Config) fun
 			cfg,
 			r.serverTaskRepository,
 			r.commandFactory,
+			r.watchdog,
+			gamequery.NewPlayersCounter(),
+			r.announcer,
 		)
 
+		r.subscribeServerScheduler(scheduler)
+		metrics.RegisterServerTaskQueue(scheduler.QueueLen)
+
 		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
 			"service": "server tasks scheduler",
 		}))
@@ -130,6 +169,93 @@ func (r *Runner) RunServerScheduler(ctx context.Context, cfg *config.Config) fun
 	}
 }
 
+func (r *Runner) RunOutbox(ctx context.Context, cfg *config.Config) func() error {
+	return func() error {
+		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
+			"service": "api outbox",
+		}))
+
+		log.Trace("Running api outbox...")
+		return runService(ctx, func(ctx context.Context) error {
+			return r.outbox.Run(ctx, cfg.Offline.ReplayPeriod)
+		})
+	}
+}
+
+func (r *Runner) RunPushClient(ctx context.Context, _ *config.Config) func() error {
+	return func() error {
+		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
+			"service": "push client",
+		}))
+
+		log.Trace("Running push client...")
+		return runService(ctx, r.pushClient.Run)
+	}
+}
+
+func (r *Runner) RunMetricsServer(ctx context.Context, cfg *config.Config) func() error {
+	return func() error {
+		if !cfg.Metrics.Enabled {
+			return nil
+		}
+
+		srv := metrics.NewServer(cfg.Metrics.ListenAddress)
+
+		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
+			"service": "metrics server",
+		}))
+
+		log.Trace("Running metrics server...")
+		return runService(ctx, srv.Run)
+	}
+}
+
+func (r *Runner) subscribeTaskManager() {
+	r.pushClient.OnStateChanged(func(connected bool) {
+		r.gdTaskManager.SetPushConnected(connected)
+		// Reconcile tasks that might have been missed while the channel was (re)connecting
+		r.gdTaskManager.Refresh()
+	})
+
+	r.pushClient.Subscribe(push.EventGDTasks, func(ctx context.Context, event push.Event) {
+		id, ok := event.ID()
+		if !ok {
+			r.gdTaskManager.Refresh()
+			return
+		}
+
+		err := r.gdTaskManager.AddTaskByID(ctx, id)
+		if err != nil {
+			logger.WithError(ctx, err).Warn("Failed to add pushed task, reloading tasks")
+			r.gdTaskManager.Refresh()
+		}
+	})
+}
+
+func (r *Runner) subscribeServerScheduler(scheduler *serversscheduler.Scheduler) {
+	r.pushClient.OnStateChanged(func(connected bool) {
+		scheduler.SetPushConnected(connected)
+		scheduler.Refresh()
+	})
+
+	r.pushClient.Subscribe(push.EventServerTasks, func(_ context.Context, _ push.Event) {
+		scheduler.Refresh()
+	})
+}
+
+func (r *Runner) subscribeServerRepository() {
+	repo, ok := r.serverRepository.(interface{ Invalidate(id int) })
+	if !ok {
+		return
+	}
+
+	r.pushClient.Subscribe(push.EventServers, func(_ context.Context, event push.Event) {
+		if id, ok := event.ID(); ok {
+			repo.Invalidate(id)
+		}
+	})
+}
+
 func runService(ctx context.Context, runFunc func(ctx context.Context) error) error {
 	for {
 		select {
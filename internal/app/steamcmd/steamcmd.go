@@ -0,0 +1,215 @@
+package steamcmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+const (
+	// bootstrappedMarkerFile is created in the SteamCMD directory after the first self-update.
+	bootstrappedMarkerFile = ".gameap-bootstrapped"
+
+	// SteamCMD exits with a non-zero code after updating itself, so the first run is repeated.
+	maxSelfUpdateTries = 3
+)
+
+var ErrNotInstalled = errors.New("steamcmd is not installed")
+
+func DownloadURL() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "https://steamcdn-a.akamaihd.net/client/installer/steamcmd.zip"
+	case "darwin":
+		return "https://steamcdn-a.akamaihd.net/client/installer/steamcmd_osx.tar.gz"
+	default:
+		return "https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz"
+	}
+}
+
+// SteamCMD installs SteamCMD to the configured path if it is missing
+// and keeps the bootstrap state to avoid repeated checks.
+type SteamCMD struct {
+	cfg         *config.Config
+	executor    contracts.Executor
+	downloadURL string
+
+	mu           sync.Mutex
+	bootstrapped bool
+	lastError    error
+}
+
+func NewSteamCMD(cfg *config.Config, executor contracts.Executor) *SteamCMD {
+	return &SteamCMD{
+		cfg:         cfg,
+		executor:    executor,
+		downloadURL: DownloadURL(),
+	}
+}
+
+func (s *SteamCMD) path() string {
+	if s.cfg.SteamCMDPath != "" {
+		return s.cfg.SteamCMDPath
+	}
+
+	return filepath.Join(s.cfg.ToolsPath, "steamcmd")
+}
+
+func (s *SteamCMD) ExecutablePath() string {
+	return filepath.Join(s.path(), config.SteamCMDExecutableFile)
+}
+
+// EnsureInstalled downloads and unpacks SteamCMD if it is not found in the configured path
+// and runs its first self-update. The state is kept in memory and in the marker file,
+// so the self-update isn't repeated after the daemon restart.
+func (s *SteamCMD) EnsureInstalled(ctx context.Context, out io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bootstrapped && s.installed() {
+		return nil
+	}
+
+	err := s.bootstrap(ctx, out)
+	s.lastError = err
+	if err != nil {
+		return err
+	}
+
+	s.bootstrapped = true
+
+	return nil
+}
+
+// Name returns the name of the health check.
+func (s *SteamCMD) Name() string {
+	return "steamcmd"
+}
+
+// Check reports whether SteamCMD is ready to install game servers.
+// It doesn't download SteamCMD, the installation happens on the first game server installation.
+func (s *SteamCMD) Check(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastError != nil {
+		return s.lastError
+	}
+
+	if !s.installed() {
+		return ErrNotInstalled
+	}
+
+	return nil
+}
+
+func (s *SteamCMD) installed() bool {
+	stat, err := os.Stat(s.ExecutablePath())
+
+	return err == nil && stat.Mode().IsRegular()
+}
+
+func (s *SteamCMD) selfUpdated() bool {
+	_, err := os.Stat(filepath.Join(s.path(), bootstrappedMarkerFile))
+
+	return err == nil
+}
+
+func (s *SteamCMD) bootstrap(ctx context.Context, out io.Writer) error {
+	if !s.installed() {
+		err := s.download(ctx, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.selfUpdated() {
+		return nil
+	}
+
+	err := s.selfUpdate(ctx, out)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(s.path(), bootstrappedMarkerFile), nil, 0600)
+	if err != nil {
+		return errors.WithMessage(err, "[steamcmd.SteamCMD] failed to write bootstrap marker")
+	}
+
+	return nil
+}
+
+func (s *SteamCMD) download(ctx context.Context, out io.Writer) error {
+	path := s.path()
+
+	writeLine(out, "SteamCMD not found, downloading from "+s.downloadURL+" to "+path+" ...")
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return errors.WithMessage(err, "[steamcmd.SteamCMD] failed to create steamcmd directory")
+	}
+
+	c := getter.Client{
+		Ctx:  ctx,
+		Src:  s.downloadURL,
+		Dst:  path,
+		Mode: getter.ClientModeDir,
+	}
+
+	err = c.Get()
+	if err != nil {
+		return errors.WithMessage(err, "[steamcmd.SteamCMD] failed to download steamcmd")
+	}
+
+	if !s.installed() {
+		return errors.WithMessage(ErrNotInstalled, "[steamcmd.SteamCMD] executable not found in the downloaded archive")
+	}
+
+	err = os.Chmod(s.ExecutablePath(), 0755)
+	if err != nil {
+		return errors.WithMessage(err, "[steamcmd.SteamCMD] failed to chmod steamcmd")
+	}
+
+	return nil
+}
+
+func (s *SteamCMD) selfUpdate(ctx context.Context, out io.Writer) error {
+	writeLine(out, "Updating SteamCMD ...")
+
+	var result int
+	var err error
+	for i := 0; i < maxSelfUpdateTries; i++ {
+		result, err = s.executor.ExecWithWriter(ctx, s.ExecutablePath()+" +quit", out, contracts.ExecutorOptions{
+			WorkDir: s.path(),
+		})
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return errors.WithMessage(err, "[steamcmd.SteamCMD] failed to execute steamcmd")
+		}
+		if exitErr != nil {
+			result = exitErr.ExitCode()
+		}
+
+		if result == int(domain.SuccessResult) {
+			writeLine(out, "SteamCMD successfully updated")
+			return nil
+		}
+	}
+
+	return errors.Errorf("[steamcmd.SteamCMD] steamcmd self-update failed with exit code %d", result)
+}
+
+func writeLine(out io.Writer, line string) {
+	_, _ = out.Write([]byte(line + "\n"))
+}
@@ -0,0 +1,113 @@
+package steamcmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureInstalled_NotInstalled_DownloadedAndUpdated(t *testing.T) {
+	cfg := &config.Config{ToolsPath: t.TempDir()}
+	executor := &selfUpdateExecutor{results: []int{7, 0}}
+	s := NewSteamCMD(cfg, executor)
+	s.downloadURL = givenSteamCMDArchive(t)
+	out := &bytes.Buffer{}
+
+	require.ErrorIs(t, s.Check(context.Background()), ErrNotInstalled)
+
+	err := s.EnsureInstalled(context.Background(), out)
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(cfg.ToolsPath, "steamcmd", config.SteamCMDExecutableFile))
+	assert.FileExists(t, filepath.Join(cfg.ToolsPath, "steamcmd", bootstrappedMarkerFile))
+	assert.Equal(t, 2, executor.calls)
+	assert.Equal(t, s.ExecutablePath()+" +quit", executor.command)
+	assert.Contains(t, out.String(), "SteamCMD successfully updated")
+	assert.NoError(t, s.Check(context.Background()))
+}
+
+func TestEnsureInstalled_Bootstrapped_NotRepeated(t *testing.T) {
+	cfg := &config.Config{ToolsPath: t.TempDir()}
+	executor := &selfUpdateExecutor{}
+	s := NewSteamCMD(cfg, executor)
+	s.downloadURL = givenSteamCMDArchive(t)
+
+	require.NoError(t, s.EnsureInstalled(context.Background(), io.Discard))
+	require.NoError(t, s.EnsureInstalled(context.Background(), io.Discard))
+
+	restarted := NewSteamCMD(cfg, executor)
+	restarted.downloadURL = "/invalid/steamcmd.tar.gz"
+	require.NoError(t, restarted.EnsureInstalled(context.Background(), io.Discard))
+
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestEnsureInstalled_SelfUpdateFailed(t *testing.T) {
+	cfg := &config.Config{SteamCMDPath: filepath.Join(t.TempDir(), "steamcmd")}
+	executor := &selfUpdateExecutor{results: []int{8, 8, 8}}
+	s := NewSteamCMD(cfg, executor)
+	s.downloadURL = givenSteamCMDArchive(t)
+
+	err := s.EnsureInstalled(context.Background(), io.Discard)
+
+	require.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(cfg.SteamCMDPath, bootstrappedMarkerFile))
+	assert.Equal(t, err, s.Check(context.Background()))
+}
+
+func givenSteamCMDArchive(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "steamcmd.tar.gz")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	content := []byte("#!/bin/sh\nexit 0\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: config.SteamCMDExecutableFile,
+		Mode: 0755,
+		Size: int64(len(content)),
+	}))
+	_, err = tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return path
+}
+
+type selfUpdateExecutor struct {
+	results []int
+	calls   int
+	command string
+}
+
+func (ex *selfUpdateExecutor) Exec(_ context.Context, _ string, _ contracts.ExecutorOptions) ([]byte, int, error) {
+	return nil, 0, nil
+}
+
+func (ex *selfUpdateExecutor) ExecWithWriter(
+	_ context.Context, command string, _ io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	ex.command = command
+	ex.calls++
+
+	if len(ex.results) < ex.calls {
+		return 0, nil
+	}
+
+	return ex.results[ex.calls-1], nil
+}
@@ -0,0 +1,150 @@
+package verification
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	minisignKeyIDSize = 8
+
+	// minisignAlgorithm signs the file content.
+	minisignAlgorithm = "Ed"
+	// minisignPrehashedAlgorithm signs the BLAKE2b-512 hash of the file.
+	minisignPrehashedAlgorithm = "ED"
+
+	trustedCommentPrefix = "trusted comment: "
+)
+
+var (
+	errInvalidMinisignKey       = errors.New("invalid minisign public key")
+	errInvalidMinisignSignature = errors.New("invalid minisign signature file")
+	errUnknownMinisignKey       = errors.New("file is signed by an unknown minisign key")
+)
+
+type minisignPublicKey struct {
+	keyID []byte
+	key   ed25519.PublicKey
+}
+
+// parseMinisignPublicKey parses the base64 encoded key or the content of the minisign .pub file.
+func parseMinisignPublicKey(value string) (minisignPublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(value), "\n")
+	encoded := strings.TrimSpace(lines[len(lines)-1])
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != 2+minisignKeyIDSize+ed25519.PublicKeySize {
+		return minisignPublicKey{}, errInvalidMinisignKey
+	}
+
+	if string(decoded[:2]) != minisignAlgorithm {
+		return minisignPublicKey{}, errInvalidMinisignKey
+	}
+
+	return minisignPublicKey{
+		keyID: decoded[2 : 2+minisignKeyIDSize],
+		key:   decoded[2+minisignKeyIDSize:],
+	}, nil
+}
+
+type minisignSignature struct {
+	algorithm       string
+	keyID           []byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+func parseMinisignSignature(content []byte) (minisignSignature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return minisignSignature{}, errInvalidMinisignSignature
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(decoded) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return minisignSignature{}, errInvalidMinisignSignature
+	}
+
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSignature) != ed25519.SignatureSize {
+		return minisignSignature{}, errInvalidMinisignSignature
+	}
+
+	return minisignSignature{
+		algorithm:       string(decoded[:2]),
+		keyID:           decoded[2 : 2+minisignKeyIDSize],
+		signature:       decoded[2+minisignKeyIDSize:],
+		trustedComment:  strings.TrimPrefix(lines[2], trustedCommentPrefix),
+		globalSignature: globalSignature,
+	}, nil
+}
+
+// verifyMinisign verifies the file by the minisign signature made by one of the trusted keys.
+func verifyMinisign(keys []minisignPublicKey, file string, content []byte) error {
+	sig, err := parseMinisignSignature(content)
+	if err != nil {
+		return err
+	}
+
+	var key *minisignPublicKey
+	for i := range keys {
+		if bytes.Equal(keys[i].keyID, sig.keyID) {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return errUnknownMinisignKey
+	}
+
+	var message []byte
+	switch sig.algorithm {
+	case minisignAlgorithm:
+		message, err = os.ReadFile(file)
+	case minisignPrehashedAlgorithm:
+		message, err = blake2bFile(file)
+	default:
+		return errInvalidMinisignSignature
+	}
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key.key, message, sig.signature) {
+		return errors.New("minisign signature doesn't match the file")
+	}
+
+	if !ed25519.Verify(key.key, append(sig.signature, sig.trustedComment...), sig.globalSignature) {
+		return errors.New("minisign trusted comment signature is invalid")
+	}
+
+	return nil
+}
+
+func blake2bFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h, err := blake2b.New512(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,378 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxSidecarSize = 64 * 1024
+	sidecarTimeout = 30 * time.Second
+)
+
+var (
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrChecksumMissing   = errors.New("no checksum to verify the file")
+	ErrSignatureInvalid  = errors.New("invalid signature")
+	ErrSignatureMissing  = errors.New("no trusted signature to verify the file")
+	ErrUnverifiable      = errors.New("files from the source can't be verified")
+	errInvalidChecksum   = errors.New("invalid checksum file")
+	errSidecarNotFound   = errors.New("sidecar file not found")
+	errInvalidTrustedKey = errors.New("invalid trusted key")
+)
+
+// Verifier checks downloaded files against the sidecar checksum (.sha256)
+// and the minisign (.minisig) or GPG (.asc, .sig) signatures made by the trusted keys.
+type Verifier struct {
+	cfg        *config.Config
+	httpClient *http.Client
+
+	minisignKeys []minisignPublicKey
+	gpgKeyring   openpgp.EntityList
+	keysErr      error
+}
+
+func NewVerifier(cfg *config.Config) *Verifier {
+	v := &Verifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: sidecarTimeout},
+	}
+
+	v.keysErr = v.loadKeys()
+
+	return v
+}
+
+func (v *Verifier) loadKeys() error {
+	for _, value := range v.cfg.Verification.MinisignKeys {
+		key, err := parseMinisignPublicKey(value)
+		if err != nil {
+			return err
+		}
+		v.minisignKeys = append(v.minisignKeys, key)
+	}
+
+	for _, keyFile := range v.cfg.Verification.GPGKeys {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return errors.WithMessagef(errInvalidTrustedKey, "failed to read gpg key %s: %s", keyFile, err)
+		}
+
+		keyring, err := readKeyRing(content)
+		if err != nil {
+			return errors.WithMessagef(errInvalidTrustedKey, "failed to read gpg key %s: %s", keyFile, err)
+		}
+		v.gpgKeyring = append(v.gpgKeyring, keyring...)
+	}
+
+	return nil
+}
+
+// Required reports whether files must be verified, so sources that can't be verified are refused.
+func (v *Verifier) Required() bool {
+	return v.cfg.Verification.RequireChecksum || v.cfg.Verification.RequireSignature
+}
+
+// configured reports whether the remote sidecar files are worth fetching:
+// the verification is required or the trusted keys are set.
+func (v *Verifier) configured() bool {
+	return v.Required() || len(v.minisignKeys) > 0 || len(v.gpgKeyring) > 0
+}
+
+// IsVerifiableSource reports whether the source is a local file or an HTTP(S) URL.
+// The only go-getter parameter allowed for verifiable URLs is checksum.
+func IsVerifiableSource(source string) bool {
+	if isLocalPath(source) {
+		return true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	for param := range u.Query() {
+		if param != "checksum" {
+			return false
+		}
+	}
+
+	return !strings.Contains(u.Path, "//")
+}
+
+// Verify checks the file downloaded from the source.
+// A checksum passed in the source checksum parameter is verified by go-getter while downloading.
+// Sidecar checksum and signatures are optional unless verification.require_checksum
+// or verification.require_signature is set. Remote sidecars aren't requested if the verification isn't configured.
+func (v *Verifier) Verify(ctx context.Context, source, file string, out io.Writer) error {
+	if v.keysErr != nil {
+		return v.keysErr
+	}
+
+	checksumVerified, err := v.verifyChecksum(ctx, source, file, out)
+	if err != nil {
+		return err
+	}
+
+	if v.cfg.Verification.RequireChecksum && !checksumVerified {
+		return ErrChecksumMissing
+	}
+
+	signatureVerified, err := v.verifySignature(ctx, source, file, out)
+	if err != nil {
+		return err
+	}
+
+	if v.cfg.Verification.RequireSignature && !signatureVerified {
+		return ErrSignatureMissing
+	}
+
+	return nil
+}
+
+func (v *Verifier) verifyChecksum(ctx context.Context, source, file string, out io.Writer) (bool, error) {
+	verified := hasChecksumParam(source)
+
+	// Local sidecars are cheap to check, the remote ones are requested only if the verification is configured
+	if !isLocalPath(source) && !v.configured() {
+		return verified, nil
+	}
+
+	content, err := v.readSidecar(ctx, source, ".sha256")
+	if errors.Is(err, errSidecarNotFound) {
+		return verified, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := parseChecksumFile(content, sourceFileName(source))
+	if err != nil {
+		return false, err
+	}
+
+	actual, err := sha256File(file)
+	if err != nil {
+		return false, errors.WithMessage(err, "[verification.Verifier] failed to calculate checksum")
+	}
+
+	if !strings.EqualFold(expected, actual) {
+		return false, errors.WithMessagef(ErrChecksumMismatch, "expected sha256 %s, got %s", expected, actual)
+	}
+
+	writeLine(out, "Checksum sha256:"+actual+" verified")
+
+	return true, nil
+}
+
+func (v *Verifier) verifySignature(ctx context.Context, source, file string, out io.Writer) (bool, error) {
+	if len(v.minisignKeys) > 0 {
+		content, err := v.readSidecar(ctx, source, ".minisig")
+		if err == nil {
+			err = verifyMinisign(v.minisignKeys, file, content)
+			if err != nil {
+				return false, errors.WithMessage(ErrSignatureInvalid, err.Error())
+			}
+
+			writeLine(out, "Minisign signature verified")
+
+			return true, nil
+		}
+		if !errors.Is(err, errSidecarNotFound) {
+			return false, err
+		}
+	}
+
+	if len(v.gpgKeyring) > 0 {
+		for _, ext := range []string{".asc", ".sig"} {
+			content, err := v.readSidecar(ctx, source, ext)
+			if errors.Is(err, errSidecarNotFound) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+
+			err = v.verifyGPG(file, content)
+			if err != nil {
+				return false, errors.WithMessage(ErrSignatureInvalid, err.Error())
+			}
+
+			writeLine(out, "GPG signature verified")
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (v *Verifier) verifyGPG(file string, signature []byte) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isArmored(signature) {
+		_, err = openpgp.CheckArmoredDetachedSignature(v.gpgKeyring, f, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(v.gpgKeyring, f, bytes.NewReader(signature), nil)
+	}
+
+	return err
+}
+
+// readSidecar reads the file with the extension next to the source.
+func (v *Verifier) readSidecar(ctx context.Context, source, ext string) ([]byte, error) {
+	if isLocalPath(source) {
+		content, err := os.ReadFile(source + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errSidecarNotFound
+		}
+
+		return content, err
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = ""
+	u.Path += ext
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.WithMessage(errSidecarNotFound, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errSidecarNotFound
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxSidecarSize))
+}
+
+// parseChecksumFile parses the sha256sum output. The checksum of the file name is chosen
+// if the file contains several checksums.
+func parseChecksumFile(content []byte, fileName string) (string, error) {
+	var first string
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		checksum := fields[0]
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return "", errInvalidChecksum
+		}
+
+		if len(fields) == 1 {
+			return checksum, nil
+		}
+
+		if strings.TrimPrefix(fields[1], "*") == fileName {
+			return checksum, nil
+		}
+
+		if first == "" {
+			first = checksum
+		}
+	}
+
+	if first == "" {
+		return "", errInvalidChecksum
+	}
+
+	return first, nil
+}
+
+func hasChecksumParam(source string) bool {
+	if isLocalPath(source) {
+		return false
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+
+	return u.Query().Get("checksum") != ""
+}
+
+func sourceFileName(source string) string {
+	if isLocalPath(source) {
+		return filepath.Base(source)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return filepath.Base(source)
+	}
+
+	return path.Base(u.Path)
+}
+
+// isLocalPath reports whether the source is a local file path, e.g. the local repository.
+func isLocalPath(source string) bool {
+	return !strings.Contains(source, "://")
+}
+
+func sha256File(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readKeyRing(content []byte) (openpgp.EntityList, error) {
+	if isArmored(content) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	}
+
+	return openpgp.ReadKeyRing(bytes.NewReader(content))
+}
+
+func isArmored(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP"))
+}
+
+func writeLine(out io.Writer, line string) {
+	_, _ = out.Write([]byte(line + "\n"))
+}
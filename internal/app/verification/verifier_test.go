@@ -0,0 +1,262 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+const archiveChecksum = "0eec6a0ff00d26294fba2c43da432fd8e2d0ee33da12b6a5ef4d5c5ec4e6e8c3"
+
+func TestVerify_LocalSidecarChecksum(t *testing.T) {
+	file := givenArchive(t)
+	writeSidecar(t, file, ".sha256", sha256Of(t, file)+"  archive.tar.gz\n")
+	out := &bytes.Buffer{}
+
+	err := NewVerifier(&config.Config{}).Verify(context.Background(), file, file, out)
+
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Checksum sha256:"+sha256Of(t, file)+" verified")
+}
+
+func TestVerify_ChecksumMismatch(t *testing.T) {
+	file := givenArchive(t)
+	writeSidecar(t, file, ".sha256", archiveChecksum)
+
+	err := NewVerifier(&config.Config{}).Verify(context.Background(), file, file, &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Contains(t, err.Error(), "expected sha256 "+archiveChecksum)
+}
+
+func TestVerify_RemoteSidecarChecksumOfListedFile(t *testing.T) {
+	file := givenArchive(t)
+	checksums := archiveChecksum + "  other.tar.gz\n" + sha256Of(t, file) + " *archive.tar.gz\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/games/archive.tar.gz.sha256" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(checksums))
+	}))
+	defer server.Close()
+	cfg := &config.Config{}
+	cfg.Verification.RequireChecksum = true
+
+	err := NewVerifier(cfg).Verify(context.Background(), server.URL+"/games/archive.tar.gz", file, &bytes.Buffer{})
+
+	require.NoError(t, err)
+}
+
+func TestVerify_NotConfigured_RemoteSidecarsNotRequested(t *testing.T) {
+	file := givenArchive(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := NewVerifier(&config.Config{}).Verify(context.Background(), server.URL+"/archive.tar.gz", file, &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, requests)
+}
+
+func TestVerify_ChecksumRequired_ChecksumParameterAccepted(t *testing.T) {
+	file := givenArchive(t)
+	cfg := &config.Config{}
+	cfg.Verification.RequireChecksum = true
+	verifier := NewVerifier(cfg)
+
+	err := verifier.Verify(context.Background(), file, file, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrChecksumMissing)
+
+	// The checksum parameter is verified by go-getter while downloading
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	err = verifier.Verify(context.Background(), server.URL+"/archive.tar.gz?checksum=sha256:"+archiveChecksum, file, &bytes.Buffer{})
+	require.NoError(t, err)
+}
+
+func TestVerify_Minisign(t *testing.T) {
+	publicKey, sign := givenMinisignKey(t)
+	cfg := &config.Config{}
+	cfg.Verification.RequireSignature = true
+	cfg.Verification.MinisignKeys = []string{"untrusted comment: minisign public key\n" + publicKey}
+
+	t.Run("valid", func(t *testing.T) {
+		file := givenArchive(t)
+		writeSidecar(t, file, ".minisig", sign(file))
+		out := &bytes.Buffer{}
+
+		err := NewVerifier(cfg).Verify(context.Background(), file, file, out)
+
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "Minisign signature verified")
+	})
+
+	t.Run("tampered file", func(t *testing.T) {
+		file := givenArchive(t)
+		writeSidecar(t, file, ".minisig", sign(file))
+		require.NoError(t, os.WriteFile(file, []byte("tampered"), 0600))
+
+		err := NewVerifier(cfg).Verify(context.Background(), file, file, &bytes.Buffer{})
+
+		require.ErrorIs(t, err, ErrSignatureInvalid)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, signByOther := givenMinisignKey(t)
+		file := givenArchive(t)
+		writeSidecar(t, file, ".minisig", signByOther(file))
+
+		err := NewVerifier(cfg).Verify(context.Background(), file, file, &bytes.Buffer{})
+
+		require.ErrorIs(t, err, ErrSignatureInvalid)
+		assert.Contains(t, err.Error(), errUnknownMinisignKey.Error())
+	})
+
+	t.Run("no signature", func(t *testing.T) {
+		file := givenArchive(t)
+
+		err := NewVerifier(cfg).Verify(context.Background(), file, file, &bytes.Buffer{})
+
+		require.ErrorIs(t, err, ErrSignatureMissing)
+	})
+}
+
+func TestVerify_GPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("GameAP", "", "test@gameap.ru", nil)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "gameap.asc")
+	writeArmoredPublicKey(t, entity, keyFile)
+	cfg := &config.Config{}
+	cfg.Verification.RequireSignature = true
+	cfg.Verification.GPGKeys = []string{keyFile}
+	file := givenArchive(t)
+	signature := &bytes.Buffer{}
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.NoError(t, openpgp.ArmoredDetachSign(signature, entity, bytes.NewReader(content), nil))
+	writeSidecar(t, file, ".asc", signature.String())
+	out := &bytes.Buffer{}
+
+	err = NewVerifier(cfg).Verify(context.Background(), file, file, out)
+
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "GPG signature verified")
+
+	require.NoError(t, os.WriteFile(file, []byte("tampered"), 0600))
+	err = NewVerifier(cfg).Verify(context.Background(), file, file, out)
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+}
+
+func TestVerify_InvalidTrustedKey(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Verification.MinisignKeys = []string{"invalid"}
+	file := givenArchive(t)
+
+	err := NewVerifier(cfg).Verify(context.Background(), file, file, &bytes.Buffer{})
+
+	require.ErrorIs(t, err, errInvalidMinisignKey)
+}
+
+func TestIsVerifiableSource(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{"/srv/gameap/repository/cstrike.tar.gz", true},
+		{"https://files.gameap.ru/cstrike.tar.gz", true},
+		{"https://files.gameap.ru/cstrike.tar.gz?checksum=sha256:" + archiveChecksum, true},
+		{"https://files.gameap.ru/cstrike?archive=zip", false},
+		{"https://files.gameap.ru/repository.tar.gz//cstrike", false},
+		{"git::https://github.com/gameap/cstrike.git", false},
+		{"s3::https://s3.amazonaws.com/bucket/cstrike.tar.gz", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsVerifiableSource(test.source))
+		})
+	}
+}
+
+func givenArchive(t *testing.T) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "archive.tar.gz")
+	require.NoError(t, os.WriteFile(file, []byte("archive content"), 0600))
+
+	return file
+}
+
+func writeSidecar(t *testing.T, file, ext, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(file+ext, []byte(content), 0600))
+}
+
+func sha256Of(t *testing.T, file string) string {
+	t.Helper()
+
+	checksum, err := sha256File(file)
+	require.NoError(t, err)
+
+	return checksum
+}
+
+// givenMinisignKey returns the base64 encoded public key
+// and the function making prehashed minisign signatures of files.
+func givenMinisignKey(t *testing.T) (string, func(file string) string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := make([]byte, minisignKeyIDSize)
+	_, err = rand.Read(keyID)
+	require.NoError(t, err)
+
+	encodedKey := base64.StdEncoding.EncodeToString(append(append([]byte(minisignAlgorithm), keyID...), publicKey...))
+
+	return encodedKey, func(file string) string {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		hash := blake2b.Sum512(content)
+
+		signature := ed25519.Sign(privateKey, hash[:])
+		trustedComment := "timestamp:1700000000\tfile:archive.tar.gz"
+		globalSignature := ed25519.Sign(privateKey, append(append([]byte{}, signature...), trustedComment...))
+
+		return "untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(minisignPrehashedAlgorithm), keyID...), signature...)) + "\n" +
+			trustedCommentPrefix + trustedComment + "\n" +
+			base64.StdEncoding.EncodeToString(globalSignature) + "\n"
+	}
+}
+
+func writeArmoredPublicKey(t *testing.T, entity *openpgp.Entity, file string) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0600))
+}
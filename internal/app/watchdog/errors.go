@@ -0,0 +1,14 @@
+package watchdog
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrTimeout = errors.New("timeout exceeded")
+
+// NewTimeoutError returns ErrTimeout with the timeout in the message.
+func NewTimeoutError(timeout time.Duration) error {
+	return errors.WithMessagef(ErrTimeout, "task wasn't completed in %s", timeout)
+}
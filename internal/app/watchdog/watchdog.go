@@ -0,0 +1,142 @@
+package watchdog
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// KillGracePeriod is how long the canceled task is waited for before its worker is reported as stuck.
+var KillGracePeriod = 30 * time.Second
+
+var ErrStuckTasks = errors.New("stuck tasks")
+
+// StuckTask is the task which didn't stop after the timeout and keeps its worker busy.
+type StuckTask struct {
+	Name  string
+	Since time.Time
+}
+
+// Watchdog keeps the stuck tasks of the gdtask and server tasks schedulers.
+// It is the health checker, so the stuck tasks are shown in the status mode.
+type Watchdog struct {
+	mu    sync.Mutex
+	stuck map[string]time.Time
+}
+
+func NewWatchdog() *Watchdog {
+	return &Watchdog{
+		stuck: make(map[string]time.Time),
+	}
+}
+
+// Stuck marks the task as stuck, the name describes the task, e.g. "gdtask #12 (gsinst)".
+func (w *Watchdog) Stuck(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.stuck[name]; !ok {
+		w.stuck[name] = time.Now()
+	}
+}
+
+// Release removes the task from the stuck list when its worker is finally completed.
+func (w *Watchdog) Release(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.stuck, name)
+}
+
+// StuckTasks returns the stuck tasks sorted by the time they got stuck.
+func (w *Watchdog) StuckTasks() []StuckTask {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tasks := make([]StuckTask, 0, len(w.stuck))
+	for name, since := range w.stuck {
+		tasks = append(tasks, StuckTask{Name: name, Since: since})
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Since.Equal(tasks[j].Since) {
+			return tasks[i].Name < tasks[j].Name
+		}
+
+		return tasks[i].Since.Before(tasks[j].Since)
+	})
+
+	return tasks
+}
+
+// Name returns the name of the health check.
+func (w *Watchdog) Name() string {
+	return "tasks"
+}
+
+// Check fails if any task is stuck.
+func (w *Watchdog) Check(_ context.Context) error {
+	tasks := w.StuckTasks()
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		names = append(names, t.Name+" since "+t.Since.Format(time.RFC3339))
+	}
+
+	return errors.WithMessage(ErrStuckTasks, strings.Join(names, ", "))
+}
+
+// Run runs the function with the timeout.
+// If the function isn't completed during the KillGracePeriod after the context is done,
+// the task is marked as stuck and the function isn't waited for anymore.
+// The stuck task is released when the function is completed.
+func (w *Watchdog) Run(ctx context.Context, name string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return result(ctx, timeout, err)
+	case <-ctx.Done():
+	}
+
+	select {
+	case err := <-done:
+		return result(ctx, timeout, err)
+	case <-time.After(KillGracePeriod):
+	}
+
+	w.Stuck(name)
+
+	go func() {
+		<-done
+		w.Release(name)
+	}()
+
+	return result(ctx, timeout, ctx.Err())
+}
+
+func result(ctx context.Context, timeout time.Duration, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return NewTimeoutError(timeout)
+	}
+
+	return err
+}
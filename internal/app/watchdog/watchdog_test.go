@@ -0,0 +1,73 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errFailed = errors.New("failed")
+
+func TestWatchdog_Run_CompletedInTime(t *testing.T) {
+	w := NewWatchdog()
+
+	err := w.Run(context.Background(), "task", time.Second, func(_ context.Context) error {
+		return errFailed
+	})
+
+	assert.ErrorIs(t, err, errFailed)
+	assert.NoError(t, w.Check(context.Background()))
+}
+
+func TestWatchdog_Run_TimedOut(t *testing.T) {
+	w := NewWatchdog()
+
+	err := w.Run(context.Background(), "task", 50*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Empty(t, w.StuckTasks())
+}
+
+func TestWatchdog_Run_NotStoppedAfterTimeout_TaskIsStuck(t *testing.T) {
+	defer func(period time.Duration) { KillGracePeriod = period }(KillGracePeriod)
+	KillGracePeriod = 50 * time.Millisecond
+
+	w := NewWatchdog()
+	release := make(chan struct{})
+
+	err := w.Run(context.Background(), "server task #1 (restart)", 50*time.Millisecond, func(_ context.Context) error {
+		<-release
+		return nil
+	})
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	require.Len(t, w.StuckTasks(), 1)
+	assert.Equal(t, "server task #1 (restart)", w.StuckTasks()[0].Name)
+	assert.ErrorIs(t, w.Check(context.Background()), ErrStuckTasks)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return len(w.StuckTasks()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatchdog_Run_NoTimeout(t *testing.T) {
+	w := NewWatchdog()
+
+	err := w.Run(context.Background(), "task", 0, func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
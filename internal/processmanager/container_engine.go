@@ -0,0 +1,316 @@
+//go:build linux
+// +build linux
+
+package processmanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The API version supported by both Docker and the Podman compatible API.
+const containerEngineAPIVersion = "v1.41"
+
+var (
+	// errEngineNotFound is returned if the container or the image isn't found.
+	errEngineNotFound = errors.New("not found by container engine")
+	// errEngineConflict is returned if the container isn't running or its name is in use.
+	errEngineConflict = errors.New("container engine conflict")
+)
+
+// containerEngine is the Docker Engine API client, Podman serves the same API on its socket.
+type containerEngine struct {
+	socket string
+	client *http.Client
+}
+
+func newContainerEngine(socket string) *containerEngine {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	return &containerEngine{
+		socket: socket,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+type containerSpec struct {
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd"`
+	Env          []string            `json:"Env,omitempty"`
+	WorkingDir   string              `json:"WorkingDir"`
+	User         string              `json:"User,omitempty"`
+	Tty          bool                `json:"Tty"`
+	OpenStdin    bool                `json:"OpenStdin"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   containerHostConfig `json:"HostConfig"`
+}
+
+type containerHostConfig struct {
+	Binds        []string                          `json:"Binds"`
+	NetworkMode  string                            `json:"NetworkMode"`
+	PortBindings map[string][]containerPortBinding `json:"PortBindings,omitempty"`
+	NanoCPUs     int64                             `json:"NanoCpus,omitempty"`
+	Memory       int64                             `json:"Memory,omitempty"`
+	PidsLimit    int64                             `json:"PidsLimit,omitempty"`
+}
+
+type containerPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type containerState struct {
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+}
+
+// pull pulls the image and writes the pull progress to out.
+func (e *containerEngine) pull(ctx context.Context, image string, out io.Writer) error {
+	name, tag := splitImageReference(image)
+
+	// Without the tag all tags of the image are pulled
+	resp, err := e.request(ctx, http.MethodPost, "/images/create", url.Values{"fromImage": {name}, "tag": {tag}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Status   string `json:"status"`
+			Progress string `json:"progress"`
+			Error    string `json:"error"`
+		}
+
+		err = decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to read pull progress")
+		}
+
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+
+		// Progress bars are skipped, they are redrawn by the terminal only
+		if message.Status != "" && message.Progress == "" {
+			_, _ = fmt.Fprintln(out, message.Status)
+		}
+	}
+}
+
+// splitImageReference splits the tag or the digest off the image reference like the docker CLI does,
+// the tag is "latest" if it's not set.
+func splitImageReference(image string) (string, string) {
+	if name, digest, ok := strings.Cut(image, "@"); ok {
+		return name, digest
+	}
+
+	// The colon before the last slash separates the registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+
+	return image, "latest"
+}
+
+func (e *containerEngine) create(ctx context.Context, name string, spec containerSpec) error {
+	return e.call(ctx, http.MethodPost, "/containers/create", url.Values{"name": {name}}, spec)
+}
+
+func (e *containerEngine) start(ctx context.Context, name string) error {
+	return e.call(ctx, http.MethodPost, "/containers/"+name+"/start", nil, nil)
+}
+
+func (e *containerEngine) kill(ctx context.Context, name string, signal string) error {
+	return e.call(ctx, http.MethodPost, "/containers/"+name+"/kill", url.Values{"signal": {signal}}, nil)
+}
+
+func (e *containerEngine) remove(ctx context.Context, name string) error {
+	return e.call(ctx, http.MethodDelete, "/containers/"+name, url.Values{"force": {"true"}}, nil)
+}
+
+func (e *containerEngine) running(ctx context.Context, name string) (bool, error) {
+	resp, err := e.request(ctx, http.MethodGet, "/containers/"+name+"/json", nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var state containerState
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to decode container state")
+	}
+
+	return state.State.Running, nil
+}
+
+// logs writes the last lines of the container output to out.
+// The containers are created with TTY, so the output isn't multiplexed.
+func (e *containerEngine) logs(ctx context.Context, name string, tail int, out io.Writer) error {
+	resp, err := e.request(ctx, http.MethodGet, "/containers/"+name+"/logs", url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+		"tail":   {strconv.Itoa(tail)},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(out, resp.Body)
+	if err != nil {
+		return errors.WithMessage(err, "failed to read container logs")
+	}
+
+	return nil
+}
+
+// attachInput attaches to the container stdin and writes the input to it.
+// The engine hijacks the connection, so the request is written to the socket directly.
+func (e *containerEngine) attachInput(ctx context.Context, name string, input string) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "unix", e.socket)
+	if err != nil {
+		return errors.WithMessage(err, "failed to connect to container engine")
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, e.url("/containers/"+name+"/attach", url.Values{
+			"stream": {"true"},
+			"stdin":  {"true"},
+		}), nil,
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to make request")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	err = req.Write(conn)
+	if err != nil {
+		return errors.WithMessage(err, "failed to write request")
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return errors.WithMessage(err, "failed to read response")
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	_, err = io.WriteString(conn, input+"\n")
+	if err != nil {
+		return errors.WithMessage(err, "failed to write input")
+	}
+
+	return nil
+}
+
+func (e *containerEngine) call(ctx context.Context, method, path string, query url.Values, body any) error {
+	resp, err := e.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.Body.Close()
+}
+
+// request sends the request to the engine, the response body must be closed by the caller if no error.
+func (e *containerEngine) request(
+	ctx context.Context, method, path string, query url.Values, body any,
+) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to marshal request")
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.url(path, query), reader)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to make request")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to send request to container engine")
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+func (e *containerEngine) url(path string, query url.Values) string {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "engine",
+		Path:     "/" + containerEngineAPIVersion + path,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+func responseError(resp *http.Response) error {
+	var message struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&message)
+
+	if message.Message == "" {
+		message.Message = resp.Status
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.WithMessage(errEngineNotFound, message.Message)
+	case http.StatusConflict:
+		return errors.WithMessage(errEngineConflict, message.Message)
+	}
+
+	return errors.Errorf("container engine error: %s", message.Message)
+}
@@ -0,0 +1,430 @@
+//go:build linux
+// +build linux
+
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultDockerSocket = "/var/run/docker.sock"
+	defaultPodmanSocket = "/run/podman/podman.sock"
+
+	containerNetworkBridge = "bridge"
+	containerNetworkHost   = "host"
+	containerBindAddress   = "0.0.0.0"
+
+	containerServerLabel = "gameap.server.uuid"
+	containerOutputLines = 1000
+)
+
+// Server settings overriding the process_manager.config values for the server.
+const (
+	containerImageSettingKey  = "container_image"
+	containerCPUsSettingKey   = "container_cpus"
+	containerMemorySettingKey = "container_memory"
+)
+
+// Docker runs each game server in its own container. The server directory is mounted at the same path,
+// so the start command and the paths in the game configs work as with the other process managers.
+// The engine is accessed by its Unix socket API, Podman provides the same API.
+type Docker struct {
+	cfg    *config.Config
+	engine *containerEngine
+}
+
+func NewDocker(cfg *config.Config) *Docker {
+	return newDocker(cfg, defaultDockerSocket)
+}
+
+func NewPodman(cfg *config.Config) *Docker {
+	return newDocker(cfg, defaultPodmanSocket)
+}
+
+func newDocker(cfg *config.Config, defaultSocket string) *Docker {
+	socket := cfg.ProcessManager.Config["socket"]
+	if socket == "" {
+		socket = defaultSocket
+	}
+
+	return &Docker{
+		cfg:    cfg,
+		engine: newContainerEngine(socket),
+	}
+}
+
+// Install pulls the image and creates the container of the game server.
+func (pm *Docker) Install(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	spec, err := pm.containerSpec(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	err = pm.engine.pull(ctx, spec.Image, out)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to pull image")
+	}
+
+	err = pm.recreate(ctx, server, spec, out)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// Uninstall removes the container, the server files are kept.
+func (pm *Docker) Uninstall(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	err := pm.engine.remove(ctx, pm.containerName(server))
+	if err != nil && !errors.Is(err, errEngineNotFound) {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to remove container")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// Start creates the container from the current server settings and starts it.
+// The image is pulled if it isn't found.
+func (pm *Docker) Start(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	running, err := pm.running(ctx, server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get container state")
+	}
+	if running {
+		_, _ = fmt.Fprintln(out, "Container is already running")
+
+		return domain.SuccessResult, nil
+	}
+
+	spec, err := pm.containerSpec(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	err = pm.recreate(ctx, server, spec, out)
+	if errors.Is(err, errEngineNotFound) {
+		err = pm.engine.pull(ctx, spec.Image, out)
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to pull image")
+		}
+
+		err = pm.recreate(ctx, server, spec, out)
+	}
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	err = pm.engine.start(ctx, pm.containerName(server))
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to start container")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// Stop sends the game stop command, then sends the signal and SIGKILL to the container
+// if the game server isn't stopped in time.
+func (pm *Docker) Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	stopper := &gracefulStopper{
+		cfg:       pm.cfg,
+		sendInput: pm.SendInput,
+		status:    pm.Status,
+	}
+
+	if stopper.Stop(ctx, server, out) {
+		return domain.SuccessResult, nil
+	}
+
+	for _, signal := range []string{pm.cfg.GracefulStop.Signal, "SIGKILL"} {
+		if signal == "" {
+			continue
+		}
+
+		err := pm.engine.kill(ctx, pm.containerName(server), signal)
+		if errors.Is(err, errEngineNotFound) || errors.Is(err, errEngineConflict) {
+			// The container is removed or isn't running already
+			return domain.SuccessResult, nil
+		}
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to send signal to container")
+		}
+
+		if stopper.waitStopped(ctx, server, pm.cfg.GracefulStop.Timeout) {
+			return domain.SuccessResult, nil
+		}
+	}
+
+	return domain.ErrorResult, nil
+}
+
+func (pm *Docker) Restart(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	statusResult, err := pm.Status(ctx, server, out)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if statusResult == domain.SuccessResult {
+		_, err = pm.Stop(ctx, server, out)
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to stop server")
+		}
+	}
+
+	return pm.Start(ctx, server, out)
+}
+
+func (pm *Docker) Status(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	running, err := pm.running(ctx, server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get container state")
+	}
+
+	if !running {
+		return domain.ErrorResult, nil
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Docker) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	err := pm.engine.logs(ctx, pm.containerName(server), containerOutputLines, out)
+	if errors.Is(err, errEngineNotFound) {
+		return domain.ErrorResult, nil
+	}
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get container output")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Docker) SendInput(
+	ctx context.Context, input string, server *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	err := pm.engine.attachInput(ctx, pm.containerName(server), input)
+	if errors.Is(err, errEngineNotFound) || errors.Is(err, errEngineConflict) {
+		return domain.ErrorResult, nil
+	}
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to send input to container")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Docker) running(ctx context.Context, server *domain.Server) (bool, error) {
+	running, err := pm.engine.running(ctx, pm.containerName(server))
+	if errors.Is(err, errEngineNotFound) {
+		return false, nil
+	}
+
+	return running, err
+}
+
+// recreate removes the stopped container and creates the new one, so the changed settings are applied.
+func (pm *Docker) recreate(ctx context.Context, server *domain.Server, spec containerSpec, out io.Writer) error {
+	name := pm.containerName(server)
+
+	err := pm.engine.remove(ctx, name)
+	if err != nil && !errors.Is(err, errEngineNotFound) {
+		return errors.WithMessage(err, "failed to remove container")
+	}
+
+	err = pm.engine.create(ctx, name, spec)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create container")
+	}
+
+	_, _ = fmt.Fprintf(out, "Container %s is created from %s\n", name, spec.Image)
+
+	return nil
+}
+
+func (pm *Docker) containerName(server *domain.Server) string {
+	return servicePrefix + server.UUID()
+}
+
+func (pm *Docker) containerSpec(server *domain.Server) (containerSpec, error) {
+	image := pm.image(server)
+	if image == "" {
+		return containerSpec{}, ErrContainerImageNotSet
+	}
+
+	hostConfig, err := pm.hostConfig(server)
+	if err != nil {
+		return containerSpec{}, err
+	}
+
+	env := serverEnvironment(server)
+	envList := make([]string, 0, len(env))
+	for _, key := range sortedKeys(env) {
+		envList = append(envList, key+"="+env[key])
+	}
+
+	startTemplate, startCommand := pm.cfg.Scripts.Start, server.StartCommand()
+	if hostConfig.NetworkMode == containerNetworkBridge {
+		// The server IP doesn't exist inside the bridged container, the ports are published on it by the engine
+		startTemplate = bindAllAddresses(startTemplate)
+		startCommand = bindAllAddresses(startCommand)
+	}
+
+	spec := containerSpec{
+		Image: image,
+		Cmd: []string{
+			"/bin/sh", "-c",
+			domain.MakeFullCommand(pm.cfg, server, startTemplate, startCommand),
+		},
+		Env:        envList,
+		WorkingDir: server.WorkDir(pm.cfg),
+		Tty:        true,
+		OpenStdin:  true,
+		Labels:     map[string]string{containerServerLabel: server.UUID()},
+		HostConfig: hostConfig,
+	}
+
+	if server.User() != "" {
+		systemUser, err := user.Lookup(server.User())
+		if err != nil {
+			return containerSpec{}, errors.WithMessagef(err, "failed to lookup user %s", server.User())
+		}
+
+		spec.User = systemUser.Uid + ":" + systemUser.Gid
+	}
+
+	if hostConfig.NetworkMode == containerNetworkBridge {
+		spec.ExposedPorts = make(map[string]struct{}, len(hostConfig.PortBindings))
+		for port := range hostConfig.PortBindings {
+			spec.ExposedPorts[port] = struct{}{}
+		}
+	}
+
+	return spec, nil
+}
+
+func (pm *Docker) hostConfig(server *domain.Server) (containerHostConfig, error) {
+	workDir := server.WorkDir(pm.cfg)
+
+	hostConfig := containerHostConfig{
+		Binds:       []string{workDir + ":" + workDir},
+		NetworkMode: pm.option("network"),
+	}
+
+	switch hostConfig.NetworkMode {
+	case "":
+		hostConfig.NetworkMode = containerNetworkBridge
+	case containerNetworkBridge, containerNetworkHost:
+	default:
+		return containerHostConfig{}, ErrInvalidContainerNetwork
+	}
+
+	if hostConfig.NetworkMode == containerNetworkBridge {
+		hostIP := ""
+		if net.ParseIP(server.IP()) != nil {
+			hostIP = server.IP()
+		}
+
+		hostConfig.PortBindings = map[string][]containerPortBinding{}
+		for _, port := range []int{server.ConnectPort(), server.QueryPort(), server.RCONPort()} {
+			if port <= 0 {
+				continue
+			}
+
+			for _, proto := range []string{"tcp", "udp"} {
+				hostConfig.PortBindings[strconv.Itoa(port)+"/"+proto] = []containerPortBinding{
+					{HostIP: hostIP, HostPort: strconv.Itoa(port)},
+				}
+			}
+		}
+	}
+
+	if cpus := pm.serverOption(server, containerCPUsSettingKey, "cpus"); cpus != "" {
+		value, err := strconv.ParseFloat(cpus, 64)
+		if err != nil || value <= 0 {
+			return containerHostConfig{}, errors.Errorf("invalid cpus limit %q", cpus)
+		}
+
+		hostConfig.NanoCPUs = int64(value * 1e9)
+	}
+
+	if memory := pm.serverOption(server, containerMemorySettingKey, "memory"); memory != "" {
+		value, err := parseMemoryLimit(memory)
+		if err != nil {
+			return containerHostConfig{}, err
+		}
+
+		hostConfig.Memory = value
+	}
+
+	if pids := pm.option("pids_limit"); pids != "" {
+		value, err := strconv.ParseInt(pids, 10, 64)
+		if err != nil || value <= 0 {
+			return containerHostConfig{}, errors.Errorf("invalid pids limit %q", pids)
+		}
+
+		hostConfig.PidsLimit = value
+	}
+
+	return hostConfig, nil
+}
+
+// image returns the image from the server settings, then the image of the game, then the default image.
+func (pm *Docker) image(server *domain.Server) string {
+	if image := server.Setting(containerImageSettingKey); image != "" {
+		return image
+	}
+
+	if image := pm.option("image." + server.Game().Code); image != "" {
+		return image
+	}
+
+	return pm.option("image")
+}
+
+func (pm *Docker) serverOption(server *domain.Server, settingKey, optionKey string) string {
+	if value := server.Setting(settingKey); value != "" {
+		return value
+	}
+
+	return pm.option(optionKey)
+}
+
+func (pm *Docker) option(key string) string {
+	return pm.cfg.ProcessManager.Config[key]
+}
+
+// bindAllAddresses replaces the {ip} and {host} shortcodes with the address of all interfaces.
+func bindAllAddresses(command string) string {
+	return strings.NewReplacer("{ip}", containerBindAddress, "{host}", containerBindAddress).Replace(command)
+}
+
+// parseMemoryLimit parses the memory limit in bytes with the optional k, m or g suffix, e.g. "512m".
+func parseMemoryLimit(limit string) (int64, error) {
+	multipliers := map[byte]int64{'b': 1, 'k': 1 << 10, 'm': 1 << 20, 'g': 1 << 30}
+
+	s := strings.ToLower(strings.TrimSpace(limit))
+	multiplier := int64(1)
+	if s != "" {
+		if m, ok := multipliers[s[len(s)-1]]; ok {
+			multiplier = m
+			s = s[:len(s)-1]
+		}
+	}
+
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, errors.Errorf("invalid memory limit %q", limit)
+	}
+
+	return value * multiplier, nil
+}
@@ -0,0 +1,358 @@
+//go:build linux
+// +build linux
+
+package processmanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine serves the part of the Docker Engine API used by the process manager on the Unix socket.
+type fakeEngine struct {
+	mu         sync.Mutex
+	images     map[string]bool
+	containers map[string]*fakeContainer
+	requests   []string
+}
+
+type fakeContainer struct {
+	spec    containerSpec
+	running bool
+	input   []string
+	// stopsOn is the input stopping the container
+	stopsOn string
+}
+
+func givenFakeEngine(t *testing.T) (*fakeEngine, string) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "engine.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	engine := &fakeEngine{
+		images:     map[string]bool{},
+		containers: map[string]*fakeContainer{},
+	}
+
+	server := httptest.NewUnstartedServer(engine)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return engine, socket
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+containerEngineAPIVersion)
+	e.requests = append(e.requests, r.Method+" "+path)
+
+	if path == "/images/create" {
+		e.images[r.URL.Query().Get("fromImage")+":"+r.URL.Query().Get("tag")] = true
+		_, _ = io.WriteString(w, `{"status":"Pulling"}`+"\n"+`{"status":"Downloading","progress":"[=> ]"}`+"\n")
+
+		return
+	}
+
+	if path == "/containers/create" {
+		var spec containerSpec
+		_ = json.NewDecoder(r.Body).Decode(&spec)
+		if !e.images[spec.Image] {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"No such image"}`)
+
+			return
+		}
+
+		e.containers[r.URL.Query().Get("name")] = &fakeContainer{spec: spec}
+		w.WriteHeader(http.StatusCreated)
+
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(path, "/containers/"), "/", 2)
+	c, ok := e.containers[parts[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message":"No such container"}`)
+
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		delete(e.containers, parts[0])
+		w.WriteHeader(http.StatusNoContent)
+	case action == "json":
+		_ = json.NewEncoder(w).Encode(map[string]any{"State": map[string]bool{"Running": c.running}})
+	case action == "start":
+		c.running = true
+		w.WriteHeader(http.StatusNoContent)
+	case action == "kill":
+		if !c.running {
+			w.WriteHeader(http.StatusConflict)
+
+			return
+		}
+		c.running = false
+		w.WriteHeader(http.StatusNoContent)
+	case action == "logs":
+		_, _ = io.WriteString(w, "Server started\n")
+	case action == "attach":
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		line, _ := bufio.NewReader(buf).ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		c.input = append(c.input, line)
+		if line == c.stopsOn {
+			c.running = false
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (e *fakeEngine) container(name string) *fakeContainer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.containers[name]
+}
+
+func givenDockerConfig(t *testing.T, socket string) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{
+		WorkPath: t.TempDir(),
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	cfg.ProcessManager.Config = map[string]string{
+		"socket":          socket,
+		"image":           "debian:bookworm",
+		"image.minecraft": "eclipse-temurin:21-jre",
+		"memory":          "512m",
+		"cpus":            "1.5",
+	}
+	cfg.GracefulStop.Timeout = 100 * time.Millisecond
+	cfg.GracefulStop.Signal = "SIGTERM"
+	cfg.GracefulStop.Method = config.CommandMethodConsole
+
+	return cfg
+}
+
+func TestDocker_Install_ContainerCreated(t *testing.T) {
+	engine, socket := givenFakeEngine(t)
+	cfg := givenDockerConfig(t, socket)
+	cfg.Scripts.Start = "{command} +ip {ip}"
+	docker := NewDocker(cfg)
+	server := makeServerWithSettings(t, map[string]string{
+		"env.GAME_MODE":    "competitive",
+		"container_memory": "2g",
+	})
+	out := &bytes.Buffer{}
+
+	result, err := docker.Install(context.Background(), server, out)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Equal(t, "Pulling\nContainer gameap-server-759b875e-d910-11eb-aff7-d796d7fcf7ef is created from debian:bookworm\n", out.String())
+	c := engine.container("gameap-server-" + server.UUID())
+	require.NotNil(t, c)
+	workDir := server.WorkDir(docker.cfg)
+	assert.Equal(t, "debian:bookworm", c.spec.Image)
+	assert.Equal(t, []string{"/bin/sh", "-c", "/usr/bin/env +ip 0.0.0.0"}, c.spec.Cmd)
+	assert.Equal(t, []string{"GAME_MODE=competitive"}, c.spec.Env)
+	assert.Equal(t, workDir, c.spec.WorkingDir)
+	assert.NotEmpty(t, c.spec.User)
+	assert.Equal(t, []string{workDir + ":" + workDir}, c.spec.HostConfig.Binds)
+	assert.Equal(t, "bridge", c.spec.HostConfig.NetworkMode)
+	assert.Equal(t, []containerPortBinding{{HostIP: "1.3.3.7", HostPort: "1337"}}, c.spec.HostConfig.PortBindings["1337/udp"])
+	assert.Len(t, c.spec.HostConfig.PortBindings, 6)
+	assert.Len(t, c.spec.ExposedPorts, 6)
+	assert.Equal(t, int64(1500000000), c.spec.HostConfig.NanoCPUs)
+	assert.Equal(t, int64(2<<30), c.spec.HostConfig.Memory)
+}
+
+func TestDocker_Start_ImageNotFound_ImagePulledAndContainerStarted(t *testing.T) {
+	engine, socket := givenFakeEngine(t)
+	cfg := givenDockerConfig(t, socket)
+	cfg.ProcessManager.Config["network"] = "host"
+	cfg.Scripts.Start = "{command} +ip {ip}"
+	docker := NewDocker(cfg)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	result, err := docker.Start(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	c := engine.container("gameap-server-" + server.UUID())
+	require.NotNil(t, c)
+	assert.True(t, c.running)
+	assert.Equal(t, "host", c.spec.HostConfig.NetworkMode)
+	assert.Equal(t, []string{"/bin/sh", "-c", "/usr/bin/env +ip 1.3.3.7"}, c.spec.Cmd)
+	assert.Empty(t, c.spec.HostConfig.PortBindings)
+	assert.Contains(t, engine.requests, "POST /images/create")
+}
+
+func Test_splitImageReference(t *testing.T) {
+	tests := []struct {
+		image string
+		name  string
+		tag   string
+	}{
+		{"itzg/minecraft-server", "itzg/minecraft-server", "latest"},
+		{"debian:bookworm", "debian", "bookworm"},
+		{"registry.local:5000/games/cs2", "registry.local:5000/games/cs2", "latest"},
+		{"registry.local:5000/games/cs2:1.0", "registry.local:5000/games/cs2", "1.0"},
+		{"debian@sha256:0123abcd", "debian", "sha256:0123abcd"},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			name, tag := splitImageReference(test.image)
+
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.tag, tag)
+		})
+	}
+}
+
+func TestDocker_StatusOutputAndInput(t *testing.T) {
+	engine, socket := givenFakeEngine(t)
+	docker := NewDocker(givenDockerConfig(t, socket))
+	server := makeServerWithSettings(t, map[string]string{})
+
+	result, err := docker.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, 1, int(result))
+
+	_, err = docker.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	result, err = docker.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+
+	out := &bytes.Buffer{}
+	_, err = docker.GetOutput(context.Background(), server, out)
+	require.NoError(t, err)
+	assert.Equal(t, "Server started\n", out.String())
+
+	result, err = docker.SendInput(context.Background(), "say hello", server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Equal(t, []string{"say hello"}, engine.container("gameap-server-"+server.UUID()).input)
+}
+
+func TestDocker_Stop_StoppedByCommand(t *testing.T) {
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+	engine, socket := givenFakeEngine(t)
+	cfg := givenDockerConfig(t, socket)
+	cfg.GracefulStop.Command = "stop"
+	docker := NewDocker(cfg)
+	server := makeServerWithSettings(t, map[string]string{})
+	_, err := docker.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	engine.container("gameap-server-" + server.UUID()).stopsOn = "stop"
+
+	result, err := docker.Stop(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.NotContains(t, engine.requests, "POST /containers/gameap-server-"+server.UUID()+"/kill")
+}
+
+func TestDocker_Stop_NoStopCommand_StoppedBySignal(t *testing.T) {
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+	engine, socket := givenFakeEngine(t)
+	docker := NewDocker(givenDockerConfig(t, socket))
+	server := makeServerWithSettings(t, map[string]string{})
+	_, err := docker.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	result, err := docker.Stop(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.False(t, engine.container("gameap-server-"+server.UUID()).running)
+	assert.Contains(t, engine.requests, "POST /containers/gameap-server-"+server.UUID()+"/kill")
+}
+
+func TestDocker_Uninstall_ContainerRemoved(t *testing.T) {
+	engine, socket := givenFakeEngine(t)
+	docker := NewDocker(givenDockerConfig(t, socket))
+	server := makeServerWithSettings(t, map[string]string{})
+	_, err := docker.Install(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	result, err := docker.Uninstall(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Nil(t, engine.container("gameap-server-"+server.UUID()))
+
+	result, err = docker.Uninstall(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+}
+
+func TestDocker_Install_NoImage_Error(t *testing.T) {
+	_, socket := givenFakeEngine(t)
+	cfg := givenDockerConfig(t, socket)
+	delete(cfg.ProcessManager.Config, "image")
+	docker := NewDocker(cfg)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	_, err := docker.Install(context.Background(), server, io.Discard)
+
+	assert.ErrorIs(t, err, ErrContainerImageNotSet)
+}
+
+func Test_parseMemoryLimit(t *testing.T) {
+	tests := map[string]int64{
+		"1024": 1024,
+		"512m": 512 << 20,
+		"2G":   2 << 30,
+		"64k":  64 << 10,
+	}
+	for limit, expected := range tests {
+		value, err := parseMemoryLimit(limit)
+		require.NoError(t, err, limit)
+		assert.Equal(t, expected, value, limit)
+	}
+
+	for _, limit := range []string{"", "m", "-1g", "1t"} {
+		_, err := parseMemoryLimit(limit)
+		assert.Error(t, err, limit)
+	}
+}
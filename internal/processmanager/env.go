@@ -0,0 +1,29 @@
+package processmanager
+
+import (
+	"sort"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+// serverEnvironment returns all environment variables of the game server, secrets included.
+func serverEnvironment(server *domain.Server) map[string]string {
+	env := server.Environment()
+
+	for key, value := range server.SecretEnvironment() {
+		env[key] = value
+	}
+
+	return env
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
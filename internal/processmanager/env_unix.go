@@ -0,0 +1,53 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package processmanager
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// writeEnvFile writes the environment file readable only by the owner.
+// The file is removed if there are no variables.
+func writeEnvFile(path string, content string) error {
+	if content == "" {
+		return removeEnvFile(path)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create directory")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.WithMessage(err, "failed to open file")
+	}
+
+	// The file may be created by the previous version with other permissions
+	err = f.Chmod(0600)
+	if err != nil {
+		_ = f.Close()
+		return errors.WithMessage(err, "failed to change file mode")
+	}
+
+	_, err = f.WriteString(content)
+	if err != nil {
+		_ = f.Close()
+		return errors.WithMessage(err, "failed to write to file")
+	}
+
+	return f.Close()
+}
+
+func removeEnvFile(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithMessage(err, "failed to remove file")
+	}
+
+	return nil
+}
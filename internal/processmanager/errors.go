@@ -3,9 +3,14 @@ package processmanager
 import "github.com/pkg/errors"
 
 var (
-	ErrUnknownProcessManager = errors.New("unknown process manager")
-	ErrEmptyUser             = errors.New("empty user")
-	ErrUserNotFound          = errors.New("user not found")
-	ErrInvalidUserPassword   = errors.New("invalid user password")
-	ErrEmptyCommand          = errors.New("empty command")
+	ErrUnknownProcessManager   = errors.New("unknown process manager")
+	ErrEmptyUser               = errors.New("empty user")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrInvalidUserPassword     = errors.New("invalid user password")
+	ErrEmptyCommand            = errors.New("empty command")
+	ErrConsoleUnavailable      = errors.New("game server console is unavailable")
+	ErrContainerImageNotSet    = errors.New("container image is not set (process_manager.config.image)")
+	ErrInvalidRestartPolicy    = errors.New("invalid restart policy, must be no, on-failure or always (process_manager.config.restart)")
+	ErrInvalidContainerNetwork = errors.New("invalid container network, must be bridge or host (process_manager.config.network)")
+	ErrSecretEnvUnsupported    = errors.New("secret environment variables (secret_env.*) aren't supported by winsw")
 )
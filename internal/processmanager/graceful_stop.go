@@ -0,0 +1,125 @@
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/gamequery"
+	"github.com/gameap/daemon/pkg/logger"
+)
+
+// stopCheckPeriod is the period of the game server status checks while it's stopping.
+var stopCheckPeriod = 1 * time.Second
+
+type inputSenderFunc func(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
+
+type statusFunc func(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+
+// gracefulStopper sends the game stop command (e.g. "stop" for Minecraft) and waits until the game server stops.
+type gracefulStopper struct {
+	cfg *config.Config
+	// The console input sender, nil if the process manager can't send the input
+	sendInput inputSenderFunc
+	status    statusFunc
+}
+
+// Stop returns true if the game server is stopped by the stop command.
+// It returns false if the stop command isn't configured, can't be sent or the game server isn't stopped in time,
+// the game server should be stopped by the signal then.
+func (s *gracefulStopper) Stop(ctx context.Context, server *domain.Server, out io.Writer) bool {
+	stopCommand := s.cfg.GracefulStopCommand(server.Game().Code)
+	if stopCommand.Command == "" {
+		return false
+	}
+
+	// The process manager without the console stops the game server the other way, it's not an error
+	if stopCommand.Method != config.CommandMethodRCON && s.sendInput == nil {
+		logger.Debug(ctx, "Game server console is unavailable, stop command isn't sent")
+
+		return false
+	}
+
+	running, err := s.running(ctx, server)
+	if err != nil || !running {
+		return false
+	}
+
+	err = s.send(ctx, server, stopCommand)
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Warn("Failed to send stop command to game server")
+		_, _ = fmt.Fprintf(out, "Failed to send stop command: %s\n", err.Error())
+
+		return false
+	}
+
+	_, _ = fmt.Fprintf(out, "Stop command %q is sent, waiting for the game server to stop\n", stopCommand.Command)
+
+	if s.waitStopped(ctx, server, s.cfg.GracefulStop.Timeout) {
+		_, _ = fmt.Fprintln(out, "Game server is stopped")
+
+		return true
+	}
+
+	_, _ = fmt.Fprintf(out, "Game server isn't stopped in %s\n", s.cfg.GracefulStop.Timeout)
+
+	return false
+}
+
+func (s *gracefulStopper) send(ctx context.Context, server *domain.Server, stopCommand config.GracefulStopCommand) error {
+	if stopCommand.Method == config.CommandMethodRCON {
+		_, err := gamequery.NewRCON().Exec(ctx, server, stopCommand.Command)
+
+		return err
+	}
+
+	result, err := s.sendInput(ctx, stopCommand.Command, server, io.Discard)
+	if err != nil {
+		return err
+	}
+	if result != domain.SuccessResult {
+		return ErrConsoleUnavailable
+	}
+
+	return nil
+}
+
+// waitStopped waits until the game server stops, it returns false if the timeout is exceeded.
+func (s *gracefulStopper) waitStopped(ctx context.Context, server *domain.Server, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		// The status check errors are ignored until the timeout is exceeded
+		if running, err := s.running(ctx, server); err == nil && !running {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(stopCheckPeriod):
+		}
+	}
+}
+
+func (s *gracefulStopper) running(ctx context.Context, server *domain.Server) (bool, error) {
+	result, err := s.status(ctx, server, io.Discard)
+	if err != nil {
+		return false, err
+	}
+
+	return result == domain.SuccessResult, nil
+}
+
+// signalName returns the signal name without the SIG prefix, e.g. TERM, as the kill command accepts it.
+func signalName(signal string) string {
+	return strings.TrimPrefix(signal, "SIG")
+}
@@ -0,0 +1,160 @@
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGameServer struct {
+	running   atomic.Bool
+	stopsOn   string
+	inputs    []string
+	sendFails bool
+}
+
+func (s *fakeGameServer) sendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	if s.sendFails {
+		return domain.ErrorResult, nil
+	}
+
+	s.inputs = append(s.inputs, input)
+	if input == s.stopsOn {
+		s.running.Store(false)
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (s *fakeGameServer) status(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	if s.running.Load() {
+		return domain.SuccessResult, nil
+	}
+
+	return domain.ErrorResult, nil
+}
+
+func givenGracefulStopConfig(command string) *config.Config {
+	cfg := &config.Config{}
+	cfg.GracefulStop.Timeout = 100 * time.Millisecond
+	cfg.GracefulStop.Method = config.CommandMethodConsole
+	cfg.GracefulStop.Command = command
+
+	return cfg
+}
+
+// givenStopCheckPeriod shortens the status checks of the stopping game server for the test.
+func givenStopCheckPeriod(t *testing.T, period time.Duration) {
+	t.Helper()
+
+	previous := stopCheckPeriod
+	stopCheckPeriod = period
+	t.Cleanup(func() {
+		stopCheckPeriod = previous
+	})
+}
+
+func TestGracefulStopper_Stop_ServerStoppedByCommand(t *testing.T) {
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+	gameServer := &fakeGameServer{stopsOn: "stop"}
+	gameServer.running.Store(true)
+	stopper := &gracefulStopper{
+		cfg:       givenGracefulStopConfig("stop"),
+		sendInput: gameServer.sendInput,
+		status:    gameServer.status,
+	}
+	out := &bytes.Buffer{}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), out)
+
+	assert.True(t, stopped)
+	assert.Equal(t, []string{"stop"}, gameServer.inputs)
+	assert.Contains(t, out.String(), "Game server is stopped")
+}
+
+func TestGracefulStopper_Stop_ServerNotStoppedInTime(t *testing.T) {
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+	gameServer := &fakeGameServer{stopsOn: "quit"}
+	gameServer.running.Store(true)
+	stopper := &gracefulStopper{
+		cfg:       givenGracefulStopConfig("stop"),
+		sendInput: gameServer.sendInput,
+		status:    gameServer.status,
+	}
+	out := &bytes.Buffer{}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), out)
+
+	assert.False(t, stopped)
+	assert.True(t, gameServer.running.Load())
+	assert.Contains(t, out.String(), "Game server isn't stopped in 100ms")
+}
+
+func TestGracefulStopper_Stop_NoStopCommand_CommandNotSent(t *testing.T) {
+	gameServer := &fakeGameServer{}
+	gameServer.running.Store(true)
+	stopper := &gracefulStopper{
+		cfg:       givenGracefulStopConfig(""),
+		sendInput: gameServer.sendInput,
+		status:    gameServer.status,
+	}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), io.Discard)
+
+	assert.False(t, stopped)
+	assert.Empty(t, gameServer.inputs)
+}
+
+func TestGracefulStopper_Stop_ServerNotRunning_CommandNotSent(t *testing.T) {
+	gameServer := &fakeGameServer{}
+	stopper := &gracefulStopper{
+		cfg:       givenGracefulStopConfig("stop"),
+		sendInput: gameServer.sendInput,
+		status:    gameServer.status,
+	}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), io.Discard)
+
+	assert.False(t, stopped)
+	assert.Empty(t, gameServer.inputs)
+}
+
+func TestGracefulStopper_Stop_NoConsole_CommandNotSent(t *testing.T) {
+	gameServer := &fakeGameServer{}
+	gameServer.running.Store(true)
+	stopper := &gracefulStopper{
+		cfg:    givenGracefulStopConfig("stop"),
+		status: gameServer.status,
+	}
+	out := &bytes.Buffer{}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), out)
+
+	assert.False(t, stopped)
+	assert.Empty(t, out.String())
+}
+
+func TestGracefulStopper_Stop_SendFailed_CommandNotSent(t *testing.T) {
+	gameServer := &fakeGameServer{sendFails: true}
+	gameServer.running.Store(true)
+	stopper := &gracefulStopper{
+		cfg:       givenGracefulStopConfig("stop"),
+		sendInput: gameServer.sendInput,
+		status:    gameServer.status,
+	}
+
+	stopped := stopper.Stop(context.Background(), domaintest.NewServer(), io.Discard)
+
+	assert.False(t, stopped)
+	assert.True(t, gameServer.running.Load())
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 )
 
+// defaultMigrationSources are the process managers checked for the running game servers by the migration.
+// Other process managers are checked only if they're requested.
+var defaultMigrationSources = []string{"tmux", "screen"}
+
 func Load(
 	name string, cfg *config.Config,
 	executor contracts.Executor,
@@ -16,6 +20,8 @@ func Load(
 	switch name {
 	case "tmux":
 		return NewTmux(cfg, executor, detailedExecutor), nil
+	case "screen":
+		return NewScreen(cfg, executor, detailedExecutor), nil
 	case "simple":
 		return NewSimple(cfg, executor, detailedExecutor), nil
 	default:
@@ -8,16 +8,28 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 )
 
+// defaultMigrationSources are the process managers checked for the running game servers by the migration.
+// Other process managers are checked only if they're requested.
+var defaultMigrationSources = []string{"tmux", "screen", "systemd", "supervisor"}
+
 func Load(
 	name string, cfg *config.Config, executor, detailedExecutor contracts.Executor,
 ) (contracts.ProcessManager, error) {
 	switch name {
 	case "tmux":
 		return NewTmux(cfg, executor, detailedExecutor), nil
+	case "screen":
+		return NewScreen(cfg, executor, detailedExecutor), nil
 	case "systemd":
 		return NewSystemD(cfg, executor, detailedExecutor), nil
 	case "simple":
 		return NewSimple(cfg, executor, detailedExecutor), nil
+	case "docker":
+		return NewDocker(cfg), nil
+	case "podman":
+		return NewPodman(cfg), nil
+	case "supervisor":
+		return NewSupervisor(cfg)
 	default:
 		return nil, ErrUnknownProcessManager
 	}
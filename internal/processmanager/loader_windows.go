@@ -8,6 +8,10 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 )
 
+// defaultMigrationSources are the process managers checked for the running game servers by the migration.
+// Other process managers are checked only if they're requested.
+var defaultMigrationSources []string
+
 func Load(
 	name string, cfg *config.Config, executor, detailedExecutor contracts.Executor,
 ) (contracts.ProcessManager, error) {
@@ -0,0 +1,257 @@
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type MigrationOptions struct {
+	// From are the names of the previous process managers, defaultMigrationSources are checked if it's empty.
+	From []string
+	// DryRun only reports the game servers to migrate.
+	DryRun bool
+}
+
+type processManagerLoader func(name string) (contracts.ProcessManager, error)
+
+// Migrator moves the game servers running under the previous process managers to the current one,
+// so they aren't orphaned and started twice after process_manager.name is changed.
+type Migrator struct {
+	cfg    *config.Config
+	target contracts.ProcessManager
+	load   processManagerLoader
+}
+
+func NewMigrator(
+	cfg *config.Config, target contracts.ProcessManager, executor, detailedExecutor contracts.Executor,
+) *Migrator {
+	return &Migrator{
+		cfg:    cfg,
+		target: target,
+		load: func(name string) (contracts.ProcessManager, error) {
+			return Load(name, cfg, executor, detailedExecutor)
+		},
+	}
+}
+
+type migrationSource struct {
+	name string
+	pm   contracts.ProcessManager
+}
+
+// Migrate stops each game server running under the previous process manager, installs it
+// under the current process manager and starts it again. Failed game servers don't stop the migration
+// of the others, the error is returned after all game servers are processed.
+func (m *Migrator) Migrate(
+	ctx context.Context, servers []*domain.Server, options MigrationOptions, out io.Writer,
+) error {
+	sources, err := m.sources(options.From)
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		_, _ = fmt.Fprintln(out, "No process managers to migrate from")
+
+		return nil
+	}
+
+	if options.DryRun {
+		_, _ = fmt.Fprintln(out, "Dry run, game servers aren't changed")
+	}
+
+	failed := 0
+	for _, server := range servers {
+		err = m.migrate(ctx, server, sources, options.DryRun, out)
+		if err != nil {
+			failed++
+			logger.WithError(ctx, err).WithField("gameServerID", server.ID()).Warn("Failed to migrate game server")
+			_, _ = fmt.Fprintf(out, "Server %d (%s): %s\n", server.ID(), server.UUID(), err.Error())
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to migrate %d of %d game servers", failed, len(servers))
+	}
+
+	return nil
+}
+
+func (m *Migrator) migrate(
+	ctx context.Context, server *domain.Server, sources []migrationSource, dryRun bool, out io.Writer,
+) error {
+	source := m.runningUnder(ctx, server, sources)
+	if source == nil {
+		_, _ = fmt.Fprintf(out, "Server %d (%s): not running under previous process managers, skipped\n",
+			server.ID(), server.UUID(),
+		)
+
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(out, "Server %d (%s): running under %s, moving to %s\n",
+		server.ID(), server.UUID(), source.name, m.cfg.ProcessManager.Name,
+	)
+
+	if dryRun {
+		return nil
+	}
+
+	err := m.move(ctx, server, source, out)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(out, "Server %d (%s): started under %s\n", server.ID(), server.UUID(), m.cfg.ProcessManager.Name)
+
+	return nil
+}
+
+// MigrateRunning moves the game servers running under the previous process managers (the default ones)
+// to the current one. It's called before the game servers are started by autostart after the daemon start,
+// so the game servers left by the previous process manager aren't started twice.
+// It returns the IDs of the game servers failed to migrate and still running under the previous process manager.
+func (m *Migrator) MigrateRunning(ctx context.Context, servers []*domain.Server) map[int]struct{} {
+	left := map[int]struct{}{}
+
+	sources := m.availableSources(ctx)
+	if len(sources) == 0 {
+		return left
+	}
+
+	for _, server := range servers {
+		source := m.runningUnder(ctx, server, sources)
+		if source == nil {
+			continue
+		}
+
+		l := logger.Logger(ctx).WithField("gameServerID", server.ID()).WithField("processManager", source.name)
+		l.Info("Game server is running under previous process manager, migrating")
+
+		err := m.move(ctx, server, source, io.Discard)
+		if err != nil && m.runningUnder(ctx, server, []migrationSource{*source}) != nil {
+			l.WithError(err).Warn("Failed to migrate game server, it's left under previous process manager")
+			left[server.ID()] = struct{}{}
+
+			continue
+		}
+		if err != nil {
+			// The game server is stopped under the previous process manager, autostart starts it again
+			l.WithError(err).Warn("Failed to migrate game server")
+
+			continue
+		}
+
+		l.Info("Game server is migrated")
+	}
+
+	return left
+}
+
+// move stops the game server under the previous process manager and starts it under the current one.
+func (m *Migrator) move(ctx context.Context, server *domain.Server, source *migrationSource, out io.Writer) error {
+	result, err := source.pm.Stop(ctx, server, out)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to stop game server under %s", source.name)
+	}
+	if result != domain.SuccessResult {
+		return errors.Errorf("failed to stop game server under %s", source.name)
+	}
+
+	// The files of the previous process manager are removed, e.g. systemd units mustn't start the game server again
+	_, err = source.pm.Uninstall(ctx, server, out)
+	if err != nil {
+		logger.WithError(ctx, err).Warn("Failed to uninstall game server from previous process manager")
+	}
+
+	result, err = m.target.Install(ctx, server, out)
+	if err != nil {
+		return errors.WithMessage(err, "failed to install game server")
+	}
+	if result != domain.SuccessResult {
+		return errors.New("failed to install game server")
+	}
+
+	result, err = m.target.Start(ctx, server, out)
+	if err != nil {
+		return errors.WithMessage(err, "failed to start game server")
+	}
+	if result != domain.SuccessResult {
+		return errors.New("failed to start game server")
+	}
+
+	return nil
+}
+
+// runningUnder returns the previous process manager the game server is running under.
+// The process managers failed to check the status (e.g. not installed tmux) are skipped.
+func (m *Migrator) runningUnder(
+	ctx context.Context, server *domain.Server, sources []migrationSource,
+) *migrationSource {
+	for i := range sources {
+		result, err := sources[i].pm.Status(ctx, server, io.Discard)
+		if err != nil {
+			logger.WithError(ctx, err).WithField("processManager", sources[i].name).
+				Debug("Failed to check game server status")
+
+			continue
+		}
+
+		if result == domain.SuccessResult {
+			return &sources[i]
+		}
+	}
+
+	return nil
+}
+
+// availableSources returns the default previous process managers,
+// the process managers failed to load (e.g. invalid for the current config) are skipped.
+func (m *Migrator) availableSources(ctx context.Context) []migrationSource {
+	sources := make([]migrationSource, 0, len(defaultMigrationSources))
+	for _, name := range defaultMigrationSources {
+		if name == m.cfg.ProcessManager.Name {
+			continue
+		}
+
+		pm, err := m.load(name)
+		if err != nil {
+			logger.WithError(ctx, err).WithField("processManager", name).Debug("Failed to load process manager")
+
+			continue
+		}
+
+		sources = append(sources, migrationSource{name: name, pm: pm})
+	}
+
+	return sources
+}
+
+func (m *Migrator) sources(names []string) ([]migrationSource, error) {
+	if len(names) == 0 {
+		names = defaultMigrationSources
+	}
+
+	sources := make([]migrationSource, 0, len(names))
+	for _, name := range names {
+		if name == m.cfg.ProcessManager.Name {
+			continue
+		}
+
+		pm, err := m.load(name)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to load process manager %s", name)
+		}
+
+		sources = append(sources, migrationSource{name: name, pm: pm})
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,212 @@
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessManager records the calls and keeps the running game servers by UUID.
+type fakeProcessManager struct {
+	running   map[string]bool
+	calls     []string
+	statusErr error
+	stopFails bool
+}
+
+func newFakeProcessManager(running ...string) *fakeProcessManager {
+	pm := &fakeProcessManager{running: map[string]bool{}}
+	for _, uuid := range running {
+		pm.running[uuid] = true
+	}
+
+	return pm
+}
+
+func (pm *fakeProcessManager) call(name string, server *domain.Server) {
+	pm.calls = append(pm.calls, name+" "+server.UUID())
+}
+
+func (pm *fakeProcessManager) Install(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.call("install", server)
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Uninstall(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.call("uninstall", server)
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Start(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.call("start", server)
+	pm.running[server.UUID()] = true
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Stop(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.call("stop", server)
+	if pm.stopFails {
+		return domain.ErrorResult, nil
+	}
+	pm.running[server.UUID()] = false
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Restart(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Status(_ context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	if pm.statusErr != nil {
+		return domain.ErrorResult, pm.statusErr
+	}
+
+	if pm.running[server.UUID()] {
+		return domain.SuccessResult, nil
+	}
+
+	return domain.ErrorResult, nil
+}
+
+func (pm *fakeProcessManager) GetOutput(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) SendInput(
+	_ context.Context, _ string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+
+func givenMigrator(
+	target contracts.ProcessManager, sources map[string]contracts.ProcessManager,
+) *Migrator {
+	cfg := &config.Config{}
+	cfg.ProcessManager.Name = "systemd"
+
+	return &Migrator{
+		cfg:    cfg,
+		target: target,
+		load: func(name string) (contracts.ProcessManager, error) {
+			pm, ok := sources[name]
+			if !ok {
+				return nil, ErrUnknownProcessManager
+			}
+
+			return pm, nil
+		},
+	}
+}
+
+func givenMigrationServer(id int, uuid string) *domain.Server {
+	return domaintest.NewServer(domaintest.WithID(id), domaintest.WithUUID(uuid))
+}
+
+func TestMigrator_Migrate_RunningServersMoved(t *testing.T) {
+	running := givenMigrationServer(1, "11111111-d910-11eb-aff7-d796d7fcf7ef")
+	stopped := givenMigrationServer(2, "22222222-d910-11eb-aff7-d796d7fcf7ef")
+	tmux := newFakeProcessManager(running.UUID())
+	screen := newFakeProcessManager()
+	screen.statusErr = errors.New("screen is not installed")
+	target := newFakeProcessManager()
+	migrator := givenMigrator(target, map[string]contracts.ProcessManager{"tmux": tmux, "screen": screen})
+	out := &bytes.Buffer{}
+
+	err := migrator.Migrate(
+		context.Background(),
+		[]*domain.Server{running, stopped},
+		MigrationOptions{From: []string{"screen", "tmux", "systemd"}},
+		out,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stop " + running.UUID(), "uninstall " + running.UUID()}, tmux.calls)
+	assert.Equal(t, []string{"install " + running.UUID(), "start " + running.UUID()}, target.calls)
+	assert.Equal(t,
+		"Server 1 (11111111-d910-11eb-aff7-d796d7fcf7ef): running under tmux, moving to systemd\n"+
+			"Server 1 (11111111-d910-11eb-aff7-d796d7fcf7ef): started under systemd\n"+
+			"Server 2 (22222222-d910-11eb-aff7-d796d7fcf7ef): not running under previous process managers, skipped\n",
+		out.String(),
+	)
+}
+
+func TestMigrator_Migrate_DryRun_ServersNotChanged(t *testing.T) {
+	server := givenMigrationServer(1, "11111111-d910-11eb-aff7-d796d7fcf7ef")
+	tmux := newFakeProcessManager(server.UUID())
+	target := newFakeProcessManager()
+	migrator := givenMigrator(target, map[string]contracts.ProcessManager{"tmux": tmux})
+	out := &bytes.Buffer{}
+
+	err := migrator.Migrate(
+		context.Background(), []*domain.Server{server}, MigrationOptions{From: []string{"tmux"}, DryRun: true}, out,
+	)
+
+	require.NoError(t, err)
+	assert.Empty(t, tmux.calls)
+	assert.Empty(t, target.calls)
+	assert.Equal(t,
+		"Dry run, game servers aren't changed\n"+
+			"Server 1 (11111111-d910-11eb-aff7-d796d7fcf7ef): running under tmux, moving to systemd\n",
+		out.String(),
+	)
+}
+
+func TestMigrator_Migrate_UnknownProcessManager_Error(t *testing.T) {
+	migrator := givenMigrator(newFakeProcessManager(), map[string]contracts.ProcessManager{})
+
+	err := migrator.Migrate(context.Background(), nil, MigrationOptions{From: []string{"upstart"}}, io.Discard)
+
+	assert.ErrorIs(t, err, ErrUnknownProcessManager)
+}
+
+func TestMigrator_MigrateRunning_ServersMovedFromDefaultSources(t *testing.T) {
+	givenDefaultMigrationSources(t, "tmux", "screen", "systemd")
+	moved := givenMigrationServer(1, "11111111-d910-11eb-aff7-d796d7fcf7ef")
+	stuck := givenMigrationServer(2, "22222222-d910-11eb-aff7-d796d7fcf7ef")
+	stopped := givenMigrationServer(3, "33333333-d910-11eb-aff7-d796d7fcf7ef")
+	tmux := newFakeProcessManager(moved.UUID())
+	screen := newFakeProcessManager(stuck.UUID())
+	screen.stopFails = true
+	target := newFakeProcessManager()
+	migrator := givenMigrator(target, map[string]contracts.ProcessManager{"tmux": tmux, "screen": screen})
+
+	left := migrator.MigrateRunning(context.Background(), []*domain.Server{moved, stuck, stopped})
+
+	assert.Equal(t, map[int]struct{}{2: {}}, left)
+	assert.Equal(t, []string{"stop " + moved.UUID(), "uninstall " + moved.UUID()}, tmux.calls)
+	assert.Equal(t, []string{"install " + moved.UUID(), "start " + moved.UUID()}, target.calls)
+}
+
+func TestMigrator_MigrateRunning_NoSources_NothingChanged(t *testing.T) {
+	givenDefaultMigrationSources(t, "systemd")
+	target := newFakeProcessManager()
+	migrator := givenMigrator(target, map[string]contracts.ProcessManager{})
+
+	server := givenMigrationServer(1, "11111111-d910-11eb-aff7-d796d7fcf7ef")
+
+	left := migrator.MigrateRunning(context.Background(), []*domain.Server{server})
+
+	assert.Empty(t, left)
+	assert.Empty(t, target.calls)
+}
+
+func givenDefaultMigrationSources(t *testing.T, names ...string) {
+	t.Helper()
+
+	previous := defaultMigrationSources
+	defaultMigrationSources = names
+	t.Cleanup(func() {
+		defaultMigrationSources = previous
+	})
+}
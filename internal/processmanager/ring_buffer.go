@@ -0,0 +1,57 @@
+package processmanager
+
+import "sync"
+
+// ringBuffer keeps the last written bytes, the older bytes are overwritten.
+type ringBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	if n >= len(b.buf) {
+		copy(b.buf, p[n-len(b.buf):])
+		b.pos = 0
+		b.full = true
+
+		return n, nil
+	}
+
+	copied := copy(b.buf[b.pos:], p)
+	if copied < n {
+		copy(b.buf, p[copied:])
+		b.full = true
+	}
+
+	b.pos = (b.pos + n) % len(b.buf)
+	if b.pos == 0 {
+		b.full = true
+	}
+
+	return n, nil
+}
+
+// Bytes returns the copy of the kept bytes in the order they were written.
+func (b *ringBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]byte(nil), b.buf[:b.pos]...)
+	}
+
+	result := make([]byte, 0, len(b.buf))
+	result = append(result, b.buf[b.pos:]...)
+
+	return append(result, b.buf[:b.pos]...)
+}
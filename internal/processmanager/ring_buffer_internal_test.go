@@ -0,0 +1,32 @@
+package processmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{"empty", nil, ""},
+		{"not full", []string{"ab", "c"}, "abc"},
+		{"exactly full", []string{"abc", "de"}, "abcde"},
+		{"overwritten", []string{"abc", "def", "g"}, "cdefg"},
+		{"write longer than buffer", []string{"ab", "0123456789"}, "56789"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newRingBuffer(5)
+
+			for _, w := range test.writes {
+				_, _ = b.Write([]byte(w))
+			}
+
+			assert.Equal(t, test.expected, string(b.Bytes()))
+		})
+	}
+}
@@ -0,0 +1,350 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/gameap/daemon/pkg/shellquote"
+	"github.com/pkg/errors"
+)
+
+const screenFilesDir = ".screen"
+
+// Screen runs the game servers in GNU screen sessions named by the server UUID,
+// as the nodes managed by the legacy scripts do.
+type Screen struct {
+	cfg              *config.Config
+	executor         contracts.Executor
+	detailedExecutor contracts.Executor
+}
+
+func NewScreen(cfg *config.Config, executor, detailedExecutor contracts.Executor) *Screen {
+	return &Screen{
+		cfg:              cfg,
+		executor:         executor,
+		detailedExecutor: detailedExecutor,
+	}
+}
+
+func (pm *Screen) Install(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	// Nothing to do here
+	return domain.SuccessResult, nil
+}
+
+func (pm *Screen) Uninstall(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	err := removeEnvFile(pm.envFile(server))
+	if err != nil {
+		logger.WithError(ctx, err).Warn("failed to remove environment file")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Screen) Start(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	startCmd := domain.MakeFullCommand(pm.cfg, server, pm.cfg.Scripts.Start, server.StartCommand())
+
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	envFile, err := makeSessionEnvFile(pm.envFile(server), server, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to make environment file")
+	}
+	if envFile != "" {
+		startCmd = "set -a; . " + shellquote.Join(envFile) + "; set +a; " + startCmd
+	}
+
+	result, err := pm.detailedExecutor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(
+			`screen -dmS %s -h %d sh -c %s`,
+			server.UUID(), defaultHistoryLimit, shellquote.Join(startCmd),
+		),
+		out,
+		options,
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	return domain.Result(result), nil
+}
+
+// Stop sends the game stop command, then terminates the window processes by the signal and kills them
+// if the game server isn't stopped in time.
+func (pm *Screen) Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	stopper := &gracefulStopper{
+		cfg:       pm.cfg,
+		sendInput: pm.SendInput,
+		status:    pm.Status,
+	}
+
+	if stopper.Stop(ctx, server, out) {
+		return domain.SuccessResult, nil
+	}
+
+	if pm.signal(ctx, server, pm.cfg.GracefulStop.Signal, options, out) {
+		if stopper.waitStopped(ctx, server, pm.cfg.GracefulStop.Timeout) {
+			return domain.SuccessResult, nil
+		}
+
+		if pm.signal(ctx, server, "SIGKILL", options, out) && stopper.waitStopped(ctx, server, stopCheckPeriod) {
+			return domain.SuccessResult, nil
+		}
+	}
+
+	result, err := pm.detailedExecutor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`screen -S %s -X quit`, server.UUID()),
+		out,
+		options,
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	return domain.Result(result), nil
+}
+
+// signal sends the signal to the process group of the session window, it returns false if the session isn't found.
+func (pm *Screen) signal(
+	ctx context.Context, server *domain.Server, signal string, options contracts.ExecutorOptions, out io.Writer,
+) bool {
+	sessionPID, err := pm.sessionPID(ctx, server, options)
+	if err != nil || sessionPID == 0 {
+		return false
+	}
+
+	// The window process is the child of the session process and the leader of its own process group
+	output, result, err := pm.executor.Exec(ctx, fmt.Sprintf(`pgrep -P %d`, sessionPID), options)
+	if err != nil || result != 0 {
+		return false
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return false
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return false
+	}
+
+	_, err = pm.detailedExecutor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`kill -s %s -- -%d`, signalName(signal), pid),
+		out,
+		options,
+	)
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Warn("Failed to send signal to game server")
+		return false
+	}
+
+	return true
+}
+
+func (pm *Screen) Restart(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	statusResult, err := pm.Status(ctx, server, out)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if statusResult == domain.SuccessResult {
+		_, err = pm.Stop(ctx, server, out)
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to stop server")
+		}
+	}
+
+	return pm.Start(ctx, server, out)
+}
+
+func (pm *Screen) Status(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	pid, err := pm.sessionPID(ctx, server, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	if pid == 0 {
+		return domain.ErrorResult, nil
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// GetOutput writes the window contents with the scrollback to the hardcopy file and copies it to out.
+func (pm *Screen) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	path, err := pm.makeHardcopyFile(server, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to create hardcopy file")
+	}
+	defer func() {
+		_ = os.Remove(path)
+	}()
+
+	result, err := pm.executor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`screen -S %s -p 0 -X hardcopy -h %s`, server.UUID(), shellquote.Join(path)),
+		io.Discard,
+		options,
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+	if domain.Result(result) != domain.SuccessResult {
+		return domain.Result(result), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to read hardcopy file")
+	}
+
+	_, err = out.Write(trimHardcopy(content))
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to write output")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Screen) SendInput(
+	ctx context.Context, input string, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	result, err := pm.detailedExecutor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`screen -S %s -p 0 -X stuff %s`, server.UUID(), shellquote.Join(input+"\n")),
+		out,
+		options,
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	return domain.Result(result), nil
+}
+
+// sessionPID returns the PID of the session process from the "screen -ls" output, 0 if the session isn't found.
+// The exit code of "screen -ls" differs between the versions, so only the output is checked.
+func (pm *Screen) sessionPID(
+	ctx context.Context, server *domain.Server, options contracts.ExecutorOptions,
+) (int, error) {
+	output, _, err := pm.executor.Exec(ctx, fmt.Sprintf(`screen -ls %s`, server.UUID()), options)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseScreenSessionPID(output, server.UUID()), nil
+}
+
+// makeHardcopyFile creates the empty hardcopy file,
+// it's written by the session, so it must be writable by the server user.
+func (pm *Screen) makeHardcopyFile(server *domain.Server, options contracts.ExecutorOptions) (string, error) {
+	path := pm.hardcopyFile(server)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to create directory")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to open file")
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to close file")
+	}
+
+	return path, chownToUser(path, options)
+}
+
+func (pm *Screen) envFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), screenFilesDir, server.UUID()+".env")
+}
+
+func (pm *Screen) hardcopyFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), screenFilesDir, server.UUID()+".hardcopy")
+}
+
+func (pm *Screen) executeOptions(server *domain.Server) (contracts.ExecutorOptions, error) {
+	return sessionExecuteOptions(pm.cfg, server)
+}
+
+// parseScreenSessionPID finds the session line like "\t12345.<name>\t(Detached)", the dead sessions are skipped.
+func parseScreenSessionPID(output []byte, name string) int {
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.Contains(line, "(Dead") {
+			continue
+		}
+
+		pidStr, sessionName, ok := strings.Cut(fields[0], ".")
+		if !ok || sessionName != name {
+			continue
+		}
+
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		return pid
+	}
+
+	return 0
+}
+
+// trimHardcopy removes the trailing spaces of the lines and the empty lines at the end,
+// screen pads the hardcopy to the window size.
+func trimHardcopy(content []byte) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	for i := range lines {
+		lines[i] = bytes.TrimRight(lines[i], " \r")
+	}
+
+	result := bytes.TrimRight(bytes.Join(lines, []byte("\n")), "\n")
+	if len(result) == 0 {
+		return result
+	}
+
+	return append(result, '\n')
+}
@@ -0,0 +1,216 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/pkg/shellquote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// screenExecutor emulates the screen session of the game server.
+type screenExecutor struct {
+	running  bool
+	dead     bool
+	hardcopy string
+	// stopsOn is the input stopping the game server
+	stopsOn  string
+	commands []string
+}
+
+func (ex *screenExecutor) Exec(
+	ctx context.Context, command string, options contracts.ExecutorOptions,
+) ([]byte, int, error) {
+	out := &bytes.Buffer{}
+	result, err := ex.ExecWithWriter(ctx, command, out, options)
+
+	return out.Bytes(), result, err
+}
+
+func (ex *screenExecutor) ExecWithWriter(
+	_ context.Context, command string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	ex.commands = append(ex.commands, command)
+
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case strings.HasPrefix(command, "screen -dmS"):
+		ex.running = true
+	case strings.HasPrefix(command, "screen -ls"):
+		if ex.dead {
+			_, _ = fmt.Fprintf(out, "There is a screen on:\n\t4321.%s\t(Dead ???)\n", args[2])
+			return 1, nil
+		}
+		if !ex.running {
+			_, _ = fmt.Fprintf(out, "No Sockets found in /run/screen/S-gameap.\n")
+			return 1, nil
+		}
+
+		_, _ = fmt.Fprintf(out, "There is a screen on:\n\t4321.%s\t(10/18/2026 02:00:00 PM)\t(Detached)\n", args[2])
+
+		// The exit code is 1 even if the session is found in some versions
+		return 1, nil
+	case strings.HasPrefix(command, "pgrep -P 4321"):
+		_, _ = fmt.Fprintln(out, "4322")
+	case strings.HasPrefix(command, "kill -s"):
+		ex.running = false
+	case strings.Contains(command, "-X hardcopy"):
+		if !ex.running {
+			return 1, nil
+		}
+
+		return 0, os.WriteFile(args[len(args)-1], []byte(ex.hardcopy), 0600)
+	case strings.Contains(command, "-X stuff"):
+		if !ex.running {
+			return 1, nil
+		}
+		if args[len(args)-1] == ex.stopsOn+"\n" {
+			ex.running = false
+		}
+	case strings.Contains(command, "-X quit"):
+		ex.running = false
+	}
+
+	return 0, nil
+}
+
+func givenScreen(t *testing.T, executor *screenExecutor) *Screen {
+	t.Helper()
+
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+
+	cfg := &config.Config{
+		WorkPath: t.TempDir(),
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	cfg.GracefulStop.Timeout = 100 * time.Millisecond
+	cfg.GracefulStop.Signal = "SIGTERM"
+	cfg.GracefulStop.Method = config.CommandMethodConsole
+
+	return NewScreen(cfg, executor, executor)
+}
+
+func TestScreen_Start(t *testing.T) {
+	executor := &screenExecutor{}
+	screen := givenScreen(t, executor)
+	server := makeServerWithSettings(t, map[string]string{"env.GAME_MODE": "competitive"})
+
+	result, err := screen.Start(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Equal(t, []string{
+		"screen -dmS 759b875e-d910-11eb-aff7-d796d7fcf7ef -h 30000 sh -c " +
+			shellquote.Join("set -a; . "+screen.envFile(server)+"; set +a; /usr/bin/env"),
+	}, executor.commands)
+	assert.FileExists(t, screen.envFile(server))
+}
+
+func TestScreen_Status(t *testing.T) {
+	tests := []struct {
+		name     string
+		executor *screenExecutor
+		expected int
+	}{
+		{"running", &screenExecutor{running: true}, 0},
+		{"not running", &screenExecutor{}, 1},
+		{"dead", &screenExecutor{dead: true}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			screen := givenScreen(t, test.executor)
+			server := makeServerWithSettings(t, map[string]string{})
+
+			result, err := screen.Status(context.Background(), server, io.Discard)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, int(result))
+		})
+	}
+}
+
+func TestScreen_GetOutput(t *testing.T) {
+	executor := &screenExecutor{running: true, hardcopy: "Server started   \nPlayer joined\n\n\n   \n"}
+	screen := givenScreen(t, executor)
+	server := makeServerWithSettings(t, map[string]string{})
+	out := &bytes.Buffer{}
+
+	result, err := screen.GetOutput(context.Background(), server, out)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Equal(t, "Server started\nPlayer joined\n", out.String())
+	assert.NoFileExists(t, screen.hardcopyFile(server))
+}
+
+func TestScreen_SendInput(t *testing.T) {
+	executor := &screenExecutor{running: true}
+	screen := givenScreen(t, executor)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	result, err := screen.SendInput(context.Background(), "say it's me", server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Equal(t, []string{
+		"screen -S 759b875e-d910-11eb-aff7-d796d7fcf7ef -p 0 -X stuff 'say it'\\''s me\n'",
+	}, executor.commands)
+}
+
+func TestScreen_Stop_StoppedByCommand(t *testing.T) {
+	executor := &screenExecutor{running: true, stopsOn: "quit"}
+	screen := givenScreen(t, executor)
+	screen.cfg.GracefulStop.Command = "quit"
+	server := makeServerWithSettings(t, map[string]string{})
+
+	result, err := screen.Stop(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.False(t, executor.running)
+	for _, command := range executor.commands {
+		assert.NotContains(t, command, "kill")
+	}
+}
+
+func TestScreen_Stop_StoppedBySignal(t *testing.T) {
+	executor := &screenExecutor{running: true}
+	screen := givenScreen(t, executor)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	result, err := screen.Stop(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, int(result))
+	assert.Contains(t, executor.commands, "kill -s TERM -- -4322")
+	assert.NotContains(t, executor.commands, "screen -S 759b875e-d910-11eb-aff7-d796d7fcf7ef -X quit")
+}
+
+func Test_parseScreenSessionPID(t *testing.T) {
+	output := []byte("There are screens on:\n" +
+		"\t1111.other\t(Detached)\n" +
+		"\t2222.gameap-759b875e\t(Attached)\n" +
+		"\t3333.759b875e\t(Detached)\n" +
+		"2 Sockets in /run/screen/S-gameap.\n")
+
+	assert.Equal(t, 3333, parseScreenSessionPID(output, "759b875e"))
+	assert.Equal(t, 0, parseScreenSessionPID(output, "unknown"))
+}
@@ -0,0 +1,21 @@
+package processmanager
+
+import (
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+)
+
+func makeServerWithStartCommandAndDir(
+	startCommand, dir string, options ...domaintest.ServerOption,
+) *domain.Server {
+	return domaintest.NewServer(append([]domaintest.ServerOption{
+		domaintest.WithID(1337),
+		domaintest.WithStartCommand(startCommand),
+		domaintest.WithDir(dir),
+		domaintest.WithVars(map[string]string{
+			"default_map": "de_dust2",
+			"tickrate":    "1000",
+		}),
+		domaintest.Active(),
+	}, options...)...)
+}
@@ -0,0 +1,94 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package processmanager
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/shellquote"
+	"github.com/pkg/errors"
+)
+
+// makeSessionEnvFile writes the server environment variables to the file owned by the server user,
+// the terminal sessions load it before the start command. Returns an empty path if the server has no variables.
+func makeSessionEnvFile(path string, server *domain.Server, options contracts.ExecutorOptions) (string, error) {
+	env := serverEnvironment(server)
+	if len(env) == 0 {
+		return "", removeEnvFile(path)
+	}
+
+	builder := strings.Builder{}
+	for _, key := range sortedKeys(env) {
+		builder.WriteString(key)
+		builder.WriteString("=")
+		builder.WriteString(shellquote.Join(env[key]))
+		builder.WriteString("\n")
+	}
+
+	err := writeEnvFile(path, builder.String())
+	if err != nil {
+		return "", err
+	}
+
+	err = chownToUser(path, options)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// chownToUser changes the file owner to the user of the options if the daemon runs as root.
+func chownToUser(path string, options contracts.ExecutorOptions) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+
+	uid, err := strconv.Atoi(options.UID)
+	if err != nil {
+		return errors.WithMessage(err, "invalid user uid")
+	}
+	gid, err := strconv.Atoi(options.GID)
+	if err != nil {
+		return errors.WithMessage(err, "invalid user gid")
+	}
+
+	err = os.Chown(path, uid, gid)
+	if err != nil {
+		return errors.WithMessage(err, "failed to change file owner")
+	}
+
+	return nil
+}
+
+// sessionExecuteOptions returns the options to run the commands as the server user in the server directory.
+func sessionExecuteOptions(cfg *config.Config, server *domain.Server) (contracts.ExecutorOptions, error) {
+	var systemUser *user.User
+	var err error
+
+	if server.User() != "" {
+		systemUser, err = user.Lookup(server.User())
+		if err != nil {
+			return contracts.ExecutorOptions{}, errors.WithMessagef(err, "failed to lookup user %s", server.User())
+		}
+	} else {
+		systemUser, err = user.Current()
+		if err != nil {
+			return contracts.ExecutorOptions{}, errors.WithMessage(err, "failed to get current user")
+		}
+	}
+
+	return contracts.ExecutorOptions{
+		WorkDir:         server.WorkDir(cfg),
+		FallbackWorkDir: systemUser.HomeDir,
+		UID:             systemUser.Uid,
+		GID:             systemUser.Gid,
+	}, nil
+}
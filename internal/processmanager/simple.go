@@ -45,9 +45,16 @@ func (pm *Simple) Start(
 	)
 }
 
+// Stop sends the game stop command and runs the stop script if the game server isn't stopped by the command.
+// The process of the game server is unknown to the simple process manager, so the stop script takes the place
+// of the signal: graceful_stop.signal isn't sent and the script is responsible for stopping the process.
 func (pm *Simple) Stop(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
+	if pm.gracefulStopper().Stop(ctx, server, out) {
+		return domain.SuccessResult, nil
+	}
+
 	return pm.execCommand(
 		ctx,
 		server,
@@ -105,6 +112,21 @@ func (pm *Simple) SendInput(
 	)
 }
 
+func (pm *Simple) gracefulStopper() *gracefulStopper {
+	var sendInput inputSenderFunc
+
+	// The default script executes the input as the shell command, there is no console to send the input to
+	if pm.cfg.Scripts.SendCommand != "" && pm.cfg.Scripts.SendCommand != config.DefaultGameServerScriptSendInput {
+		sendInput = pm.SendInput
+	}
+
+	return &gracefulStopper{
+		cfg:       pm.cfg,
+		sendInput: sendInput,
+		status:    pm.Status,
+	}
+}
+
 func (pm *Simple) execCommand(
 	ctx context.Context, server *domain.Server, command string, out io.Writer,
 ) (domain.Result, error) {
@@ -123,6 +145,7 @@ func (pm *Simple) execCommand(
 
 func (pm *Simple) executeOptions(server *domain.Server) contracts.ExecutorOptions {
 	return contracts.ExecutorOptions{
+		Env:             serverEnvironment(server),
 		WorkDir:         server.WorkDir(pm.cfg),
 		FallbackWorkDir: pm.cfg.WorkDir(),
 	}
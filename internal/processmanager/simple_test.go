@@ -0,0 +1,53 @@
+package processmanager_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/gameap/daemon/internal/processmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimple_Start_EnvironmentPassed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("printenv is not available on Windows")
+	}
+
+	cfg := &config.Config{
+		WorkPath: t.TempDir(),
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	pm := processmanager.NewSimple(cfg, components.NewCleanExecutor(), components.NewCleanExecutor())
+	server := givenServerWithSettings(t, map[string]string{
+		"env.GAME_MODE":          "competitive",
+		"secret_env.STEAM_TOKEN": "secret",
+	})
+	out := components.NewSafeBuffer()
+
+	result, err := pm.Start(context.Background(), server, out)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Equal(t, "competitive\nsecret\n", out.String())
+}
+
+func givenServerWithSettings(t *testing.T, settings map[string]string) *domain.Server {
+	t.Helper()
+
+	return domaintest.NewServer(
+		domaintest.WithID(1337),
+		domaintest.WithDir(t.TempDir()),
+		domaintest.WithUser(""),
+		domaintest.WithStartCommand("printenv GAME_MODE STEAM_TOKEN"),
+		domaintest.WithSettings(settings),
+		domaintest.Active(),
+	)
+}
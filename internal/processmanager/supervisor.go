@@ -0,0 +1,710 @@
+//go:build linux
+// +build linux
+
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	supervisorFilesDir = ".supervisor"
+
+	supervisorRestartNever     = "no"
+	supervisorRestartOnFailure = "on-failure"
+	supervisorRestartAlways    = "always"
+
+	defaultSupervisorRestartDelay = 5 * time.Second
+	defaultSupervisorMaxRestarts  = 5
+	defaultSupervisorOutputSize   = 64 * 1024
+
+	// The log file is truncated when it exceeds the limit, the output is kept by the ring buffer.
+	supervisorLogSizeLimit = 10 * 1024 * 1024
+
+	// The restart counter is reset if the process runs longer.
+	supervisorStableRunTime = time.Minute
+)
+
+// supervisorTailPeriod is the period of reading the new output from the log file.
+var supervisorTailPeriod = 500 * time.Millisecond
+
+var errProcessExited = errors.New("process exited")
+
+var supervisorSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// Supervisor runs the game servers as the daemon child processes, without tmux or systemd.
+// The process input is a FIFO and the output is written to a log file, so the game servers
+// keep running while the daemon restarts. Running processes are found again by their pidfiles.
+type Supervisor struct {
+	cfg *config.Config
+
+	restartPolicy string
+	restartDelay  time.Duration
+	maxRestarts   int
+	outputSize    int
+	tailPeriod    time.Duration
+	checkPeriod   time.Duration
+
+	mu        sync.Mutex
+	processes map[string]*supervisedProcess
+}
+
+type supervisedProcess struct {
+	server    *domain.Server
+	pid       int
+	startedAt time.Time
+	restarts  int
+	output    *ringBuffer
+
+	// exited is closed when the process exits
+	exited   chan struct{}
+	stopping atomic.Bool
+}
+
+func (p *supervisedProcess) running() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func NewSupervisor(cfg *config.Config) (*Supervisor, error) {
+	pm := &Supervisor{
+		cfg:           cfg,
+		restartPolicy: supervisorRestartOnFailure,
+		restartDelay:  defaultSupervisorRestartDelay,
+		maxRestarts:   defaultSupervisorMaxRestarts,
+		outputSize:    defaultSupervisorOutputSize,
+		tailPeriod:    supervisorTailPeriod,
+		checkPeriod:   stopCheckPeriod,
+		processes:     map[string]*supervisedProcess{},
+	}
+
+	options := cfg.ProcessManager.Config
+
+	switch options["restart"] {
+	case "":
+	case supervisorRestartNever, supervisorRestartOnFailure, supervisorRestartAlways:
+		pm.restartPolicy = options["restart"]
+	default:
+		return nil, ErrInvalidRestartPolicy
+	}
+
+	if v := options["restart_delay"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, errors.Errorf("invalid restart delay %q (process_manager.config.restart_delay)", v)
+		}
+		pm.restartDelay = d
+	}
+
+	if v := options["max_restarts"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid max restarts %q (process_manager.config.max_restarts)", v)
+		}
+		pm.maxRestarts = n
+	}
+
+	if v := options["output_size"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, errors.Errorf("invalid output size %q (process_manager.config.output_size)", v)
+		}
+		pm.outputSize = n
+	}
+
+	return pm, nil
+}
+
+func (pm *Supervisor) Install(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	// Nothing to do here
+	return domain.SuccessResult, nil
+}
+
+func (pm *Supervisor) Uninstall(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	for _, path := range []string{pm.pidFile(server), pm.logFile(server), pm.stdinFile(server)} {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(ctx, err).Warn("failed to remove supervisor file")
+		}
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Supervisor) Start(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if p := pm.lookup(ctx, server); p != nil && p.running() {
+		_, _ = fmt.Fprintln(out, "Game server is already running")
+
+		return domain.SuccessResult, nil
+	}
+
+	p, err := pm.spawn(server, 0, nil)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	_, _ = fmt.Fprintf(out, "Game server is started with PID %d\n", p.pid)
+
+	return domain.SuccessResult, nil
+}
+
+// Stop sends the game stop command, then sends the signal and SIGKILL to the process group
+// if the game server isn't stopped in time. Stopped game servers aren't restarted.
+func (pm *Supervisor) Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	p := pm.lookup(ctx, server)
+	// Marked under the lock, so the exited process waiting for the restart isn't spawned again
+	if p != nil {
+		p.stopping.Store(true)
+	}
+	pm.mu.Unlock()
+
+	if p == nil {
+		return domain.SuccessResult, nil
+	}
+
+	if !p.running() {
+		pm.forget(p)
+
+		return domain.SuccessResult, nil
+	}
+
+	stopper := &gracefulStopper{
+		cfg:       pm.cfg,
+		sendInput: pm.SendInput,
+		status:    pm.Status,
+	}
+
+	if stopper.Stop(ctx, server, out) {
+		return domain.SuccessResult, nil
+	}
+
+	for _, signal := range []string{pm.cfg.GracefulStop.Signal, "SIGKILL"} {
+		sig, ok := supervisorSignals[signal]
+		if !ok {
+			continue
+		}
+
+		// The process is the session leader, its children get the signal too
+		err := syscall.Kill(-p.pid, sig)
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to send signal to game server")
+		}
+
+		if pm.waitExited(ctx, p, pm.cfg.GracefulStop.Timeout) {
+			return domain.SuccessResult, nil
+		}
+	}
+
+	return domain.ErrorResult, nil
+}
+
+func (pm *Supervisor) Restart(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	statusResult, err := pm.Status(ctx, server, out)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if statusResult == domain.SuccessResult {
+		_, err = pm.Stop(ctx, server, out)
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to stop server")
+		}
+	}
+
+	return pm.Start(ctx, server, out)
+}
+
+func (pm *Supervisor) Status(ctx context.Context, server *domain.Server, _ io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	p := pm.lookup(ctx, server)
+	pm.mu.Unlock()
+
+	if p == nil || !p.running() {
+		return domain.ErrorResult, nil
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Supervisor) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	p := pm.lookup(ctx, server)
+	pm.mu.Unlock()
+
+	if p != nil {
+		_, err := out.Write(p.output.Bytes())
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to write output")
+		}
+
+		return domain.SuccessResult, nil
+	}
+
+	// The output of the game server stopped before the daemon restart is left in the log file only
+	err := readFileTail(pm.logFile(server), int64(pm.outputSize), out)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.ErrorResult, nil
+	}
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to read log file")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// SendInput writes the input to the process FIFO. The FIFO can't be opened if the process isn't running.
+func (pm *Supervisor) SendInput(
+	_ context.Context, input string, server *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	f, err := os.OpenFile(pm.stdinFile(server), os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENXIO) {
+		return domain.ErrorResult, nil
+	}
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to open input file")
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(input + "\n")
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to write input")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// lookup returns the supervised process of the server. The process started before the daemon restart
+// is found by the pidfile and supervised again. The caller must hold the lock.
+func (pm *Supervisor) lookup(ctx context.Context, server *domain.Server) *supervisedProcess {
+	if p, ok := pm.processes[server.UUID()]; ok {
+		return p
+	}
+
+	pid, startTime, err := readPidFile(pm.pidFile(server))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(ctx, err).Warn("failed to read pidfile")
+		}
+
+		return nil
+	}
+
+	// The PID may be reused by another process, the start time distinguishes them
+	if !processAlive(pid, startTime) {
+		_ = os.Remove(pm.pidFile(server))
+
+		return nil
+	}
+
+	p := &supervisedProcess{
+		server:    server,
+		pid:       pid,
+		startedAt: time.Now(),
+		output:    newRingBuffer(pm.outputSize),
+		exited:    make(chan struct{}),
+	}
+	pm.processes[server.UUID()] = p
+
+	go pm.tail(p, true)
+	go pm.watch(p, func() error {
+		// The process isn't the daemon child anymore, it can't be waited
+		for processAlive(pid, startTime) {
+			time.Sleep(pm.checkPeriod)
+		}
+
+		return errProcessExited
+	})
+
+	logger.Logger(ctx).WithField("pid", pid).Info("Supervising game server started before daemon restart")
+
+	return p
+}
+
+// spawn starts the game server process, the restarted process keeps the output of the previous one.
+// The caller must hold the lock.
+func (pm *Supervisor) spawn(server *domain.Server, restarts int, output *ringBuffer) (*supervisedProcess, error) {
+	err := os.MkdirAll(filepath.Dir(pm.pidFile(server)), 0755)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create directory")
+	}
+
+	credential, err := pm.credential(server)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	stdin, err := pm.makeStdin(server, credential)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to make input file")
+	}
+	defer stdin.Close()
+
+	logFile, err := os.OpenFile(pm.logFile(server), os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open log file")
+	}
+	defer logFile.Close()
+
+	env := serverEnvironment(server)
+	cmd := exec.Command(
+		"/bin/sh", "-c",
+		domain.MakeFullCommand(pm.cfg, server, pm.cfg.Scripts.Start, server.StartCommand()),
+	)
+	cmd.Dir = server.WorkDir(pm.cfg)
+	cmd.Env = os.Environ()
+	for _, key := range sortedKeys(env) {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:     true,
+		Credential: credential,
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to start game server")
+	}
+
+	if output == nil {
+		output = newRingBuffer(pm.outputSize)
+	}
+
+	p := &supervisedProcess{
+		server:    server,
+		pid:       cmd.Process.Pid,
+		startedAt: time.Now(),
+		restarts:  restarts,
+		output:    output,
+		exited:    make(chan struct{}),
+	}
+
+	startTime, err := processStartTime(p.pid)
+	if err == nil {
+		err = writePidFile(pm.pidFile(server), p.pid, startTime)
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed to write pidfile, the game server won't be found after daemon restart")
+	}
+
+	pm.processes[server.UUID()] = p
+
+	go pm.tail(p, false)
+	go pm.watch(p, cmd.Wait)
+
+	return p, nil
+}
+
+// watch waits for the process exit and restarts it by the restart policy.
+func (pm *Supervisor) watch(p *supervisedProcess, wait func() error) {
+	err := wait()
+
+	l := log.WithField("server", p.server.UUID()).WithField("pid", p.pid)
+	if err != nil {
+		l = l.WithError(err)
+	}
+	l.Info("Game server process exited")
+
+	restart := pm.shouldRestart(p, err)
+
+	restarts := p.restarts
+	if time.Since(p.startedAt) > supervisorStableRunTime {
+		restarts = 0
+	}
+
+	if restart && restarts >= pm.maxRestarts {
+		l.Error("Game server isn't restarted, too many restarts")
+		restart = false
+	}
+
+	// The pidfile is removed before the exit is reported,
+	// so the game server is fully stopped when Stop returns
+	if !restart {
+		pm.forget(p)
+	}
+	close(p.exited)
+
+	if !restart {
+		return
+	}
+
+	time.Sleep(pm.restartDelay)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// The game server is stopped or started again while waiting
+	if p.stopping.Load() || pm.processes[p.server.UUID()] != p {
+		return
+	}
+
+	_, err = pm.spawn(p.server, restarts+1, p.output)
+	if err != nil {
+		l.WithError(err).Error("Failed to restart game server")
+	}
+}
+
+func (pm *Supervisor) shouldRestart(p *supervisedProcess, exitErr error) bool {
+	if p.stopping.Load() {
+		return false
+	}
+
+	switch pm.restartPolicy {
+	case supervisorRestartAlways:
+		return true
+	case supervisorRestartOnFailure:
+		return exitErr != nil
+	default:
+		return false
+	}
+}
+
+func (pm *Supervisor) forget(p *supervisedProcess) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.processes[p.server.UUID()] != p {
+		return
+	}
+
+	delete(pm.processes, p.server.UUID())
+
+	err := os.Remove(pm.pidFile(p.server))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.WithError(err).Warn("Failed to remove pidfile")
+	}
+}
+
+// tail copies the new output from the log file to the ring buffer until the process exits.
+func (pm *Supervisor) tail(p *supervisedProcess, fromEnd bool) {
+	path := pm.logFile(p.server)
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.WithError(err).Warn("Failed to open game server log file")
+		return
+	}
+	defer f.Close()
+
+	if fromEnd {
+		if stat, err := f.Stat(); err == nil && stat.Size() > int64(pm.outputSize) {
+			_, _ = f.Seek(stat.Size()-int64(pm.outputSize), io.SeekStart)
+		}
+	}
+
+	ticker := time.NewTicker(pm.tailPeriod)
+	defer ticker.Stop()
+
+	for {
+		_, _ = io.Copy(p.output, f)
+
+		offset, _ := f.Seek(0, io.SeekCurrent)
+		if stat, err := os.Stat(path); err == nil {
+			switch {
+			case stat.Size() > supervisorLogSizeLimit:
+				// The process appends to the file, it continues writing from the beginning
+				_ = os.Truncate(path, 0)
+				_, _ = f.Seek(0, io.SeekStart)
+			case stat.Size() < offset:
+				_, _ = f.Seek(0, io.SeekStart)
+			}
+		}
+
+		select {
+		case <-p.exited:
+			_, _ = io.Copy(p.output, f)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (pm *Supervisor) waitExited(ctx context.Context, p *supervisedProcess, timeout time.Duration) bool {
+	select {
+	case <-p.exited:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// makeStdin creates the FIFO and opens it for reading and writing, so the process never reads EOF.
+func (pm *Supervisor) makeStdin(server *domain.Server, credential *syscall.Credential) (*os.File, error) {
+	path := pm.stdinFile(server)
+
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithMessage(err, "failed to remove file")
+	}
+
+	err = unix.Mkfifo(path, 0600)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create fifo")
+	}
+
+	if credential != nil {
+		err = os.Chown(path, int(credential.Uid), int(credential.Gid))
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to change file owner")
+		}
+	}
+
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+// credential returns the credential of the server user if the daemon runs as root.
+func (pm *Supervisor) credential(server *domain.Server) (*syscall.Credential, error) {
+	if server.User() == "" || os.Geteuid() != 0 {
+		return nil, nil
+	}
+
+	systemUser, err := user.Lookup(server.User())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to lookup user %s", server.User())
+	}
+
+	uid, err := strconv.ParseUint(systemUser.Uid, 10, 32)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid user uid")
+	}
+	gid, err := strconv.ParseUint(systemUser.Gid, 10, 32)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid user gid")
+	}
+
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, nil
+}
+
+func (pm *Supervisor) pidFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), supervisorFilesDir, server.UUID()+".pid")
+}
+
+func (pm *Supervisor) logFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), supervisorFilesDir, server.UUID()+".log")
+}
+
+func (pm *Supervisor) stdinFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), supervisorFilesDir, server.UUID()+".stdin")
+}
+
+// writePidFile writes the PID and the process start time, the start time tells the reused PID.
+func writePidFile(path string, pid int, startTime uint64) error {
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d %d\n", pid, startTime)), 0644)
+}
+
+func readPidFile(path string) (int, uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 {
+		return 0, 0, errors.New("invalid pidfile format")
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return 0, 0, errors.New("invalid pid")
+	}
+
+	startTime, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid process start time")
+	}
+
+	return pid, startTime, nil
+}
+
+func processAlive(pid int, startTime uint64) bool {
+	actual, err := processStartTime(pid)
+
+	return err == nil && actual == startTime
+}
+
+// processStartTime returns the process start time in clock ticks after the system boot.
+func processStartTime(pid int) (uint64, error) {
+	content, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+
+	// The command name may contain spaces and parentheses, the fields are counted after it
+	stat := string(content)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+
+	// The state is the 3rd field, the start time is the 22nd
+	if len(fields) < 20 {
+		return 0, errors.New("invalid process stat")
+	}
+
+	// Zombies are dead already, they are waiting to be reaped
+	if fields[0] == "Z" {
+		return 0, errProcessExited
+	}
+
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+func readFileTail(path string, size int64, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() > size {
+		_, err = f.Seek(stat.Size()-size, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.Copy(out, f)
+
+	return err
+}
@@ -0,0 +1,221 @@
+//go:build linux
+// +build linux
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const consoleScript = `echo started; while read line; do echo "got $line"; [ "$line" = stop ] && exit 0; done`
+
+func givenSupervisor(t *testing.T, script string, options map[string]string) *Supervisor {
+	t.Helper()
+
+	givenStopCheckPeriod(t, 10*time.Millisecond)
+	supervisorTailPeriod = 10 * time.Millisecond
+
+	cfg := &config.Config{
+		WorkPath: t.TempDir(),
+		Scripts: config.Scripts{
+			Start: script,
+		},
+	}
+	cfg.ProcessManager.Config = options
+	cfg.GracefulStop.Timeout = 5 * time.Second
+	cfg.GracefulStop.Signal = "SIGTERM"
+	cfg.GracefulStop.Method = config.CommandMethodConsole
+
+	pm, err := NewSupervisor(cfg)
+	require.NoError(t, err)
+
+	return pm
+}
+
+// givenSupervisedServer returns the server with the existing work directory.
+func givenSupervisedServer(t *testing.T, pm *Supervisor) *domain.Server {
+	t.Helper()
+
+	server := makeServerWithSettings(t, map[string]string{})
+	require.NoError(t, os.MkdirAll(server.WorkDir(pm.cfg), 0755))
+
+	return server
+}
+
+func stopServerOnCleanup(t *testing.T, pm *Supervisor, server *domain.Server) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		_, _ = pm.Stop(context.Background(), server, io.Discard)
+	})
+}
+
+func TestSupervisor_ConsoleAndStopCommand(t *testing.T) {
+	pm := givenSupervisor(t, consoleScript, nil)
+	pm.cfg.GracefulStop.Command = "stop"
+	server := givenSupervisedServer(t, pm)
+	stopServerOnCleanup(t, pm, server)
+
+	result, err := pm.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, domain.SuccessResult, result)
+
+	result, err = pm.SendInput(context.Background(), "hello", server, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, domain.SuccessResult, result)
+
+	assert.Eventually(t, func() bool {
+		out := &bytes.Buffer{}
+		_, _ = pm.GetOutput(context.Background(), server, out)
+
+		return out.String() == "started\ngot hello\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	out := &bytes.Buffer{}
+	result, err = pm.Stop(context.Background(), server, out)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Contains(t, out.String(), "Game server is stopped")
+
+	result, err = pm.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result)
+	assert.NoFileExists(t, pm.pidFile(server))
+}
+
+func TestSupervisor_Stop_StoppedBySignal(t *testing.T) {
+	pm := givenSupervisor(t, "exec sleep 60", map[string]string{"restart": "always"})
+	server := givenSupervisedServer(t, pm)
+	stopServerOnCleanup(t, pm, server)
+	_, err := pm.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	result, err := pm.Stop(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	time.Sleep(100 * time.Millisecond)
+	result, err = pm.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result, "stopped game server mustn't be restarted")
+}
+
+func TestSupervisor_FailedProcessRestarted(t *testing.T) {
+	pm := givenSupervisor(t, "echo run >> runs.txt; exit 1", map[string]string{
+		"restart_delay": "10ms",
+		"max_restarts":  "2",
+	})
+	server := givenSupervisedServer(t, pm)
+	runsFile := filepath.Join(server.WorkDir(pm.cfg), "runs.txt")
+
+	_, err := pm.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		content, _ := os.ReadFile(runsFile)
+
+		return strings.Count(string(content), "run") == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		result, _ := pm.Status(context.Background(), server, io.Discard)
+
+		return result == domain.ErrorResult
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	content, err := os.ReadFile(runsFile)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(content), "run"))
+}
+
+func TestSupervisor_StopDuringRestartDelay_ProcessNotRestarted(t *testing.T) {
+	pm := givenSupervisor(t, "echo run >> runs.txt; exit 1", map[string]string{
+		"restart_delay": "200ms",
+	})
+	server := givenSupervisedServer(t, pm)
+	runsFile := filepath.Join(server.WorkDir(pm.cfg), "runs.txt")
+
+	_, err := pm.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		result, _ := pm.Status(context.Background(), server, io.Discard)
+
+		return result == domain.ErrorResult
+	}, 5*time.Second, 10*time.Millisecond)
+
+	result, err := pm.Stop(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+
+	time.Sleep(400 * time.Millisecond)
+	content, err := os.ReadFile(runsFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "run"))
+	result, err = pm.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result)
+	assert.NoFileExists(t, pm.pidFile(server))
+}
+
+func TestSupervisor_ProcessFoundAfterDaemonRestart(t *testing.T) {
+	pm := givenSupervisor(t, consoleScript, nil)
+	server := givenSupervisedServer(t, pm)
+	stopServerOnCleanup(t, pm, server)
+	_, err := pm.Start(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+
+	restarted, err := NewSupervisor(pm.cfg)
+	require.NoError(t, err)
+
+	result, err := restarted.Status(context.Background(), server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+
+	_, err = restarted.SendInput(context.Background(), "stop", server, io.Discard)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		result, _ := restarted.Status(context.Background(), server, io.Discard)
+
+		return result == domain.ErrorResult
+	}, 5*time.Second, 10*time.Millisecond)
+
+	out := &bytes.Buffer{}
+	_, err = restarted.GetOutput(context.Background(), server, out)
+	require.NoError(t, err)
+	assert.Equal(t, "started\ngot stop\n", out.String())
+}
+
+func TestSupervisor_PidReused_ProcessNotFound(t *testing.T) {
+	pm := givenSupervisor(t, consoleScript, nil)
+	server := makeServerWithSettings(t, map[string]string{})
+	require.NoError(t, os.MkdirAll(filepath.Dir(pm.pidFile(server)), 0755))
+	startTime, err := processStartTime(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, writePidFile(pm.pidFile(server), os.Getpid(), startTime+1))
+
+	result, err := pm.Status(context.Background(), server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result)
+	assert.NoFileExists(t, pm.pidFile(server))
+}
+
+func TestNewSupervisor_InvalidRestartPolicy(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ProcessManager.Config = map[string]string{"restart": "sometimes"}
+
+	_, err := NewSupervisor(cfg)
+
+	assert.ErrorIs(t, err, ErrInvalidRestartPolicy)
+}
@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gameap/daemon/internal/app/config"
@@ -67,6 +68,11 @@ func (pm *SystemD) Uninstall(
 		logger.WithError(ctx, err).Warn("failed to remove service file")
 	}
 
+	err = removeEnvFile(pm.envFile(server))
+	if err != nil {
+		logger.WithError(ctx, err).Warn("failed to remove environment file")
+	}
+
 	err = pm.daemonReload(ctx)
 	if err != nil {
 		logger.Logger(ctx).WithError(err).Warn("Failed to daemon-reload")
@@ -103,7 +109,17 @@ func (pm *SystemD) Start(ctx context.Context, server *domain.Server, out io.Writ
 	return pm.command(ctx, server, "start", out)
 }
 
+// Stop stops the service. The console stop command is sent by ExecStop= of the service,
+// the RCON stop command is sent before the service is stopped.
 func (pm *SystemD) Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	if pm.cfg.GracefulStopCommand(server.Game().Code).Method == config.CommandMethodRCON {
+		stopper := &gracefulStopper{
+			cfg:    pm.cfg,
+			status: pm.Status,
+		}
+		stopper.Stop(ctx, server, out)
+	}
+
 	_, err := pm.executor.ExecWithWriter(
 		ctx,
 		fmt.Sprintf("systemctl stop %s", pm.socketName(server)),
@@ -290,6 +306,11 @@ func (pm *SystemD) SendInput(
 }
 
 func (pm *SystemD) makeService(ctx context.Context, server *domain.Server) error {
+	err := writeEnvFile(pm.envFile(server), buildEnvFile(server.SecretEnvironment()))
+	if err != nil {
+		return errors.WithMessage(err, "failed to write environment file")
+	}
+
 	f, err := os.OpenFile(pm.serviceFile(server), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return errors.WithMessage(err, "failed to open file")
@@ -343,6 +364,36 @@ func (pm *SystemD) buildServiceConfig(server *domain.Server) (string, error) {
 	builder.WriteString(cmd)
 	builder.WriteString("\n")
 
+	// TimeoutStopSec= bounds ExecStop= and then the wait after the signal, so the graceful timeout
+	// is shared between them to stop the service in the graceful timeout
+	timeoutStopSec := int(pm.cfg.GracefulStop.Timeout.Seconds())
+
+	stopCommand := pm.cfg.GracefulStopCommand(server.Game().Code)
+	if stopCommand.Command != "" && stopCommand.Method == config.CommandMethodConsole {
+		wait := 0
+		if timeoutStopSec > 0 {
+			wait = max(timeoutStopSec/2, 1)
+			timeoutStopSec = max(timeoutStopSec-wait, wait)
+		}
+
+		builder.WriteString("ExecStop=")
+		builder.WriteString(pm.makeStopCommand(server, stopCommand.Command, wait))
+		builder.WriteString("\n")
+	}
+
+	// The signal is sent after ExecStop=, SIGKILL is sent if the service isn't stopped in TimeoutStopSec=
+	if pm.cfg.GracefulStop.Signal != "" {
+		builder.WriteString("KillSignal=")
+		builder.WriteString(pm.cfg.GracefulStop.Signal)
+		builder.WriteString("\n")
+	}
+
+	if timeoutStopSec > 0 {
+		builder.WriteString("TimeoutStopSec=")
+		builder.WriteString(strconv.Itoa(timeoutStopSec))
+		builder.WriteString("\n")
+	}
+
 	builder.WriteString("Sockets=")
 	builder.WriteString(server.UUID())
 	builder.WriteString(".socket\n")
@@ -365,6 +416,20 @@ func (pm *SystemD) buildServiceConfig(server *domain.Server) (string, error) {
 
 	builder.WriteString("Restart=always\n")
 
+	env := server.Environment()
+	for _, key := range sortedKeys(env) {
+		builder.WriteString("Environment=")
+		builder.WriteString(quoteEnvValue(escapeUnitSpecifiers(key + "=" + env[key])))
+		builder.WriteString("\n")
+	}
+
+	// Secrets are kept in the file readable only by root, the unit file is world-readable
+	if len(server.SecretEnvironment()) > 0 {
+		builder.WriteString("EnvironmentFile=")
+		builder.WriteString(pm.envFile(server))
+		builder.WriteString("\n")
+	}
+
 	runAsUser, group, err := pm.user(server)
 	if err != nil {
 		return "", errors.WithMessage(err, "failed to get user")
@@ -421,6 +486,21 @@ func (pm *SystemD) makeStartCommand(server *domain.Server) (string, error) {
 	return domain.MakeFullCommand(pm.cfg, server, pm.cfg.Scripts.Start, startCommand), nil
 }
 
+// makeStopCommand makes the ExecStop= command writing the stop command to the service stdin
+// and waiting until the main process exits, but no longer than the wait seconds if the wait is set.
+func (pm *SystemD) makeStopCommand(server *domain.Server, command string, wait int) string {
+	waitScript := "while kill -0 $MAINPID 2>/dev/null; do sleep 1; done"
+	if wait > 0 {
+		waitScript = "timeout " + strconv.Itoa(wait) + " sh -c " + shellquote.Join(waitScript)
+	}
+
+	script := "echo " + shellquote.Join(command) + " > " + shellquote.Join(pm.stdinFile(server)) +
+		"; " + waitScript
+
+	// "$$" is passed to the shell as "$", the variables are expanded by the shell
+	return "/bin/sh -c " + quoteEnvValue(escapeUnitSpecifiers(strings.ReplaceAll(script, "$", "$$")))
+}
+
 func (pm *SystemD) makeSocket(ctx context.Context, server *domain.Server) error {
 	f, err := os.OpenFile(pm.socketFile(server), os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -482,6 +562,11 @@ func (pm *SystemD) logFile(server *domain.Server) string {
 	return builder.String()
 }
 
+// Full path to the file with the secret environment variables.
+func (pm *SystemD) envFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), systemdFilesDir, server.UUID()+".env")
+}
+
 // Full path to stdin file.
 func (pm *SystemD) stdinFile(server *domain.Server) string {
 	builder := strings.Builder{}
@@ -545,3 +630,28 @@ func (pm *SystemD) user(server *domain.Server) (string, string, error) {
 
 	return systemUser.Username, systemUser.Gid, nil
 }
+
+// buildEnvFile builds the EnvironmentFile content, values are double-quoted.
+func buildEnvFile(env map[string]string) string {
+	builder := strings.Builder{}
+
+	for _, key := range sortedKeys(env) {
+		builder.WriteString(key)
+		builder.WriteString("=")
+		builder.WriteString(quoteEnvValue(env[key]))
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func quoteEnvValue(value string) string {
+	return `"` + envValueReplacer.Replace(value) + `"`
+}
+
+var envValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeUnitSpecifiers escapes the "%" specifiers which are expanded in the unit files.
+func escapeUnitSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
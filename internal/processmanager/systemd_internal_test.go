@@ -174,39 +174,99 @@ func Test_makeCommand(t *testing.T) {
 	}
 }
 
-func makeServerWithStartCommandAndDir(startCommand, dir string) *domain.Server {
-	return domain.NewServer(
-		1337,
-		true,
-		domain.ServerInstalled,
-		false,
-		"name",
-		"759b875e-d910-11eb-aff7-d796d7fcf7ef",
-		"759b875e",
-		domain.Game{
-			StartCode: "cstrike",
+func Test_buildServiceConfig_Environment(t *testing.T) {
+	workPath := t.TempDir()
+	systemd := NewSystemD(&config.Config{
+		WorkPath: workPath,
+		Scripts: config.Scripts{
+			Start: "{command}",
 		},
-		domain.GameMod{
-			Name: "public",
-		},
-		"1.3.3.7",
-		1337,
-		1338,
-		1339,
-		"paS$w0rD",
-		dir,
-		"gameap-user",
-		startCommand,
-		"",
-		"",
-		"",
-		true,
-		time.Now(),
-		map[string]string{
-			"default_map": "de_dust2",
-			"tickrate":    "1000",
+	}, nil, nil)
+	server := makeServerWithSettings(t, map[string]string{
+		"env.GAME_MODE":          "competitive 100%",
+		"env.MOTD":               `Say "hello"`,
+		"env.1INVALID":           "skipped",
+		"secret_env.STEAM_TOKEN": "secret",
+	})
+
+	serviceConfig, err := systemd.buildServiceConfig(server)
+
+	require.NoError(t, err)
+	assert.Contains(t, serviceConfig, "Environment=\"GAME_MODE=competitive 100%%\"\nEnvironment=\"MOTD=Say \\\"hello\\\"\"\n")
+	assert.NotContains(t, serviceConfig, "INVALID")
+	assert.NotContains(t, serviceConfig, "secret")
+	assert.Contains(
+		t,
+		serviceConfig,
+		"EnvironmentFile="+filepath.Join(workPath, systemdFilesDir, "759b875e-d910-11eb-aff7-d796d7fcf7ef.env")+"\n",
+	)
+}
+
+func Test_buildServiceConfig_GracefulStop(t *testing.T) {
+	workPath := t.TempDir()
+	cfg := &config.Config{
+		WorkPath: workPath,
+		Scripts: config.Scripts{
+			Start: "{command}",
 		},
-		map[string]string{},
-		time.Now(),
+	}
+	cfg.GracefulStop.Timeout = 45 * time.Second
+	cfg.GracefulStop.Signal = "SIGINT"
+	cfg.GracefulStop.Method = config.CommandMethodConsole
+	cfg.GracefulStop.Command = "say bye; stop"
+	systemd := NewSystemD(cfg, nil, nil)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	serviceConfig, err := systemd.buildServiceConfig(server)
+
+	require.NoError(t, err)
+	assert.Contains(
+		t,
+		serviceConfig,
+		"ExecStop=/bin/sh -c \"echo 'say bye; stop' > "+
+			filepath.Join(workPath, systemdFilesDir, "759b875e-d910-11eb-aff7-d796d7fcf7ef.stdin")+
+			"; timeout 22 sh -c 'while kill -0 $$MAINPID 2>/dev/null; do sleep 1; done'\"\n",
 	)
+	assert.Contains(t, serviceConfig, "KillSignal=SIGINT\nTimeoutStopSec=23\n")
+}
+
+func Test_buildServiceConfig_GracefulStopWithoutCommand_WholeTimeoutAfterSignal(t *testing.T) {
+	cfg := &config.Config{
+		WorkPath: t.TempDir(),
+		Scripts: config.Scripts{
+			Start: "{command}",
+		},
+	}
+	cfg.GracefulStop.Timeout = 45 * time.Second
+	cfg.GracefulStop.Signal = "SIGINT"
+	systemd := NewSystemD(cfg, nil, nil)
+	server := makeServerWithSettings(t, map[string]string{})
+
+	serviceConfig, err := systemd.buildServiceConfig(server)
+
+	require.NoError(t, err)
+	assert.NotContains(t, serviceConfig, "ExecStop=")
+	assert.Contains(t, serviceConfig, "KillSignal=SIGINT\nTimeoutStopSec=45\n")
+}
+
+func Test_writeEnvFile_SecretsReadableOnlyByOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), systemdFilesDir, "server.env")
+
+	err := writeEnvFile(path, buildEnvFile(map[string]string{
+		"STEAM_TOKEN": "se\"cr\\et",
+		"API_KEY":     "key",
+	}))
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=\"key\"\nSTEAM_TOKEN=\"se\\\"cr\\\\et\"\n", string(content))
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	err = writeEnvFile(path, buildEnvFile(map[string]string{}))
+
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
 }
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -16,12 +17,15 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/pkg/logger"
+	"github.com/gameap/daemon/pkg/shellquote"
 	"github.com/pkg/errors"
 )
 
 const (
 	defaultWidth        = 200
 	defaultHistoryLimit = 30000
+
+	tmuxFilesDir = ".tmux"
 )
 
 type Tmux struct {
@@ -53,13 +57,23 @@ func (pm *Tmux) Start(
 ) (domain.Result, error) {
 	startCmd := domain.MakeFullCommand(pm.cfg, server, pm.cfg.Scripts.Start, server.StartCommand())
 
-	startCmd = strconv.Quote(strings.ReplaceAll(startCmd, `\"`, `"`))
-
 	options, err := pm.executeOptions(server)
 	if err != nil {
 		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
 	}
 
+	// The session is created by the running tmux server, so the variables
+	// are loaded from the file instead of the client environment
+	envFile, err := pm.makeEnvFile(server, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to make environment file")
+	}
+	if envFile != "" {
+		startCmd = "set -a; . " + shellquote.Join(envFile) + "; set +a; " + startCmd
+	}
+
+	startCmd = strconv.Quote(strings.ReplaceAll(startCmd, `\"`, `"`))
+
 	err = pm.makeTmuxInitialSession(ctx, server, out)
 	if err != nil {
 		return domain.ErrorResult, errors.WithMessage(err, "failed to create initial tmux session")
@@ -89,6 +103,8 @@ func (pm *Tmux) Start(
 	return domain.Result(result), nil
 }
 
+// Stop sends the game stop command, then terminates the session processes by the signal and kills them
+// if the game server isn't stopped in time.
 func (pm *Tmux) Stop(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
@@ -97,6 +113,26 @@ func (pm *Tmux) Stop(
 		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
 	}
 
+	stopper := &gracefulStopper{
+		cfg:       pm.cfg,
+		sendInput: pm.SendInput,
+		status:    pm.Status,
+	}
+
+	if stopper.Stop(ctx, server, out) {
+		return domain.SuccessResult, nil
+	}
+
+	if pm.signal(ctx, server, pm.cfg.GracefulStop.Signal, options, out) {
+		if stopper.waitStopped(ctx, server, pm.cfg.GracefulStop.Timeout) {
+			return domain.SuccessResult, nil
+		}
+
+		if pm.signal(ctx, server, "SIGKILL", options, out) && stopper.waitStopped(ctx, server, stopCheckPeriod) {
+			return domain.SuccessResult, nil
+		}
+	}
+
 	result, err := pm.detailedExecutor.ExecWithWriter(
 		ctx,
 		fmt.Sprintf(`tmux kill-session -t %s`, server.UUID()),
@@ -110,6 +146,39 @@ func (pm *Tmux) Stop(
 	return domain.Result(result), nil
 }
 
+// signal sends the signal to the process group of the session, it returns false if the session isn't found.
+func (pm *Tmux) signal(
+	ctx context.Context, server *domain.Server, signal string, options contracts.ExecutorOptions, out io.Writer,
+) bool {
+	output, result, err := pm.executor.Exec(
+		ctx,
+		fmt.Sprintf(`tmux list-panes -t %s -F "#{pane_pid}"`, server.UUID()),
+		options,
+	)
+	if err != nil || result != 0 {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+
+	// The pane process is the process group leader, its children get the signal too
+	_, err = pm.detailedExecutor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`kill -s %s -- -%d`, signalName(signal), pid),
+		out,
+		options,
+	)
+	if err != nil {
+		logger.Logger(ctx).WithError(err).Warn("Failed to send signal to game server")
+		return false
+	}
+
+	return true
+}
+
 func (pm *Tmux) Restart(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
@@ -242,26 +311,16 @@ func (pm *Tmux) makeTmuxInitialSession(ctx context.Context, server *domain.Serve
 	return nil
 }
 
-func (pm *Tmux) executeOptions(server *domain.Server) (contracts.ExecutorOptions, error) {
-	var systemUser *user.User
-	var err error
+// makeEnvFile writes the server environment variables to the file owned by the server user.
+// Returns an empty path if the server has no variables.
+func (pm *Tmux) makeEnvFile(server *domain.Server, options contracts.ExecutorOptions) (string, error) {
+	return makeSessionEnvFile(pm.envFile(server), server, options)
+}
 
-	if server.User() != "" {
-		systemUser, err = user.Lookup(server.User())
-		if err != nil {
-			return contracts.ExecutorOptions{}, errors.WithMessagef(err, "failed to lookup user %s", server.User())
-		}
-	} else {
-		systemUser, err = user.Current()
-		if err != nil {
-			return contracts.ExecutorOptions{}, errors.WithMessage(err, "failed to get current user")
-		}
-	}
+func (pm *Tmux) envFile(server *domain.Server) string {
+	return filepath.Join(pm.cfg.WorkDir(), tmuxFilesDir, server.UUID()+".env")
+}
 
-	return contracts.ExecutorOptions{
-		WorkDir:         server.WorkDir(pm.cfg),
-		FallbackWorkDir: systemUser.HomeDir,
-		UID:             systemUser.Uid,
-		GID:             systemUser.Gid,
-	}, nil
+func (pm *Tmux) executeOptions(server *domain.Server) (contracts.ExecutorOptions, error) {
+	return sessionExecuteOptions(pm.cfg, server)
 }
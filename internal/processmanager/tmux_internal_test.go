@@ -0,0 +1,66 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package processmanager
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTmux_makeEnvFile(t *testing.T) {
+	tmux := NewTmux(&config.Config{WorkPath: t.TempDir()}, nil, nil)
+	server := makeServerWithSettings(t, map[string]string{
+		"env.GAME_MODE":          "competitive",
+		"secret_env.STEAM_TOKEN": "it's secret",
+	})
+	options, err := tmux.executeOptions(server)
+	require.NoError(t, err)
+
+	path, err := tmux.makeEnvFile(server, options)
+
+	require.NoError(t, err)
+	assert.Equal(t, tmux.envFile(server), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "GAME_MODE=competitive\nSTEAM_TOKEN='it'\\''s secret'\n", string(content))
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
+
+func TestTmux_makeEnvFile_NoEnvironment_FileRemoved(t *testing.T) {
+	tmux := NewTmux(&config.Config{WorkPath: t.TempDir()}, nil, nil)
+	server := makeServerWithSettings(t, map[string]string{})
+	require.NoError(t, os.MkdirAll(filepath.Dir(tmux.envFile(server)), 0700))
+	require.NoError(t, os.WriteFile(tmux.envFile(server), []byte("STALE=1\n"), 0600))
+	options, err := tmux.executeOptions(server)
+	require.NoError(t, err)
+
+	path, err := tmux.makeEnvFile(server, options)
+
+	require.NoError(t, err)
+	assert.Empty(t, path)
+	assert.NoFileExists(t, tmux.envFile(server))
+}
+
+// makeServerWithSettings returns the server owned by the current user.
+func makeServerWithSettings(t *testing.T, settings map[string]string) *domain.Server {
+	t.Helper()
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	return makeServerWithStartCommandAndDir(
+		"/usr/bin/env", t.TempDir(),
+		domaintest.WithUser(currentUser.Username), domaintest.WithSettings(settings),
+	)
+}
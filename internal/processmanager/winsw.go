@@ -415,6 +415,16 @@ func (pm *WinSW) buildServiceConfig(server *domain.Server) (string, error) {
 		AutoRefresh:  "false",
 	}
 
+	// WinSW reads the environment from the service file only, secrets mustn't be written there
+	if len(server.SecretEnvironment()) > 0 {
+		return "", ErrSecretEnvUnsupported
+	}
+
+	env := server.Environment()
+	for _, key := range sortedKeys(env) {
+		serviceConfig.Env = append(serviceConfig.Env, envVar{Name: key, Value: env[key]})
+	}
+
 	rawPw, exists := pm.cfg.Users[server.User()]
 	if !exists {
 		return "", ErrUserNotFound
@@ -476,6 +486,8 @@ type WinSWServiceConfig struct {
 	Arguments        string `xml:"arguments,omitempty"`
 	WorkingDirectory string `xml:"workingdirectory,omitempty"`
 
+	Env []envVar `xml:"env,omitempty"`
+
 	StopExecutable string `xml:"stopexecutable,omitempty"`
 	StopArguments  string `xml:"stoparguments,omitempty"`
 	StopTimeout    string `xml:"stoptimeout,omitempty"`
@@ -494,6 +506,11 @@ type WinSWServiceConfig struct {
 	} `xml:"serviceaccount,omitempty"`
 }
 
+type envVar struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
 type onFailure struct {
 	Action string `xml:"action,attr"`
 	Delay  string `xml:"delay,attr,omitempty"`
@@ -0,0 +1,51 @@
+//go:build windows
+// +build windows
+
+package processmanager
+
+import (
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain/domaintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenWinSW(t *testing.T) *WinSW {
+	t.Helper()
+
+	return NewWinSW(&config.Config{
+		WorkPath: t.TempDir(),
+		Users:    map[string]string{"gameap-user": "password"},
+	}, nil, nil)
+}
+
+func TestWinSW_buildServiceConfig_EnvWritten(t *testing.T) {
+	pm := givenWinSW(t)
+	server := domaintest.NewServer(
+		domaintest.WithStartCommand("server.exe"),
+		domaintest.WithSettings(map[string]string{"env.GAME_MODE": "competitive"}),
+	)
+
+	serviceConfig, err := pm.buildServiceConfig(server)
+
+	require.NoError(t, err)
+	assert.Contains(t, serviceConfig, `<env name="GAME_MODE" value="competitive"></env>`)
+}
+
+func TestWinSW_buildServiceConfig_SecretEnv_NotWritten(t *testing.T) {
+	pm := givenWinSW(t)
+	server := domaintest.NewServer(
+		domaintest.WithStartCommand("server.exe"),
+		domaintest.WithSettings(map[string]string{
+			"env.GAME_MODE":          "competitive",
+			"secret_env.STEAM_TOKEN": "secret-token",
+		}),
+	)
+
+	serviceConfig, err := pm.buildServiceConfig(server)
+
+	require.ErrorIs(t, err, ErrSecretEnvUnsupported)
+	assert.NotContains(t, serviceConfig, "secret-token")
+}
@@ -0,0 +1,323 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// searchYears limits the search of the next time for the expressions which never match, like "0 0 30 2 *".
+const searchYears = 5
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday too, it's moved to 0 after parsing.
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is the parsed cron expression in the standard 5 fields format:
+// minute, hour, day of month, month and day of week.
+// The times are matched by the wall clock in the schedule location.
+type Schedule struct {
+	location *time.Location
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	// If both day of month and day of week are restricted, the day matches any of them.
+	domRestricted bool
+	dowRestricted bool
+}
+
+// Parse parses the cron expression. Lists (1,15), ranges (1-5), steps (*/15, 0-30/10),
+// month and day names (jan, mon) and macros (@daily, @hourly etc.) are supported.
+// The nil location means UTC.
+func Parse(expression string, location *time.Location) (*Schedule, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	expression = strings.TrimSpace(expression)
+	if macro, ok := macros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errors.WithMessagef(ErrInvalidExpression, "expected 5 fields, got %d", len(fields))
+	}
+
+	s := &Schedule{location: location}
+
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domRestricted = !isWildcard(fields[2])
+	s.dowRestricted = !isWildcard(fields[4])
+
+	return s, nil
+}
+
+func isWildcard(value string) bool {
+	return value == "*" || value == "?"
+}
+
+func parseField(value string, f field) (uint64, error) {
+	var result uint64
+
+	for _, part := range strings.Split(value, ",") {
+		bitsPart, err := parsePart(strings.ToLower(part), f)
+		if err != nil {
+			return 0, errors.WithMessagef(ErrInvalidExpression, "%s: %s", f.name, err.Error())
+		}
+
+		result |= bitsPart
+	}
+
+	return result, nil
+}
+
+func parsePart(part string, f field) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	from, to := f.min, f.max
+	switch {
+	case rangePart == "*" || rangePart == "?":
+	case strings.Contains(rangePart, "-"):
+		fromValue, toValue, _ := strings.Cut(rangePart, "-")
+
+		var err error
+		if from, err = parseValue(fromValue, f); err != nil {
+			return 0, err
+		}
+		if to, err = parseValue(toValue, f); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if from, err = parseValue(rangePart, f); err != nil {
+			return 0, err
+		}
+
+		// "5/10" means from 5 to the max every 10
+		if !hasStep {
+			to = from
+		}
+	}
+
+	if from > to {
+		return 0, errors.Errorf("invalid range %q", part)
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step < 1 {
+			return 0, errors.Errorf("invalid step %q", part)
+		}
+	}
+
+	var result uint64
+	for i := from; i <= to; i += step {
+		result |= 1 << uint(i)
+	}
+
+	return result, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if n, ok := f.names[value]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", value)
+	}
+
+	if n < f.min || n > f.max {
+		return 0, errors.Errorf("value %d out of range %d-%d", n, f.min, f.max)
+	}
+
+	return n, nil
+}
+
+// Location returns the time zone of the schedule.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the next matching time after t, the result is in the location of t.
+// Times skipped by the DST transition match the first instant after the transition, as cronie and systemd run them.
+// Times repeated by the DST transition match only once.
+// The zero time is returned if the expression never matches.
+func (s *Schedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+
+	// The times are searched by the wall clock, then the wall clock is converted to the instant
+	clock := wallClock(t.In(s.location))
+
+	for {
+		clock = s.next(clock)
+		if clock.IsZero() {
+			return clock
+		}
+
+		// The repeated wall clock time may be converted to the instant before t
+		next := s.instant(clock)
+		if next.After(t) {
+			return next.In(origLocation)
+		}
+	}
+}
+
+// instant returns the time of the wall clock in the schedule location.
+// The wall clock skipped by the DST transition is moved to the transition.
+func (s *Schedule) instant(clock time.Time) time.Time {
+	t := time.Date(clock.Year(), clock.Month(), clock.Day(), clock.Hour(), clock.Minute(), 0, 0, s.location)
+
+	if wallClock(t).Equal(clock) {
+		return t
+	}
+
+	// The skipped wall clock is converted to the time either before or after the transition
+	start, end := t.ZoneBounds()
+	if wallClock(t).Before(clock) {
+		return end
+	}
+
+	return start
+}
+
+// next returns the next matching wall clock time after the wall clock t.
+// The wall clock is in UTC, it has no DST transitions.
+func (s *Schedule) next(t time.Time) time.Time {
+	loc := time.UTC
+
+	// The next whole minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + searchYears
+
+	// The lower units are reset only once when the higher unit is changed
+	added := false
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
+
+// wallClock returns the time with the same clock reading in UTC, so the times can be compared by the clock.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		expression string
+		location   *time.Location
+		from       string
+		expected   string
+	}{
+		{"every minute", "* * * * *", nil, "2024-01-01T10:15:30Z", "2024-01-01T10:16:00Z"},
+		{"daily", "0 6 * * *", nil, "2024-01-01T10:15:00Z", "2024-01-02T06:00:00Z"},
+		{"daily macro", "@daily", nil, "2024-01-01T10:15:00Z", "2024-01-02T00:00:00Z"},
+		{"step", "*/15 * * * *", nil, "2024-01-01T10:15:00Z", "2024-01-01T10:30:00Z"},
+		{"range with step", "0 8-18/4 * * *", nil, "2024-01-01T12:00:00Z", "2024-01-01T16:00:00Z"},
+		{"list", "0 6,18 * * *", nil, "2024-01-01T07:00:00Z", "2024-01-01T18:00:00Z"},
+		{"day of week by name", "0 6 * * mon", nil, "2024-01-01T07:00:00Z", "2024-01-08T06:00:00Z"},
+		{"sunday as 7", "0 6 * * 7", nil, "2024-01-01T07:00:00Z", "2024-01-07T06:00:00Z"},
+		{"day of month or day of week", "0 0 15 * fri", nil, "2024-01-01T00:00:00Z", "2024-01-05T00:00:00Z"},
+		{"month by name", "0 0 1 mar *", nil, "2024-01-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", nil, "2024-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"never matches", "0 0 30 2 *", nil, "2024-01-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+		{"time zone", "0 6 * * *", newYork, "2024-01-01T12:00:00Z", "2024-01-02T11:00:00Z"},
+		{"time zone after DST start", "0 6 * * *", newYork, "2024-03-09T12:00:00Z", "2024-03-10T10:00:00Z"},
+		{"skipped by DST start", "30 2 * * *", newYork, "2024-03-09T12:00:00Z", "2024-03-10T07:00:00Z"},
+		{"after skipped by DST start", "30 2 * * *", newYork, "2024-03-10T07:00:00Z", "2024-03-11T06:30:00Z"},
+		{"skipped and valid times once", "0,30 2-3 * * *", newYork, "2024-03-10T06:59:00Z", "2024-03-10T07:00:00Z"},
+		{"after skipped and valid times", "0,30 2-3 * * *", newYork, "2024-03-10T07:00:00Z", "2024-03-10T07:30:00Z"},
+		{"repeated by DST end", "30 1 * * *", newYork, "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		{"repeated by DST end from first pass", "30 1 * * *", newYork, "2024-11-03T05:00:00Z", "2024-11-03T05:30:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse(test.expression, test.location)
+			require.NoError(t, err)
+
+			from, err := time.Parse(time.RFC3339, test.from)
+			require.NoError(t, err)
+
+			next := schedule.Next(from)
+
+			assert.Equal(t, test.expected, next.UTC().Format(time.RFC3339))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	expressions := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every 5m",
+	}
+
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			_, err := Parse(expression, nil)
+
+			assert.ErrorIs(t, err, ErrInvalidExpression)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	gdTaskIDField     = "gdTaskID"
+	gameServerIDField = "gameServerID"
+
+	gdTasksDir = "gdtasks"
+	serversDir = "servers"
+)
+
+// FilesHook writes log entries of GameAP Daemon tasks and game servers to separate files
+// in the logs directory: gdtasks/<id>.log and servers/<id>.log.
+type FilesHook struct {
+	path      string
+	formatter log.Formatter
+	cfg       config.Config
+
+	mu      sync.Mutex
+	servers map[string]*lumberjack.Logger
+}
+
+func NewFilesHook(path string, formatter log.Formatter, cfg config.Config) *FilesHook {
+	return &FilesHook{
+		path:      path,
+		formatter: formatter,
+		cfg:       cfg,
+		servers:   map[string]*lumberjack.Logger{},
+	}
+}
+
+func (h *FilesHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *FilesHook) Fire(entry *log.Entry) error {
+	taskID, hasTask := entry.Data[gdTaskIDField]
+	serverID, hasServer := entry.Data[gameServerIDField]
+
+	if !hasTask && !hasServer {
+		return nil
+	}
+
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	if hasTask {
+		err = h.writeTaskLog(fileName(taskID), line)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasServer {
+		err = h.writeServerLog(fileName(serverID), line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTaskOutput appends the raw task output to the task log file.
+func (h *FilesHook) WriteTaskOutput(taskID int, output []byte) error {
+	return h.writeTaskLog(fileName(taskID), output)
+}
+
+func (h *FilesHook) writeTaskLog(name string, data []byte) error {
+	path := filepath.Join(h.path, gdTasksDir, name+".log")
+
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create task logs directory")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return errors.WithMessage(err, "failed to open task log file")
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+
+	return err
+}
+
+func (h *FilesHook) writeServerLog(name string, data []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writer, ok := h.servers[name]
+	if !ok {
+		// Game server logs are always rotated, lumberjack uses 100 megabytes if max size is not set
+		writer = newRotatedWriter(filepath.Join(h.path, serversDir, name+".log"), h.cfg)
+		h.servers[name] = writer
+	}
+
+	_, err := writer.Write(data)
+
+	return err
+}
+
+// TaskOutput writes the task output to the task log file if per-task log files are enabled.
+func TaskOutput(ctx context.Context, taskID int, output []byte) {
+	hook := findFilesHook(Logger(ctx))
+	if hook == nil {
+		return
+	}
+
+	err := hook.WriteTaskOutput(taskID, output)
+	if err != nil {
+		WithError(ctx, err).Warn("Failed to write task output to the log file")
+	}
+}
+
+func findFilesHook(fieldLogger log.FieldLogger) *FilesHook {
+	var logger *log.Logger
+
+	switch l := fieldLogger.(type) {
+	case *log.Logger:
+		logger = l
+	case *log.Entry:
+		logger = l.Logger
+	default:
+		return nil
+	}
+
+	if logger == nil {
+		return nil
+	}
+
+	for _, hook := range logger.Hooks[log.InfoLevel] {
+		if filesHook, ok := hook.(*FilesHook); ok {
+			return filesHook
+		}
+	}
+
+	return nil
+}
+
+func fileName(id interface{}) string {
+	return filepath.Base(filepath.Clean(fmt.Sprint(id)))
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenLogger(t *testing.T) (*log.Logger, string) {
+	t.Helper()
+
+	logsPath := t.TempDir()
+	cfg := config.Config{
+		LogLevel:  "info",
+		LogFormat: "json",
+		LogsPath:  logsPath,
+	}
+
+	logger := NewLogger(cfg)
+	logger.SetOutput(io.Discard)
+
+	return logger, logsPath
+}
+
+func TestFilesHook_EntriesWrittenToTaskAndServerFiles(t *testing.T) {
+	logger, logsPath := givenLogger(t)
+	ctx := WithLogger(context.Background(), logger.WithFields(log.Fields{
+		"gdTaskID":     12,
+		"gameServerID": 3,
+	}))
+
+	Info(ctx, "Task completed")
+	logger.Info("Daemon started")
+
+	taskLog, err := os.ReadFile(filepath.Join(logsPath, "gdtasks", "12.log"))
+	require.NoError(t, err)
+	serverLog, err := os.ReadFile(filepath.Join(logsPath, "servers", "3.log"))
+	require.NoError(t, err)
+	assert.Equal(t, taskLog, serverLog)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(taskLog, &entry))
+	assert.Equal(t, "Task completed", entry["msg"])
+	assert.Equal(t, float64(12), entry["gdTaskID"])
+	assert.Equal(t, float64(3), entry["gameServerID"])
+}
+
+func TestTaskOutput_OutputAppendedToTaskFile(t *testing.T) {
+	logger, logsPath := givenLogger(t)
+	ctx := WithLogger(context.Background(), logger.WithField("gdTaskID", 7))
+
+	TaskOutput(ctx, 7, []byte("Downloading files...\n"))
+	TaskOutput(ctx, 7, []byte("Done\n"))
+
+	taskLog, err := os.ReadFile(filepath.Join(logsPath, "gdtasks", "7.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Downloading files...\nDone\n", string(taskLog))
+}
+
+func TestTaskOutput_FilesHookDisabled(t *testing.T) {
+	ctx := WithLogger(context.Background(), log.New())
+
+	assert.NotPanics(t, func() {
+		TaskOutput(ctx, 7, []byte("output"))
+	})
+}
+
+func TestNewLogger_ErrorLogContainsOnlyErrors(t *testing.T) {
+	errorLog := filepath.Join(t.TempDir(), "error.log")
+	logger := NewLogger(config.Config{
+		LogLevel: "debug",
+		ErrorLog: errorLog,
+	})
+	logger.SetOutput(io.Discard)
+
+	logger.Info("Info message")
+	logger.Error("Error message")
+
+	content, err := os.ReadFile(errorLog)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "Info message")
+	assert.Equal(t, 1, strings.Count(string(content), "Error message"))
+}
@@ -23,12 +23,41 @@ func NewLogger(cfg config.Config) *log.Logger {
 	logger := log.New()
 	logger.SetLevel(defineLogLevel(cfg))
 
-	logger.SetFormatter(&log.TextFormatter{})
+	if cfg.LogFormat == jsonFormat {
+		logger.SetFormatter(&log.JSONFormatter{})
+	} else {
+		logger.SetFormatter(&log.TextFormatter{})
+	}
+
+	if cfg.LogsPath != "" {
+		logger.AddHook(NewFilesHook(cfg.LogsPath, fileFormatter(cfg), cfg))
+	}
+
+	if cfg.ErrorLog != "" {
+		errorLog, err := newLogWriter(cfg.ErrorLog, cfg)
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.AddHook(newWriterHook(errorLog, fileFormatter(cfg), log.ErrorLevel))
+		}
+	}
 
 	if cfg.OutputLog == "" {
 		return logger
 	}
 
+	if rotationEnabled(cfg) {
+		outputLog, err := newLogWriter(cfg.OutputLog, cfg)
+		if err != nil {
+			logger.Error(err)
+			return logger
+		}
+
+		logger.SetOutput(outputLog)
+
+		return logger
+	}
+
 	oldLogFile, err := os.Stat(cfg.OutputLog)
 	if err == nil {
 		name := strings.TrimSuffix(oldLogFile.Name(), filepath.Ext(oldLogFile.Name()))
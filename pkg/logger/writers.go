@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const jsonFormat = "json"
+
+func rotationEnabled(cfg config.Config) bool {
+	return cfg.LogRotation.MaxSize > 0
+}
+
+// newLogWriter opens the log file for appending. If rotation is enabled,
+// the file is rotated by size and age.
+func newLogWriter(path string, cfg config.Config) (io.Writer, error) {
+	if rotationEnabled(cfg) {
+		return newRotatedWriter(path, cfg), nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create log directory")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open log file")
+	}
+
+	return file, nil
+}
+
+func newRotatedWriter(path string, cfg config.Config) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.LogRotation.MaxSize,
+		MaxAge:     cfg.LogRotation.MaxAge,
+		MaxBackups: cfg.LogRotation.MaxBackups,
+		Compress:   cfg.LogRotation.Compress,
+	}
+}
+
+// fileFormatter returns the formatter for log files written by hooks.
+func fileFormatter(cfg config.Config) log.Formatter {
+	if cfg.LogFormat == jsonFormat {
+		return &log.JSONFormatter{}
+	}
+
+	return &log.TextFormatter{
+		DisableColors: true,
+		FullTimestamp: true,
+	}
+}
+
+// writerHook writes entries of the given level and more severe ones to the writer.
+type writerHook struct {
+	writer    io.Writer
+	formatter log.Formatter
+	levels    []log.Level
+}
+
+func newWriterHook(writer io.Writer, formatter log.Formatter, minLevel log.Level) *writerHook {
+	levels := make([]log.Level, 0, len(log.AllLevels))
+	for _, level := range log.AllLevels {
+		if level <= minLevel {
+			levels = append(levels, level)
+		}
+	}
+
+	return &writerHook{
+		writer:    writer,
+		formatter: formatter,
+		levels:    levels,
+	}
+}
+
+func (h *writerHook) Levels() []log.Level {
+	return h.levels
+}
+
+func (h *writerHook) Fire(entry *log.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.writer.Write(line)
+
+	return err
+}
@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+func (suite *Suite) TestGameCachePruneSuccess() {
+	err := suite.GameCache.Store(context.Background(), "steam:90", "1337", suite.WorkPath+"/server")
+	suite.Require().NoError(err)
+	task := suite.GivenGDTask(domain.GDTaskGameCachePrune, "")
+
+	suite.RunTaskManagerUntilTasksCompleted([]*domain.GDTask{task})
+
+	suite.AssertGDTaskExist(
+		domain.NewGDTask(
+			task.ID(),
+			task.RunAfterID(),
+			nil,
+			task.Task(),
+			task.Command(),
+			domain.GDTaskStatusSuccess,
+		),
+	)
+	suite.Assert().Empty(suite.GameCache.Version("steam:90"))
+}
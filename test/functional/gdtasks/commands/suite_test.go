@@ -40,6 +40,7 @@ func (suite *Suite) SetupTest() {
 
 	suite.Cfg.WorkPath = suite.WorkPath
 	suite.Cfg.ToolsPath = filepath.Join(suite.WorkPath, "tools")
+	suite.Cfg.GameCache.Path = filepath.Join(suite.WorkPath, "cache")
 }
 
 func (suite *Suite) TearDownTest() {
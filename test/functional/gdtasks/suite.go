@@ -14,8 +14,12 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
 	"github.com/gameap/daemon/internal/app/services"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/test/functional"
 	"github.com/gameap/daemon/test/mocks"
@@ -32,6 +36,7 @@ type Suite struct {
 	Executor         contracts.Executor
 	ProcessManager   contracts.ProcessManager
 	Cache            contracts.Cache
+	GameCache        *gamecache.Cache
 	Cfg              *config.Config
 
 	WorkPath string
@@ -46,9 +51,13 @@ func (suite *Suite) SetupTest() {
 			suite.ServerRepository,
 			suite.Executor,
 			suite.ProcessManager,
+			steamcmd.NewSteamCMD(suite.Cfg, suite.Executor),
+			suite.GameCache,
+			verification.NewVerifier(suite.Cfg),
 		),
 		suite.Executor,
 		suite.Cfg,
+		watchdog.NewWatchdog(),
 	)
 }
 
@@ -68,7 +77,10 @@ func (suite *Suite) SetupSuite() {
 	executor := components.NewDefaultExtendableExecutor(components.NewCleanExecutor())
 	suite.ProcessManager = processmanager.NewSimple(suite.Cfg, executor, executor)
 
-	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(suite.Cfg).Handle)
+	suite.GameCache = gamecache.NewCache(suite.Cfg)
+
+	executor.RegisterHandler("get-tool", customhandlers.NewGetTool(suite.Cfg, verification.NewVerifier(suite.Cfg)).Handle)
+	executor.RegisterHandler("cache-prune", customhandlers.NewCachePrune(suite.GameCache).Handle)
 	suite.Executor = executor
 
 	suite.Cache, err = services.NewLocalCache(suite.Cfg)
@@ -150,13 +162,17 @@ func (suite *Suite) AssertGDTaskExist(task *domain.GDTask) {
 }
 
 func (suite *Suite) GivenGDTaskWithCommand(cmd string) *domain.GDTask {
+	return suite.GivenGDTask(domain.GDTaskCommandExecute, cmd)
+}
+
+func (suite *Suite) GivenGDTask(taskCommand domain.GDTaskCommand, cmd string) *domain.GDTask {
 	minID := 100
 	maxID := 1000000000
 	task := domain.NewGDTask(
 		rand.Intn(maxID-minID)+minID,
 		0,
 		nil,
-		domain.GDTaskCommandExecute,
+		taskCommand,
 		cmd,
 		domain.GDTaskStatusWaiting,
 	)
@@ -133,3 +133,31 @@ var JSONApiGetServersTasks = []byte(`
     }
 ]
 `)
+
+var JSONApiGetServersTasksWithCron = []byte(`
+[
+    {
+        "id": 2,
+        "command": "restart",
+        "server_id": 1,
+        "repeat": 0,
+        "repeat_period": 0,
+        "counter": 0,
+        "execute_date": "2021-11-14 06:00:00",
+        "cron": "0 6 * * *",
+        "timezone": "Europe/Berlin",
+        "jitter": 300,
+        "skip_if_players_online": true
+    },
+    {
+        "id": 3,
+        "command": "restart",
+        "server_id": 1,
+        "repeat": 0,
+        "repeat_period": 0,
+        "counter": 0,
+        "execute_date": "2021-11-14 06:00:00",
+        "cron": "0 25 * * *"
+    }
+]
+`)
@@ -37,6 +37,33 @@ func (suite *Suite) TestFind_Success() {
 	suite.Assert().Equal(time.Date(2021, 11, 14, 0, 0, 0, 0, time.UTC), tasks[0].ExecuteDate())
 }
 
+func (suite *Suite) TestFind_WithCron_ExpectScheduleParsedAndInvalidTaskSkipped() {
+	suite.GivenAPIResponse(
+		"/gdaemon_api/servers_tasks",
+		http.StatusOK,
+		repositoriestest.JSONApiGetServersTasksWithCron,
+	)
+	suite.GivenAPIResponse(
+		"/gdaemon_api/servers/1",
+		http.StatusOK,
+		repositoriestest.JSONApiGetServerResponseBody,
+	)
+
+	tasks, err := suite.ServerTaskRepository.Find(context.Background())
+
+	suite.Require().Nil(err)
+	suite.Require().Len(tasks, 1)
+	suite.Assert().Equal(2, tasks[0].ID())
+	suite.Require().NotNil(tasks[0].Schedule())
+	suite.Assert().Equal(5*time.Minute, tasks[0].Jitter())
+	suite.Assert().True(tasks[0].SkipIfPlayersOnline())
+	// 06:00 in Berlin is 05:00 UTC in November
+	suite.Assert().Equal(
+		time.Date(2021, 11, 15, 5, 0, 0, 0, time.UTC),
+		tasks[0].Schedule().Next(time.Date(2021, 11, 14, 6, 0, 0, 0, time.UTC)).UTC(),
+	)
+}
+
 func (suite *Suite) TestSave_Success() {
 	suite.GivenAPIResponse("/gdaemon_api/servers_tasks/2", http.StatusOK, nil)
 	task := domain.NewServerTask(
@@ -2,7 +2,12 @@ package servertasks
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/cron"
+	"github.com/gameap/daemon/test/mocks"
 )
 
 func (suite *Suite) TestScheduler_ExpectTaskExecutedAndUpdated() {
@@ -30,3 +35,98 @@ func (suite *Suite) TestScheduler_ExpectTaskDidNotExecuteAndUpdated() {
 	suite.Assert().Equal(0, task.Repeat())
 	suite.Assert().Equal(executeDate, task.ExecuteDate())
 }
+
+func (suite *Suite) TestScheduler_CronTask_ExpectTaskExecutedAndRescheduled() {
+	schedule, err := cron.Parse("0 6 * * *", time.UTC)
+	suite.Require().NoError(err)
+	task := suite.GivenTask(3, time.Now().Add(1*time.Second), 0)
+	task.SetSchedule(schedule, 0)
+
+	suite.RunServerSchedulerUntilTaskCounterIncreased(task)
+
+	task, _ = suite.ServerTaskRepository.FindByID(context.Background(), 3)
+	suite.Assert().Equal(1, task.Counter())
+	suite.Assert().Equal(schedule.Next(time.Now()), task.ExecuteDate())
+}
+
+func (suite *Suite) TestScheduler_PlayersOnline_ExpectTaskSkippedAndRescheduled() {
+	suite.PlayersCounter.Players = 3
+	task := suite.GivenTask(4, time.Now().Add(-1*time.Second), 10*time.Minute)
+	task.SetSkipIfPlayersOnline(true)
+
+	suite.RunServerSchedulerWithTimeout(2 * time.Second)
+
+	task, _ = suite.ServerTaskRepository.FindByID(context.Background(), 4)
+	suite.Assert().Equal(1, suite.PlayersCounter.Queried())
+	suite.Assert().Equal(0, task.Counter())
+	suite.Assert().True(task.ExecuteDate().After(time.Now().Add(9 * time.Minute)))
+	suite.Assert().NoFileExists(suite.WorkPath + "/server/file.txt")
+}
+
+func (suite *Suite) TestScheduler_PlayersQueryFailed_ExpectTaskExecuted() {
+	suite.PlayersCounter.Err = errors.New("no response")
+	task := suite.GivenTask(5, time.Now().Add(1*time.Second), 10*time.Minute)
+	task.SetSkipIfPlayersOnline(true)
+
+	suite.RunServerSchedulerUntilTaskCounterIncreased(task)
+
+	task, _ = suite.ServerTaskRepository.FindByID(context.Background(), 5)
+	suite.Assert().Equal(1, task.Counter())
+}
+
+func (suite *Suite) TestScheduler_Announcements_ExpectCountdownAnnouncedBeforeExecution() {
+	suite.Cfg.Announcements.Intervals = []time.Duration{1 * time.Second, 2 * time.Second}
+	suite.Cfg.Announcements.Commands = []string{"start"}
+	executeDate := time.Now().Add(3 * time.Second)
+	task := suite.GivenTask(6, executeDate, 10*time.Minute)
+
+	suite.RunServerSchedulerUntilTaskCounterIncreased(task)
+
+	suite.Assert().Equal([]mocks.Announcement{
+		{ServerID: task.Server().ID(), Action: "start", Left: 2 * time.Second},
+		{ServerID: task.Server().ID(), Action: "start", Left: 1 * time.Second},
+	}, suite.Announcer.Announcements())
+	suite.Assert().Equal(1, task.Counter())
+	suite.Assert().FileExists(suite.WorkPath + "/server/file.txt")
+}
+
+func (suite *Suite) TestScheduler_Announcements_SchedulerStopped_ExpectCountdownAborted() {
+	suite.Cfg.Announcements.Intervals = []time.Duration{1 * time.Second, 2 * time.Second}
+	suite.Cfg.Announcements.Commands = []string{"start"}
+	task := suite.GivenTask(7, time.Now().Add(3*time.Second), 10*time.Minute)
+
+	suite.RunServerSchedulerWithTimeout(1500 * time.Millisecond)
+
+	suite.Assert().Eventually(func() bool {
+		return len(suite.Announcer.Announcements()) == 2
+	}, 2*time.Second, 50*time.Millisecond)
+	suite.Assert().Equal([]mocks.Announcement{
+		{ServerID: task.Server().ID(), Action: "start", Left: 2 * time.Second},
+		{ServerID: task.Server().ID(), Action: "start", Aborted: true},
+	}, suite.Announcer.Announcements())
+	suite.Assert().Equal(0, task.Counter())
+	suite.Assert().NoFileExists(suite.WorkPath + "/server/file.txt")
+}
+
+func (suite *Suite) TestScheduler_Announcements_TasksOfSameServer_ExpectExecutedOneByOne() {
+	suite.Cfg.Announcements.Intervals = []time.Duration{1 * time.Second}
+	suite.Cfg.Announcements.Commands = []string{"start"}
+	server := suite.GivenServerWithStartCommand("./sleep_and_check.sh")
+	executeDate := time.Now().Add(1 * time.Second)
+	first := domain.NewServerTask(8, domain.ServerTaskStart, server, 0, 10*time.Minute, 0, executeDate)
+	second := domain.NewServerTask(9, domain.ServerTaskStart, server, 0, 10*time.Minute, 0, executeDate)
+	suite.ServerRepository.Set([]*domain.Server{server})
+	suite.ServerTaskRepository.Set([]*domain.ServerTask{first, second})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = suite.Scheduler.Run(ctx)
+	}()
+
+	suite.Assert().Eventually(func() bool {
+		return first.Counter() == 1 && second.Counter() == 1
+	}, 15*time.Second, 50*time.Millisecond)
+	suite.Assert().FileExists(suite.WorkPath + "/server/sleep_and_check.txt")
+	suite.Assert().NoFileExists(suite.WorkPath + "/server/sleep_and_check_fail.txt")
+}
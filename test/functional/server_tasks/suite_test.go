@@ -11,7 +11,11 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
 	serversscheduler "github.com/gameap/daemon/internal/app/servers_scheduler"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
+	"github.com/gameap/daemon/internal/app/watchdog"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/test/functional"
 	"github.com/gameap/daemon/test/mocks"
@@ -25,6 +29,8 @@ type Suite struct {
 	Scheduler            *serversscheduler.Scheduler
 	ServerTaskRepository *mocks.ServerTaskRepository
 	ServerRepository     *mocks.ServerRepository
+	PlayersCounter       *mocks.PlayersCounter
+	Announcer            *mocks.ServerAnnouncer
 	Executor             contracts.Executor
 	ProcessManager       contracts.ProcessManager
 	Cfg                  *config.Config
@@ -55,6 +61,10 @@ func (suite *Suite) SetupTest() {
 
 	suite.ServerRepository.Clear()
 	suite.ServerTaskRepository.Clear()
+	suite.PlayersCounter = &mocks.PlayersCounter{}
+	suite.Announcer = &mocks.ServerAnnouncer{}
+	suite.Cfg.Announcements.Intervals = nil
+	suite.Cfg.Announcements.Commands = nil
 
 	suite.Scheduler = serversscheduler.NewScheduler(
 		suite.Cfg,
@@ -64,7 +74,13 @@ func (suite *Suite) SetupTest() {
 			suite.ServerRepository,
 			suite.Executor,
 			suite.ProcessManager,
+			steamcmd.NewSteamCMD(suite.Cfg, suite.Executor),
+			gamecache.NewCache(suite.Cfg),
+			verification.NewVerifier(suite.Cfg),
 		),
+		watchdog.NewWatchdog(),
+		suite.PlayersCounter,
+		suite.Announcer,
 	)
 
 	suite.WorkPath, err = os.MkdirTemp(os.TempDir(), "gameap-daemon-test")
@@ -8,6 +8,9 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/gamecache"
+	"github.com/gameap/daemon/internal/app/steamcmd"
+	"github.com/gameap/daemon/internal/app/verification"
 	"github.com/gameap/daemon/internal/processmanager"
 	"github.com/gameap/daemon/test/functional"
 	"github.com/gameap/daemon/test/mocks"
@@ -36,7 +39,15 @@ func (suite *NotInstalledServerSuite) SetupSuite() {
 	suite.Executor = components.NewCleanExecutor()
 	suite.ProcessManager = processmanager.NewSimple(suite.Cfg, suite.Executor, suite.Executor)
 
-	suite.CommandFactory = gameservercommands.NewFactory(suite.Cfg, suite.ServerRepository, suite.Executor, suite.ProcessManager)
+	suite.CommandFactory = gameservercommands.NewFactory(
+		suite.Cfg,
+		suite.ServerRepository,
+		suite.Executor,
+		suite.ProcessManager,
+		steamcmd.NewSteamCMD(suite.Cfg, suite.Executor),
+		gamecache.NewCache(suite.Cfg),
+		verification.NewVerifier(suite.Cfg),
+	)
 }
 
 func (suite *NotInstalledServerSuite) SetupTest() {
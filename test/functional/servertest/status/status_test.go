@@ -31,3 +31,68 @@ func (suite *Suite) TestVersionAndStatusSuccess() {
 	suite.Require().Equal(response.StatusOK, response.Code(r1[0].(uint8)))
 	suite.Require().Equal(response.StatusOK, response.Code(r2[0].(uint8)))
 }
+
+func (suite *Suite) TestStatusDetailsSuccess() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.StatusDetails})
+
+	suite.Require().Equal(response.StatusOK, response.Code(r[0].(uint8)))
+	suite.Require().Len(r, 6)
+	suite.Assert().Equal([]interface{}{
+		[]interface{}{"steamcmd", "ok"},
+		[]interface{}{"disk", "not enough disk space"},
+	}, r[5])
+}
+
+func (suite *Suite) TestFreePortsSuccess() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.FreePorts})
+
+	suite.Require().Equal(response.StatusOK, response.Code(r[0].(uint8)))
+	suite.Require().Len(r, 2)
+	suite.Assert().Equal([]interface{}{
+		[]interface{}{"1.3.3.7", []interface{}{
+			[]interface{}{uint16(27015), uint16(27020)},
+			[]interface{}{uint16(27030), uint16(27999)},
+		}},
+		[]interface{}{"1.3.3.8", []interface{}(nil)},
+	}, r[1])
+}
+
+func (suite *Suite) TestFreePortsByIPSuccess() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.FreePorts, "1.3.3.8", 27015, 27999})
+
+	suite.Require().Equal(response.StatusOK, response.Code(r[0].(uint8)))
+	suite.Assert().Equal([]interface{}{
+		[]interface{}{"1.3.3.8", []interface{}(nil)},
+	}, r[1])
+}
+
+func (suite *Suite) TestFreePortsInvalidRequest() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.FreePorts, 1337})
+
+	suite.Require().Equal(response.StatusError, response.Code(r[0].(uint8)))
+}
+
+func (suite *Suite) TestCancelTaskSuccess() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.CancelTask, 42})
+
+	suite.Require().Equal(response.StatusOK, response.Code(r[0].(uint8)))
+	suite.Assert().Contains(suite.TaskCanceler.Canceled(), 42)
+}
+
+func (suite *Suite) TestCancelTaskInvalidRequest() {
+	suite.Auth(server.ModeStatus)
+
+	r := suite.ClientWriteReadAndDecodeList([]interface{}{status.CancelTask, "42"})
+
+	suite.Require().Equal(response.StatusError, response.Code(r[0].(uint8)))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/et-nik/binngo/decode"
 	"github.com/gameap/daemon/internal/app/components"
 	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/internal/app/server"
 	"github.com/gameap/daemon/internal/app/server/response"
 	"github.com/gameap/daemon/test/mocks"
@@ -35,13 +36,27 @@ type Suite struct {
 
 	Executor        contracts.Executor
 	TaskStatsReader *mocks.TasksStatsReader
+	TaskCanceler    *mocks.GDTaskCanceler
+	PortAllocator   *mocks.PortAllocator
+	HealthCheckers  []contracts.HealthChecker
 }
 
 func (suite *Suite) SetupSuite() {
 	var err error
 
 	suite.TaskStatsReader = &mocks.TasksStatsReader{}
+	suite.TaskCanceler = &mocks.GDTaskCanceler{}
 	suite.Executor = components.NewCleanExecutor()
+	suite.PortAllocator = &mocks.PortAllocator{
+		Ports: []domain.FreePorts{
+			{IP: "1.3.3.7", Ranges: []domain.PortRange{{From: 27015, To: 27020}, {From: 27030, To: 27999}}},
+			{IP: "1.3.3.8", Ranges: []domain.PortRange{}},
+		},
+	}
+	suite.HealthCheckers = []contracts.HealthChecker{
+		&mocks.HealthChecker{CheckName: "steamcmd"},
+		&mocks.HealthChecker{CheckName: "disk", Err: errors.New("not enough disk space")},
+	}
 
 	suite.Server, err = server.NewServer(
 		"127.0.0.1",
@@ -55,6 +70,9 @@ func (suite *Suite) SetupSuite() {
 		},
 		suite.Executor,
 		suite.TaskStatsReader,
+		suite.TaskCanceler,
+		suite.PortAllocator,
+		suite.HealthCheckers...,
 	)
 	if err != nil {
 		suite.T().Fatal(err)
@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+type Announcement struct {
+	ServerID int
+	Action   string
+	Left     time.Duration
+	Aborted  bool
+}
+
+type ServerAnnouncer struct {
+	mu            sync.Mutex
+	announcements []Announcement
+}
+
+func (a *ServerAnnouncer) AnnounceCountdown(
+	_ context.Context, server *domain.Server, action string, left time.Duration,
+) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.announcements = append(a.announcements, Announcement{ServerID: server.ID(), Action: action, Left: left})
+
+	return nil
+}
+
+func (a *ServerAnnouncer) AnnounceAborted(_ context.Context, server *domain.Server, action string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.announcements = append(a.announcements, Announcement{ServerID: server.ID(), Action: action, Aborted: true})
+
+	return nil
+}
+
+func (a *ServerAnnouncer) Announcements() []Announcement {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]Announcement{}, a.announcements...)
+}
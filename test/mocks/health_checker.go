@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+)
+
+type HealthChecker struct {
+	CheckName string
+	Err       error
+}
+
+func (h *HealthChecker) Name() string {
+	return h.CheckName
+}
+
+func (h *HealthChecker) Check(_ context.Context) error {
+	return h.Err
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+type PlayersCounter struct {
+	Players int
+	Err     error
+
+	queried atomic.Int32
+}
+
+func (c *PlayersCounter) OnlinePlayers(_ context.Context, _ *domain.Server) (int, error) {
+	c.queried.Add(1)
+
+	return c.Players, c.Err
+}
+
+func (c *PlayersCounter) Queried() int {
+	return int(c.queried.Load())
+}
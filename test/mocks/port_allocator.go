@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+type PortAllocator struct {
+	Ports []domain.FreePorts
+	Err   error
+}
+
+func (a *PortAllocator) FreePorts(_ context.Context, ip string, _, _ int) ([]domain.FreePorts, error) {
+	if ip == "" {
+		return a.Ports, a.Err
+	}
+
+	for _, p := range a.Ports {
+		if p.IP == ip {
+			return []domain.FreePorts{p}, a.Err
+		}
+	}
+
+	return []domain.FreePorts{}, a.Err
+}
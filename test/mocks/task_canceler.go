@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+)
+
+type GDTaskCanceler struct {
+	Err error
+
+	mu       sync.Mutex
+	canceled []int
+}
+
+func (c *GDTaskCanceler) CancelTask(_ context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Err != nil {
+		return c.Err
+	}
+
+	c.canceled = append(c.canceled, id)
+
+	return nil
+}
+
+func (c *GDTaskCanceler) Canceled() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.canceled
+}